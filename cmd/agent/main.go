@@ -2,9 +2,11 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/TejParker/bigdata-manager/internal/logtail"
 	"github.com/TejParker/bigdata-manager/pkg/model"
 	"log"
 	"net/http"
@@ -27,6 +29,7 @@ var (
 	heartbeatSec  int
 	collectionSec int
 	apiEndpoint   string
+	logConfig     string
 	version       = "0.1.0"
 )
 
@@ -46,6 +49,7 @@ func init() {
 	flag.IntVar(&hostID, "id", 0, "主机ID")
 	flag.IntVar(&heartbeatSec, "heartbeat", 10, "心跳间隔(秒)")
 	flag.IntVar(&collectionSec, "collection", 15, "指标收集间隔(秒)")
+	flag.StringVar(&logConfig, "log-config", "", "日志采集配置文件，为空时不采集日志")
 	flag.Parse()
 
 	if hostID == 0 {
@@ -72,6 +76,10 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// 启动日志采集
+	stopLogTailer := startLogTailer()
+	defer stopLogTailer()
+
 	// 发送首次心跳
 	sendHeartbeat()
 
@@ -91,6 +99,35 @@ func main() {
 	}
 }
 
+// startLogTailer 按配置启动日志文件采集，返回的函数停止采集并等待读取位置保存完成
+func startLogTailer() func() {
+	if logConfig == "" {
+		return func() {}
+	}
+
+	cfg, err := logtail.LoadConfig(logConfig)
+	if err != nil {
+		log.Fatalf("加载日志采集配置失败: %v", err)
+	}
+	tailer, err := logtail.New(cfg, logtail.NewHTTPShipper(serverAddr, hostID))
+	if err != nil {
+		log.Fatalf("初始化日志采集失败: %v", err)
+	}
+	log.Printf("日志采集已启动, 共 %d 个日志源\n", len(cfg.Sources))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tailer.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
 // sendHeartbeat 发送心跳请求
 func sendHeartbeat() {
 	metrics := collectMetrics()
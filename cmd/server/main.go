@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/TejParker/bigdata-manager/internal/api"
+	"github.com/TejParker/bigdata-manager/internal/auth"
+	"github.com/TejParker/bigdata-manager/internal/config"
 	"github.com/TejParker/bigdata-manager/internal/db"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
 )
 
 func init() {
@@ -33,6 +39,36 @@ func main() {
 		log.Fatalf("初始化数据库失败: %v", err)
 	}
 	defer db.CloseDB()
+
+	// 加载告警、通知相关配置
+	var cfg config.Config
+	if err := viper.Unmarshal(&cfg); err != nil {
+		log.Fatalf("解析配置失败: %v", err)
+	}
+
+	// 同步告警、通知数据表结构
+	if err := model.AutoMigrate(db.GormDB); err != nil {
+		log.Fatalf("同步数据表失败: %v", err)
+	}
+
+	// 初始化认证提供者链
+	if err := auth.InitProviders(&cfg.Auth); err != nil {
+		log.Fatalf("初始化认证失败: %v", err)
+	}
+
+	// 初始化告警服务，启动通知发送队列、表达式规则评估和日志索引
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.InitServices(db.GormDB, &cfg)
+	service.GetNotificationService().StartQueue(ctx)
+	service.GetAlertService().StartExpressionEvaluator(ctx, time.Duration(cfg.Alert.ProcessInterval)*time.Second)
+	service.GetAlertService().StartLogEvaluator(ctx, time.Duration(cfg.Alert.ProcessInterval)*time.Second)
+	service.GetAlertService().StartNotificationDispatcher(ctx)
+	service.GetEscalationService().Start(ctx, time.Duration(cfg.Alert.ProcessInterval)*time.Second)
+	service.GetLogService().Start(ctx)
+	service.GetLogPatternService().Start(ctx)
+	service.GetRetentionService().Start(ctx)
+	service.GetExportService().Start(ctx)
 	
 	// 设置API路由
 	router := api.SetupRouter()
@@ -53,4 +89,6 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("正在关闭服务器...")
+	cancel()
+	service.GetLogService().Close()
 } 
\ No newline at end of file
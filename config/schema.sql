@@ -142,6 +142,8 @@ CREATE TABLE IF NOT EXISTS log_record (
     service_id INT,
     component_id INT,
     log_level VARCHAR(16),
+    logger VARCHAR(255),
+    thread VARCHAR(255),
     timestamp TIMESTAMP NOT NULL,
     message TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -188,6 +190,7 @@ CREATE TABLE IF NOT EXISTS user (
     email VARCHAR(128),
     phone VARCHAR(32),
     status ENUM('ACTIVE', 'DISABLED') DEFAULT 'ACTIVE',
+    auth_source VARCHAR(20) NOT NULL DEFAULT 'LOCAL',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     UNIQUE KEY (username)
@@ -252,20 +255,21 @@ INSERT INTO privilege (name, description) VALUES
 ('VIEW_METRIC', '查看监控指标'),
 ('MANAGE_ALERT', '管理告警规则'),
 ('VIEW_ALERT', '查看告警'),
-('MANAGE_USER', '管理用户和权限');
+('MANAGE_USER', '管理用户和权限'),
+('VIEW_AUDIT', '查看和导出审计日志');
 
 -- 为角色分配权限
 -- 管理员拥有所有权限
 INSERT INTO role_privilege (role_id, privilege_id)
 SELECT 1, id FROM privilege;
 
--- 运维人员拥有除用户管理外的所有权限
+-- 运维人员拥有除用户管理和审计外的所有权限
 INSERT INTO role_privilege (role_id, privilege_id)
-SELECT 2, id FROM privilege WHERE name != 'MANAGE_USER';
+SELECT 2, id FROM privilege WHERE name NOT IN ('MANAGE_USER', 'VIEW_AUDIT');
 
--- 只读用户只有查看权限
+-- 只读用户只有查看权限，不包括审计日志
 INSERT INTO role_privilege (role_id, privilege_id)
-SELECT 3, id FROM privilege WHERE name LIKE 'VIEW_%';
+SELECT 3, id FROM privilege WHERE name LIKE 'VIEW_%' AND name != 'VIEW_AUDIT';
 
 -- 创建默认管理员用户 (密码为 admin)
 INSERT INTO user (username, password_hash, email) 
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetAlertEvents 获取告警事件列表
+func GetAlertEvents(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{}
+	if status := c.Query("status"); status != "" {
+		filters["status = ?"] = status
+	}
+	if severity := c.Query("severity"); severity != "" {
+		filters["severity = ?"] = severity
+	}
+	if ruleID := c.Query("alert_rule_id"); ruleID != "" {
+		filters["alert_rule_id = ?"] = ruleID
+	}
+	// 只返回有权查看的集群和服务的告警
+	if condition, args := scopeFilter(requestAccess(c), "cluster_id", "service_id"); condition != "" {
+		filters[condition] = args
+	}
+
+	events, total, err := service.GetAlertService().ListAlertEvents(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询告警事件列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, events, int(total), page, pageSize)
+}
+
+// getAlertEvent 根据路径参数获取告警事件，并检查当前用户能否访问事件所属的集群和服务
+func getAlertEvent(c *gin.Context) (*imodel.AlertEvent, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	event, err := service.GetAlertService().GetAlertEvent(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "告警事件不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询告警事件失败")
+		}
+		return nil, false
+	}
+
+	// 没有集群和服务的告警只有全局权限能访问
+	var clusterID, serviceID int
+	if event.ClusterID != nil {
+		clusterID = int(*event.ClusterID)
+	}
+	if event.ServiceID != nil {
+		serviceID = int(*event.ServiceID)
+	}
+	if !requestAccess(c).AllowService(clusterID, serviceID) {
+		ResponseError(c, http.StatusForbidden, "无权访问该告警事件")
+		return nil, false
+	}
+	return event, true
+}
+
+// GetAlertEventById 根据ID获取告警事件
+func GetAlertEventById(c *gin.Context) {
+	event, ok := getAlertEvent(c)
+	if !ok {
+		return
+	}
+
+	ResponseSuccess(c, event)
+}
+
+// AcknowledgeAlertEvent 确认告警事件，确认后停止升级通知
+func AcknowledgeAlertEvent(c *gin.Context) {
+	event, ok := getAlertEvent(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetAlertService().AcknowledgeAlertEvent(event.ID, uint(c.GetInt("userID"))); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "告警事件不存在")
+		} else {
+			ResponseError(c, http.StatusBadRequest, "确认告警失败: "+err.Error())
+		}
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "告警已确认", nil)
+}
+
+// ResolveAlertEvent 解决告警事件
+func ResolveAlertEvent(c *gin.Context) {
+	event, ok := getAlertEvent(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetAlertService().ResolveAlertEvent(event.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "告警事件不存在")
+		} else {
+			ResponseError(c, http.StatusBadRequest, "解决告警失败: "+err.Error())
+		}
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "告警已解决", nil)
+}
+
+// RegisterAlertRoutes 注册告警事件、指标告警规则和日志告警规则相关路由
+func RegisterAlertRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 需要告警查看权限的接口
+	viewRouter := authRouter.Group("/")
+	viewRouter.Use(PrivilegeMiddleware("VIEW_ALERT"))
+	{
+		viewRouter.GET("/alert-events", GetAlertEvents)
+		viewRouter.GET("/alert-events/:id", GetAlertEventById)
+		viewRouter.GET("/metric-alert-rules", GetMetricAlertRules)
+		viewRouter.GET("/metric-alert-rules/:id", GetMetricAlertRuleById)
+		viewRouter.POST("/metric-alert-rules/query", QueryAlertExpression)
+		viewRouter.GET("/log-alert-rules", GetLogAlertRules)
+		viewRouter.GET("/log-alert-rules/:id", GetLogAlertRuleById)
+	}
+
+	// 需要告警管理权限的接口
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_ALERT"))
+	{
+		manageRouter.POST("/alert-events/:id/acknowledge", AcknowledgeAlertEvent)
+		manageRouter.POST("/alert-events/:id/resolve", ResolveAlertEvent)
+		manageRouter.POST("/metric-alert-rules", CreateMetricAlertRule)
+		manageRouter.PUT("/metric-alert-rules/:id", UpdateMetricAlertRule)
+		manageRouter.DELETE("/metric-alert-rules/:id", DeleteMetricAlertRule)
+		manageRouter.POST("/log-alert-rules", CreateLogAlertRule)
+		manageRouter.PUT("/log-alert-rules/:id", UpdateLogAlertRule)
+		manageRouter.DELETE("/log-alert-rules/:id", DeleteLogAlertRule)
+	}
+}
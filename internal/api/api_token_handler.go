@@ -0,0 +1,245 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiTokenRequest 创建API令牌的请求参数，expires_at 和 expires_in_days 都为空时令牌不过期
+type apiTokenRequest struct {
+	Name          string     `json:"name" binding:"required"`
+	Privileges    []string   `json:"privileges"` // 为空时拥有用户的全部权限
+	ExpiresAt     *time.Time `json:"expires_at"`
+	ExpiresInDays int        `json:"expires_in_days"`
+}
+
+// bindAPITokenRequest 解析创建API令牌的请求参数
+func bindAPITokenRequest(c *gin.Context, userID int) (*imodel.APIToken, bool) {
+	var req apiTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ExpiresInDays < 0 {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return nil, false
+	}
+
+	token := &imodel.APIToken{
+		UserID:     userID,
+		Name:       req.Name,
+		Privileges: req.Privileges,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedBy:  c.GetInt("userID"),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	return token, true
+}
+
+// responseAPIToken 返回新创建的API令牌，令牌明文只返回这一次
+func responseAPIToken(c *gin.Context, token *imodel.APIToken, raw string) {
+	ResponseSuccessWithMessage(c, "API令牌创建成功，令牌只显示一次，请妥善保存", gin.H{
+		"token":     raw,
+		"api_token": token,
+	})
+}
+
+// getAPIToken 根据路径参数获取API令牌，不存在时返回404
+func getAPIToken(c *gin.Context) (*imodel.APIToken, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	token, err := service.GetAPITokenService().GetToken(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "API令牌不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询API令牌失败")
+		}
+		return nil, false
+	}
+	return token, true
+}
+
+// revokeAPIToken 吊销API令牌
+func revokeAPIToken(c *gin.Context, token *imodel.APIToken) {
+	if err := service.GetAPITokenService().RevokeToken(token.ID); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "吊销API令牌失败")
+		return
+	}
+	ResponseSuccessWithMessage(c, "API令牌已吊销", nil)
+}
+
+// GetMyAPITokens 获取当前用户的API令牌
+func GetMyAPITokens(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{
+		"api_tokens.user_id = ?": c.GetInt("userID"),
+	}
+	tokens, total, err := service.GetAPITokenService().ListTokens(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询API令牌失败")
+		return
+	}
+
+	ResponsePageSuccess(c, tokens, int(total), page, pageSize)
+}
+
+// CreateMyAPIToken 为当前用户创建API令牌
+func CreateMyAPIToken(c *gin.Context) {
+	token, ok := bindAPITokenRequest(c, c.GetInt("userID"))
+	if !ok {
+		return
+	}
+
+	raw, err := service.GetAPITokenService().CreateToken(token)
+	if err != nil {
+		responseUserError(c, "创建API令牌", err)
+		return
+	}
+	responseAPIToken(c, token, raw)
+}
+
+// RevokeMyAPIToken 吊销当前用户的API令牌
+func RevokeMyAPIToken(c *gin.Context) {
+	token, ok := getAPIToken(c)
+	if !ok {
+		return
+	}
+	if token.UserID != c.GetInt("userID") {
+		ResponseError(c, http.StatusNotFound, "API令牌不存在")
+		return
+	}
+	revokeAPIToken(c, token)
+}
+
+// GetAPITokens 获取全部用户和服务账号的API令牌，可按 user_id 过滤
+func GetAPITokens(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的用户ID")
+			return
+		}
+		filters["api_tokens.user_id = ?"] = id
+	}
+
+	tokens, total, err := service.GetAPITokenService().ListTokens(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询API令牌失败")
+		return
+	}
+
+	ResponsePageSuccess(c, tokens, int(total), page, pageSize)
+}
+
+// RevokeAPIToken 吊销任意用户或服务账号的API令牌
+func RevokeAPIToken(c *gin.Context) {
+	token, ok := getAPIToken(c)
+	if !ok {
+		return
+	}
+	revokeAPIToken(c, token)
+}
+
+// GetServiceAccounts 获取服务账号列表
+func GetServiceAccounts(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{
+		"auth_source = ?": imodel.UserAuthSourceService,
+	}
+	users, total, err := service.GetUserService().ListUsers(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询服务账号列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, users, int(total), page, pageSize)
+}
+
+// CreateServiceAccount 创建服务账号并分配角色，服务账号通过API令牌访问接口
+func CreateServiceAccount(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Email    string `json:"email"`
+		RoleIDs  []int  `json:"role_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	user := &imodel.User{
+		Username: req.Username,
+		Email:    req.Email,
+	}
+	if err := service.GetUserService().CreateServiceAccount(user, req.RoleIDs); err != nil {
+		responseUserError(c, "创建服务账号", err)
+		return
+	}
+
+	user, err := service.GetUserService().GetUser(user.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询用户失败")
+		return
+	}
+	ResponseSuccessWithMessage(c, "服务账号创建成功", user)
+}
+
+// CreateServiceAccountToken 为服务账号创建API令牌
+func CreateServiceAccountToken(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+	token, ok := bindAPITokenRequest(c, user.ID)
+	if !ok {
+		return
+	}
+
+	raw, err := service.GetAPITokenService().CreateServiceAccountToken(token)
+	if err != nil {
+		responseUserError(c, "创建API令牌", err)
+		return
+	}
+	responseAPIToken(c, token, raw)
+}
+
+// RegisterAPITokenRoutes 注册API令牌和服务账号管理路由
+func RegisterAPITokenRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 管理自己的API令牌需要使用登录令牌
+	userRouter := authRouter.Group("/")
+	userRouter.Use(SessionOnlyMiddleware())
+	{
+		userRouter.GET("/user/api-tokens", GetMyAPITokens)
+		userRouter.POST("/user/api-tokens", CreateMyAPIToken)
+		userRouter.DELETE("/user/api-tokens/:id", RevokeMyAPIToken)
+	}
+
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_USER"))
+	{
+		manageRouter.GET("/api-tokens", GetAPITokens)
+		manageRouter.DELETE("/api-tokens/:id", RevokeAPIToken)
+
+		manageRouter.GET("/service-accounts", GetServiceAccounts)
+		manageRouter.POST("/service-accounts", CreateServiceAccount)
+		manageRouter.POST("/service-accounts/:id/api-tokens", CreateServiceAccountToken)
+	}
+}
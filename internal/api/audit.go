@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+)
+
+// 请求上下文中保存审计信息的键，由处理函数设置
+const (
+	auditActionKey   = "auditAction"   // 覆盖默认的操作名称，设置后只读请求也会被审计
+	auditUserIDKey   = "auditUserID"   // 未经认证中间件的请求（如登录）的操作者
+	auditUsernameKey = "auditUsername" // 同上，登录失败时为尝试登录的用户名
+)
+
+// 审计记录参数
+const (
+	auditMaxBodySize     = 64 * 1024 // 超过此大小的请求体不记录内容
+	auditMaxResponseSize = 4 * 1024  // 读取响应中错误信息时最多缓存的响应大小
+	auditRedacted        = "******"
+)
+
+// auditSensitiveKeys 参数名包含这些词时内容脱敏
+var auditSensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "credential", "private_key", "privatekey", "access_key", "api_key", "apikey",
+}
+
+// auditSensitiveExactKeys 参数名等于这些词时内容脱敏，如 OIDC 回调的授权码
+var auditSensitiveExactKeys = []string{"code", "state"}
+
+// auditResponseWriter 缓存响应的开头部分，用于从失败响应中读取错误信息
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) capture(data []byte) {
+	if remain := auditMaxResponseSize - w.body.Len(); remain > 0 {
+		if len(data) > remain {
+			data = data[:remain]
+		}
+		w.body.Write(data)
+	}
+}
+
+// AuditMiddleware 审计中间件，记录修改类接口（POST、PUT、PATCH、DELETE）的调用，以及处理函数标记需要审计的请求，
+// 包括操作者、来源IP、操作、目标资源、脱敏后的请求参数和结果。Agent 上报心跳和日志的接口不记录。
+func AuditMiddleware(apiPrefix string) gin.HandlerFunc {
+	agentPrefix := apiPrefix + "/agent/"
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, agentPrefix) {
+			c.Next()
+			return
+		}
+
+		mutating := isMutatingMethod(c.Request.Method)
+		var body []byte
+		bodyTooLarge := false
+		if mutating && c.Request.Body != nil && strings.Contains(c.ContentType(), "json") {
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, auditMaxBodySize+1))
+			// 处理函数仍能读取完整的请求体
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+			if len(body) > auditMaxBodySize {
+				body, bodyTooLarge = nil, true
+			}
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		action := c.GetString(auditActionKey)
+		route := strings.TrimPrefix(c.FullPath(), apiPrefix)
+		if (!mutating && action == "") || c.FullPath() == "" {
+			return
+		}
+		if action == "" {
+			action = c.Request.Method + " " + route
+		}
+
+		entry := &imodel.AuditLog{
+			UserID:       c.GetInt("userID"),
+			Username:     c.GetString("username"),
+			ClientIP:     c.ClientIP(),
+			Method:       c.Request.Method,
+			Path:         c.Request.URL.Path,
+			Action:       action,
+			ResourceType: auditResourceType(route),
+			ResourceID:   c.Param("id"),
+			Params:       auditParams(c.Request.URL.Query(), body, bodyTooLarge),
+			StatusCode:   writer.Status(),
+			Result:       imodel.AuditResultSuccess,
+		}
+		if entry.UserID == 0 {
+			entry.UserID = c.GetInt(auditUserIDKey)
+			entry.Username = c.GetString(auditUsernameKey)
+		}
+		if _, ok := c.Get(apiTokenKey); ok {
+			entry.AuthMethod = imodel.AuditAuthAPIToken
+		} else if _, ok := c.Get(sessionKey); ok {
+			entry.AuthMethod = imodel.AuditAuthSession
+		}
+		if entry.StatusCode >= http.StatusBadRequest {
+			entry.Result = imodel.AuditResultFailure
+			entry.Message = auditResponseMessage(writer.body.Bytes())
+		}
+
+		if auditService := service.GetAuditService(); auditService != nil {
+			if err := auditService.Record(entry); err != nil {
+				log.Printf("写入审计记录失败: %s %s: %v", entry.Method, entry.Path, err)
+			}
+		}
+	}
+}
+
+// setAuditActor 设置未经认证中间件的请求的操作者，用于登录等接口
+func setAuditActor(c *gin.Context, userID int, username string) {
+	c.Set(auditUserIDKey, userID)
+	c.Set(auditUsernameKey, username)
+}
+
+// isMutatingMethod 判断请求方法是否会修改数据
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// auditResourceType 取路由的第一段作为资源类型，如 /clusters/:id/services 为 clusters
+func auditResourceType(route string) string {
+	resource, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+	return resource
+}
+
+// auditParams 将查询参数和JSON请求体脱敏后序列化为JSON，没有参数时返回空字符串
+func auditParams(query url.Values, body []byte, bodyTooLarge bool) string {
+	params := map[string]interface{}{}
+	if len(query) > 0 {
+		values := map[string]interface{}{}
+		for key, value := range query {
+			if isSensitiveKey(key) {
+				values[key] = auditRedacted
+			} else if len(value) == 1 {
+				values[key] = value[0]
+			} else {
+				values[key] = value
+			}
+		}
+		params["query"] = values
+	}
+
+	switch {
+	case bodyTooLarge:
+		params["body"] = "request body larger than " + strconv.Itoa(auditMaxBodySize) + " bytes omitted"
+	case len(bytes.TrimSpace(body)) > 0:
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err != nil {
+			params["body"] = "invalid JSON omitted"
+		} else {
+			params["body"] = redactSensitive(value)
+		}
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(params)
+	return string(data)
+}
+
+// redactSensitive 递归替换JSON中敏感字段的值
+func redactSensitive(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isSensitiveKey(key) {
+				v[key] = auditRedacted
+			} else {
+				v[key] = redactSensitive(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactSensitive(item)
+		}
+	}
+	return value
+}
+
+// isSensitiveKey 判断参数名是否表示密码、密钥、令牌等敏感信息
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, exact := range auditSensitiveExactKeys {
+		if key == exact {
+			return true
+		}
+	}
+	for _, sensitive := range auditSensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditResponseMessage 读取失败响应中的错误信息
+func auditResponseMessage(body []byte) string {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Message != "" {
+		return resp.Message
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// parseAuditFilters 解析审计记录的过滤参数
+func parseAuditFilters(c *gin.Context) (map[string]interface{}, bool) {
+	filters := map[string]interface{}{
+		"username = ?":      c.Query("username"),
+		"resource_type = ?": c.Query("resource_type"),
+		"resource_id = ?":   c.Query("resource_id"),
+		"result = ?":        strings.ToUpper(c.Query("result")),
+		"client_ip = ?":     c.Query("client_ip"),
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的用户ID")
+			return nil, false
+		}
+		filters["user_id = ?"] = id
+	}
+	if action := c.Query("action"); action != "" {
+		filters["action LIKE ?"] = "%" + action + "%"
+	}
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的开始时间格式")
+			return nil, false
+		}
+		filters["created_at >= ?"] = startTime
+	}
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的结束时间格式")
+			return nil, false
+		}
+		filters["created_at <= ?"] = endTime
+	}
+	return filters, true
+}
+
+// GetAuditLogs 获取审计记录列表，按时间倒序，可按操作者、操作、资源、结果、来源IP和时间范围过滤
+func GetAuditLogs(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters, ok := parseAuditFilters(c)
+	if !ok {
+		return
+	}
+
+	entries, total, err := service.GetAuditService().ListAuditLogs(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询审计记录失败")
+		return
+	}
+
+	ResponsePageSuccess(c, entries, int(total), page, pageSize)
+}
+
+// GetAuditLogById 获取审计记录
+func GetAuditLogById(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	entry, err := service.GetAuditService().GetAuditLog(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "审计记录不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询审计记录失败")
+		}
+		return
+	}
+
+	ResponseSuccess(c, entry)
+}
+
+// ExportAuditLogs 导出审计记录，过滤参数与 GetAuditLogs 相同，结果按ID顺序直接下载，
+// 包含哈希字段，可离线校验哈希链。导出操作本身也会记录审计。
+func ExportAuditLogs(c *gin.Context) {
+	c.Set(auditActionKey, c.Request.Method+" /audit-logs/export")
+
+	format, gzipped, ok := parseExportOptions(c)
+	if !ok {
+		return
+	}
+	filters, ok := parseAuditFilters(c)
+	if !ok {
+		return
+	}
+
+	fileName := fmt.Sprintf("audit-logs-%s.%s", time.Now().Format("20060102150405"), format)
+	if gzipped {
+		fileName += ".gz"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Header("Content-Type", "application/octet-stream")
+	c.Status(http.StatusOK)
+
+	var w io.Writer = c.Writer
+	if gzipped {
+		gz := gzip.NewWriter(c.Writer)
+		defer gz.Close()
+		w = gz
+	}
+
+	// 响应已开始发送，出错时只能中断下载
+	if _, err := service.GetAuditService().ExportAuditLogs(w, format, filters); err != nil {
+		log.Printf("导出审计记录失败: %v", err)
+		c.Abort()
+	}
+}
+
+// VerifyAuditLogs 校验审计记录的哈希链，返回第一条被修改、删除或插入的记录
+func VerifyAuditLogs(c *gin.Context) {
+	result, err := service.GetAuditService().VerifyAuditLogs()
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "校验审计记录失败")
+		return
+	}
+
+	if !result.Valid {
+		ResponseSuccessWithMessage(c, "审计记录校验失败，记录可能已被篡改", result)
+		return
+	}
+	ResponseSuccessWithMessage(c, "审计记录校验通过", result)
+}
+
+// RegisterAuditRoutes 注册审计记录路由
+func RegisterAuditRoutes(router *gin.RouterGroup) {
+	auditRouter := router.Group("/audit-logs")
+	auditRouter.Use(JWTAuthMiddleware(), PrivilegeMiddleware(imodel.PrivilegeViewAudit), globalAccessMiddleware())
+	{
+		auditRouter.GET("", GetAuditLogs)
+		auditRouter.GET("/export", ExportAuditLogs)
+		auditRouter.GET("/verify", VerifyAuditLogs)
+		auditRouter.GET("/:id", GetAuditLogById)
+	}
+}
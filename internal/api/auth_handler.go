@@ -1,12 +1,19 @@
 package api
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/TejParker/bigdata-manager/internal/auth"
 	"github.com/TejParker/bigdata-manager/internal/db"
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
 	"github.com/TejParker/bigdata-manager/pkg/model"
+	"gorm.io/gorm"
 )
 
 // Login 用户登录处理
@@ -17,6 +24,10 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 登录成功和失败都记录审计，失败时记录尝试登录的用户名
+	c.Set(auditActionKey, imodel.AuditActionLogin)
+	setAuditActor(c, 0, req.Username)
+
 	// 验证用户名和密码
 	userID, err := auth.ValidateUser(req.Username, req.Password)
 	if err != nil {
@@ -24,18 +35,107 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 生成JWT令牌
-	token, err := auth.GenerateToken(userID, req.Username)
+	// 创建登录会话，生成访问令牌和刷新令牌
+	setAuditActor(c, userID, req.Username)
+	tokens, err := auth.CreateSession(userID, req.Username, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		ResponseError(c, http.StatusInternalServerError, "生成令牌失败")
 		return
 	}
 
 	// 返回令牌
-	ResponseSuccess(c, model.LoginResponse{
-		Token:  token,
-		UserID: userID,
-	})
+	ResponseSuccess(c, loginResponse(tokens))
+}
+
+// loginResponse 登录和刷新令牌的响应
+func loginResponse(tokens *auth.TokenPair) model.LoginResponse {
+	return model.LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		UserID:       tokens.UserID,
+	}
+}
+
+// RefreshToken 使用刷新令牌获取新的访问令牌，同时返回新的刷新令牌，原刷新令牌失效
+func RefreshToken(c *gin.Context) {
+	var req model.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	tokens, err := auth.RefreshSession(req.RefreshToken, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			ResponseError(c, http.StatusUnauthorized, err.Error())
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "刷新令牌失败")
+		}
+		return
+	}
+
+	setAuditActor(c, tokens.UserID, tokens.Username)
+	ResponseSuccess(c, loginResponse(tokens))
+}
+
+// Logout 退出登录，吊销当前会话
+func Logout(c *gin.Context) {
+	if err := auth.RevokeSession(c.GetString(sessionKey)); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "退出登录失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "已退出登录", nil)
+}
+
+// LogoutAll 退出全部登录会话，包括当前会话
+func LogoutAll(c *gin.Context) {
+	if err := auth.RevokeUserSessions(c.GetInt("userID"), ""); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "退出登录失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "已退出全部登录会话", nil)
+}
+
+// GetMySessions 获取当前用户有效的登录会话
+func GetMySessions(c *gin.Context) {
+	sessions, err := service.GetUserService().ListSessions(c.GetInt("userID"))
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询登录会话失败")
+		return
+	}
+
+	current := c.GetString(sessionKey)
+	for _, session := range sessions {
+		session.Current = session.SessionID == current
+	}
+	ResponseSuccess(c, sessions)
+}
+
+// RevokeMySession 吊销当前用户的某个登录会话，如在其他设备上的登录
+func RevokeMySession(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	session, err := service.GetUserService().GetSession(id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ResponseError(c, http.StatusInternalServerError, "查询登录会话失败")
+		return
+	}
+	if err != nil || session.UserID != c.GetInt("userID") {
+		ResponseError(c, http.StatusNotFound, "登录会话不存在")
+		return
+	}
+
+	if err := auth.RevokeSession(session.SessionID); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "吊销登录会话失败")
+		return
+	}
+	ResponseSuccessWithMessage(c, "登录会话已吊销", nil)
 }
 
 // GetUserInfo 获取当前用户信息
@@ -114,14 +214,19 @@ func ChangePassword(c *gin.Context) {
 	var (
 		username     string
 		passwordHash string
+		authSource   string
 	)
 
-	query := "SELECT username, password_hash FROM user WHERE id = ?"
-	err := db.DB.QueryRow(query, userID).Scan(&username, &passwordHash)
+	query := "SELECT username, password_hash, auth_source FROM user WHERE id = ?"
+	err := db.DB.QueryRow(query, userID).Scan(&username, &passwordHash, &authSource)
 	if err != nil {
 		ResponseError(c, http.StatusInternalServerError, "获取用户信息失败")
 		return
 	}
+	if authSource != auth.SourceLocal {
+		ResponseError(c, http.StatusBadRequest, "外部认证的用户不能修改密码，请在认证服务器中修改")
+		return
+	}
 
 	if err := auth.CompareHashAndPassword([]byte(passwordHash), []byte(req.OldPassword)); err != nil {
 		ResponseError(c, http.StatusBadRequest, "原密码错误")
@@ -143,18 +248,122 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 密码修改后其他设备上的登录会话失效
+	if err := auth.RevokeUserSessions(userID, c.GetString(sessionKey)); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "吊销其他登录会话失败")
+		return
+	}
+
 	ResponseSuccessWithMessage(c, "密码修改成功", nil)
 }
 
+// OIDCLogin 发起 OIDC 单点登录，跳转到身份提供者的登录页
+func OIDCLogin(c *gin.Context) {
+	provider := auth.GetOIDCProvider()
+	if provider == nil {
+		ResponseError(c, http.StatusNotFound, "未启用OIDC登录")
+		return
+	}
+
+	authURL, state, err := provider.AuthCodeURL()
+	if err != nil {
+		log.Printf("发起OIDC登录失败: %v", err)
+		ResponseError(c, http.StatusBadGateway, "连接身份提供者失败")
+		return
+	}
+
+	// 登录状态只在回调时使用，跨站跳转回来时需要携带 Cookie，SameSite 为 Lax
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(auth.OIDCStateCookie, state, int(auth.OIDCStateTTL.Seconds()), "/", "", provider.SecureCookie(), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 处理身份提供者的登录回调，验证通过后签发登录令牌
+func OIDCCallback(c *gin.Context) {
+	provider := auth.GetOIDCProvider()
+	if provider == nil {
+		ResponseError(c, http.StatusNotFound, "未启用OIDC登录")
+		return
+	}
+
+	// 回调是 GET 请求，需要标记后才会记录审计
+	c.Set(auditActionKey, imodel.AuditActionLogin)
+
+	// 登录状态只能使用一次
+	state, _ := c.Cookie(auth.OIDCStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(auth.OIDCStateCookie, "", -1, "/", "", provider.SecureCookie(), true)
+
+	if errCode := c.Query("error"); errCode != "" {
+		message := errCode
+		if description := c.Query("error_description"); description != "" {
+			message += ": " + description
+		}
+		ResponseError(c, http.StatusUnauthorized, "身份提供者拒绝登录: "+message)
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		ResponseError(c, http.StatusBadRequest, "缺少授权码")
+		return
+	}
+
+	userID, username, err := provider.Login(c.Request.Context(), code, c.Query("state"), state)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrOIDCState), errors.Is(err, auth.ErrUserNotFound):
+			ResponseError(c, http.StatusUnauthorized, err.Error())
+		default:
+			log.Printf("OIDC登录失败: %v", err)
+			ResponseError(c, http.StatusUnauthorized, "OIDC登录失败")
+		}
+		return
+	}
+
+	// 创建登录会话，生成访问令牌和刷新令牌
+	setAuditActor(c, userID, username)
+	tokens, err := auth.CreateSession(userID, username, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "生成令牌失败")
+		return
+	}
+
+	// 配置了前端地址时跳转，令牌放在 URL 片段中，不会发送到服务器或出现在访问日志中
+	if redirect := provider.SuccessRedirect(); redirect != "" {
+		fragment := url.Values{}
+		fragment.Set("token", tokens.AccessToken)
+		fragment.Set("refresh_token", tokens.RefreshToken)
+		fragment.Set("expires_in", strconv.Itoa(tokens.ExpiresIn))
+		fragment.Set("user_id", strconv.Itoa(userID))
+		c.Redirect(http.StatusFound, redirect+"#"+fragment.Encode())
+		return
+	}
+
+	ResponseSuccess(c, loginResponse(tokens))
+}
+
 // RegisterAuthRoutes 注册认证相关路由
 func RegisterAuthRoutes(router *gin.RouterGroup) {
 	router.POST("/login", Login)
+	router.POST("/auth/refresh", RefreshToken)
+	router.GET("/auth/oidc/login", OIDCLogin)
+	router.GET("/auth/oidc/callback", OIDCCallback)
 
 	// 以下路由需要认证
 	authRouter := router.Group("/")
 	authRouter.Use(JWTAuthMiddleware())
 	{
 		authRouter.GET("/user/info", GetUserInfo)
-		authRouter.POST("/user/change-password", ChangePassword)
+	}
+
+	// 以下路由只能使用登录令牌访问
+	sessionRouter := authRouter.Group("/")
+	sessionRouter.Use(SessionOnlyMiddleware())
+	{
+		sessionRouter.POST("/user/change-password", ChangePassword)
+		sessionRouter.POST("/logout", Logout)
+		sessionRouter.POST("/logout-all", LogoutAll)
+		sessionRouter.GET("/user/sessions", GetMySessions)
+		sessionRouter.DELETE("/user/sessions/:id", RevokeMySession)
 	}
 }
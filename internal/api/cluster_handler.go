@@ -12,6 +12,10 @@ import (
 
 // CreateCluster 创建新集群
 func CreateCluster(c *gin.Context) {
+	if !requireGlobalAccess(c) {
+		return
+	}
+
 	var cluster model.Cluster
 	if err := c.ShouldBindJSON(&cluster); err != nil {
 		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
@@ -51,18 +55,26 @@ func GetClusters(c *gin.Context) {
 	}
 	offset := (page - 1) * pageSize
 
+	// 只返回有权查看的集群
+	whereClause := ""
+	condition, args := scopeCondition(requestAccess(c), "id", "")
+	if condition != "" {
+		whereClause = " WHERE " + condition
+	}
+
 	// 查询总数
 	var total int
-	countQuery := "SELECT COUNT(*) FROM cluster"
-	err := db.DB.QueryRow(countQuery).Scan(&total)
+	countQuery := "SELECT COUNT(*) FROM cluster" + whereClause
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
 	if err != nil {
 		ResponseError(c, http.StatusInternalServerError, "查询集群总数失败")
 		return
 	}
 
 	// 查询分页数据
-	query := "SELECT id, name, description, created_at, updated_at FROM cluster ORDER BY id DESC LIMIT ? OFFSET ?"
-	rows, err := db.DB.Query(query, pageSize, offset)
+	query := "SELECT id, name, description, created_at, updated_at FROM cluster" + whereClause + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, pageSize, offset)
+	rows, err := db.DB.Query(query, args...)
 	if err != nil {
 		ResponseError(c, http.StatusInternalServerError, "查询集群列表失败")
 		return
@@ -96,6 +108,9 @@ func GetClusterById(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的集群ID")
 		return
 	}
+	if !authorizeCluster(c, clusterID) {
+		return
+	}
 
 	// 查询集群基本信息
 	var cluster model.Cluster
@@ -145,6 +160,9 @@ func UpdateCluster(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的集群ID")
 		return
 	}
+	if !authorizeCluster(c, clusterID) {
+		return
+	}
 
 	var cluster model.Cluster
 	if err := c.ShouldBindJSON(&cluster); err != nil {
@@ -183,6 +201,9 @@ func DeleteCluster(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的集群ID")
 		return
 	}
+	if !authorizeCluster(c, clusterID) {
+		return
+	}
 
 	// 检查集群是否存在
 	var exists bool
@@ -210,6 +231,13 @@ func DeleteCluster(c *gin.Context) {
 		return
 	}
 
+	// 删除集群上的角色绑定
+	_, err = db.DB.Exec("DELETE FROM role_bindings WHERE cluster_id = ?", clusterID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "删除集群角色绑定失败")
+		return
+	}
+
 	// 删除集群
 	deleteQuery := "DELETE FROM cluster WHERE id = ?"
 	_, err = db.DB.Exec(deleteQuery, clusterID)
@@ -223,10 +251,12 @@ func DeleteCluster(c *gin.Context) {
 
 // RegisterClusterRoutes 注册集群相关路由
 func RegisterClusterRoutes(router *gin.RouterGroup) {
-	router.Use(JWTAuthMiddleware())
+	// 以下路由需要认证
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
 	
 	// 需要集群查看权限的接口
-	viewRouter := router.Group("/")
+	viewRouter := authRouter.Group("/")
 	viewRouter.Use(PrivilegeMiddleware("VIEW_CLUSTER"))
 	{
 		viewRouter.GET("/clusters", GetClusters)
@@ -234,7 +264,7 @@ func RegisterClusterRoutes(router *gin.RouterGroup) {
 	}
 	
 	// 需要集群管理权限的接口
-	manageRouter := router.Group("/")
+	manageRouter := authRouter.Group("/")
 	manageRouter.Use(PrivilegeMiddleware("MANAGE_CLUSTER"))
 	{
 		manageRouter.POST("/clusters", CreateCluster)
@@ -6,7 +6,7 @@ import (
 
 	"github.com/TejParker/bigdata-manager/internal/deploy"
 	"github.com/TejParker/bigdata-manager/pkg/model"
-	
+	"github.com/gin-gonic/gin"
 )
 
 // RegisterComponent 注册组件
@@ -48,8 +48,8 @@ func GetComponents(c *gin.Context) {
 	ResponseSuccess(c, components)
 }
 
-// DeployComponent 部署组件
-func DeployComponent(c *gin.Context) {
+// DeployRegisteredComponent 部署已注册的组件包
+func DeployRegisteredComponent(c *gin.Context) {
 	var req struct {
 		HostID      int `json:"host_id" binding:"required"`
 		ComponentID int `json:"component_id" binding:"required"`
@@ -182,7 +182,7 @@ func ConfigureComponent(c *gin.Context) {
 func RegisterDeployRoutes(router *gin.RouterGroup) {
 	router.POST("/components", RegisterComponent)
 	router.GET("/components", GetComponents)
-	router.POST("/deployments", DeployComponent)
+	router.POST("/deployments", DeployRegisteredComponent)
 	router.GET("/deployments", GetDeployments)
 	router.POST("/components/start", StartComponent)
 	router.POST("/components/stop", StopComponent)
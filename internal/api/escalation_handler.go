@@ -0,0 +1,331 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetEscalationPolicies 获取升级策略列表
+func GetEscalationPolicies(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	policies, total, err := service.GetEscalationService().ListEscalationPolicies(page, pageSize, nil)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询升级策略列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, policies, int(total), page, pageSize)
+}
+
+// GetEscalationPolicyById 根据ID获取升级策略
+func GetEscalationPolicyById(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	policy, err := service.GetEscalationService().GetEscalationPolicy(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "升级策略不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询升级策略失败")
+		}
+		return
+	}
+
+	ResponseSuccess(c, policy)
+}
+
+// CreateEscalationPolicy 创建升级策略
+func CreateEscalationPolicy(c *gin.Context) {
+	var policy imodel.EscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	policy.ID = 0
+	policy.CreatedBy = uint(c.GetInt("userID"))
+
+	if err := service.GetEscalationService().CreateEscalationPolicy(&policy); err != nil {
+		ResponseError(c, http.StatusBadRequest, "创建升级策略失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "升级策略创建成功", policy)
+}
+
+// UpdateEscalationPolicy 更新升级策略
+func UpdateEscalationPolicy(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	escalationService := service.GetEscalationService()
+	existing, err := escalationService.GetEscalationPolicy(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "升级策略不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询升级策略失败")
+		}
+		return
+	}
+
+	var policy imodel.EscalationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	policy.ID = existing.ID
+	policy.CreatedBy = existing.CreatedBy
+	policy.CreatedAt = existing.CreatedAt
+
+	if err := escalationService.UpdateEscalationPolicy(&policy); err != nil {
+		ResponseError(c, http.StatusBadRequest, "更新升级策略失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "升级策略更新成功", policy)
+}
+
+// DeleteEscalationPolicy 删除升级策略
+func DeleteEscalationPolicy(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := service.GetEscalationService().DeleteEscalationPolicy(id); err != nil {
+		ResponseError(c, http.StatusBadRequest, "删除升级策略失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "升级策略删除成功", nil)
+}
+
+// GetOnCallSchedules 获取值班表列表
+func GetOnCallSchedules(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	schedules, total, err := service.GetEscalationService().ListOnCallSchedules(page, pageSize, nil)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询值班表列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, schedules, int(total), page, pageSize)
+}
+
+// GetOnCallScheduleById 根据ID获取值班表，包含替换记录
+func GetOnCallScheduleById(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	schedule, err := service.GetEscalationService().GetOnCallSchedule(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "值班表不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询值班表失败")
+		}
+		return
+	}
+
+	ResponseSuccess(c, schedule)
+}
+
+// GetCurrentOnCall 获取值班表当前(或指定时间)的值班人员
+func GetCurrentOnCall(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	at := time.Now()
+	if atStr := c.Query("at"); atStr != "" {
+		parsed, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的时间格式，请使用RFC3339格式")
+			return
+		}
+		at = parsed
+	}
+
+	contact, err := service.GetEscalationService().CurrentOnCall(id, at)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "值班表不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询值班人员失败")
+		}
+		return
+	}
+
+	ResponseSuccess(c, gin.H{
+		"at":      at,
+		"on_call": contact,
+	})
+}
+
+// CreateOnCallSchedule 创建值班表
+func CreateOnCallSchedule(c *gin.Context) {
+	var schedule imodel.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	schedule.ID = 0
+	schedule.CreatedBy = uint(c.GetInt("userID"))
+
+	if err := service.GetEscalationService().CreateOnCallSchedule(&schedule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "创建值班表失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "值班表创建成功", schedule)
+}
+
+// UpdateOnCallSchedule 更新值班表
+func UpdateOnCallSchedule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	escalationService := service.GetEscalationService()
+	existing, err := escalationService.GetOnCallSchedule(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "值班表不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询值班表失败")
+		}
+		return
+	}
+
+	var schedule imodel.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	schedule.ID = existing.ID
+	schedule.Overrides = nil
+	schedule.CreatedBy = existing.CreatedBy
+	schedule.CreatedAt = existing.CreatedAt
+
+	if err := escalationService.UpdateOnCallSchedule(&schedule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "更新值班表失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "值班表更新成功", schedule)
+}
+
+// DeleteOnCallSchedule 删除值班表
+func DeleteOnCallSchedule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := service.GetEscalationService().DeleteOnCallSchedule(id); err != nil {
+		ResponseError(c, http.StatusBadRequest, "删除值班表失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "值班表删除成功", nil)
+}
+
+// CreateOnCallOverride 为值班表添加替换
+func CreateOnCallOverride(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var override imodel.OnCallOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	override.ID = 0
+	override.ScheduleID = id
+	override.CreatedBy = uint(c.GetInt("userID"))
+
+	if err := service.GetEscalationService().CreateOnCallOverride(&override); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "值班表不存在")
+		} else {
+			ResponseError(c, http.StatusBadRequest, "创建值班替换失败: "+err.Error())
+		}
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "值班替换创建成功", override)
+}
+
+// DeleteOnCallOverride 删除值班替换
+func DeleteOnCallOverride(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	overrideID, ok := parseIDParam(c, "override_id")
+	if !ok {
+		return
+	}
+
+	if err := service.GetEscalationService().DeleteOnCallOverride(id, overrideID); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "删除值班替换失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "值班替换删除成功", nil)
+}
+
+// RegisterEscalationRoutes 注册升级策略和值班表相关路由
+func RegisterEscalationRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 升级策略和值班表不属于集群，作用于所有集群的告警规则，需要在全局范围拥有告警查看权限
+	viewRouter := authRouter.Group("/")
+	viewRouter.Use(PrivilegeMiddleware("VIEW_ALERT"), globalAccessMiddleware())
+	{
+		viewRouter.GET("/escalation-policies", GetEscalationPolicies)
+		viewRouter.GET("/escalation-policies/:id", GetEscalationPolicyById)
+		viewRouter.GET("/oncall-schedules", GetOnCallSchedules)
+		viewRouter.GET("/oncall-schedules/:id", GetOnCallScheduleById)
+		viewRouter.GET("/oncall-schedules/:id/current", GetCurrentOnCall)
+	}
+
+	// 需要在全局范围拥有告警管理权限的接口
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_ALERT"), globalAccessMiddleware())
+	{
+		manageRouter.POST("/escalation-policies", CreateEscalationPolicy)
+		manageRouter.PUT("/escalation-policies/:id", UpdateEscalationPolicy)
+		manageRouter.DELETE("/escalation-policies/:id", DeleteEscalationPolicy)
+		manageRouter.POST("/oncall-schedules", CreateOnCallSchedule)
+		manageRouter.PUT("/oncall-schedules/:id", UpdateOnCallSchedule)
+		manageRouter.DELETE("/oncall-schedules/:id", DeleteOnCallSchedule)
+		manageRouter.POST("/oncall-schedules/:id/overrides", CreateOnCallOverride)
+		manageRouter.DELETE("/oncall-schedules/:id/overrides/:override_id", DeleteOnCallOverride)
+	}
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/logstore"
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// parseExportOptions 解析导出格式（csv 或 ndjson，默认 csv）和是否 gzip 压缩
+func parseExportOptions(c *gin.Context) (imodel.ExportFormat, bool, bool) {
+	format := imodel.ExportFormat(strings.ToLower(c.DefaultQuery("format", string(imodel.ExportFormatCSV))))
+	if format != imodel.ExportFormatCSV && format != imodel.ExportFormatNDJSON {
+		ResponseError(c, http.StatusBadRequest, "无效的format参数，支持csv、ndjson")
+		return "", false, false
+	}
+	gzipped, err := strconv.ParseBool(c.DefaultQuery("gzip", "false"))
+	if err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的gzip参数")
+		return "", false, false
+	}
+	return format, gzipped, true
+}
+
+// parseExportTimeRange 解析 RFC3339 格式的开始和结束时间，未指定时为nil
+func parseExportTimeRange(c *gin.Context, params *service.ExportParams) bool {
+	for name, target := range map[string]**time.Time{
+		"start_time": &params.StartTime,
+		"end_time":   &params.EndTime,
+	} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				ResponseError(c, http.StatusBadRequest, "无效的"+name+"参数，请使用RFC3339格式")
+				return false
+			}
+			*target = &t
+		}
+	}
+	return true
+}
+
+// createExportJob 创建导出任务并返回任务信息
+func createExportJob(c *gin.Context, source imodel.ExportSource, params *service.ExportParams) {
+	format, gzipped, ok := parseExportOptions(c)
+	if !ok {
+		return
+	}
+
+	job, err := service.GetExportService().CreateExportJob(source, format, gzipped, params, uint(c.GetInt("userID")))
+	if err != nil {
+		var syntaxErr *logstore.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			ResponseError(c, http.StatusBadRequest, "检索语法错误: "+syntaxErr.Error())
+		} else {
+			ResponseError(c, http.StatusBadRequest, "创建导出任务失败: "+err.Error())
+		}
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "导出任务已创建", job)
+}
+
+// ExportLogs 创建日志导出任务，过滤参数与 GetLogs 相同，结果按日志ID顺序导出
+func ExportLogs(c *gin.Context) {
+	req := &service.LogSearchRequest{}
+	if !bindLogFilters(c, req) {
+		return
+	}
+	params := &service.ExportParams{
+		Query:       req.Query,
+		HostID:      req.HostID,
+		ServiceID:   req.ServiceID,
+		ComponentID: req.ComponentID,
+		Levels:      req.Levels,
+		Scope:       req.Scope,
+	}
+	if !parseExportTimeRange(c, params) {
+		return
+	}
+
+	createExportJob(c, imodel.ExportSourceLog, params)
+}
+
+// ExportMetrics 创建指标导出任务，metric_name 可以逗号分隔指定多个指标，时间范围默认为过去1小时
+func ExportMetrics(c *gin.Context) {
+	params := &service.ExportParams{}
+	for name, target := range map[string]*int{
+		"host_id":    &params.HostID,
+		"service_id": &params.ServiceID,
+		"cluster_id": &params.ClusterID,
+	} {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				ResponseError(c, http.StatusBadRequest, "无效的"+name+"参数")
+				return
+			}
+			*target = id
+		}
+	}
+	for _, name := range strings.Split(c.Query("metric_name"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			params.MetricNames = append(params.MetricNames, name)
+		}
+	}
+	if !parseExportTimeRange(c, params) {
+		return
+	}
+	if params.StartTime == nil {
+		startTime := time.Now().Add(-1 * time.Hour)
+		params.StartTime = &startTime
+	}
+	if params.EndTime == nil {
+		endTime := time.Now()
+		params.EndTime = &endTime
+	}
+
+	scope, ok := dataScope(c)
+	if !ok {
+		return
+	}
+	params.Scope = scope
+
+	createExportJob(c, imodel.ExportSourceMetric, params)
+}
+
+// withDownloadURL 为导出完成的任务设置下载地址
+func withDownloadURL(job *imodel.ExportJob) *imodel.ExportJob {
+	if job.Status == imodel.ExportJobSucceeded {
+		job.DownloadURL = fmt.Sprintf("%s/exports/%d/download", viper.GetString("server.api_prefix"), job.ID)
+	}
+	return job
+}
+
+// getExportJob 根据路径参数获取当前用户创建的导出任务，不存在时返回404
+func getExportJob(c *gin.Context) (*imodel.ExportJob, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	job, err := service.GetExportService().GetExportJob(id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ResponseError(c, http.StatusInternalServerError, "查询导出任务失败")
+		return nil, false
+	}
+	if err != nil || job.CreatedBy != uint(c.GetInt("userID")) {
+		ResponseError(c, http.StatusNotFound, "导出任务不存在")
+		return nil, false
+	}
+	return withDownloadURL(job), true
+}
+
+// GetExportJobs 获取当前用户的导出任务列表
+func GetExportJobs(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{
+		"created_by = ?": c.GetInt("userID"),
+		"status = ?":     c.Query("status"),
+		"source = ?":     strings.ToUpper(c.Query("source")),
+	}
+
+	jobs, total, err := service.GetExportService().ListExportJobs(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询导出任务列表失败")
+		return
+	}
+	for _, job := range jobs {
+		withDownloadURL(job)
+	}
+
+	ResponsePageSuccess(c, jobs, int(total), page, pageSize)
+}
+
+// GetExportJobById 获取导出任务，导出中时 row_count 为已导出的行数
+func GetExportJobById(c *gin.Context) {
+	job, ok := getExportJob(c)
+	if !ok {
+		return
+	}
+
+	ResponseSuccess(c, job)
+}
+
+// DownloadExportFile 下载导出文件
+func DownloadExportFile(c *gin.Context) {
+	job, ok := getExportJob(c)
+	if !ok {
+		return
+	}
+
+	path, err := service.GetExportService().ExportFilePath(job)
+	if err != nil {
+		ResponseError(c, http.StatusConflict, "导出任务尚未完成")
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		ResponseError(c, http.StatusNotFound, "导出文件不存在或已过期")
+		return
+	}
+
+	c.FileAttachment(path, job.FileName)
+}
+
+// DeleteExportJob 删除导出任务及导出文件，导出中的任务会被取消
+func DeleteExportJob(c *gin.Context) {
+	job, ok := getExportJob(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetExportService().DeleteExportJob(job); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "删除导出任务失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "导出任务删除成功", nil)
+}
+
+// RegisterExportRoutes 注册日志和指标导出路由，导出任务只能由创建者查看和下载
+func RegisterExportRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+	{
+		authRouter.GET("/exports", GetExportJobs)
+		authRouter.GET("/exports/:id", GetExportJobById)
+		authRouter.GET("/exports/:id/download", DownloadExportFile)
+		authRouter.DELETE("/exports/:id", DeleteExportJob)
+	}
+
+	// 需要日志查看权限的接口
+	logRouter := authRouter.Group("/")
+	logRouter.Use(PrivilegeMiddleware("VIEW_LOG"))
+	{
+		logRouter.POST("/logs/export", ExportLogs)
+	}
+
+	// 需要指标查看权限的接口
+	metricRouter := authRouter.Group("/")
+	metricRouter.Use(PrivilegeMiddleware("VIEW_METRIC"))
+	{
+		metricRouter.POST("/metrics/export", ExportMetrics)
+	}
+}
@@ -18,6 +18,9 @@ func CreateHost(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
 		return
 	}
+	if !authorizeCluster(c, host.ClusterID) {
+		return
+	}
 
 	// 检查集群是否存在
 	var clusterExists bool
@@ -108,6 +111,16 @@ func GetHosts(c *gin.Context) {
 		args = append(args, status)
 	}
 
+	// 只返回有权查看的集群中的主机
+	if condition, scopeArgs := scopeCondition(requestAccess(c), "cluster_id", ""); condition != "" {
+		if whereClause == "" {
+			whereClause = " WHERE " + condition
+		} else {
+			whereClause += " AND " + condition
+		}
+		args = append(args, scopeArgs...)
+	}
+
 	// 查询总数
 	countQuery := "SELECT COUNT(*) FROM host" + whereClause
 	var total int
@@ -181,6 +194,9 @@ func GetHostById(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的主机ID")
 		return
 	}
+	if !authorizeHost(c, hostID) {
+		return
+	}
 
 	// 查询主机基本信息
 	query := `SELECT h.id, h.hostname, h.ip, h.cluster_id, c.name as cluster_name,
@@ -264,6 +280,9 @@ func UpdateHost(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的主机ID")
 		return
 	}
+	if !authorizeHost(c, hostID) {
+		return
+	}
 
 	var host model.Host
 	if err := c.ShouldBindJSON(&host); err != nil {
@@ -314,6 +333,9 @@ func DeleteHost(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的主机ID")
 		return
 	}
+	if !authorizeHost(c, hostID) {
+		return
+	}
 
 	// 检查主机是否存在
 	var exists bool
@@ -387,6 +409,9 @@ func UpdateHostStatus(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的主机ID")
 		return
 	}
+	if !authorizeHost(c, hostID) {
+		return
+	}
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// getLogAlertRule 根据路径参数获取日志告警规则，不存在或不是日志规则时返回404
+func getLogAlertRule(c *gin.Context) (*imodel.AlertRule, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	rule, err := service.GetAlertService().GetAlertRule(id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ResponseError(c, http.StatusInternalServerError, "查询日志告警规则失败")
+		return nil, false
+	}
+	if err != nil || rule.RuleType != imodel.AlertRuleTypeLog {
+		ResponseError(c, http.StatusNotFound, "日志告警规则不存在")
+		return nil, false
+	}
+	if !authorizeAlertRule(c, rule) {
+		return nil, false
+	}
+	return rule, true
+}
+
+// GetLogAlertRules 获取日志告警规则列表
+func GetLogAlertRules(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{"rule_type = ?": imodel.AlertRuleTypeLog}
+	if serviceID := c.Query("service_id"); serviceID != "" {
+		filters["service_id = ?"] = serviceID
+	}
+	if severity := c.Query("severity"); severity != "" {
+		filters["severity = ?"] = severity
+	}
+	// 只返回有权查看的集群和服务的规则
+	if condition, args := scopeFilter(requestAccess(c), "cluster_id", "service_id"); condition != "" {
+		filters[condition] = args
+	}
+
+	rules, total, err := service.GetAlertService().ListAlertRules(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询日志告警规则列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, rules, int(total), page, pageSize)
+}
+
+// GetLogAlertRuleById 根据ID获取日志告警规则
+func GetLogAlertRuleById(c *gin.Context) {
+	rule, ok := getLogAlertRule(c)
+	if !ok {
+		return
+	}
+
+	ResponseSuccess(c, rule)
+}
+
+// CreateLogAlertRule 创建日志告警规则，时间窗口内匹配的日志数满足阈值条件时告警
+func CreateLogAlertRule(c *gin.Context) {
+	var rule imodel.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	rule.ID = 0
+	rule.RuleType = imodel.AlertRuleTypeLog
+	rule.CreatedBy = uint(c.GetInt("userID"))
+	if !authorizeAlertRule(c, &rule) {
+		return
+	}
+
+	if err := service.GetAlertService().CreateAlertRule(&rule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "创建日志告警规则失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "日志告警规则创建成功", rule)
+}
+
+// UpdateLogAlertRule 更新日志告警规则，规则修改后重新统计时间窗口内的日志
+func UpdateLogAlertRule(c *gin.Context) {
+	existing, ok := getLogAlertRule(c)
+	if !ok {
+		return
+	}
+
+	var rule imodel.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	rule.ID = existing.ID
+	rule.RuleType = imodel.AlertRuleTypeLog
+	rule.CreatedBy = existing.CreatedBy
+	rule.CreatedAt = existing.CreatedAt
+	if !authorizeAlertRule(c, &rule) {
+		return
+	}
+
+	if err := service.GetAlertService().UpdateAlertRule(&rule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "更新日志告警规则失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "日志告警规则更新成功", rule)
+}
+
+// DeleteLogAlertRule 删除日志告警规则
+func DeleteLogAlertRule(c *gin.Context) {
+	rule, ok := getLogAlertRule(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetAlertService().DeleteAlertRule(rule.ID); err != nil {
+		ResponseError(c, http.StatusBadRequest, "删除日志告警规则失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "日志告警规则删除成功", nil)
+}
@@ -2,17 +2,29 @@ package api
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/TejParker/bigdata-manager/internal/db"
+	"github.com/TejParker/bigdata-manager/internal/logparse"
+	"github.com/TejParker/bigdata-manager/internal/logstore"
+	"github.com/TejParker/bigdata-manager/internal/service"
 	"github.com/TejParker/bigdata-manager/pkg/model"
 )
 
-// GetLogs 查询日志记录
+// GetLogs 查询日志记录，启用全文索引时 keyword（或 q）支持检索语法，否则按关键词模糊匹配
 func GetLogs(c *gin.Context) {
+	if logService := service.GetLogService(); logService != nil && logService.IndexEnabled() {
+		searchLogs(c, logService)
+		return
+	}
+
 	// 分页参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
@@ -49,7 +61,7 @@ func GetLogs(c *gin.Context) {
 			ResponseError(c, http.StatusBadRequest, "无效的开始时间格式，请使用RFC3339格式")
 			return
 		}
-		conditions = append(conditions, "timestamp >= ?")
+		conditions = append(conditions, "lr.timestamp >= ?")
 		args = append(args, startTime)
 	}
 
@@ -59,7 +71,7 @@ func GetLogs(c *gin.Context) {
 			ResponseError(c, http.StatusBadRequest, "无效的结束时间格式，请使用RFC3339格式")
 			return
 		}
-		conditions = append(conditions, "timestamp <= ?")
+		conditions = append(conditions, "lr.timestamp <= ?")
 		args = append(args, endTime)
 	}
 	
@@ -69,7 +81,7 @@ func GetLogs(c *gin.Context) {
 			ResponseError(c, http.StatusBadRequest, "无效的host_id参数")
 			return
 		}
-		conditions = append(conditions, "host_id = ?")
+		conditions = append(conditions, "lr.host_id = ?")
 		args = append(args, hostIDInt)
 	}
 	
@@ -79,7 +91,7 @@ func GetLogs(c *gin.Context) {
 			ResponseError(c, http.StatusBadRequest, "无效的service_id参数")
 			return
 		}
-		conditions = append(conditions, "service_id = ?")
+		conditions = append(conditions, "lr.service_id = ?")
 		args = append(args, serviceIDInt)
 	}
 	
@@ -89,19 +101,29 @@ func GetLogs(c *gin.Context) {
 			ResponseError(c, http.StatusBadRequest, "无效的component_id参数")
 			return
 		}
-		conditions = append(conditions, "component_id = ?")
+		conditions = append(conditions, "lr.component_id = ?")
 		args = append(args, componentIDInt)
 	}
 	
 	if logLevel != "" {
-		conditions = append(conditions, "log_level = ?")
+		conditions = append(conditions, "lr.log_level = ?")
 		args = append(args, logLevel)
 	}
 	
 	if keyword != "" {
-		conditions = append(conditions, "message LIKE ?")
+		conditions = append(conditions, "lr.message LIKE ?")
 		args = append(args, "%"+keyword+"%")
 	}
+
+	// 只返回有权查看的主机和服务的日志
+	scope, ok := dataScope(c)
+	if !ok {
+		return
+	}
+	if condition, scopeArgs := scopeSQL(scope, "lr.host_id", "lr.service_id"); condition != "" {
+		conditions = append(conditions, condition)
+		args = append(args, scopeArgs...)
+	}
 	
 	if len(conditions) > 0 {
 		whereClause = " WHERE " + conditions[0]
@@ -111,7 +133,7 @@ func GetLogs(c *gin.Context) {
 	}
 
 	// 查询总数
-	countQuery := "SELECT COUNT(*) FROM log_record" + whereClause
+	countQuery := "SELECT COUNT(*) FROM log_record lr" + whereClause
 	var total int
 	err = db.DB.QueryRow(countQuery, args...).Scan(&total)
 	if err != nil {
@@ -121,7 +143,7 @@ func GetLogs(c *gin.Context) {
 
 	// 查询分页数据
 	query := `SELECT lr.id, lr.host_id, lr.service_id, lr.component_id, lr.log_level, 
-		lr.timestamp, lr.message, lr.created_at,
+		IFNULL(lr.logger, ''), IFNULL(lr.thread, ''), lr.timestamp, lr.message, lr.created_at,
 		h.hostname, s.service_name, sc.component_type
 		FROM log_record lr
 		LEFT JOIN host h ON lr.host_id = h.id
@@ -145,6 +167,8 @@ func GetLogs(c *gin.Context) {
 		ServiceID     int       `json:"service_id,omitempty"`
 		ComponentID   int       `json:"component_id,omitempty"`
 		LogLevel      string    `json:"log_level"`
+		Logger        string    `json:"logger,omitempty"`
+		Thread        string    `json:"thread,omitempty"`
 		Timestamp     time.Time `json:"timestamp"`
 		Message       string    `json:"message"`
 		CreatedAt     time.Time `json:"created_at"`
@@ -161,7 +185,7 @@ func GetLogs(c *gin.Context) {
 		
 		if err := rows.Scan(
 			&log.ID, &hostIDNull, &serviceIDNull, &componentIDNull, &log.LogLevel,
-			&log.Timestamp, &log.Message, &log.CreatedAt,
+			&log.Logger, &log.Thread, &log.Timestamp, &log.Message, &log.CreatedAt,
 			&hostnameNull, &serviceNameNull, &componentTypeNull); err != nil {
 			ResponseError(c, http.StatusInternalServerError, "读取日志数据失败")
 			return
@@ -203,6 +227,222 @@ func GetLogs(c *gin.Context) {
 	ResponsePageSuccess(c, logs, total, page, pageSize)
 }
 
+// searchLogs 使用全文索引查询日志，分页参数和返回格式与数据库查询一致，并返回关键词的高亮位置
+func searchLogs(c *gin.Context, logService *service.LogService) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	req := &service.LogSearchRequest{
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if !bindLogFilters(c, req) {
+		return
+	}
+
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的开始时间格式，请使用RFC3339格式")
+			return
+		}
+		req.StartTime = startTime
+	}
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的结束时间格式，请使用RFC3339格式")
+			return
+		}
+		req.EndTime = endTime
+	}
+
+	logs, total, err := logService.SearchLogs(req)
+	if err != nil {
+		var syntaxErr *logstore.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			ResponseError(c, http.StatusBadRequest, "检索语法错误: "+syntaxErr.Error())
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询日志失败")
+		}
+		return
+	}
+
+	ResponsePageSuccess(c, logs, int(total), page, pageSize)
+}
+
+// bindLogFilters 解析日志检索和实时日志共用的过滤参数并限定在用户有权查看的范围内，参数无效时返回错误响应并返回false
+func bindLogFilters(c *gin.Context, req *service.LogSearchRequest) bool {
+	req.Query = c.Query("q")
+	if req.Query == "" {
+		req.Query = c.Query("keyword")
+	}
+
+	for name, target := range map[string]*int{
+		"host_id":      &req.HostID,
+		"service_id":   &req.ServiceID,
+		"component_id": &req.ComponentID,
+	} {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				ResponseError(c, http.StatusBadRequest, "无效的"+name+"参数")
+				return false
+			}
+			*target = id
+		}
+	}
+
+	// 多个日志级别以逗号分隔
+	if logLevel := c.Query("log_level"); logLevel != "" {
+		req.Levels = strings.Split(logLevel, ",")
+	}
+
+	scope, ok := dataScope(c)
+	if !ok {
+		return false
+	}
+	req.Scope = scope
+	return true
+}
+
+// StreamLogs 以 Server-Sent Events 实时推送新写入的日志，过滤参数与 GetLogs 相同
+//
+// 每条日志为一个 log 事件，事件ID为日志ID。断线重连时通过 Last-Event-ID 请求头或 last_id 参数
+// 从最后收到的日志之后继续推送；tail 参数指定开始订阅时先发送的最近日志条数。
+// 客户端处理过慢时服务端发送 overflow 事件后断开，客户端应从最后收到的日志ID重连。
+func StreamLogs(c *gin.Context) {
+	req := &service.LogStreamRequest{}
+	if !bindLogFilters(c, &req.LogSearchRequest) {
+		return
+	}
+
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_id")
+	}
+	if lastID != "" {
+		id, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || id < 0 {
+			ResponseError(c, http.StatusBadRequest, "无效的last_id参数")
+			return
+		}
+		req.LastID = id
+	}
+	if tail := c.Query("tail"); tail != "" {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 0 || n > service.LogStreamMaxBacklog {
+			ResponseError(c, http.StatusBadRequest, "无效的tail参数，取值范围为0-"+strconv.Itoa(service.LogStreamMaxBacklog))
+			return
+		}
+		req.Tail = n
+	}
+
+	logService := service.GetLogService()
+	sub, err := logService.SubscribeLogs(req)
+	if err != nil {
+		var syntaxErr *logstore.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			ResponseError(c, http.StatusBadRequest, "检索语法错误: "+syntaxErr.Error())
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "订阅实时日志失败")
+		}
+		return
+	}
+	defer logService.UnsubscribeLogs(sub)
+
+	backlog, truncated, err := logService.StreamBacklog(sub)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询历史日志失败")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// 浏览器断线后3秒重连
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	if truncated {
+		writeSSE(c, "", "gap", gin.H{"message": "断线期间的日志过多，部分日志未补发"})
+	}
+	for _, hit := range backlog {
+		writeSSE(c, strconv.FormatInt(hit.ID, 10), "log", hit)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case hit, ok := <-sub.C():
+			if !ok {
+				if sub.Overflowed() {
+					writeSSE(c, "", "overflow", gin.H{"message": "日志推送过快，请从最后收到的日志重新连接"})
+					c.Writer.Flush()
+				}
+				return
+			}
+			if err := writeSSE(c, strconv.FormatInt(hit.ID, 10), "log", hit); err != nil {
+				return
+			}
+			// 已缓存的日志一次写出后再刷新
+			for pending := len(sub.C()); pending > 0; pending-- {
+				hit, ok := <-sub.C()
+				if !ok {
+					break
+				}
+				if err := writeSSE(c, strconv.FormatInt(hit.ID, 10), "log", hit); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			// 注释行，防止代理因连接空闲断开
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeSSE 写入一个 Server-Sent Events 事件，id 为空时不设置事件ID
+func writeSSE(c *gin.Context, id, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+// GetLogIndexStatus 获取日志全文索引状态
+func GetLogIndexStatus(c *gin.Context) {
+	status, err := service.GetLogService().GetLogIndexStatus()
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询日志索引状态失败")
+		return
+	}
+
+	ResponseSuccess(c, status)
+}
+
 // UploadLogs 上传日志记录
 func UploadLogs(c *gin.Context) {
 	var req struct {
@@ -249,8 +489,8 @@ func UploadLogs(c *gin.Context) {
 
 	// 批量插入日志
 	stmt, err := tx.Prepare(`
-		INSERT INTO log_record (host_id, service_id, component_id, log_level, timestamp, message)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO log_record (host_id, service_id, component_id, log_level, logger, thread, timestamp, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		tx.Rollback()
@@ -273,11 +513,15 @@ func UploadLogs(c *gin.Context) {
 			componentIDParam = log.ComponentID
 		}
 		
+		parseLogRecord(&log)
+
 		_, err := stmt.Exec(
 			req.HostID,
 			serviceIDParam,
 			componentIDParam,
 			log.LogLevel,
+			log.Logger,
+			log.Thread,
 			log.Timestamp,
 			log.Message,
 		)
@@ -293,9 +537,47 @@ func UploadLogs(c *gin.Context) {
 		return
 	}
 
+	// 立即索引新上传的日志、聚类日志模式并评估日志告警规则
+	service.GetLogService().NotifyIngested()
+	service.GetLogPatternService().NotifyIngested()
+	service.GetAlertService().NotifyLogsIngested()
+
 	ResponseSuccessWithMessage(c, "日志上传成功", gin.H{"count": len(req.Logs)})
 }
 
+// parseLogRecord 统一日志级别名称；未解析的日志（未设置级别）从首行识别级别、logger 和线程，未设置时间时使用识别出的时间
+func parseLogRecord(record *model.LogRecord) {
+	if record.LogLevel == "" {
+		firstLine, _, _ := strings.Cut(record.Message, "\n")
+		if entry, ok := logparse.Detect(strings.TrimRight(firstLine, "\r")); ok {
+			record.LogLevel = entry.Level
+			if record.Logger == "" {
+				record.Logger = entry.Logger
+			}
+			if record.Thread == "" {
+				record.Thread = entry.Thread
+			}
+			if record.Timestamp.IsZero() {
+				record.Timestamp = entry.Timestamp
+			}
+		}
+	}
+	record.LogLevel = logparse.NormalizeLevel(record.LogLevel)
+	record.Logger = truncateField(record.Logger, 255)
+	record.Thread = truncateField(record.Thread, 255)
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+}
+
+// truncateField 截断超过列长度的字段
+func truncateField(value string, maxChars int) string {
+	if runes := []rune(value); len(runes) > maxChars {
+		return string(runes[:maxChars])
+	}
+	return value
+}
+
 // GetLogLevels 获取日志级别列表
 func GetLogLevels(c *gin.Context) {
 	// 查询数据库中存在的日志级别
@@ -345,16 +627,27 @@ func GetLogStats(c *gin.Context) {
 	// 计算起始时间
 	startTime := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
 
+	// 只统计有权查看的主机和服务的日志
+	scope, ok := dataScope(c)
+	if !ok {
+		return
+	}
+	scopeClause, scopeArgs := scopeSQL(scope, "l.host_id", "l.service_id")
+	if scopeClause != "" {
+		scopeClause = " AND " + scopeClause
+	}
+	args := append([]interface{}{startTime}, scopeArgs...)
+
 	// 查询各个级别的日志数量
 	levelQuery := `
 		SELECT log_level, COUNT(*) as count 
-		FROM log_record 
-		WHERE timestamp >= ? 
+		FROM log_record l
+		WHERE l.timestamp >= ?` + scopeClause + `
 		GROUP BY log_level 
 		ORDER BY count DESC
 	`
 	
-	levelRows, err := db.DB.Query(levelQuery, startTime)
+	levelRows, err := db.DB.Query(levelQuery, args...)
 	if err != nil {
 		ResponseError(c, http.StatusInternalServerError, "查询日志级别统计失败")
 		return
@@ -374,14 +667,14 @@ func GetLogStats(c *gin.Context) {
 
 	// 查询每天的日志数量
 	dailyQuery := `
-		SELECT DATE(timestamp) as date, COUNT(*) as count 
-		FROM log_record 
-		WHERE timestamp >= ? 
-		GROUP BY DATE(timestamp) 
+		SELECT DATE(l.timestamp) as date, COUNT(*) as count 
+		FROM log_record l
+		WHERE l.timestamp >= ?` + scopeClause + `
+		GROUP BY DATE(l.timestamp) 
 		ORDER BY date
 	`
 	
-	dailyRows, err := db.DB.Query(dailyQuery, startTime)
+	dailyRows, err := db.DB.Query(dailyQuery, args...)
 	if err != nil {
 		ResponseError(c, http.StatusInternalServerError, "查询日志日期统计失败")
 		return
@@ -408,13 +701,13 @@ func GetLogStats(c *gin.Context) {
 		SELECT h.hostname, COUNT(l.id) as count 
 		FROM log_record l
 		JOIN host h ON l.host_id = h.id
-		WHERE l.timestamp >= ? 
+		WHERE l.timestamp >= ?` + scopeClause + `
 		GROUP BY l.host_id
 		ORDER BY count DESC
 		LIMIT 10
 	`
 	
-	hostRows, err := db.DB.Query(hostQuery, startTime)
+	hostRows, err := db.DB.Query(hostQuery, args...)
 	if err != nil {
 		ResponseError(c, http.StatusInternalServerError, "查询日志主机统计失败")
 		return
@@ -436,12 +729,32 @@ func GetLogStats(c *gin.Context) {
 		hostStats = append(hostStats, stat)
 	}
 
-	ResponseSuccess(c, gin.H{
+	stats := gin.H{
 		"level_stats": levelStats,
 		"daily_stats": dailyStats,
 		"host_stats":  hostStats,
 		"days":        days,
-	})
+	}
+
+	// 最近新出现和突增的日志模式数
+	if patternService := service.GetLogPatternService(); patternService != nil && patternService.Enabled() {
+		req := &service.LogPatternAnomalyRequest{}
+		if scope != nil {
+			req.ServiceIDs = scope.ServiceIDs
+		}
+		anomalies, err := patternService.DetectLogPatternAnomalies(req)
+		if err != nil {
+			ResponseError(c, http.StatusInternalServerError, "查询日志模式统计失败")
+			return
+		}
+		stats["pattern_stats"] = gin.H{
+			"window_start":  anomalies.WindowStart,
+			"new_count":     len(anomalies.New),
+			"spiking_count": len(anomalies.Spiking),
+		}
+	}
+
+	ResponseSuccess(c, stats)
 }
 
 // RegisterLogRoutes 注册日志相关路由
@@ -458,7 +771,12 @@ func RegisterLogRoutes(router *gin.RouterGroup) {
 	viewRouter.Use(PrivilegeMiddleware("VIEW_LOG"))
 	{
 		viewRouter.GET("/logs", GetLogs)
+		viewRouter.GET("/logs/stream", StreamLogs)
 		viewRouter.GET("/log-levels", GetLogLevels)
 		viewRouter.GET("/log-stats", GetLogStats)
+		viewRouter.GET("/log-index/status", GetLogIndexStatus)
+		viewRouter.GET("/log-patterns", GetLogPatterns)
+		viewRouter.GET("/log-patterns/anomalies", GetLogPatternAnomalies)
+		viewRouter.GET("/log-patterns/:id", GetLogPatternById)
 	}
 } 
\ No newline at end of file
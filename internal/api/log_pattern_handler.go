@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// parseLogPatternSource 解析服务、组件和日志级别过滤参数，多个日志级别以逗号分隔
+func parseLogPatternSource(c *gin.Context) (serviceID, componentID int, levels []string, ok bool) {
+	for name, target := range map[string]*int{
+		"service_id":   &serviceID,
+		"component_id": &componentID,
+	} {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				ResponseError(c, http.StatusBadRequest, "无效的"+name+"参数")
+				return 0, 0, nil, false
+			}
+			*target = id
+		}
+	}
+	for _, level := range strings.Split(c.Query("log_level"), ",") {
+		if level = strings.ToUpper(strings.TrimSpace(level)); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return serviceID, componentID, levels, true
+}
+
+// authorizePatternService 检查当前用户能否查看服务的日志模式，没有服务的模式只有全局权限能查看
+func authorizePatternService(c *gin.Context, serviceID int) bool {
+	scope, ok := dataScope(c)
+	if !ok {
+		return false
+	}
+	if scope == nil {
+		return true
+	}
+	for _, id := range scope.ServiceIDs {
+		if id == serviceID {
+			return true
+		}
+	}
+	ResponseError(c, http.StatusForbidden, "无权查看该日志模式")
+	return false
+}
+
+// GetLogPatterns 获取日志模式列表，sort 为 count（默认）、last_seen 或 first_seen
+func GetLogPatterns(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+	serviceID, componentID, levels, ok := parseLogPatternSource(c)
+	if !ok {
+		return
+	}
+
+	filters := map[string]interface{}{}
+	if serviceID > 0 {
+		filters["lp.service_id = ?"] = serviceID
+	}
+	if componentID > 0 {
+		filters["lp.component_id = ?"] = componentID
+	}
+	if len(levels) > 0 {
+		filters["lp.level IN ?"] = levels
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		filters["lp.template LIKE ?"] = "%" + keyword + "%"
+	}
+	scope, ok := dataScope(c)
+	if !ok {
+		return
+	}
+	if scope != nil {
+		filters["lp.service_id IN ?"] = scope.ServiceIDs
+	}
+
+	patterns, total, err := service.GetLogPatternService().ListLogPatterns(page, pageSize, filters, c.Query("sort"))
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询日志模式列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, patterns, int(total), page, pageSize)
+}
+
+// GetLogPatternById 获取日志模式及最近 hours 小时（默认24）各时间段的日志数
+func GetLogPatternById(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 24*30 {
+		ResponseError(c, http.StatusBadRequest, "无效的hours参数，范围为1-720")
+		return
+	}
+
+	series, err := service.GetLogPatternService().GetLogPatternSeries(int64(id), time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "日志模式不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询日志模式失败")
+		}
+		return
+	}
+	if !authorizePatternService(c, series.ServiceID) {
+		return
+	}
+
+	ResponseSuccess(c, series)
+}
+
+// GetLogPatternAnomalies 获取观察窗口内新出现的日志模式和日志数相比基线突增的日志模式
+//
+// window_minutes、baseline_minutes、spike_ratio、min_count 未指定时使用 log_pattern 配置。
+func GetLogPatternAnomalies(c *gin.Context) {
+	req := &service.LogPatternAnomalyRequest{}
+	var ok bool
+	if req.ServiceID, req.ComponentID, req.Levels, ok = parseLogPatternSource(c); !ok {
+		return
+	}
+
+	for name, target := range map[string]*time.Duration{
+		"window_minutes":   &req.Window,
+		"baseline_minutes": &req.Baseline,
+	} {
+		if value := c.Query(name); value != "" {
+			minutes, err := strconv.Atoi(value)
+			if err != nil || minutes < 1 || minutes > 60*24*30 {
+				ResponseError(c, http.StatusBadRequest, "无效的"+name+"参数")
+				return
+			}
+			*target = time.Duration(minutes) * time.Minute
+		}
+	}
+	if value := c.Query("spike_ratio"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio <= 1 {
+			ResponseError(c, http.StatusBadRequest, "无效的spike_ratio参数，需大于1")
+			return
+		}
+		req.SpikeRatio = ratio
+	}
+	if value := c.Query("min_count"); value != "" {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || count < 1 {
+			ResponseError(c, http.StatusBadRequest, "无效的min_count参数")
+			return
+		}
+		req.MinCount = count
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 500 {
+			ResponseError(c, http.StatusBadRequest, "无效的limit参数，范围为1-500")
+			return
+		}
+		req.Limit = limit
+	}
+	scope, ok := dataScope(c)
+	if !ok {
+		return
+	}
+	if scope != nil {
+		req.ServiceIDs = scope.ServiceIDs
+	}
+
+	anomalies, err := service.GetLogPatternService().DetectLogPatternAnomalies(req)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询日志模式异常失败")
+		return
+	}
+
+	ResponseSuccess(c, anomalies)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// authorizeAlertRule 按规则限定的服务、集群或主机检查当前用户能否访问规则，不限定范围的规则需要全局权限
+func authorizeAlertRule(c *gin.Context, rule *imodel.AlertRule) bool {
+	return authorizeAlertTarget(c, rule.ClusterID, rule.ServiceID, rule.HostID)
+}
+
+// getMetricAlertRule 根据路径参数获取指标告警规则，不存在或不是指标规则时返回404
+func getMetricAlertRule(c *gin.Context) (*imodel.AlertRule, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	rule, err := service.GetAlertService().GetAlertRule(id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ResponseError(c, http.StatusInternalServerError, "查询指标告警规则失败")
+		return nil, false
+	}
+	if err != nil || rule.RuleType != imodel.AlertRuleTypeMetric {
+		ResponseError(c, http.StatusNotFound, "指标告警规则不存在")
+		return nil, false
+	}
+	if !authorizeAlertRule(c, rule) {
+		return nil, false
+	}
+	return rule, true
+}
+
+// bindMetricAlertRule 解析指标告警规则的请求参数，并检查用户能否管理规则限定的范围
+func bindMetricAlertRule(c *gin.Context) (*imodel.AlertRule, bool) {
+	var rule imodel.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return nil, false
+	}
+
+	rule.RuleType = imodel.AlertRuleTypeMetric
+	rule.LogQuery, rule.LogLevels, rule.LogWindow, rule.LogGroupBy = "", "", 0, ""
+	if !authorizeAlertRule(c, &rule) {
+		return nil, false
+	}
+	return &rule, true
+}
+
+// GetMetricAlertRules 获取指标告警规则列表，只返回有权查看的集群和服务的规则
+func GetMetricAlertRules(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{"rule_type = ?": imodel.AlertRuleTypeMetric}
+	if clusterID := c.Query("cluster_id"); clusterID != "" {
+		filters["cluster_id = ?"] = clusterID
+	}
+	if serviceID := c.Query("service_id"); serviceID != "" {
+		filters["service_id = ?"] = serviceID
+	}
+	if severity := c.Query("severity"); severity != "" {
+		filters["severity = ?"] = severity
+	}
+	if condition, args := scopeFilter(requestAccess(c), "cluster_id", "service_id"); condition != "" {
+		filters[condition] = args
+	}
+
+	rules, total, err := service.GetAlertService().ListAlertRules(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询指标告警规则列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, rules, int(total), page, pageSize)
+}
+
+// GetMetricAlertRuleById 根据ID获取指标告警规则
+func GetMetricAlertRuleById(c *gin.Context) {
+	rule, ok := getMetricAlertRule(c)
+	if !ok {
+		return
+	}
+
+	ResponseSuccess(c, rule)
+}
+
+// CreateMetricAlertRule 创建指标告警规则：设置 expression 时按表达式结果的每个序列告警，
+// 否则按 metric_name 指标与 threshold 比较。group_by 为逗号分隔的通知分组标签，为空时使用 alert.group_by 配置；
+// escalation_policy_id 为未确认告警的升级策略，策略需已存在
+func CreateMetricAlertRule(c *gin.Context) {
+	rule, ok := bindMetricAlertRule(c)
+	if !ok {
+		return
+	}
+
+	rule.ID = 0
+	rule.CreatedBy = uint(c.GetInt("userID"))
+
+	if err := service.GetAlertService().CreateAlertRule(rule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "创建指标告警规则失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "指标告警规则创建成功", rule)
+}
+
+// UpdateMetricAlertRule 更新指标告警规则
+func UpdateMetricAlertRule(c *gin.Context) {
+	existing, ok := getMetricAlertRule(c)
+	if !ok {
+		return
+	}
+	rule, ok := bindMetricAlertRule(c)
+	if !ok {
+		return
+	}
+
+	rule.ID = existing.ID
+	rule.CreatedBy = existing.CreatedBy
+	rule.CreatedAt = existing.CreatedAt
+
+	if err := service.GetAlertService().UpdateAlertRule(rule); err != nil {
+		ResponseError(c, http.StatusBadRequest, "更新指标告警规则失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "指标告警规则更新成功", rule)
+}
+
+// DeleteMetricAlertRule 删除指标告警规则
+func DeleteMetricAlertRule(c *gin.Context) {
+	rule, ok := getMetricAlertRule(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetAlertService().DeleteAlertRule(rule.ID); err != nil {
+		ResponseError(c, http.StatusBadRequest, "删除指标告警规则失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "指标告警规则删除成功", nil)
+}
+
+// QueryAlertExpression 对告警表达式即时求值，用于编写和调试规则；time 为空时使用当前时间。
+// 表达式可以查询任意主机和服务的指标，需要全局权限
+func QueryAlertExpression(c *gin.Context) {
+	if !requireGlobalAccess(c) {
+		return
+	}
+
+	var req struct {
+		Expression string     `json:"expression" binding:"required"`
+		Time       *time.Time `json:"time"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+	ts := time.Now()
+	if req.Time != nil {
+		ts = *req.Time
+	}
+
+	vector, err := service.GetAlertService().QueryExpression(c.Request.Context(), req.Expression, ts)
+	if err != nil {
+		ResponseError(c, http.StatusBadRequest, "表达式求值失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccess(c, gin.H{
+		"time":   ts,
+		"result": vector,
+	})
+}
@@ -9,7 +9,13 @@ import (
 	"github.com/TejParker/bigdata-manager/pkg/model"
 )
 
-// JWTAuthMiddleware JWT认证中间件
+// 请求上下文中保存认证信息的键
+const (
+	apiTokenKey = "apiToken"  // API令牌身份，使用JWT令牌认证时不设置
+	sessionKey  = "sessionID" // JWT令牌所属的登录会话，使用API令牌认证时不设置
+)
+
+// JWTAuthMiddleware JWT认证中间件，同时接受以 auth.APITokenPrefix 开头的API令牌
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取token
@@ -28,6 +34,30 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// API令牌，路由组嵌套使用本中间件时只验证一次
+		if auth.IsAPIToken(parts[1]) {
+			if _, ok := c.Get(apiTokenKey); ok {
+				c.Next()
+				return
+			}
+			identity, err := auth.ValidateAPIToken(parts[1], c.ClientIP())
+			if err != nil {
+				if err == auth.ErrInvalidAPIToken {
+					ResponseError(c, http.StatusUnauthorized, err.Error())
+				} else {
+					ResponseError(c, http.StatusInternalServerError, "验证API令牌失败")
+				}
+				c.Abort()
+				return
+			}
+
+			c.Set("userID", identity.UserID)
+			c.Set("username", identity.Username)
+			c.Set(apiTokenKey, identity)
+			c.Next()
+			return
+		}
+
 		// 解析token
 		claims, err := auth.ParseToken(parts[1])
 		if err != nil {
@@ -36,14 +66,29 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查会话未被吊销且用户仍为启用状态，路由组嵌套使用本中间件时只检查一次
+		if checked, ok := c.Get(sessionKey); !ok || checked != claims.SessionID {
+			if err := auth.ValidateSession(claims); err != nil {
+				if err == auth.ErrSessionRevoked {
+					ResponseError(c, http.StatusUnauthorized, err.Error())
+				} else {
+					ResponseError(c, http.StatusInternalServerError, "验证会话失败")
+				}
+				c.Abort()
+				return
+			}
+		}
+
 		// 将用户信息保存到请求上下文
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set(sessionKey, claims.SessionID)
 		c.Next()
 	}
 }
 
-// PrivilegeMiddleware 权限检查中间件
+// PrivilegeMiddleware 权限检查中间件，用户在任意集群或服务范围内拥有权限即可通过，
+// 授权范围保存在请求上下文中，由处理函数按请求的资源检查
 func PrivilegeMiddleware(requiredPrivilege string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("userID")
@@ -53,20 +98,41 @@ func PrivilegeMiddleware(requiredPrivilege string) gin.HandlerFunc {
 			return
 		}
 
+		// API令牌限定了权限时，只能使用其中的权限
+		if token, ok := c.Get(apiTokenKey); ok && !token.(*auth.APITokenIdentity).Allow(requiredPrivilege) {
+			ResponseError(c, http.StatusForbidden, "API令牌无权执行此操作")
+			c.Abort()
+			return
+		}
+
 		// 检查用户是否有权限
-		hasPrivilege, err := auth.CheckUserPrivilege(userID.(int), requiredPrivilege)
+		access, err := auth.GetUserAccess(userID.(int), requiredPrivilege)
 		if err != nil {
 			ResponseError(c, http.StatusInternalServerError, "权限检查失败")
 			c.Abort()
 			return
 		}
 
-		if !hasPrivilege {
+		if !access.Granted() {
 			ResponseError(c, http.StatusForbidden, "无权执行此操作")
 			c.Abort()
 			return
 		}
 
+		c.Set(accessKey, access)
+		c.Next()
+	}
+}
+
+// SessionOnlyMiddleware 只允许登录令牌访问，拒绝API令牌，用于修改密码、管理自己的API令牌等接口，
+// 避免权限受限的API令牌创建不受限的令牌
+func SessionOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(apiTokenKey); ok {
+			ResponseError(c, http.StatusForbidden, "API令牌不能访问此接口，请使用登录令牌")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
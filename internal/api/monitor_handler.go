@@ -1,12 +1,10 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/TejParker/bigdata-manager/internal/db"
 	"github.com/TejParker/bigdata-manager/internal/monitor"
 	"github.com/TejParker/bigdata-manager/pkg/model"
 	"github.com/gin-gonic/gin"
@@ -22,6 +20,16 @@ func GetMetrics(c *gin.Context) {
 		return
 	}
 
+	// 未指定主机时返回所有主机的数据，需要全局权限
+	if hostID != 0 {
+		if !authorizeHost(c, hostID) {
+			return
+		}
+	} else if !requestAccess(c).Global {
+		ResponseError(c, http.StatusForbidden, "请指定有权访问的主机")
+		return
+	}
+
 	metricName := c.DefaultQuery("metric_name", "")
 
 	// 解析时间范围
@@ -99,8 +107,25 @@ func GetAlerts(c *gin.Context) {
 	// 获取监控服务
 	monitorService := monitor.GetMonitorService()
 
-	// 查询告警数据
+	// 查询告警数据，只返回有权访问的主机的告警
 	alerts := monitorService.GetAlerts(startTime, endTime)
+	scope, ok := dataScope(c)
+	if !ok {
+		return
+	}
+	if scope != nil {
+		allowed := make(map[int]bool, len(scope.HostIDs))
+		for _, id := range scope.HostIDs {
+			allowed[id] = true
+		}
+		filtered := alerts[:0:0]
+		for _, alert := range alerts {
+			if allowed[alert.HostID] {
+				filtered = append(filtered, alert)
+			}
+		}
+		alerts = filtered
+	}
 
 	// 返回结果
 	ResponseSuccess(c, alerts)
@@ -153,8 +178,28 @@ func GetAlertRules(c *gin.Context) {
 
 // RegisterMonitorRoutes 注册监控相关路由
 func RegisterMonitorRoutes(router *gin.RouterGroup) {
-	router.GET("/metrics", GetMetrics)
-	router.GET("/alerts", GetAlerts)
-	router.GET("/alert-rules", GetAlertRules)
-	router.POST("/alert-rules", CreateAlertRule)
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 需要指标查看权限的接口，按主机检查授权范围
+	metricRouter := authRouter.Group("/")
+	metricRouter.Use(PrivilegeMiddleware("VIEW_METRIC"))
+	{
+		metricRouter.GET("/metrics", GetMetrics)
+		metricRouter.GET("/alerts", GetAlerts)
+	}
+
+	// 需要告警查看权限的接口
+	viewRouter := authRouter.Group("/")
+	viewRouter.Use(PrivilegeMiddleware("VIEW_ALERT"))
+	{
+		viewRouter.GET("/alert-rules", GetAlertRules)
+	}
+
+	// 内存中的告警规则作用于所有主机，创建需要在全局范围拥有告警管理权限
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_ALERT"), globalAccessMiddleware())
+	{
+		manageRouter.POST("/alert-rules", CreateAlertRule)
+	}
 }
@@ -0,0 +1,455 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetNotificationJobs 获取通知发送任务列表
+func GetNotificationJobs(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{}
+	if status := c.Query("status"); status != "" {
+		filters["status = ?"] = status
+	}
+	if configID := c.Query("notification_config_id"); configID != "" {
+		filters["notification_config_id = ?"] = configID
+	}
+
+	jobs, total, err := service.GetNotificationService().ListNotificationJobs(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询通知发送任务列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, jobs, int(total), page, pageSize)
+}
+
+// GetNotificationJobById 根据ID获取通知发送任务
+func GetNotificationJobById(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	job, err := service.GetNotificationService().GetNotificationJob(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "通知发送任务不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询通知发送任务失败")
+		}
+		return
+	}
+
+	ResponseSuccess(c, job)
+}
+
+// ResendNotificationJob 重新发送死信或已成功的通知发送任务
+func ResendNotificationJob(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	job, err := service.GetNotificationService().ResendNotificationJob(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "通知发送任务不存在")
+		} else {
+			ResponseError(c, http.StatusBadRequest, "重新发送失败: "+err.Error())
+		}
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "通知已重新加入发送队列", job)
+}
+
+// GetNotificationTemplates 获取通知模板列表
+func GetNotificationTemplates(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{}
+	if notificationType := c.Query("type"); notificationType != "" {
+		filters["type = ?"] = notificationType
+	}
+	if configID := c.Query("notification_config_id"); configID != "" {
+		filters["notification_config_id = ?"] = configID
+	}
+	if severity := c.Query("severity"); severity != "" {
+		filters["severity = ?"] = severity
+	}
+
+	templates, total, err := service.GetNotificationService().ListNotificationTemplates(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询通知模板列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, templates, int(total), page, pageSize)
+}
+
+// GetNotificationTemplateById 根据ID获取通知模板
+func GetNotificationTemplateById(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	template, err := service.GetNotificationService().GetNotificationTemplate(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "通知模板不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询通知模板失败")
+		}
+		return
+	}
+
+	ResponseSuccess(c, template)
+}
+
+// CreateNotificationTemplate 创建通知模板，保存前校验模板语法
+func CreateNotificationTemplate(c *gin.Context) {
+	var template imodel.NotificationTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	template.ID = 0
+	template.CreatedBy = uint(c.GetInt("userID"))
+
+	if err := service.GetNotificationService().CreateNotificationTemplate(&template); err != nil {
+		ResponseError(c, http.StatusBadRequest, "创建通知模板失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "通知模板创建成功", template)
+}
+
+// UpdateNotificationTemplate 更新通知模板
+func UpdateNotificationTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	notificationService := service.GetNotificationService()
+	existing, err := notificationService.GetNotificationTemplate(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "通知模板不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询通知模板失败")
+		}
+		return
+	}
+
+	var template imodel.NotificationTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	template.ID = existing.ID
+	template.CreatedBy = existing.CreatedBy
+	template.CreatedAt = existing.CreatedAt
+
+	if err := notificationService.UpdateNotificationTemplate(&template); err != nil {
+		ResponseError(c, http.StatusBadRequest, "更新通知模板失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "通知模板更新成功", template)
+}
+
+// DeleteNotificationTemplate 删除通知模板
+func DeleteNotificationTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := service.GetNotificationService().DeleteNotificationTemplate(id); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "删除通知模板失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "通知模板删除成功", nil)
+}
+
+// PreviewNotificationTemplate 预览未保存的通知模板，指定 alert_event_id 时使用该告警渲染，否则使用示例告警
+func PreviewNotificationTemplate(c *gin.Context) {
+	var req struct {
+		imodel.NotificationTemplate
+		AlertEventID uint `json:"alert_event_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	preview, err := service.GetNotificationService().PreviewNotificationTemplate(&req.NotificationTemplate, req.AlertEventID)
+	if err != nil {
+		ResponseError(c, http.StatusBadRequest, "模板渲染失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccess(c, preview)
+}
+
+// PreviewSavedNotificationTemplate 预览已保存的通知模板，可通过 alert_event_id 参数指定告警
+func PreviewSavedNotificationTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var alertEventID uint
+	if value := c.Query("alert_event_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的告警事件ID")
+			return
+		}
+		alertEventID = uint(parsed)
+	}
+
+	notificationService := service.GetNotificationService()
+	template, err := notificationService.GetNotificationTemplate(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "通知模板不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询通知模板失败")
+		}
+		return
+	}
+
+	preview, err := notificationService.PreviewNotificationTemplate(template, alertEventID)
+	if err != nil {
+		ResponseError(c, http.StatusBadRequest, "模板渲染失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccess(c, preview)
+}
+
+// parseTimeRangeParams 解析 start_time、end_time 参数，未指定时默认为最近 defaultRange 的时间范围
+func parseTimeRangeParams(c *gin.Context, defaultRange time.Duration) (time.Time, time.Time, bool) {
+	endTime := time.Now()
+	if value := c.Query("end_time"); value != "" {
+		parsedTime, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的结束时间格式")
+			return time.Time{}, time.Time{}, false
+		}
+		endTime = parsedTime
+	}
+
+	startTime := endTime.Add(-defaultRange)
+	if value := c.Query("start_time"); value != "" {
+		parsedTime, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的开始时间格式")
+			return time.Time{}, time.Time{}, false
+		}
+		startTime = parsedTime
+	}
+
+	if !startTime.Before(endTime) {
+		ResponseError(c, http.StatusBadRequest, "开始时间必须早于结束时间")
+		return time.Time{}, time.Time{}, false
+	}
+	return startTime, endTime, true
+}
+
+// TestNotificationConfig 通过通知配置发送测试通知，可在请求中指定临时接收人和告警级别
+func TestNotificationConfig(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Recipients string               `json:"recipients"`
+		Severity   imodel.AlertSeverity `json:"severity"`
+	}
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+			return
+		}
+	}
+
+	result, err := service.GetNotificationService().TestNotificationConfig(c.Request.Context(), id, req.Recipients, req.Severity)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "通知配置不存在")
+		} else {
+			ResponseError(c, http.StatusBadRequest, "发送测试通知失败: "+err.Error())
+		}
+		return
+	}
+
+	if !result.Success {
+		ResponseSuccessWithMessage(c, "测试通知发送失败", result)
+		return
+	}
+	ResponseSuccessWithMessage(c, "测试通知发送成功", result)
+}
+
+// GetNotificationConfigStats 获取通知配置在时间范围内的发送统计，默认统计最近7天
+func GetNotificationConfigStats(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	startTime, endTime, ok := parseTimeRangeParams(c, 7*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	stats, err := service.GetNotificationService().GetNotificationConfigStats(id, startTime, endTime)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "通知配置不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询通知统计失败")
+		}
+		return
+	}
+
+	ResponseSuccess(c, stats)
+}
+
+// GetNotificationHistory 获取通知历史列表
+func GetNotificationHistory(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{}
+	if configID := c.Query("notification_config_id"); configID != "" {
+		filters["notification_config_id = ?"] = configID
+	}
+	if alertEventID := c.Query("alert_event_id"); alertEventID != "" {
+		filters["alert_event_id = ?"] = alertEventID
+	}
+	if jobID := c.Query("job_id"); jobID != "" {
+		filters["job_id = ?"] = jobID
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status = ?"] = status
+	}
+	if notificationType := c.Query("type"); notificationType != "" {
+		filters["type = ?"] = notificationType
+	}
+	if recipient := c.Query("recipient"); recipient != "" {
+		filters["recipient LIKE ?"] = "%" + recipient + "%"
+	}
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的开始时间格式")
+			return
+		}
+		filters["sent_at >= ?"] = startTime
+	}
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			ResponseError(c, http.StatusBadRequest, "无效的结束时间格式")
+			return
+		}
+		filters["sent_at <= ?"] = endTime
+	}
+
+	histories, total, err := service.GetNotificationService().ListNotificationHistory(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询通知历史失败")
+		return
+	}
+
+	ResponsePageSuccess(c, histories, int(total), page, pageSize)
+}
+
+// GetNotificationStats 获取各通知配置在时间范围内的发送统计，默认统计最近7天
+func GetNotificationStats(c *gin.Context) {
+	startTime, endTime, ok := parseTimeRangeParams(c, 7*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	stats, err := service.GetNotificationService().GetNotificationStats(startTime, endTime)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询通知统计失败")
+		return
+	}
+
+	ResponseSuccess(c, stats)
+}
+
+// GetAlertEventNotifications 获取告警事件的通知历史
+func GetAlertEventNotifications(c *gin.Context) {
+	event, ok := getAlertEvent(c)
+	if !ok {
+		return
+	}
+
+	histories, err := service.GetNotificationService().GetAlertEventNotifications(event.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询通知历史失败")
+		return
+	}
+
+	ResponseSuccess(c, histories)
+}
+
+// RegisterNotificationRoutes 注册通知相关路由
+func RegisterNotificationRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 需要告警查看权限的接口，告警事件的通知历史按事件所属的集群和服务检查
+	viewRouter := authRouter.Group("/")
+	viewRouter.Use(PrivilegeMiddleware("VIEW_ALERT"))
+	{
+		viewRouter.GET("/alert-events/:id/notifications", GetAlertEventNotifications)
+	}
+
+	// 通知历史、发送任务和模板包含所有集群的告警，需要在全局范围拥有告警查看权限
+	globalViewRouter := viewRouter.Group("/")
+	globalViewRouter.Use(globalAccessMiddleware())
+	{
+		globalViewRouter.GET("/notification-configs/:id/stats", GetNotificationConfigStats)
+		globalViewRouter.GET("/notification-history", GetNotificationHistory)
+		globalViewRouter.GET("/notification-history/stats", GetNotificationStats)
+		globalViewRouter.GET("/notification-jobs", GetNotificationJobs)
+		globalViewRouter.GET("/notification-jobs/:id", GetNotificationJobById)
+		globalViewRouter.GET("/notification-templates", GetNotificationTemplates)
+		globalViewRouter.GET("/notification-templates/:id", GetNotificationTemplateById)
+		globalViewRouter.GET("/notification-templates/:id/preview", PreviewSavedNotificationTemplate)
+	}
+
+	// 需要在全局范围拥有告警管理权限的接口
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_ALERT"), globalAccessMiddleware())
+	{
+		manageRouter.POST("/notification-configs/:id/test", TestNotificationConfig)
+		manageRouter.POST("/notification-jobs/:id/resend", ResendNotificationJob)
+		manageRouter.POST("/notification-templates", CreateNotificationTemplate)
+		manageRouter.POST("/notification-templates/preview", PreviewNotificationTemplate)
+		manageRouter.PUT("/notification-templates/:id", UpdateNotificationTemplate)
+		manageRouter.DELETE("/notification-templates/:id", DeleteNotificationTemplate)
+	}
+}
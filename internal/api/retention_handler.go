@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRetentionStatus 获取数据保留策略和最近一次清理结果
+func GetRetentionStatus(c *gin.Context) {
+	ResponseSuccess(c, service.GetRetentionService().GetRetentionStatus())
+}
+
+// RunRetention 立即执行一次过期数据清理
+func RunRetention(c *gin.Context) {
+	err := service.GetRetentionService().RunNow()
+	switch {
+	case errors.Is(err, service.ErrRetentionDisabled):
+		ResponseError(c, http.StatusBadRequest, "数据保留未启用")
+		return
+	case errors.Is(err, service.ErrRetentionRunning):
+		ResponseError(c, http.StatusConflict, "清理任务正在执行")
+		return
+	case err != nil:
+		ResponseError(c, http.StatusInternalServerError, "启动清理任务失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "清理任务已启动", nil)
+}
+
+// RegisterRetentionRoutes 注册数据保留路由
+func RegisterRetentionRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 数据清理作用于所有集群，需要在全局范围拥有集群查看权限
+	viewRouter := authRouter.Group("/")
+	viewRouter.Use(PrivilegeMiddleware("VIEW_CLUSTER"), globalAccessMiddleware())
+	{
+		viewRouter.GET("/retention/status", GetRetentionStatus)
+	}
+
+	// 需要在全局范围拥有集群管理权限的接口
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_CLUSTER"), globalAccessMiddleware())
+	{
+		manageRouter.POST("/retention/run", RunRetention)
+	}
+}
@@ -15,6 +15,7 @@ func SetupRouter() *gin.Engine {
 	
 	// API前缀
 	apiPrefix := viper.GetString("server.api_prefix")
+	r.Use(AuditMiddleware(apiPrefix))
 	apiGroup := r.Group(apiPrefix)
 	
 	// 注册各模块路由
@@ -24,6 +25,15 @@ func SetupRouter() *gin.Engine {
 	RegisterServiceRoutes(apiGroup)
 	RegisterMonitorRoutes(apiGroup)
 	RegisterLogRoutes(apiGroup)
+	RegisterAlertRoutes(apiGroup)
+	RegisterSilenceRoutes(apiGroup)
+	RegisterEscalationRoutes(apiGroup)
+	RegisterNotificationRoutes(apiGroup)
+	RegisterRetentionRoutes(apiGroup)
+	RegisterExportRoutes(apiGroup)
+	RegisterUserRoutes(apiGroup)
+	RegisterAPITokenRoutes(apiGroup)
+	RegisterAuditRoutes(apiGroup)
 	
 	return r
 } 
\ No newline at end of file
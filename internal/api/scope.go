@@ -0,0 +1,207 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/TejParker/bigdata-manager/internal/auth"
+	"github.com/TejParker/bigdata-manager/internal/db"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// accessKey 请求上下文中保存授权范围的键
+const accessKey = "access"
+
+// requestAccess 当前请求所需权限的授权范围，由 PrivilegeMiddleware 设置，未设置时没有任何范围
+func requestAccess(c *gin.Context) *auth.Access {
+	if value, ok := c.Get(accessKey); ok {
+		return value.(*auth.Access)
+	}
+	return &auth.Access{}
+}
+
+// requireGlobalAccess 检查当前用户拥有全局权限，如创建集群
+func requireGlobalAccess(c *gin.Context) bool {
+	if !requestAccess(c).Global {
+		ResponseError(c, http.StatusForbidden, "无权执行此操作，需要全局权限")
+		return false
+	}
+	return true
+}
+
+// globalAccessMiddleware 要求 PrivilegeMiddleware 检查的权限在全局范围内授予，用于审计记录等不属于集群的资源
+func globalAccessMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireGlobalAccess(c) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorizeCluster 检查当前用户能否访问集群，无权访问时返回403
+func authorizeCluster(c *gin.Context, clusterID int) bool {
+	if !requestAccess(c).AllowCluster(clusterID) {
+		ResponseError(c, http.StatusForbidden, "无权访问该集群")
+		return false
+	}
+	return true
+}
+
+// authorizeHost 按主机所属集群检查当前用户能否访问主机，主机不存在时返回404
+func authorizeHost(c *gin.Context, hostID int) bool {
+	access := requestAccess(c)
+	if access.Global {
+		return true
+	}
+
+	var clusterID int
+	err := db.DB.QueryRow("SELECT cluster_id FROM host WHERE id = ?", hostID).Scan(&clusterID)
+	if err == sql.ErrNoRows {
+		ResponseError(c, http.StatusNotFound, "主机不存在")
+		return false
+	}
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询主机失败")
+		return false
+	}
+	if !access.AllowCluster(clusterID) {
+		ResponseError(c, http.StatusForbidden, "无权访问该主机")
+		return false
+	}
+	return true
+}
+
+// authorizeService 按服务及其所属集群检查当前用户能否访问服务，服务不存在时返回404
+func authorizeService(c *gin.Context, serviceID int) bool {
+	access := requestAccess(c)
+	if access.Global {
+		return true
+	}
+
+	var clusterID int
+	err := db.DB.QueryRow("SELECT cluster_id FROM service WHERE id = ?", serviceID).Scan(&clusterID)
+	if err == sql.ErrNoRows {
+		ResponseError(c, http.StatusNotFound, "服务不存在")
+		return false
+	}
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询服务失败")
+		return false
+	}
+	if !access.AllowService(clusterID, serviceID) {
+		ResponseError(c, http.StatusForbidden, "无权访问该服务")
+		return false
+	}
+	return true
+}
+
+// authorizeComponent 按组件所属服务检查当前用户能否访问组件，组件不存在时返回404
+func authorizeComponent(c *gin.Context, componentID int) bool {
+	if requestAccess(c).Global {
+		return true
+	}
+
+	var serviceID int
+	err := db.DB.QueryRow("SELECT service_id FROM service_component WHERE id = ?", componentID).Scan(&serviceID)
+	if err == sql.ErrNoRows {
+		ResponseError(c, http.StatusNotFound, "组件不存在")
+		return false
+	}
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询组件失败")
+		return false
+	}
+	return authorizeService(c, serviceID)
+}
+
+// authorizeAlertTarget 按告警规则、静默或维护窗口限定的服务、集群或主机检查当前用户能否访问；
+// 限定条件需同时满足，检查其中一项即可。都未设置时作用于所有集群，需要全局权限
+func authorizeAlertTarget(c *gin.Context, clusterID, serviceID, hostID *uint) bool {
+	switch {
+	case serviceID != nil:
+		return authorizeService(c, int(*serviceID))
+	case clusterID != nil:
+		return authorizeCluster(c, int(*clusterID))
+	case hostID != nil:
+		return authorizeHost(c, int(*hostID))
+	default:
+		return requireGlobalAccess(c)
+	}
+}
+
+// scopeCondition 生成限定在授权集群和服务内的SQL条件，clusterColumn 或 serviceColumn 为空时不按该列限定；
+// 全局权限时返回空条件
+func scopeCondition(access *auth.Access, clusterColumn, serviceColumn string) (string, []interface{}) {
+	if access.Global {
+		return "", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	if clusterColumn != "" {
+		placeholders, clusterArgs := auth.InClause(access.ClusterIDs)
+		conditions = append(conditions, clusterColumn+" IN "+placeholders)
+		args = append(args, clusterArgs...)
+	}
+	if serviceColumn != "" {
+		placeholders, serviceArgs := auth.InClause(access.ServiceIDs)
+		conditions = append(conditions, serviceColumn+" IN "+placeholders)
+		args = append(args, serviceArgs...)
+	}
+
+	condition := "(" + conditions[0]
+	for _, cond := range conditions[1:] {
+		condition += " OR " + cond
+	}
+	return condition + ")", args
+}
+
+// scopeFilter 与 scopeCondition 相同，但条件使用命名参数，可作为 repository 过滤条件的一项
+func scopeFilter(access *auth.Access, clusterColumn, serviceColumn string) (string, map[string]interface{}) {
+	if access.Global {
+		return "", nil
+	}
+	clusterIDs, serviceIDs := access.ClusterIDs, access.ServiceIDs
+	if clusterIDs == nil {
+		clusterIDs = []int{}
+	}
+	if serviceIDs == nil {
+		serviceIDs = []int{}
+	}
+	return "(" + clusterColumn + " IN @scope_clusters OR " + serviceColumn + " IN @scope_services)",
+		map[string]interface{}{"scope_clusters": clusterIDs, "scope_services": serviceIDs}
+}
+
+// dataScope 生成日志和指标的访问范围：授权集群中主机的数据和授权服务的数据；全局权限时返回nil
+func dataScope(c *gin.Context) (*service.AccessScope, bool) {
+	access := requestAccess(c)
+	if access.Global {
+		return nil, true
+	}
+
+	hostIDs, err := access.HostIDs()
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询授权范围失败")
+		return nil, false
+	}
+	serviceIDs, err := access.AllServiceIDs()
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询授权范围失败")
+		return nil, false
+	}
+	return &service.AccessScope{HostIDs: hostIDs, ServiceIDs: serviceIDs}, true
+}
+
+// scopeSQL 生成限定在访问范围内的SQL条件，scope 为nil时返回空条件
+func scopeSQL(scope *service.AccessScope, hostColumn, serviceColumn string) (string, []interface{}) {
+	if scope == nil {
+		return "", nil
+	}
+	hostPlaceholders, args := auth.InClause(scope.HostIDs)
+	servicePlaceholders, serviceArgs := auth.InClause(scope.ServiceIDs)
+	return "(" + hostColumn + " IN " + hostPlaceholders + " OR " + serviceColumn + " IN " + servicePlaceholders + ")",
+		append(args, serviceArgs...)
+}
@@ -18,6 +18,9 @@ func CreateService(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
 		return
 	}
+	if !authorizeCluster(c, service.ClusterID) {
+		return
+	}
 
 	// 检查集群是否存在
 	var clusterExists bool
@@ -153,6 +156,16 @@ func GetServices(c *gin.Context) {
 		}
 	}
 
+	// 只返回有权查看的集群中的服务和单独授权的服务
+	if condition, scopeArgs := scopeCondition(requestAccess(c), "s.cluster_id", "s.id"); condition != "" {
+		if whereClause == "" {
+			whereClause = " WHERE " + condition
+		} else {
+			whereClause += " AND " + condition
+		}
+		args = append(args, scopeArgs...)
+	}
+
 	// 查询总数
 	countQuery := "SELECT COUNT(*) FROM service s" + whereClause
 	var total int
@@ -214,6 +227,9 @@ func GetServiceById(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的服务ID")
 		return
 	}
+	if !authorizeService(c, serviceID) {
+		return
+	}
 
 	// 查询服务基本信息
 	query := `SELECT s.id, s.service_type, s.service_name, s.version, s.status,
@@ -306,6 +322,9 @@ func UpdateService(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的服务ID")
 		return
 	}
+	if !authorizeService(c, serviceID) {
+		return
+	}
 
 	var service model.Service
 	if err := c.ShouldBindJSON(&service); err != nil {
@@ -350,6 +369,9 @@ func StartService(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的服务ID")
 		return
 	}
+	if !authorizeService(c, serviceID) {
+		return
+	}
 
 	// 检查服务是否存在
 	var status string
@@ -409,6 +431,9 @@ func StopService(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的服务ID")
 		return
 	}
+	if !authorizeService(c, serviceID) {
+		return
+	}
 
 	// 检查服务是否存在
 	var status string
@@ -468,6 +493,9 @@ func DeleteService(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的服务ID")
 		return
 	}
+	if !authorizeService(c, serviceID) {
+		return
+	}
 
 	// 检查服务是否存在
 	var status string
@@ -518,6 +546,14 @@ func DeleteService(c *gin.Context) {
 		return
 	}
 
+	// 删除服务上的角色绑定
+	_, err = tx.Exec("DELETE FROM role_bindings WHERE service_id = ?", serviceID)
+	if err != nil {
+		tx.Rollback()
+		ResponseError(c, http.StatusInternalServerError, "删除服务角色绑定失败")
+		return
+	}
+
 	// 删除服务
 	_, err = tx.Exec("DELETE FROM service WHERE id = ?", serviceID)
 	if err != nil {
@@ -541,6 +577,9 @@ func AddServiceComponent(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的服务ID")
 		return
 	}
+	if !authorizeService(c, serviceID) {
+		return
+	}
 
 	var component model.ServiceComponent
 	if err := c.ShouldBindJSON(&component); err != nil {
@@ -596,6 +635,9 @@ func UpdateServiceComponent(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的组件ID")
 		return
 	}
+	if !authorizeComponent(c, componentID) {
+		return
+	}
 
 	var component model.ServiceComponent
 	if err := c.ShouldBindJSON(&component); err != nil {
@@ -634,6 +676,9 @@ func DeleteServiceComponent(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的组件ID")
 		return
 	}
+	if !authorizeComponent(c, componentID) {
+		return
+	}
 
 	// 检查组件是否存在
 	var exists bool
@@ -679,6 +724,9 @@ func DeployComponent(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, "无效的组件ID")
 		return
 	}
+	if !authorizeComponent(c, componentID) {
+		return
+	}
 
 	var req struct {
 		HostIDs []int `json:"host_ids" binding:"required"`
@@ -689,6 +737,13 @@ func DeployComponent(c *gin.Context) {
 		return
 	}
 
+	// 部署的主机需在有权管理的集群中
+	for _, hostID := range req.HostIDs {
+		if !authorizeHost(c, hostID) {
+			return
+		}
+	}
+
 	// 检查组件是否存在
 	var (
 		serviceID     int
@@ -775,10 +830,12 @@ func DeployComponent(c *gin.Context) {
 
 // RegisterServiceRoutes 注册服务相关路由
 func RegisterServiceRoutes(router *gin.RouterGroup) {
-	router.Use(JWTAuthMiddleware())
+	// 以下路由需要认证
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
 
 	// 需要服务查看权限的接口
-	viewRouter := router.Group("/")
+	viewRouter := authRouter.Group("/")
 	viewRouter.Use(PrivilegeMiddleware("VIEW_SERVICE"))
 	{
 		viewRouter.GET("/services", GetServices)
@@ -786,7 +843,7 @@ func RegisterServiceRoutes(router *gin.RouterGroup) {
 	}
 
 	// 需要服务管理权限的接口
-	manageRouter := router.Group("/")
+	manageRouter := authRouter.Group("/")
 	manageRouter.Use(PrivilegeMiddleware("MANAGE_SERVICE"))
 	{
 		// 服务管理
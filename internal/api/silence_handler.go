@@ -0,0 +1,289 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// parseIDParam 解析路径中的ID参数
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || id == 0 {
+		ResponseError(c, http.StatusBadRequest, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parsePageParams 解析分页参数
+func parsePageParams(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+// authorizeAlertMatcher 检查当前用户能否管理匹配条件限定的告警，只按规则或级别匹配的条件作用于所有集群，需要全局权限
+func authorizeAlertMatcher(c *gin.Context, matcher imodel.AlertMatcher) bool {
+	return authorizeAlertTarget(c, matcher.ClusterID, matcher.ServiceID, matcher.HostID)
+}
+
+// getSilence 根据路径参数获取告警静默，并检查当前用户能否访问
+func getSilence(c *gin.Context) (*imodel.Silence, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	silence, err := service.GetSilenceService().GetSilence(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "静默不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询静默失败")
+		}
+		return nil, false
+	}
+	if !authorizeAlertMatcher(c, silence.AlertMatcher) {
+		return nil, false
+	}
+	return silence, true
+}
+
+// getMaintenanceWindow 根据路径参数获取维护窗口，并检查当前用户能否访问
+func getMaintenanceWindow(c *gin.Context) (*imodel.MaintenanceWindow, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	window, err := service.GetSilenceService().GetMaintenanceWindow(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "维护窗口不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询维护窗口失败")
+		}
+		return nil, false
+	}
+	if !authorizeAlertMatcher(c, window.AlertMatcher) {
+		return nil, false
+	}
+	return window, true
+}
+
+// GetSilences 获取告警静默列表，只返回有权查看的集群和服务的静默
+func GetSilences(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{}
+	switch c.Query("state") {
+	case "active":
+		filters["ends_at > ?"] = time.Now()
+	case "expired":
+		filters["ends_at <= ?"] = time.Now()
+	}
+	if ruleID := c.Query("alert_rule_id"); ruleID != "" {
+		filters["alert_rule_id = ?"] = ruleID
+	}
+	if condition, args := scopeFilter(requestAccess(c), "cluster_id", "service_id"); condition != "" {
+		filters[condition] = args
+	}
+
+	silences, total, err := service.GetSilenceService().ListSilences(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询静默列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, silences, int(total), page, pageSize)
+}
+
+// GetSilenceById 根据ID获取告警静默
+func GetSilenceById(c *gin.Context) {
+	silence, ok := getSilence(c)
+	if !ok {
+		return
+	}
+
+	ResponseSuccess(c, silence)
+}
+
+// CreateSilence 创建告警静默
+func CreateSilence(c *gin.Context) {
+	var silence imodel.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if !authorizeAlertMatcher(c, silence.AlertMatcher) {
+		return
+	}
+
+	silence.ID = 0
+	silence.CreatedBy = uint(c.GetInt("userID"))
+
+	if err := service.GetSilenceService().CreateSilence(&silence); err != nil {
+		ResponseError(c, http.StatusBadRequest, "创建静默失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "静默创建成功", silence)
+}
+
+// ExpireSilence 立即结束告警静默
+func ExpireSilence(c *gin.Context) {
+	silence, ok := getSilence(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetSilenceService().ExpireSilence(silence.ID); err != nil {
+		ResponseError(c, http.StatusBadRequest, "结束静默失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "静默已结束", nil)
+}
+
+// GetMaintenanceWindows 获取维护窗口列表，只返回有权查看的集群和服务的维护窗口
+func GetMaintenanceWindows(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{}
+	if enabled := c.Query("enabled"); enabled != "" {
+		filters["enabled = ?"] = enabled == "true" || enabled == "1"
+	}
+	if condition, args := scopeFilter(requestAccess(c), "cluster_id", "service_id"); condition != "" {
+		filters[condition] = args
+	}
+
+	windows, total, err := service.GetSilenceService().ListMaintenanceWindows(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询维护窗口列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, windows, int(total), page, pageSize)
+}
+
+// GetMaintenanceWindowById 根据ID获取维护窗口
+func GetMaintenanceWindowById(c *gin.Context) {
+	window, ok := getMaintenanceWindow(c)
+	if !ok {
+		return
+	}
+
+	active, _ := service.GetSilenceService().IsMaintenanceWindowActive(window, time.Now())
+	ResponseSuccess(c, gin.H{
+		"window": window,
+		"active": active,
+	})
+}
+
+// CreateMaintenanceWindow 创建维护窗口
+func CreateMaintenanceWindow(c *gin.Context) {
+	// 请求未指定 enabled 时默认启用
+	window := imodel.MaintenanceWindow{Enabled: true}
+	if err := c.ShouldBindJSON(&window); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if !authorizeAlertMatcher(c, window.AlertMatcher) {
+		return
+	}
+
+	window.ID = 0
+	window.CreatedBy = uint(c.GetInt("userID"))
+
+	if err := service.GetSilenceService().CreateMaintenanceWindow(&window); err != nil {
+		ResponseError(c, http.StatusBadRequest, "创建维护窗口失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "维护窗口创建成功", window)
+}
+
+// UpdateMaintenanceWindow 更新维护窗口
+func UpdateMaintenanceWindow(c *gin.Context) {
+	existing, ok := getMaintenanceWindow(c)
+	if !ok {
+		return
+	}
+
+	window := imodel.MaintenanceWindow{Enabled: true}
+	if err := c.ShouldBindJSON(&window); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+	if !authorizeAlertMatcher(c, window.AlertMatcher) {
+		return
+	}
+
+	window.ID = existing.ID
+	window.CreatedBy = existing.CreatedBy
+	window.CreatedAt = existing.CreatedAt
+
+	if err := service.GetSilenceService().UpdateMaintenanceWindow(&window); err != nil {
+		ResponseError(c, http.StatusBadRequest, "更新维护窗口失败: "+err.Error())
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "维护窗口更新成功", window)
+}
+
+// DeleteMaintenanceWindow 删除维护窗口
+func DeleteMaintenanceWindow(c *gin.Context) {
+	window, ok := getMaintenanceWindow(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetSilenceService().DeleteMaintenanceWindow(window.ID); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "删除维护窗口失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "维护窗口删除成功", nil)
+}
+
+// RegisterSilenceRoutes 注册告警静默和维护窗口相关路由
+func RegisterSilenceRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 需要告警查看权限的接口
+	viewRouter := authRouter.Group("/")
+	viewRouter.Use(PrivilegeMiddleware("VIEW_ALERT"))
+	{
+		viewRouter.GET("/silences", GetSilences)
+		viewRouter.GET("/silences/:id", GetSilenceById)
+		viewRouter.GET("/maintenance-windows", GetMaintenanceWindows)
+		viewRouter.GET("/maintenance-windows/:id", GetMaintenanceWindowById)
+	}
+
+	// 需要告警管理权限的接口
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_ALERT"))
+	{
+		manageRouter.POST("/silences", CreateSilence)
+		manageRouter.DELETE("/silences/:id", ExpireSilence)
+		manageRouter.POST("/maintenance-windows", CreateMaintenanceWindow)
+		manageRouter.PUT("/maintenance-windows/:id", UpdateMaintenanceWindow)
+		manageRouter.DELETE("/maintenance-windows/:id", DeleteMaintenanceWindow)
+	}
+}
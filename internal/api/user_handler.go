@@ -0,0 +1,530 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	imodel "github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userErrorMessages 用户管理错误对应的提示
+var userErrorMessages = map[error]string{
+	service.ErrUsernameExists:    "用户名已存在",
+	service.ErrRoleNameExists:    "角色名已存在",
+	service.ErrRoleNotFound:      "角色不存在",
+	service.ErrPrivilegeNotFound: "权限不存在",
+	service.ErrPasswordTooShort:  "密码长度不能少于6位",
+	service.ErrLastUserManager:   "至少需要保留一个拥有用户管理权限的启用用户",
+	service.ErrCannotModifySelf:  "不能禁用或删除当前登录的用户",
+	service.ErrInvalidUserStatus: "无效的用户状态",
+	service.ErrUsernameRequired:  "用户名不能为空",
+	service.ErrRoleNameRequired:  "角色名不能为空",
+	service.ErrExternalUser:      "外部认证的用户不能修改密码",
+
+	service.ErrClusterNotFound:     "集群不存在",
+	service.ErrServiceNotInCluster: "服务不存在或不属于该集群",
+	service.ErrRoleBindingExists:   "用户已在该范围绑定此角色",
+
+	service.ErrTokenNameRequired:  "令牌名称不能为空",
+	service.ErrTokenExpiresInPast: "过期时间必须晚于当前时间",
+	service.ErrNotServiceAccount:  "用户不是服务账号",
+}
+
+// responseUserError 返回用户管理操作的错误响应，action 为操作名称
+func responseUserError(c *gin.Context, action string, err error) {
+	for target, message := range userErrorMessages {
+		if errors.Is(err, target) {
+			ResponseError(c, http.StatusBadRequest, action+"失败: "+message)
+			return
+		}
+	}
+	ResponseError(c, http.StatusInternalServerError, action+"失败")
+}
+
+// getUser 根据路径参数获取用户，不存在时返回404
+func getUser(c *gin.Context) (*imodel.User, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	user, err := service.GetUserService().GetUser(int(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "用户不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询用户失败")
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// getRole 根据路径参数获取角色，不存在时返回404
+func getRole(c *gin.Context) (*imodel.Role, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	role, err := service.GetUserService().GetRole(int(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "角色不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询角色失败")
+		}
+		return nil, false
+	}
+	return role, true
+}
+
+// GetUsers 获取用户列表，keyword 按用户名、邮箱模糊匹配
+func GetUsers(c *gin.Context) {
+	page, pageSize := parsePageParams(c)
+
+	filters := map[string]interface{}{
+		"status = ?": strings.ToUpper(c.Query("status")),
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		filters["CONCAT(username, ' ', IFNULL(email, '')) LIKE ?"] = "%" + keyword + "%"
+	}
+
+	users, total, err := service.GetUserService().ListUsers(page, pageSize, filters)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询用户列表失败")
+		return
+	}
+
+	ResponsePageSuccess(c, users, int(total), page, pageSize)
+}
+
+// GetUserById 根据ID获取用户及其角色
+func GetUserById(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	ResponseSuccess(c, user)
+}
+
+// CreateUser 创建用户并分配角色
+func CreateUser(c *gin.Context) {
+	var req struct {
+		Username string            `json:"username" binding:"required"`
+		Password string            `json:"password" binding:"required"`
+		Email    string            `json:"email"`
+		Phone    string            `json:"phone"`
+		Status   imodel.UserStatus `json:"status"`
+		RoleIDs  []int             `json:"role_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	user := &imodel.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Status:   req.Status,
+	}
+	if err := service.GetUserService().CreateUser(user, req.Password, req.RoleIDs); err != nil {
+		responseUserError(c, "创建用户", err)
+		return
+	}
+
+	user, err := service.GetUserService().GetUser(user.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询用户失败")
+		return
+	}
+	ResponseSuccessWithMessage(c, "用户创建成功", user)
+}
+
+// UpdateUser 更新用户的邮箱和手机号
+func UpdateUser(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Phone string `json:"phone"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	user.Email = req.Email
+	user.Phone = req.Phone
+	if err := service.GetUserService().UpdateUser(user); err != nil {
+		responseUserError(c, "更新用户", err)
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "用户更新成功", user)
+}
+
+// DeleteUser 删除用户
+func DeleteUser(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetUserService().DeleteUser(user.ID, c.GetInt("userID")); err != nil {
+		responseUserError(c, "删除用户", err)
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "用户删除成功", nil)
+}
+
+// setUserStatus 启用或禁用用户
+func setUserStatus(c *gin.Context, status imodel.UserStatus, action string) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetUserService().SetUserStatus(user.ID, status, c.GetInt("userID")); err != nil {
+		responseUserError(c, action+"用户", err)
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "用户已"+action, nil)
+}
+
+// DisableUser 禁用用户，禁用后不能登录，已登录的会话立即失效
+func DisableUser(c *gin.Context) {
+	setUserStatus(c, imodel.UserStatusDisabled, "禁用")
+}
+
+// EnableUser 启用用户
+func EnableUser(c *gin.Context) {
+	setUserStatus(c, imodel.UserStatusActive, "启用")
+}
+
+// ResetUserPassword 管理员重置用户密码
+func ResetUserPassword(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := service.GetUserService().ResetPassword(user.ID, req.NewPassword); err != nil {
+		responseUserError(c, "重置密码", err)
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "密码重置成功", nil)
+}
+
+// SetUserRoles 设置用户的角色，替换原有角色
+func SetUserRoles(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		RoleIDs []int `json:"role_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := service.GetUserService().SetUserRoles(user.ID, req.RoleIDs); err != nil {
+		responseUserError(c, "设置用户角色", err)
+		return
+	}
+
+	user, err := service.GetUserService().GetUser(user.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询用户失败")
+		return
+	}
+	ResponseSuccessWithMessage(c, "用户角色设置成功", user)
+}
+
+// GetUserSessions 获取用户有效的登录会话
+func GetUserSessions(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := service.GetUserService().ListSessions(user.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询登录会话失败")
+		return
+	}
+
+	ResponseSuccess(c, sessions)
+}
+
+// RevokeUserSessions 吊销用户的全部登录会话，强制用户重新登录
+func RevokeUserSessions(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetUserService().RevokeSessions(user.ID); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "吊销登录会话失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "用户的登录会话已全部吊销", nil)
+}
+
+// GetUserRoleBindings 获取用户在集群和服务范围内的角色绑定
+func GetUserRoleBindings(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	bindings, err := service.GetUserService().ListRoleBindings(user.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询角色绑定失败")
+		return
+	}
+
+	ResponseSuccess(c, bindings)
+}
+
+// CreateUserRoleBinding 在集群范围内为用户绑定角色，指定 service_id 时只作用于集群中的该服务
+func CreateUserRoleBinding(c *gin.Context) {
+	user, ok := getUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		RoleID    int  `json:"role_id" binding:"required"`
+		ClusterID int  `json:"cluster_id" binding:"required"`
+		ServiceID *int `json:"service_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	binding := &imodel.RoleBinding{
+		UserID:    user.ID,
+		RoleID:    req.RoleID,
+		ClusterID: req.ClusterID,
+		ServiceID: req.ServiceID,
+		CreatedBy: uint(c.GetInt("userID")),
+	}
+	if err := service.GetUserService().CreateRoleBinding(binding); err != nil {
+		responseUserError(c, "绑定角色", err)
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "角色绑定成功", binding)
+}
+
+// DeleteRoleBinding 删除角色绑定
+func DeleteRoleBinding(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if _, err := service.GetUserService().GetRoleBinding(int(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ResponseError(c, http.StatusNotFound, "角色绑定不存在")
+		} else {
+			ResponseError(c, http.StatusInternalServerError, "查询角色绑定失败")
+		}
+		return
+	}
+
+	if err := service.GetUserService().DeleteRoleBinding(int(id)); err != nil {
+		ResponseError(c, http.StatusInternalServerError, "删除角色绑定失败")
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "角色绑定删除成功", nil)
+}
+
+// GetRoles 获取全部角色及其权限
+func GetRoles(c *gin.Context) {
+	roles, err := service.GetUserService().ListRoles()
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询角色列表失败")
+		return
+	}
+
+	ResponseSuccess(c, roles)
+}
+
+// GetRoleById 根据ID获取角色及其权限
+func GetRoleById(c *gin.Context) {
+	role, ok := getRole(c)
+	if !ok {
+		return
+	}
+
+	ResponseSuccess(c, role)
+}
+
+// CreateRole 创建角色并分配权限
+func CreateRole(c *gin.Context) {
+	var req struct {
+		Name         string `json:"name" binding:"required"`
+		Description  string `json:"description"`
+		PrivilegeIDs []int  `json:"privilege_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	role := &imodel.Role{Name: req.Name, Description: req.Description}
+	if err := service.GetUserService().CreateRole(role, req.PrivilegeIDs); err != nil {
+		responseUserError(c, "创建角色", err)
+		return
+	}
+
+	role, err := service.GetUserService().GetRole(role.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询角色失败")
+		return
+	}
+	ResponseSuccessWithMessage(c, "角色创建成功", role)
+}
+
+// UpdateRole 更新角色名称和描述
+func UpdateRole(c *gin.Context) {
+	role, ok := getRole(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	role.Name = req.Name
+	role.Description = req.Description
+	if err := service.GetUserService().UpdateRole(role); err != nil {
+		responseUserError(c, "更新角色", err)
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "角色更新成功", role)
+}
+
+// DeleteRole 删除角色
+func DeleteRole(c *gin.Context) {
+	role, ok := getRole(c)
+	if !ok {
+		return
+	}
+
+	if err := service.GetUserService().DeleteRole(role.ID); err != nil {
+		responseUserError(c, "删除角色", err)
+		return
+	}
+
+	ResponseSuccessWithMessage(c, "角色删除成功", nil)
+}
+
+// SetRolePrivileges 设置角色的权限，替换原有权限
+func SetRolePrivileges(c *gin.Context) {
+	role, ok := getRole(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PrivilegeIDs []int `json:"privilege_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := service.GetUserService().SetRolePrivileges(role.ID, req.PrivilegeIDs); err != nil {
+		responseUserError(c, "设置角色权限", err)
+		return
+	}
+
+	role, err := service.GetUserService().GetRole(role.ID)
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询角色失败")
+		return
+	}
+	ResponseSuccessWithMessage(c, "角色权限设置成功", role)
+}
+
+// GetPrivileges 获取全部权限
+func GetPrivileges(c *gin.Context) {
+	privileges, err := service.GetUserService().ListPrivileges()
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, "查询权限列表失败")
+		return
+	}
+
+	ResponseSuccess(c, privileges)
+}
+
+// RegisterUserRoutes 注册用户、角色和权限管理路由
+func RegisterUserRoutes(router *gin.RouterGroup) {
+	authRouter := router.Group("/")
+	authRouter.Use(JWTAuthMiddleware())
+
+	// 需要用户管理权限的接口
+	manageRouter := authRouter.Group("/")
+	manageRouter.Use(PrivilegeMiddleware("MANAGE_USER"))
+	{
+		manageRouter.GET("/users", GetUsers)
+		manageRouter.GET("/users/:id", GetUserById)
+		manageRouter.POST("/users", CreateUser)
+		manageRouter.PUT("/users/:id", UpdateUser)
+		manageRouter.DELETE("/users/:id", DeleteUser)
+		manageRouter.POST("/users/:id/disable", DisableUser)
+		manageRouter.POST("/users/:id/enable", EnableUser)
+		manageRouter.POST("/users/:id/reset-password", ResetUserPassword)
+		manageRouter.PUT("/users/:id/roles", SetUserRoles)
+		manageRouter.GET("/users/:id/sessions", GetUserSessions)
+		manageRouter.DELETE("/users/:id/sessions", RevokeUserSessions)
+		manageRouter.GET("/users/:id/role-bindings", GetUserRoleBindings)
+		manageRouter.POST("/users/:id/role-bindings", CreateUserRoleBinding)
+		manageRouter.DELETE("/role-bindings/:id", DeleteRoleBinding)
+
+		manageRouter.GET("/roles", GetRoles)
+		manageRouter.GET("/roles/:id", GetRoleById)
+		manageRouter.POST("/roles", CreateRole)
+		manageRouter.PUT("/roles/:id", UpdateRole)
+		manageRouter.DELETE("/roles/:id", DeleteRole)
+		manageRouter.PUT("/roles/:id/privileges", SetRolePrivileges)
+
+		manageRouter.GET("/privileges", GetPrivileges)
+	}
+}
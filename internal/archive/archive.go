@@ -0,0 +1,47 @@
+// Package archive 将过期数据的归档文件写入本地目录或 S3 兼容存储
+package archive
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// 归档存储类型
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+)
+
+// File 正在写入的归档文件
+type File interface {
+	io.Writer
+	// Commit 完成写入，成功返回后文件才出现在目标位置
+	Commit() error
+	// Abort 放弃写入并删除临时文件
+	Abort()
+}
+
+// Store 归档存储
+type Store interface {
+	// Create 创建归档文件，name 为相对路径，如 log_record/2024/01/log_record-20240102.ndjson.gz
+	Create(name string) (File, error)
+	// Location 归档文件的完整位置，用于日志和状态展示
+	Location(name string) string
+}
+
+// New 根据配置创建归档存储
+func New(cfg config.ArchiveConfig) (Store, error) {
+	switch cfg.Type {
+	case "", TypeLocal:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("archive dir is required")
+		}
+		return NewLocalStore(cfg.Dir), nil
+	case TypeS3:
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unsupported archive type: %s", cfg.Type)
+	}
+}
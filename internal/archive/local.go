@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// LocalStore 本地目录归档存储
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore 创建本地目录归档存储
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir: dir}
+}
+
+// Create 先写入同目录下的临时文件，提交时重命名
+func (s *LocalStore) Create(name string) (File, error) {
+	path := s.Location(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &localFile{File: file, path: path}, nil
+}
+
+// Location 归档文件的本地路径
+func (s *LocalStore) Location(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+// localFile 本地归档文件
+type localFile struct {
+	*os.File
+	path string
+}
+
+func (f *localFile) Commit() error {
+	if err := f.File.Sync(); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	return os.Rename(f.File.Name(), f.path)
+}
+
+func (f *localFile) Abort() {
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
@@ -0,0 +1,182 @@
+package archive
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// S3Store S3 兼容存储（AWS S3、MinIO 等），使用路径形式的地址和 AWS Signature V4 上传对象
+type S3Store struct {
+	cfg      config.S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Store 创建 S3 兼容存储
+func NewS3Store(cfg config.S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 endpoint and bucket are required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3 access key and secret key are required")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3Store{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+// Create 先写入本地临时文件，提交时上传
+func (s *S3Store) Create(name string) (File, error) {
+	file, err := os.CreateTemp("", "archive-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	return &s3File{
+		store:  s,
+		key:    s.objectKey(name),
+		file:   file,
+		hasher: hasher,
+		writer: io.MultiWriter(file, hasher),
+	}, nil
+}
+
+// Location 归档对象的地址
+func (s *S3Store) Location(name string) string {
+	return "s3://" + s.cfg.Bucket + "/" + s.objectKey(name)
+}
+
+// objectKey 加上前缀的对象名
+func (s *S3Store) objectKey(name string) string {
+	if prefix := strings.Trim(s.cfg.Prefix, "/"); prefix != "" {
+		return prefix + "/" + name
+	}
+	return name
+}
+
+// s3File 上传前暂存在本地的归档文件
+type s3File struct {
+	store  *S3Store
+	key    string
+	file   *os.File
+	hasher hash.Hash
+	writer io.Writer
+	size   int64
+}
+
+func (f *s3File) Write(p []byte) (int, error) {
+	n, err := f.writer.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *s3File) Commit() error {
+	defer f.Abort()
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return f.store.putObject(f.key, f.file, f.size, hex.EncodeToString(f.hasher.Sum(nil)))
+}
+
+func (f *s3File) Abort() {
+	f.file.Close()
+	os.Remove(f.file.Name())
+}
+
+// putObject 上传对象
+func (s *S3Store) putObject(key string, body io.Reader, size int64, payloadHash string) error {
+	u := *s.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	req, err := http.NewRequest(http.MethodPut, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 upload failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// sign 按 AWS Signature V4 签名请求
+func (s *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode 按 SigV4 规则编码路径，保留 /
+func uriEncode(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/db"
+)
+
+// API令牌参数
+const (
+	APITokenPrefix = "bdm_" // API令牌的前缀，用于与JWT令牌区分
+
+	// apiTokenTouchInterval 更新最后使用时间的最小间隔，避免每个请求都写数据库
+	apiTokenTouchInterval = time.Minute
+)
+
+// ErrInvalidAPIToken API令牌不存在、已吊销、已过期，或所属用户已禁用
+var ErrInvalidAPIToken = errors.New("无效、已吊销或过期的API令牌")
+
+// APITokenIdentity API令牌验证通过后的身份
+type APITokenIdentity struct {
+	TokenID    uint
+	UserID     int
+	Username   string
+	Privileges []string // 令牌限定的权限，为空时拥有用户的全部权限
+}
+
+// Allow 令牌是否允许使用指定权限
+func (i *APITokenIdentity) Allow(privilege string) bool {
+	if len(i.Privileges) == 0 {
+		return true
+	}
+	for _, p := range i.Privileges {
+		if p == privilege {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIToken 认证令牌是否为API令牌
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// GenerateAPIToken 生成新的API令牌，返回令牌明文和保存到数据库的哈希
+func GenerateAPIToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken 计算API令牌或刷新令牌的哈希；令牌是高熵随机串，使用 SHA-256 即可按哈希直接查找
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateAPIToken 验证API令牌并记录最后使用时间和来源IP
+func ValidateAPIToken(token, clientIP string) (*APITokenIdentity, error) {
+	var (
+		identity   APITokenIdentity
+		privileges sql.NullString
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+	query := `
+		SELECT t.id, t.user_id, u.username, t.privileges, t.expires_at, t.last_used_at
+		FROM api_tokens t
+		JOIN user u ON t.user_id = u.id
+		WHERE t.token_hash = ? AND t.revoked_at IS NULL AND u.status = 'ACTIVE'
+	`
+	err := db.DB.QueryRow(query, HashToken(token)).Scan(
+		&identity.TokenID, &identity.UserID, &identity.Username, &privileges, &expiresAt, &lastUsedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if expiresAt.Valid && !expiresAt.Time.After(now) {
+		return nil, ErrInvalidAPIToken
+	}
+	if privileges.Valid && privileges.String != "" {
+		if err := json.Unmarshal([]byte(privileges.String), &identity.Privileges); err != nil {
+			return nil, err
+		}
+	}
+
+	if !lastUsedAt.Valid || now.Sub(lastUsedAt.Time) >= apiTokenTouchInterval {
+		_, err := db.DB.Exec("UPDATE api_tokens SET last_used_at = ?, last_used_ip = ? WHERE id = ?", now, clientIP, identity.TokenID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &identity, nil
+}
@@ -15,44 +15,32 @@ import (
 type Claims struct {
 	UserID int    `json:"user_id"`
 	Username string `json:"username"`
+	SessionID string `json:"sid"` // 令牌所属的登录会话
 	jwt.RegisteredClaims
 }
 
-// ValidateUser 验证用户名和密码
-func ValidateUser(username, password string) (int, error) {
-	var (
-		userID       int
-		passwordHash string
-	)
-
-	query := "SELECT id, password_hash FROM user WHERE username = ? AND status = 'ACTIVE'"
-	err := db.DB.QueryRow(query, username).Scan(&userID, &passwordHash)
-	if err != nil {
-		return 0, fmt.Errorf("用户不存在或已禁用: %v", err)
-	}
-
-	// 验证密码
-	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
-	if err != nil {
-		return 0, errors.New("密码错误")
+// AccessTokenTTL 访问令牌的有效期，由 auth.token_expiration 配置，单位分钟
+func AccessTokenTTL() time.Duration {
+	minutes := viper.GetInt("auth.token_expiration")
+	if minutes <= 0 {
+		minutes = defaultAccessTokenExpiration
 	}
-
-	return userID, nil
+	return time.Duration(minutes) * time.Minute
 }
 
-// GenerateToken 生成JWT令牌
-func GenerateToken(userID int, username string) (string, error) {
+// GenerateToken 生成登录会话的JWT访问令牌
+func GenerateToken(userID int, username, sessionID string) (string, error) {
 	// 获取配置
 	secret := viper.GetString("server.jwt_secret")
-	expHours := viper.GetInt("server.jwt_expiration")
 
 	// 创建令牌声明
 	now := time.Now()
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:    userID,
+		Username:  username,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expHours) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "bigdata-manager",
@@ -106,14 +94,15 @@ func CompareHashAndPassword(hashedPassword, password []byte) error {
 	return bcrypt.CompareHashAndPassword(hashedPassword, password)
 }
 
-// GetUserPrivileges 获取用户权限列表
+// GetUserPrivileges 获取用户权限列表，已禁用的用户没有任何权限
 func GetUserPrivileges(userID int) ([]string, error) {
 	query := `
 		SELECT DISTINCT p.name
 		FROM privilege p
 		JOIN role_privilege rp ON p.id = rp.privilege_id
 		JOIN user_role ur ON rp.role_id = ur.role_id
-		WHERE ur.user_id = ?
+		JOIN user u ON ur.user_id = u.id
+		WHERE ur.user_id = ? AND u.status = 'ACTIVE'
 	`
 
 	rows, err := db.DB.Query(query, userID)
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TejParker/bigdata-manager/internal/db"
+)
+
+// fakeUser user 表中的一行
+type fakeUser struct {
+	id           int
+	username     string
+	passwordHash string
+	email        string
+	phone        string
+	status       string
+	authSource   string
+}
+
+// fakeStore 认证测试使用的内存数据库，只支持本地认证和外部用户同步用到的语句
+type fakeStore struct {
+	mu        sync.Mutex
+	users     map[string]*fakeUser
+	roles     map[string]int // 角色名对应的角色ID
+	userRoles map[int][]int
+	nextID    int
+}
+
+var (
+	fakeStoresMu sync.Mutex
+	fakeStores   = map[string]*fakeStore{}
+)
+
+func init() {
+	sql.Register("authfake", fakeDriver{})
+}
+
+// newFakeDB 创建内存数据库并替换 db.DB，测试结束后恢复
+func newFakeDB(t *testing.T) *fakeStore {
+	t.Helper()
+	store := &fakeStore{
+		users:     map[string]*fakeUser{},
+		roles:     map[string]int{"ADMIN": 1, "OPERATOR": 2, "VIEWER": 3},
+		userRoles: map[int][]int{},
+		nextID:    1,
+	}
+	fakeStoresMu.Lock()
+	fakeStores[t.Name()] = store
+	fakeStoresMu.Unlock()
+
+	conn, err := sql.Open("authfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := db.DB
+	db.DB = conn
+	t.Cleanup(func() {
+		db.DB = previous
+		conn.Close()
+		fakeStoresMu.Lock()
+		delete(fakeStores, t.Name())
+		fakeStoresMu.Unlock()
+	})
+	return store
+}
+
+// addUser 添加用户，password 不为空时保存其哈希
+func (s *fakeStore) addUser(t *testing.T, username, password, source string) *fakeUser {
+	t.Helper()
+	var hash string
+	if password != "" {
+		var err error
+		if hash, err = HashPassword(password); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user := &fakeUser{id: s.nextID, username: username, passwordHash: hash, status: "ACTIVE", authSource: source}
+	s.nextID++
+	s.users[username] = user
+	return user
+}
+
+// user 按用户名获取用户
+func (s *fakeStore) user(username string) *fakeUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[username]
+}
+
+// roleNames 用户的角色名
+func (s *fakeStore) roleNames(userID int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, roleID := range s.userRoles[userID] {
+		for name, id := range s.roles {
+			if id == roleID {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// exec 执行写入语句
+func (s *fakeStore) exec(query string, args []driver.Value) (driver.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO user ("):
+		user := &fakeUser{
+			id:         s.nextID,
+			username:   args[0].(string),
+			email:      args[1].(string),
+			phone:      args[2].(string),
+			status:     "ACTIVE",
+			authSource: args[3].(string),
+		}
+		s.nextID++
+		s.users[user.username] = user
+		return fakeResult{lastID: int64(user.id), rows: 1}, nil
+	case strings.HasPrefix(query, "UPDATE user SET email"):
+		for _, user := range s.users {
+			if int64(user.id) == args[4].(int64) {
+				if email := args[0].(string); email != "" {
+					user.email = email
+				}
+				if phone := args[2].(string); phone != "" {
+					user.phone = phone
+				}
+				return fakeResult{rows: 1}, nil
+			}
+		}
+		return fakeResult{}, nil
+	case strings.HasPrefix(query, "DELETE FROM user_role WHERE user_id = ?"):
+		userID := int(args[0].(int64))
+		rows := int64(len(s.userRoles[userID]))
+		delete(s.userRoles, userID)
+		return fakeResult{rows: rows}, nil
+	case strings.HasPrefix(query, "INSERT INTO user_role"):
+		roleID, ok := s.roles[args[1].(string)]
+		if !ok {
+			return fakeResult{}, nil
+		}
+		userID := int(args[0].(int64))
+		s.userRoles[userID] = append(s.userRoles[userID], roleID)
+		return fakeResult{rows: 1}, nil
+	}
+	return nil, fmt.Errorf("fake db: unsupported exec: %s", query)
+}
+
+// query 执行查询语句
+func (s *fakeStore) query(query string, args []driver.Value) (driver.Rows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT id, password_hash FROM user WHERE username = ? AND status = 'ACTIVE' AND auth_source = ?"):
+		rows := &fakeRows{columns: []string{"id", "password_hash"}}
+		if user := s.users[args[0].(string)]; user != nil && user.status == "ACTIVE" && user.authSource == args[1].(string) {
+			rows.values = append(rows.values, []driver.Value{int64(user.id), user.passwordHash})
+		}
+		return rows, nil
+	case strings.HasPrefix(query, "SELECT id, status, auth_source FROM user WHERE username = ?"):
+		rows := &fakeRows{columns: []string{"id", "status", "auth_source"}}
+		if user := s.users[args[0].(string)]; user != nil {
+			rows.values = append(rows.values, []driver.Value{int64(user.id), user.status, user.authSource})
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("fake db: unsupported query: %s", query)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeStoresMu.Lock()
+	defer fakeStoresMu.Unlock()
+	store, ok := fakeStores[name]
+	if !ok {
+		return nil, fmt.Errorf("fake db: unknown store %s", name)
+	}
+	return &fakeConn{store: store}, nil
+}
+
+type fakeConn struct {
+	store *fakeStore
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{store: c.store, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+// Begin 内存数据库不支持回滚，测试只检查提交后的结果
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	store *fakeStore
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.store.exec(s.query, args)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.store.query(s.query, args)
+}
+
+type fakeResult struct {
+	lastID int64
+	rows   int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rows, nil }
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
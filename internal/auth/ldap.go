@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// LDAP 认证默认参数
+const (
+	defaultLDAPTimeout            = 10 * time.Second
+	defaultLDAPUserFilter         = "(&(objectClass=user)(sAMAccountName={username}))"
+	defaultLDAPGroupNameAttribute = "cn"
+)
+
+// LDAPProvider LDAP/AD 认证：用服务账号查找用户条目，再以用户DN和密码绑定验证密码，并查找用户所属的组
+type LDAPProvider struct {
+	cfg        config.LDAPConfig
+	timeout    time.Duration
+	tlsConfig  *tls.Config
+	groupRoles map[string][]string // 组名或组DN（小写）对应的角色名
+}
+
+// NewLDAPProvider 创建 LDAP 认证提供者，校验配置并加载CA证书
+func NewLDAPProvider(cfg *config.LDAPConfig) (*LDAPProvider, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url: %q", cfg.URL)
+	}
+	if cfg.BaseDN == "" {
+		return nil, errors.New("base_dn is required")
+	}
+
+	p := &LDAPProvider{
+		cfg:     *cfg,
+		timeout: time.Duration(cfg.Timeout) * time.Second,
+		tlsConfig: &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		},
+	}
+	if p.timeout <= 0 {
+		p.timeout = defaultLDAPTimeout
+	}
+	if p.cfg.UserFilter == "" {
+		p.cfg.UserFilter = defaultLDAPUserFilter
+	}
+	if p.cfg.GroupBaseDN == "" {
+		p.cfg.GroupBaseDN = cfg.BaseDN
+	}
+	if p.cfg.GroupNameAttribute == "" {
+		p.cfg.GroupNameAttribute = defaultLDAPGroupNameAttribute
+	}
+
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", cfg.CACertFile)
+		}
+		p.tlsConfig.RootCAs = pool
+	}
+
+	if len(cfg.GroupMappings) > 0 {
+		p.groupRoles = make(map[string][]string)
+		for _, mapping := range cfg.GroupMappings {
+			group := strings.ToLower(strings.TrimSpace(mapping.Group))
+			if group != "" {
+				p.groupRoles[group] = append(p.groupRoles[group], mapping.Roles...)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Name 提供者名称
+func (p *LDAPProvider) Name() string {
+	return SourceLDAP
+}
+
+// dial 连接 LDAP 服务器，配置了 StartTLS 时升级为TLS连接
+func (p *LDAPProvider) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(p.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.timeout}),
+		ldap.DialWithTLSConfig(p.tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(p.timeout)
+
+	if p.cfg.StartTLS && strings.HasPrefix(strings.ToLower(p.cfg.URL), "ldap://") {
+		if err := conn.StartTLS(p.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// bindService 以服务账号绑定，没有配置服务账号时匿名查找
+func (p *LDAPProvider) bindService(conn *ldap.Conn) error {
+	if p.cfg.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+		return fmt.Errorf("service account bind: %w", err)
+	}
+	return nil
+}
+
+// Authenticate 查找用户并以用户的DN和密码绑定，成功后返回用户的属性和所属组
+func (p *LDAPProvider) Authenticate(username, password string) (*Identity, error) {
+	// 空密码的绑定是匿名绑定，会被服务器视为成功
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+	entry, err := p.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	identity := &Identity{
+		Source:   SourceLDAP,
+		Username: username,
+	}
+	if p.cfg.EmailAttribute != "" {
+		identity.Email = entry.GetAttributeValue(p.cfg.EmailAttribute)
+	}
+	if p.cfg.PhoneAttribute != "" {
+		identity.Phone = entry.GetAttributeValue(p.cfg.PhoneAttribute)
+	}
+
+	// 用户自身可能没有读取组的权限，查找组前重新以服务账号绑定
+	if p.cfg.GroupFilter != "" && p.cfg.BindDN != "" {
+		if err := p.bindService(conn); err != nil {
+			return nil, err
+		}
+	}
+	groups, err := p.findGroups(conn, entry, username)
+	if err != nil {
+		return nil, err
+	}
+	identity.Groups = groups
+	identity.Roles = p.mappedRoles(groups)
+	return identity, nil
+}
+
+// mappedRoles 所属组映射的角色名，没有配置组映射时返回nil，不同步用户的角色
+func (p *LDAPProvider) mappedRoles(groups []string) []string {
+	if p.groupRoles == nil {
+		return nil
+	}
+	var keys []string
+	for _, group := range groups {
+		keys = append(keys, strings.ToLower(group))
+	}
+	return mapRoles(p.groupRoles, keys)
+}
+
+// findUser 按过滤条件查找唯一的用户条目
+func (p *LDAPProvider) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	attributes := []string{"dn"}
+	for _, attribute := range []string{p.cfg.EmailAttribute, p.cfg.PhoneAttribute, p.cfg.MemberOfAttribute} {
+		if attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	filter := strings.ReplaceAll(p.cfg.UserFilter, "{username}", ldap.EscapeFilter(username))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(p.timeout/time.Second), false, filter, attributes, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("search user: %w", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+		return result.Entries[0], nil
+	default:
+		return nil, fmt.Errorf("user filter matched %d entries for %s", len(result.Entries), username)
+	}
+}
+
+// findGroups 获取用户所属组的DN和组名：读取用户条目的 memberOf 属性，并按组过滤条件查找组条目
+func (p *LDAPProvider) findGroups(conn *ldap.Conn, entry *ldap.Entry, username string) ([]string, error) {
+	var groups []string
+	seen := make(map[string]bool)
+	add := func(values ...string) {
+		for _, value := range values {
+			key := strings.ToLower(value)
+			if value != "" && !seen[key] {
+				seen[key] = true
+				groups = append(groups, value)
+			}
+		}
+	}
+
+	if p.cfg.MemberOfAttribute != "" {
+		for _, dn := range entry.GetAttributeValues(p.cfg.MemberOfAttribute) {
+			add(dn, groupName(dn))
+		}
+	}
+
+	if p.cfg.GroupFilter != "" {
+		filter := strings.ReplaceAll(p.cfg.GroupFilter, "{dn}", ldap.EscapeFilter(entry.DN))
+		filter = strings.ReplaceAll(filter, "{username}", ldap.EscapeFilter(username))
+		result, err := conn.Search(ldap.NewSearchRequest(
+			p.cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+			0, int(p.timeout/time.Second), false, filter, []string{p.cfg.GroupNameAttribute}, nil,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("search groups: %w", err)
+		}
+		for _, group := range result.Entries {
+			add(group.DN, group.GetAttributeValue(p.cfg.GroupNameAttribute))
+		}
+	}
+	return groups, nil
+}
+
+// groupName 组DN的第一个RDN的值，如 CN=Admins,OU=Groups,DC=example,DC=com 的组名为 Admins
+func groupName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
@@ -0,0 +1,438 @@
+package auth
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// testLDAPEntry 测试 LDAP 服务器中的条目
+type testLDAPEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// testLDAPServer 进程内的 LDAP 服务器，支持简单绑定和按 and/or/not/等值/存在过滤条件查找
+type testLDAPServer struct {
+	listener net.Listener
+	entries  []*testLDAPEntry
+
+	mu    sync.Mutex // 保护 entries 和 binds
+	binds []string   // 收到的绑定请求的DN
+}
+
+// newTestLDAPServer 启动 LDAP 服务器，测试结束后关闭
+func newTestLDAPServer(t *testing.T, entries ...*testLDAPEntry) *testLDAPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testLDAPServer{listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// URL 服务器地址
+func (s *testLDAPServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// boundDNs 收到的绑定请求的DN
+func (s *testLDAPServer) boundDNs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *testLDAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, password := op.Children[1].Data.String(), op.Children[2].Data.String()
+			s.mu.Lock()
+			s.binds = append(s.binds, dn)
+			code := s.bind(dn, password)
+			s.mu.Unlock()
+			conn.Write(ldapResult(messageID, ldap.ApplicationBindResponse, code).Bytes())
+		case ldap.ApplicationSearchRequest:
+			base := strings.ToLower(op.Children[0].Data.String())
+			var attributes []string
+			for _, attribute := range op.Children[7].Children {
+				attributes = append(attributes, attribute.Data.String())
+			}
+			s.mu.Lock()
+			var results []*ber.Packet
+			for _, entry := range s.entries {
+				if strings.HasSuffix(strings.ToLower(entry.dn), base) && matchFilter(entry, op.Children[6]) {
+					results = append(results, searchEntry(messageID, entry, attributes))
+				}
+			}
+			s.mu.Unlock()
+			for _, result := range results {
+				conn.Write(result.Bytes())
+			}
+			conn.Write(ldapResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			return
+		}
+	}
+}
+
+// bind 简单绑定，与真实服务器相同，DN不为空而密码为空时视为未认证绑定并返回成功
+func (s *testLDAPServer) bind(dn, password string) uint16 {
+	if password == "" {
+		return ldap.LDAPResultSuccess
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) && entry.password != "" && entry.password == password {
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+// matchFilter 判断条目是否满足过滤条件
+func matchFilter(entry *testLDAPEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(entry, filter.Children[0])
+	case ldap.FilterEqualityMatch:
+		attribute, value := filter.Children[0].Data.String(), filter.Children[1].Data.String()
+		for _, v := range entry.values(attribute) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(entry.values(filter.Data.String())) > 0
+	}
+	return false
+}
+
+// values 属性的值，属性名不区分大小写
+func (e *testLDAPEntry) values(attribute string) []string {
+	for name, values := range e.attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+func ldapResult(messageID interface{}, tag ber.Tag, code uint16) *ber.Packet {
+	response := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	response.AppendChild(result)
+	return response
+}
+
+func searchEntry(messageID interface{}, entry *testLDAPEntry, attributes []string) *ber.Packet {
+	response := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "DN"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, name := range attributes {
+		values := entry.values(name)
+		if len(values) == 0 {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	result.AppendChild(list)
+	response.AppendChild(result)
+	return response
+}
+
+const (
+	testServiceDN = "cn=svc,dc=example,dc=com"
+	testAliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+	testBobDN     = "uid=bob,ou=people,dc=example,dc=com"
+	testAdminDN   = "uid=admin,ou=people,dc=example,dc=com"
+)
+
+// testDirectory 测试目录：alice 通过 memberOf 属于 admins 组，bob 是 ops 组的成员，
+// admin 与本地管理员账号同名
+func testDirectory() []*testLDAPEntry {
+	person := func(dn, uid, password string, attributes map[string][]string) *testLDAPEntry {
+		attributes["objectClass"] = []string{"person"}
+		attributes["uid"] = []string{uid}
+		return &testLDAPEntry{dn: dn, password: password, attributes: attributes}
+	}
+	return []*testLDAPEntry{
+		{dn: testServiceDN, password: "svc-secret", attributes: map[string][]string{"cn": {"svc"}}},
+		person(testAliceDN, "alice", "alice-pw", map[string][]string{
+			"mail":     {"alice@example.com"},
+			"mobile":   {"13800000000"},
+			"memberOf": {"cn=admins,ou=groups,dc=example,dc=com"},
+		}),
+		person(testBobDN, "bob", "bob-pw", map[string][]string{"mail": {"bob@example.com"}}),
+		person(testAdminDN, "admin", "ldap-admin-pw", map[string][]string{}),
+		{dn: "cn=ops,ou=groups,dc=example,dc=com", attributes: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"cn":          {"ops"},
+			"member":      {testBobDN},
+		}},
+	}
+}
+
+// testLDAPConfig 使用服务账号查找用户、按 memberOf 和组成员查找组的配置
+func testLDAPConfig(url string) *config.LDAPConfig {
+	return &config.LDAPConfig{
+		URL:               url,
+		Timeout:           2,
+		BindDN:            testServiceDN,
+		BindPassword:      "svc-secret",
+		BaseDN:            "dc=example,dc=com",
+		UserFilter:        "(&(objectClass=person)(uid={username}))",
+		EmailAttribute:    "mail",
+		PhoneAttribute:    "mobile",
+		MemberOfAttribute: "memberOf",
+		GroupBaseDN:       "ou=groups,dc=example,dc=com",
+		GroupFilter:       "(&(objectClass=groupOfNames)(member={dn}))",
+		GroupMappings: []config.LDAPGroupMapping{
+			{Group: "Admins", Roles: []string{"ADMIN"}},
+			{Group: "cn=ops,ou=groups,dc=example,dc=com", Roles: []string{"OPERATOR", "VIEWER"}},
+		},
+	}
+}
+
+// useProviders 按配置初始化认证提供者链，测试结束后恢复
+func useProviders(t *testing.T, cfg *config.AuthConfig) {
+	t.Helper()
+	previous, previousOIDC := providers, oidcProvider
+	t.Cleanup(func() { providers, oidcProvider = previous, previousOIDC })
+	if err := InitProviders(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	server := newTestLDAPServer(t, testDirectory()...)
+	provider, err := NewLDAPProvider(testLDAPConfig(server.URL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := provider.Authenticate("alice", "alice-pw")
+	if err != nil {
+		t.Fatalf("Authenticate(alice) error: %v", err)
+	}
+	if identity.Source != SourceLDAP || identity.Username != "alice" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if identity.Email != "alice@example.com" || identity.Phone != "13800000000" {
+		t.Errorf("email, phone = %q, %q", identity.Email, identity.Phone)
+	}
+	if got := strings.Join(identity.Roles, ","); got != "ADMIN" {
+		t.Errorf("alice roles = %q, want ADMIN", got)
+	}
+
+	identity, err = provider.Authenticate("bob", "bob-pw")
+	if err != nil {
+		t.Fatalf("Authenticate(bob) error: %v", err)
+	}
+	if got := strings.Join(identity.Roles, ","); got != "OPERATOR,VIEWER" {
+		t.Errorf("bob roles = %q, want OPERATOR,VIEWER", got)
+	}
+
+	if _, err := provider.Authenticate("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := provider.Authenticate("nobody", "pw"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestLDAPAuthenticateServiceBindFailure(t *testing.T) {
+	server := newTestLDAPServer(t, testDirectory()...)
+	cfg := testLDAPConfig(server.URL())
+	cfg.BindPassword = "wrong"
+	provider, err := NewLDAPProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务账号绑定失败是配置错误，不能当作用户密码错误
+	_, err = provider.Authenticate("alice", "alice-pw")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrUserNotFound) {
+		t.Errorf("error = %v, want service bind error", err)
+	}
+}
+
+func TestLDAPRejectsEmptyPassword(t *testing.T) {
+	server := newTestLDAPServer(t, testDirectory()...)
+	provider, err := NewLDAPProvider(testLDAPConfig(server.URL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务器将空密码绑定视为成功的未认证绑定，必须在绑定前拒绝
+	if _, err := provider.Authenticate("alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("error = %v, want ErrInvalidCredentials", err)
+	}
+	for _, dn := range server.boundDNs() {
+		if strings.EqualFold(dn, testAliceDN) {
+			t.Errorf("server received bind for %s with empty password", dn)
+		}
+	}
+}
+
+func TestValidateUserSyncsLDAPUserAndRoles(t *testing.T) {
+	store := newFakeDB(t)
+	server := newTestLDAPServer(t, testDirectory()...)
+	useProviders(t, &config.AuthConfig{
+		Providers: []string{"ldap", "local"},
+		LDAP:      *testLDAPConfig(server.URL()),
+	})
+
+	userID, err := ValidateUser("alice", "alice-pw")
+	if err != nil {
+		t.Fatalf("ValidateUser error: %v", err)
+	}
+	user := store.user("alice")
+	if user == nil || user.id != userID {
+		t.Fatalf("alice not created, user = %+v", user)
+	}
+	if user.authSource != SourceLDAP || user.email != "alice@example.com" || user.passwordHash != "" {
+		t.Errorf("synced user = %+v", user)
+	}
+	if got := store.roleNames(userID); strings.Join(got, ",") != "ADMIN" {
+		t.Errorf("roles = %v, want [ADMIN]", got)
+	}
+
+	// 再次登录时按最新的组成员关系替换角色
+	server.mu.Lock()
+	server.entries[1].attributes["memberOf"] = nil
+	server.entries[4].attributes["member"] = append(server.entries[4].attributes["member"], testAliceDN)
+	server.mu.Unlock()
+	if again, err := ValidateUser("alice", "alice-pw"); err != nil || again != userID {
+		t.Fatalf("second login = %d, %v; want %d", again, err, userID)
+	}
+	got := store.roleNames(userID)
+	sort.Strings(got)
+	if strings.Join(got, ",") != "OPERATOR,VIEWER" {
+		t.Errorf("roles after group change = %v, want [OPERATOR VIEWER]", got)
+	}
+
+	if _, err := ValidateUser("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestValidateUserFallsBackToLocalWhenLDAPUnavailable(t *testing.T) {
+	store := newFakeDB(t)
+	admin := store.addUser(t, "admin", "local-pw", SourceLocal)
+
+	// 监听后立即关闭，得到一个无法连接的地址
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "ldap://" + listener.Addr().String()
+	listener.Close()
+
+	useProviders(t, &config.AuthConfig{
+		Providers: []string{"ldap", "local"},
+		LDAP:      *testLDAPConfig(url),
+	})
+
+	userID, err := ValidateUser("admin", "local-pw")
+	if err != nil || userID != admin.id {
+		t.Fatalf("local login = %d, %v; want %d", userID, err, admin.id)
+	}
+	if _, err := ValidateUser("admin", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong local password error = %v, want ErrInvalidCredentials", err)
+	}
+
+	// 只有 LDAP 中的用户无法登录，返回认证服务不可用而不是用户不存在
+	_, err = ValidateUser("alice", "alice-pw")
+	if err == nil || errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("ldap-only user error = %v, want service unavailable", err)
+	}
+}
+
+func TestValidateUserDoesNotTakeOverLocalUser(t *testing.T) {
+	store := newFakeDB(t)
+	admin := store.addUser(t, "admin", "local-pw", SourceLocal)
+	store.userRoles[admin.id] = []int{store.roles["ADMIN"]}
+
+	server := newTestLDAPServer(t, testDirectory()...)
+	useProviders(t, &config.AuthConfig{
+		Providers: []string{"ldap", "local"},
+		LDAP:      *testLDAPConfig(server.URL()),
+	})
+
+	// LDAP 中同名用户的密码正确，但不能登录为本地管理员
+	if _, err := ValidateUser("admin", "ldap-admin-pw"); err == nil {
+		t.Fatal("ldap user logged in as local admin")
+	}
+	user := store.user("admin")
+	if user.authSource != SourceLocal {
+		t.Errorf("auth source = %s, want LOCAL", user.authSource)
+	}
+	if got := store.roleNames(admin.id); strings.Join(got, ",") != "ADMIN" {
+		t.Errorf("roles = %v, want [ADMIN]", got)
+	}
+
+	// 本地密码仍然有效
+	if userID, err := ValidateUser("admin", "local-pw"); err != nil || userID != admin.id {
+		t.Errorf("local login = %d, %v; want %d", userID, err, admin.id)
+	}
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// OIDC 登录参数
+const (
+	OIDCStateCookie    = "oidc_state"     // 保存登录状态的 Cookie
+	OIDCStateTTL       = 10 * time.Minute // 从跳转到身份提供者到回调的最长时间
+	oidcRequestTimeout = 10 * time.Second
+
+	defaultOIDCUsernameClaim = "preferred_username"
+	defaultOIDCEmailClaim    = "email"
+	defaultOIDCPhoneClaim    = "phone_number"
+)
+
+// ErrOIDCState 回调的登录状态与发起登录时不一致或已过期
+var ErrOIDCState = errors.New("登录状态无效或已过期，请重新登录")
+
+// oidcProvider 启用 OIDC 单点登录时的身份提供者
+var oidcProvider *OIDCProvider
+
+// GetOIDCProvider 获取 OIDC 身份提供者，未启用时返回nil
+func GetOIDCProvider() *OIDCProvider {
+	return oidcProvider
+}
+
+// oidcLoginState 发起登录时生成的状态，签名后保存在 Cookie 中，回调时校验
+type oidcLoginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code_verifier
+	jwt.RegisteredClaims
+}
+
+// OIDCProvider OIDC 授权码模式登录：跳转到身份提供者登录，回调时用授权码和 PKCE 校验码换取令牌，
+// 通过身份提供者的 JWKS 校验 ID Token，按声明映射同步用户角色
+type OIDCProvider struct {
+	cfg        config.OIDCConfig
+	client     *http.Client
+	claimRoles map[string][]string // 声明名和声明值（小写）对应的角色名
+
+	mu       sync.Mutex
+	provider *oidc.Provider // 首次登录时通过发现端点获取，获取失败时下次登录重试
+}
+
+// NewOIDCProvider 创建 OIDC 身份提供者
+func NewOIDCProvider(cfg *config.OIDCConfig) (*OIDCProvider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("issuer, client_id and redirect_url are required")
+	}
+
+	p := &OIDCProvider{
+		cfg:    *cfg,
+		client: &http.Client{Timeout: oidcRequestTimeout},
+	}
+	if len(p.cfg.Scopes) == 0 {
+		p.cfg.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if p.cfg.UsernameClaim == "" {
+		p.cfg.UsernameClaim = defaultOIDCUsernameClaim
+	}
+	if p.cfg.EmailClaim == "" {
+		p.cfg.EmailClaim = defaultOIDCEmailClaim
+	}
+	if p.cfg.PhoneClaim == "" {
+		p.cfg.PhoneClaim = defaultOIDCPhoneClaim
+	}
+
+	if len(cfg.ClaimMappings) > 0 {
+		p.claimRoles = make(map[string][]string)
+		for _, mapping := range cfg.ClaimMappings {
+			key := claimKey(mapping.Claim, mapping.Value)
+			p.claimRoles[key] = append(p.claimRoles[key], mapping.Roles...)
+		}
+	}
+	return p, nil
+}
+
+// SuccessRedirect 登录成功后跳转的前端地址，为空时回调返回JSON
+func (p *OIDCProvider) SuccessRedirect() string {
+	return p.cfg.SuccessRedirect
+}
+
+// SecureCookie 回调地址为 HTTPS 时状态 Cookie 只通过 HTTPS 发送
+func (p *OIDCProvider) SecureCookie() bool {
+	return strings.HasPrefix(strings.ToLower(p.cfg.RedirectURL), "https://")
+}
+
+// context 携带超时 HTTP 客户端的上下文，用于访问身份提供者
+func (p *OIDCProvider) context(ctx context.Context) context.Context {
+	ctx = oidc.ClientContext(ctx, p.client)
+	return context.WithValue(ctx, oauth2.HTTPClient, p.client)
+}
+
+// discover 获取身份提供者的端点和 JWKS 地址，成功后缓存
+func (p *OIDCProvider) discover() (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider != nil {
+		return p.provider, nil
+	}
+
+	// 发现结果中的 JWKS 在之后校验令牌时按需刷新，不能使用请求的上下文
+	provider, err := oidc.NewProvider(p.context(context.Background()), p.cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc provider: %w", err)
+	}
+	p.provider = provider
+	return provider, nil
+}
+
+// oauth2Config 授权码模式的客户端配置
+func (p *OIDCProvider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+// AuthCodeURL 生成跳转到身份提供者的登录地址和签名后的登录状态，登录状态需保存到 OIDCStateCookie
+func (p *OIDCProvider) AuthCodeURL() (authURL, stateCookie string, err error) {
+	provider, err := p.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	loginState := &oidcLoginState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(OIDCStateTTL)),
+		},
+	}
+	stateCookie, err = jwt.NewWithClaims(jwt.SigningMethodHS256, loginState).SignedString(oidcStateKey())
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL = p.oauth2Config(provider).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(loginState.Verifier),
+	)
+	return authURL, stateCookie, nil
+}
+
+// Login 处理身份提供者的回调：校验登录状态，用授权码换取令牌并校验 ID Token，将用户同步为本地用户
+func (p *OIDCProvider) Login(ctx context.Context, code, state, stateCookie string) (userID int, username string, err error) {
+	loginState := &oidcLoginState{}
+	_, err = jwt.ParseWithClaims(stateCookie, loginState, func(token *jwt.Token) (interface{}, error) {
+		return oidcStateKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || state == "" || loginState.State != state {
+		return 0, "", ErrOIDCState
+	}
+
+	provider, err := p.discover()
+	if err != nil {
+		return 0, "", err
+	}
+	ctx = p.context(ctx)
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(loginState.Verifier))
+	if err != nil {
+		return 0, "", fmt.Errorf("exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return 0, "", errors.New("token response has no id_token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return 0, "", fmt.Errorf("verify id_token: %w", err)
+	}
+	if idToken.Nonce != loginState.Nonce {
+		return 0, "", errors.New("id_token nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return 0, "", fmt.Errorf("parse id_token claims: %w", err)
+	}
+	identity, err := p.identity(claims)
+	if err != nil {
+		return 0, "", err
+	}
+
+	userID, err = syncExternalUser(identity)
+	if err != nil {
+		return 0, "", err
+	}
+	return userID, identity.Username, nil
+}
+
+// identity 从 ID Token 的声明中获取用户名、邮箱、手机号和映射的角色
+func (p *OIDCProvider) identity(claims map[string]interface{}) (*Identity, error) {
+	identity := &Identity{Source: SourceOIDC}
+	if values := claimValues(claims, p.cfg.UsernameClaim); len(values) > 0 {
+		identity.Username = strings.TrimSpace(values[0])
+	}
+	if identity.Username == "" {
+		return nil, fmt.Errorf("id_token has no %s claim", p.cfg.UsernameClaim)
+	}
+	if len(identity.Username) > 64 {
+		return nil, fmt.Errorf("username is longer than 64 characters: %s", identity.Username)
+	}
+	if values := claimValues(claims, p.cfg.EmailClaim); len(values) > 0 {
+		identity.Email = values[0]
+	}
+	if values := claimValues(claims, p.cfg.PhoneClaim); len(values) > 0 {
+		identity.Phone = values[0]
+	}
+
+	if p.claimRoles != nil {
+		var keys []string
+		seen := make(map[string]bool)
+		for _, mapping := range p.cfg.ClaimMappings {
+			if seen[mapping.Claim] {
+				continue
+			}
+			seen[mapping.Claim] = true
+			for _, value := range claimValues(claims, mapping.Claim) {
+				keys = append(keys, claimKey(mapping.Claim, value))
+			}
+		}
+		identity.Roles = mapRoles(p.claimRoles, keys)
+	}
+	return identity, nil
+}
+
+// claimKey 声明映射的键，声明值不区分大小写
+func claimKey(claim, value string) string {
+	return claim + "=" + strings.ToLower(strings.TrimSpace(value))
+}
+
+// claimValues 获取声明的值，嵌套声明用点号分隔；数组声明返回每个元素，其余类型转换为字符串
+func claimValues(claims map[string]interface{}, path string) []string {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if value, ok = object[name]; !ok {
+			return nil
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				values = append(values, fmt.Sprint(item))
+			}
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// oidcStateKey 登录状态的签名密钥，由JWT密钥派生，与登录令牌的密钥不同
+func oidcStateKey() []byte {
+	return []byte("oidc-state:" + viper.GetString("server.jwt_secret"))
+}
+
+// randomString 生成用于 state 和 nonce 的随机字符串
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
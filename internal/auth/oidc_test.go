@@ -0,0 +1,512 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+const testOIDCClientID = "bigdata-manager"
+
+// testAuthCode 测试身份提供者签发的授权码
+type testAuthCode struct {
+	challenge string // PKCE code_challenge
+	idToken   string
+}
+
+// testIdP 基于 httptest 的身份提供者，提供发现端点、JWKS 和令牌端点；
+// authorize 模拟用户在身份提供者登录后签发授权码
+type testIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+	signer *rsa.PrivateKey // 签名 ID Token 的密钥，默认为 key
+
+	mu            sync.Mutex // 保护以下字段
+	codes         map[string]testAuthCode
+	verifiers     []string // 令牌端点收到的 code_verifier
+	tokenRequests int
+}
+
+// newTestIdP 启动身份提供者，测试结束后关闭
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdP{key: key, kid: "test-key", signer: key, codes: map[string]testAuthCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.handleDiscovery)
+	mux.HandleFunc("/keys", idp.handleKeys)
+	mux.HandleFunc("/token", idp.handleToken)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// Issuer 身份提供者地址
+func (idp *testIdP) Issuer() string {
+	return idp.server.URL
+}
+
+func (idp *testIdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                idp.Issuer(),
+		"authorization_endpoint":                idp.Issuer() + "/authorize",
+		"token_endpoint":                        idp.Issuer() + "/token",
+		"jwks_uri":                              idp.Issuer() + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (idp *testIdP) handleKeys(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": idp.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+// handleToken 用授权码换取令牌，code_verifier 与授权时的 code_challenge 不一致时拒绝
+func (idp *testIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.tokenRequests++
+	verifier := r.PostForm.Get("code_verifier")
+	idp.verifiers = append(idp.verifiers, verifier)
+
+	code, ok := idp.codes[r.PostForm.Get("code")]
+	if !ok || verifier == "" || s256Challenge(verifier) != code.challenge {
+		writeTestJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	delete(idp.codes, r.PostForm.Get("code"))
+
+	writeTestJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     code.idToken,
+	})
+}
+
+// authorize 校验登录地址的参数并签发授权码，ID Token 包含 claims，未指定的 iss、aud、exp、nonce 等使用正确的值
+func (idp *testIdP) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, idp.Issuer()+"/authorize?") {
+		t.Fatalf("auth URL = %s, want the authorization endpoint", authURL)
+	}
+	query := u.Query()
+	if query.Get("client_id") != testOIDCClientID || query.Get("response_type") != "code" {
+		t.Fatalf("auth URL query = %v", query)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("auth URL has no S256 code_challenge: %v", query)
+	}
+	if query.Get("state") == "" || query.Get("nonce") == "" {
+		t.Fatalf("auth URL has no state or nonce: %v", query)
+	}
+
+	now := time.Now()
+	defaults := jwt.MapClaims{
+		"iss":   idp.Issuer(),
+		"aud":   testOIDCClientID,
+		"sub":   "subject",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range defaults {
+		if _, ok := claims[name]; !ok {
+			claims[name] = value
+		}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	idToken, err := token.SignedString(idp.signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err = randomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.codes[code] = testAuthCode{challenge: query.Get("code_challenge"), idToken: idToken}
+	idp.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// stats 令牌端点的请求次数和收到的 code_verifier
+func (idp *testIdP) stats() (int, []string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.tokenRequests, append([]string(nil), idp.verifiers...)
+}
+
+func s256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// testOIDCConfig 测试身份提供者的客户端配置，groups 和 realm_access.roles 声明映射为角色
+func testOIDCConfig(issuer string) *config.OIDCConfig {
+	return &config.OIDCConfig{
+		Enabled:     true,
+		Issuer:      issuer,
+		ClientID:    testOIDCClientID,
+		RedirectURL: "http://localhost:8080/api/v1/auth/oidc/callback",
+		ClaimMappings: []config.OIDCClaimMapping{
+			{Claim: "groups", Value: "admins", Roles: []string{"ADMIN"}},
+			{Claim: "groups", Value: "operators", Roles: []string{"OPERATOR"}},
+			{Claim: "realm_access.roles", Value: "viewer", Roles: []string{"VIEWER", "OPERATOR"}},
+		},
+	}
+}
+
+func newTestOIDCProvider(t *testing.T, idp *testIdP) *OIDCProvider {
+	t.Helper()
+	provider, err := NewOIDCProvider(testOIDCConfig(idp.Issuer()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestOIDCLogin(t *testing.T) {
+	store := newFakeDB(t)
+	idp := newTestIdP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	authURL, stateCookie, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatalf("AuthCodeURL error: %v", err)
+	}
+	code, state := idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"phone_number":       "13800000000",
+		"groups":             []string{"Admins", "developers"},
+		"realm_access":       map[string]interface{}{"roles": []string{"viewer"}},
+	})
+
+	userID, username, err := provider.Login(context.Background(), code, state, stateCookie)
+	if err != nil {
+		t.Fatalf("Login error: %v", err)
+	}
+	if username != "alice" {
+		t.Errorf("username = %q, want alice", username)
+	}
+
+	// 令牌端点收到的 code_verifier 与登录地址中的 code_challenge 对应
+	requests, verifiers := idp.stats()
+	if requests != 1 || len(verifiers) != 1 {
+		t.Fatalf("token requests = %d, verifiers = %v, want one request", requests, verifiers)
+	}
+	challenge, _ := url.Parse(authURL)
+	if s256Challenge(verifiers[0]) != challenge.Query().Get("code_challenge") {
+		t.Errorf("code_verifier %q does not match code_challenge", verifiers[0])
+	}
+
+	user := store.user("alice")
+	if user == nil || user.id != userID {
+		t.Fatalf("user alice = %+v, want id %d", user, userID)
+	}
+	if user.authSource != SourceOIDC || user.email != "alice@example.com" || user.phone != "13800000000" || user.passwordHash != "" {
+		t.Errorf("user alice = %+v", user)
+	}
+	roles := store.roleNames(userID)
+	sort.Strings(roles)
+	if want := []string{"ADMIN", "OPERATOR", "VIEWER"}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("roles = %v, want %v", roles, want)
+	}
+
+	// 再次登录时按新的声明替换角色
+	authURL, stateCookie, err = provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state = idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "alice",
+		"groups":             "operators",
+	})
+	if _, _, err := provider.Login(context.Background(), code, state, stateCookie); err != nil {
+		t.Fatalf("second Login error: %v", err)
+	}
+	if roles := store.roleNames(userID); !reflect.DeepEqual(roles, []string{"OPERATOR"}) {
+		t.Errorf("roles after second login = %v, want [OPERATOR]", roles)
+	}
+	if user := store.user("alice"); user.email != "alice@example.com" {
+		t.Errorf("email after login without email claim = %q, want it kept", user.email)
+	}
+}
+
+func TestOIDCLoginRejectsStateMismatch(t *testing.T) {
+	store := newFakeDB(t)
+	idp := newTestIdP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	authURL, stateCookie, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.authorize(t, authURL, jwt.MapClaims{"preferred_username": "alice"})
+	_, otherCookie, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &oidcLoginState{
+		State:            state,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	}).SignedString([]byte("other-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &oidcLoginState{
+		State:            state,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+	}).SignedString(oidcStateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		state       string
+		stateCookie string
+	}{
+		{"wrong state", "other-state", stateCookie},
+		{"empty state", "", stateCookie},
+		{"cookie of another login", state, otherCookie},
+		{"no cookie", state, ""},
+		{"forged cookie", state, forged},
+		{"expired cookie", state, expired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := provider.Login(context.Background(), code, tt.state, tt.stateCookie)
+			if !errors.Is(err, ErrOIDCState) {
+				t.Errorf("Login error = %v, want ErrOIDCState", err)
+			}
+		})
+	}
+
+	// 登录状态无效时不会用授权码换取令牌
+	if requests, _ := idp.stats(); requests != 0 {
+		t.Errorf("token requests = %d, want 0", requests)
+	}
+	if store.user("alice") != nil {
+		t.Error("user alice was created")
+	}
+}
+
+func TestOIDCLoginRejectsNonceMismatch(t *testing.T) {
+	store := newFakeDB(t)
+	idp := newTestIdP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	authURL, stateCookie, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "alice",
+		"nonce":              "replayed-nonce",
+	})
+
+	_, _, err = provider.Login(context.Background(), code, state, stateCookie)
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("Login error = %v, want nonce mismatch", err)
+	}
+	if store.user("alice") != nil {
+		t.Error("user alice was created")
+	}
+}
+
+func TestOIDCLoginRejectsInvalidIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		signer *rsa.PrivateKey
+	}{
+		{"bad signature", jwt.MapClaims{}, otherKey},
+		{"wrong audience", jwt.MapClaims{"aud": "other-client"}, nil},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://idp.example.com"}, nil},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeDB(t)
+			idp := newTestIdP(t)
+			if tt.signer != nil {
+				idp.signer = tt.signer
+			}
+			provider := newTestOIDCProvider(t, idp)
+
+			authURL, stateCookie, err := provider.AuthCodeURL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.claims["preferred_username"] = "alice"
+			code, state := idp.authorize(t, authURL, tt.claims)
+
+			_, _, err = provider.Login(context.Background(), code, state, stateCookie)
+			if err == nil || !strings.Contains(err.Error(), "verify id_token") {
+				t.Fatalf("Login error = %v, want id_token verification failure", err)
+			}
+			if store.user("alice") != nil {
+				t.Error("user alice was created")
+			}
+		})
+	}
+}
+
+func TestOIDCLoginDoesNotTakeOverLocalUser(t *testing.T) {
+	store := newFakeDB(t)
+	admin := store.addUser(t, "admin", "local-pw", SourceLocal)
+	idp := newTestIdP(t)
+	provider := newTestOIDCProvider(t, idp)
+
+	authURL, stateCookie, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "admin",
+		"groups":             []string{"admins"},
+	})
+
+	if _, _, err := provider.Login(context.Background(), code, state, stateCookie); err == nil {
+		t.Fatal("Login as an existing local user succeeded")
+	}
+	if user := store.user("admin"); user.authSource != SourceLocal {
+		t.Errorf("admin auth source = %s, want %s", user.authSource, SourceLocal)
+	}
+	if roles := store.roleNames(admin.id); len(roles) != 0 {
+		t.Errorf("admin roles = %v, want unchanged", roles)
+	}
+}
+
+func TestOIDCIdentity(t *testing.T) {
+	provider, err := NewOIDCProvider(&config.OIDCConfig{
+		Issuer:        "https://idp.example.com",
+		ClientID:      testOIDCClientID,
+		RedirectURL:   "http://localhost:8080/api/v1/auth/oidc/callback",
+		UsernameClaim: "upn",
+		ClaimMappings: testOIDCConfig("").ClaimMappings,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		claims    map[string]interface{}
+		wantRoles []string
+		wantErr   bool
+	}{
+		{
+			name:      "array claim is case insensitive",
+			claims:    map[string]interface{}{"upn": "alice", "groups": []interface{}{" ADMINS ", "dev"}},
+			wantRoles: []string{"ADMIN"},
+		},
+		{
+			name:      "string claim",
+			claims:    map[string]interface{}{"upn": "alice", "groups": "operators"},
+			wantRoles: []string{"OPERATOR"},
+		},
+		{
+			name: "nested claim and duplicate roles",
+			claims: map[string]interface{}{
+				"upn":          "alice",
+				"groups":       []interface{}{"operators"},
+				"realm_access": map[string]interface{}{"roles": []interface{}{"viewer"}},
+			},
+			wantRoles: []string{"OPERATOR", "VIEWER"},
+		},
+		{
+			name:      "no matching claim clears roles",
+			claims:    map[string]interface{}{"upn": "alice", "realm_access": "viewer"},
+			wantRoles: []string{},
+		},
+		{
+			name:    "missing username claim",
+			claims:  map[string]interface{}{"preferred_username": "alice"},
+			wantErr: true,
+		},
+		{
+			name:    "username too long",
+			claims:  map[string]interface{}{"upn": strings.Repeat("a", 65)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := provider.identity(tt.claims)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("identity() = %+v, want error", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("identity() error: %v", err)
+			}
+			if identity.Username != "alice" || identity.Source != SourceOIDC {
+				t.Errorf("identity = %+v", identity)
+			}
+			sort.Strings(identity.Roles)
+			if !reflect.DeepEqual(identity.Roles, tt.wantRoles) {
+				t.Errorf("roles = %v, want %v", identity.Roles, tt.wantRoles)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/db"
+)
+
+// 用户的认证来源，与认证提供者的名称一致
+const (
+	SourceLocal = "LOCAL"
+	SourceLDAP  = "LDAP"
+	SourceOIDC  = "OIDC"
+
+	SourceService = "SERVICE" // 服务账号，不能登录，只能使用API令牌
+)
+
+// 认证错误
+var (
+	ErrUserNotFound       = errors.New("用户不存在或已禁用")
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+)
+
+// Identity 认证提供者验证通过的用户身份
+type Identity struct {
+	Source   string
+	UserID   int // 本地账号的用户ID，外部认证的用户登录后同步为本地用户
+	Username string
+	Email    string
+	Phone    string
+	Groups   []string // 所属组的DN和组名
+	Roles    []string // 按组或声明映射的角色名，为nil时不同步用户的角色
+}
+
+// Provider 认证提供者，用户不存在时返回 ErrUserNotFound，密码错误时返回 ErrInvalidCredentials
+type Provider interface {
+	Name() string
+	Authenticate(username, password string) (*Identity, error)
+}
+
+// providers 按顺序尝试的认证提供者，未初始化时只使用本地账号
+var providers = []Provider{&LocalProvider{}}
+
+// InitProviders 按配置初始化认证提供者链
+func InitProviders(cfg *config.AuthConfig) error {
+	names := cfg.Providers
+	if len(names) == 0 {
+		names = []string{"local"}
+	}
+
+	chain := make([]Provider, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "local":
+			chain = append(chain, &LocalProvider{})
+		case "ldap":
+			provider, err := NewLDAPProvider(&cfg.LDAP)
+			if err != nil {
+				return fmt.Errorf("ldap provider: %w", err)
+			}
+			chain = append(chain, provider)
+		default:
+			return fmt.Errorf("unknown auth provider: %s", name)
+		}
+	}
+
+	// OIDC 通过浏览器跳转登录，不参与用户名密码认证
+	var oidc *OIDCProvider
+	if cfg.OIDC.Enabled {
+		provider, err := NewOIDCProvider(&cfg.OIDC)
+		if err != nil {
+			return fmt.Errorf("oidc provider: %w", err)
+		}
+		oidc = provider
+	}
+
+	providers = chain
+	oidcProvider = oidc
+	return nil
+}
+
+// ValidateUser 验证用户名和密码，依次尝试各认证提供者，返回本地用户ID
+//
+// 前一个提供者找不到用户或不可用时尝试下一个，LDAP 不可用时本地账号仍可登录。
+// 都失败时优先返回密码错误，其次是认证服务不可用，最后是用户不存在。
+// 外部认证通过的用户同步为本地用户，并按组映射同步角色。
+func ValidateUser(username, password string) (int, error) {
+	var invalid, unavailable bool
+	for _, provider := range providers {
+		identity, err := provider.Authenticate(username, password)
+		switch {
+		case err == nil:
+			if identity.Source == SourceLocal {
+				return identity.UserID, nil
+			}
+			return syncExternalUser(identity)
+		case errors.Is(err, ErrInvalidCredentials):
+			invalid = true
+		case !errors.Is(err, ErrUserNotFound):
+			log.Printf("%s认证失败: %v", provider.Name(), err)
+			unavailable = true
+		}
+	}
+	switch {
+	case invalid:
+		return 0, ErrInvalidCredentials
+	case unavailable:
+		return 0, errors.New("认证服务不可用，请稍后重试")
+	default:
+		return 0, ErrUserNotFound
+	}
+}
+
+// LocalProvider 本地账号认证，只验证认证来源为本地的用户
+type LocalProvider struct{}
+
+// Name 提供者名称
+func (p *LocalProvider) Name() string {
+	return SourceLocal
+}
+
+// Authenticate 使用 user 表中的密码哈希验证密码
+func (p *LocalProvider) Authenticate(username, password string) (*Identity, error) {
+	var (
+		userID       int
+		passwordHash string
+	)
+	query := "SELECT id, password_hash FROM user WHERE username = ? AND status = 'ACTIVE' AND auth_source = ?"
+	err := db.DB.QueryRow(query, username, SourceLocal).Scan(&userID, &passwordHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Identity{Source: SourceLocal, UserID: userID, Username: username}, nil
+}
+
+// syncExternalUser 将外部认证的用户同步为本地用户：首次登录时创建，之后更新邮箱和手机号，
+// identity.Roles 不为nil时将用户的角色替换为映射的角色
+//
+// 同名的本地账号不会被外部认证接管；在平台中禁用的用户不能登录。
+func syncExternalUser(identity *Identity) (int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID int
+		status string
+		source string
+	)
+	err = tx.QueryRow("SELECT id, status, auth_source FROM user WHERE username = ? FOR UPDATE", identity.Username).
+		Scan(&userID, &status, &source)
+	switch {
+	case err == sql.ErrNoRows:
+		// 外部用户没有本地密码，密码哈希为空时本地认证不会通过
+		result, err := tx.Exec(
+			"INSERT INTO user (username, password_hash, email, phone, status, auth_source) VALUES (?, '', ?, ?, 'ACTIVE', ?)",
+			identity.Username, identity.Email, identity.Phone, identity.Source,
+		)
+		if err != nil {
+			return 0, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		userID = int(id)
+	case err != nil:
+		return 0, err
+	case source != identity.Source:
+		return 0, errors.New("用户名已被其他认证方式的账号使用")
+	case status != "ACTIVE":
+		return 0, ErrUserNotFound
+	default:
+		_, err = tx.Exec(
+			"UPDATE user SET email = IF(? = '', email, ?), phone = IF(? = '', phone, ?) WHERE id = ?",
+			identity.Email, identity.Email, identity.Phone, identity.Phone, userID,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if identity.Roles != nil {
+		if err := syncUserRoles(tx, userID, identity.Roles); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// syncUserRoles 将用户的全局角色替换为 roleNames 中存在的角色
+func syncUserRoles(tx *sql.Tx, userID int, roleNames []string) error {
+	if _, err := tx.Exec("DELETE FROM user_role WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, name := range roleNames {
+		result, err := tx.Exec("INSERT INTO user_role (user_id, role_id) SELECT ?, id FROM role WHERE name = ?", userID, name)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			log.Printf("映射的角色不存在: %s", name)
+		}
+	}
+	return nil
+}
+
+// mapRoles 按映射表获取 keys 对应的角色名并去重，没有匹配时返回空列表
+func mapRoles(mapping map[string][]string, keys []string) []string {
+	seen := make(map[string]bool)
+	roles := []string{}
+	for _, key := range keys {
+		for _, role := range mapping[key] {
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
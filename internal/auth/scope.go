@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"database/sql"
+
+	"github.com/TejParker/bigdata-manager/internal/db"
+)
+
+// ScopedPrivileges 可以按集群或服务授予的权限，其余权限只能通过 user_role 全局授予
+var ScopedPrivileges = map[string]bool{
+	"VIEW_CLUSTER":   true,
+	"MANAGE_CLUSTER": true,
+	"VIEW_SERVICE":   true,
+	"MANAGE_SERVICE": true,
+	"VIEW_HOST":      true,
+	"MANAGE_HOST":    true,
+	"VIEW_LOG":       true,
+	"VIEW_METRIC":    true,
+	"VIEW_ALERT":     true,
+}
+
+// Access 用户拥有某项权限的范围：全局，或者指定的集群和服务
+//
+// 在集群上拥有的权限作用于集群本身及其中的主机和服务，在服务上拥有的权限只作用于该服务。
+type Access struct {
+	Global     bool
+	ClusterIDs []int
+	ServiceIDs []int
+}
+
+// GetUserAccess 获取用户拥有指定权限的范围，已禁用的用户没有任何权限
+func GetUserAccess(userID int, privilege string) (*Access, error) {
+	access := &Access{}
+
+	var global bool
+	globalQuery := `
+		SELECT EXISTS(
+			SELECT 1 FROM user_role ur
+			JOIN role_privilege rp ON ur.role_id = rp.role_id
+			JOIN privilege p ON rp.privilege_id = p.id
+			JOIN user u ON ur.user_id = u.id
+			WHERE ur.user_id = ? AND u.status = 'ACTIVE' AND p.name = ?
+		)
+	`
+	if err := db.DB.QueryRow(globalQuery, userID, privilege).Scan(&global); err != nil {
+		return nil, err
+	}
+	if global {
+		access.Global = true
+		return access, nil
+	}
+	if !ScopedPrivileges[privilege] {
+		return access, nil
+	}
+
+	bindingQuery := `
+		SELECT DISTINCT rb.cluster_id, rb.service_id
+		FROM role_bindings rb
+		JOIN role_privilege rp ON rb.role_id = rp.role_id
+		JOIN privilege p ON rp.privilege_id = p.id
+		JOIN user u ON rb.user_id = u.id
+		WHERE rb.user_id = ? AND u.status = 'ACTIVE' AND p.name = ?
+	`
+	rows, err := db.DB.Query(bindingQuery, userID, privilege)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var clusterID int
+		var serviceID sql.NullInt64
+		if err := rows.Scan(&clusterID, &serviceID); err != nil {
+			return nil, err
+		}
+		if serviceID.Valid && serviceID.Int64 > 0 {
+			access.ServiceIDs = append(access.ServiceIDs, int(serviceID.Int64))
+		} else {
+			access.ClusterIDs = append(access.ClusterIDs, clusterID)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// Granted 是否在任意范围拥有权限
+func (a *Access) Granted() bool {
+	return a.Global || len(a.ClusterIDs) > 0 || len(a.ServiceIDs) > 0
+}
+
+// AllowCluster 是否在集群上拥有权限
+func (a *Access) AllowCluster(clusterID int) bool {
+	if a.Global {
+		return true
+	}
+	for _, id := range a.ClusterIDs {
+		if id == clusterID {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowService 是否在服务上拥有权限，clusterID 为服务所属的集群
+func (a *Access) AllowService(clusterID, serviceID int) bool {
+	if a.AllowCluster(clusterID) {
+		return true
+	}
+	for _, id := range a.ServiceIDs {
+		if id == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// HostIDs 有权访问的主机，即授权集群中的主机；全局权限时返回nil
+func (a *Access) HostIDs() ([]int, error) {
+	if a.Global {
+		return nil, nil
+	}
+	return queryIDs("SELECT id FROM host WHERE cluster_id IN ", a.ClusterIDs)
+}
+
+// AllServiceIDs 有权访问的服务，包括授权集群中的服务和单独授权的服务；全局权限时返回nil
+func (a *Access) AllServiceIDs() ([]int, error) {
+	if a.Global {
+		return nil, nil
+	}
+	ids, err := queryIDs("SELECT id FROM service WHERE cluster_id IN ", a.ClusterIDs)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, id := range a.ServiceIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// queryIDs 执行以 IN 结尾的查询，in 为空时返回空列表
+func queryIDs(query string, in []int) ([]int, error) {
+	ids := []int{}
+	if len(in) == 0 {
+		return ids, nil
+	}
+	placeholders, args := InClause(in)
+	rows, err := db.DB.Query(query+placeholders, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// InClause 生成 IN 条件的占位符和参数，如 (?, ?, ?)；ids 为空时生成 (NULL)，不匹配任何行
+func InClause(ids []int) (string, []interface{}) {
+	if len(ids) == 0 {
+		return "(NULL)", nil
+	}
+	placeholders := make([]byte, 0, len(ids)*3)
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		if i > 0 {
+			placeholders = append(placeholders, ", "...)
+		}
+		placeholders = append(placeholders, '?')
+		args[i] = id
+	}
+	return "(" + string(placeholders) + ")", args
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/TejParker/bigdata-manager/internal/db"
+)
+
+// 登录会话参数
+const (
+	RefreshTokenPrefix = "bdr_" // 刷新令牌的前缀，格式为 bdr_{会话ID}.{随机串}
+
+	defaultAccessTokenExpiration  = 15     // 访问令牌默认有效期，单位分钟
+	defaultRefreshTokenExpiration = 7 * 24 // 刷新令牌默认有效期，单位小时
+)
+
+// 会话错误
+var (
+	ErrInvalidRefreshToken = errors.New("无效或过期的刷新令牌，请重新登录")
+	ErrSessionRevoked      = errors.New("会话已失效，请重新登录")
+)
+
+// TokenPair 登录或刷新后签发的访问令牌和刷新令牌
+type TokenPair struct {
+	UserID       int
+	Username     string
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // 访问令牌的有效期，单位秒
+}
+
+// RefreshTokenTTL 刷新令牌的有效期，由 auth.refresh_token_expiration 配置，单位小时
+func RefreshTokenTTL() time.Duration {
+	hours := viper.GetInt("auth.refresh_token_expiration")
+	if hours <= 0 {
+		hours = defaultRefreshTokenExpiration
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// CreateSession 用户登录后创建会话，签发访问令牌和刷新令牌，并清理用户已过期的会话
+func CreateSession(userID int, username, clientIP, userAgent string) (*TokenPair, error) {
+	sessionID, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshHash, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	now := time.Now()
+	_, err = db.DB.Exec(
+		"INSERT INTO user_sessions (session_id, user_id, refresh_token_hash, client_ip, user_agent, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		sessionID, userID, refreshHash, clientIP, userAgent, now.Add(RefreshTokenTTL()), now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.DB.Exec("DELETE FROM user_sessions WHERE user_id = ? AND expires_at < ?", userID, now); err != nil {
+		log.Printf("清理过期会话失败: %v", err)
+	}
+
+	return newTokenPair(userID, username, sessionID, refreshToken)
+}
+
+// RefreshSession 使用刷新令牌签发新的访问令牌，并轮换刷新令牌，旧的刷新令牌随即失效
+//
+// 已轮换的刷新令牌再次被使用说明令牌可能已泄露，此时吊销整个会话。
+func RefreshSession(refreshToken, clientIP string) (*TokenPair, error) {
+	sessionID, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID    int
+		username  string
+		status    string
+		tokenHash string
+		expiresAt time.Time
+		revokedAt sql.NullTime
+	)
+	query := `
+		SELECT s.user_id, u.username, u.status, s.refresh_token_hash, s.expires_at, s.revoked_at
+		FROM user_sessions s
+		JOIN user u ON s.user_id = u.id
+		WHERE s.session_id = ?
+		FOR UPDATE
+	`
+	err = tx.QueryRow(query, sessionID).Scan(&userID, &username, &status, &tokenHash, &expiresAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if revokedAt.Valid || !expiresAt.After(now) || status != "ACTIVE" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if HashToken(refreshToken) != tokenHash {
+		if _, err := tx.Exec("UPDATE user_sessions SET revoked_at = ? WHERE session_id = ?", now, sessionID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		log.Printf("用户 %s 的刷新令牌被重复使用，已吊销会话，来源IP: %s", username, clientIP)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(
+		"UPDATE user_sessions SET refresh_token_hash = ?, client_ip = ?, expires_at = ?, last_refreshed_at = ? WHERE session_id = ?",
+		newHash, clientIP, now.Add(RefreshTokenTTL()), now, sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return newTokenPair(userID, username, sessionID, newToken)
+}
+
+// ValidateSession 检查访问令牌所属的会话未被吊销、未过期，且用户仍为启用状态
+func ValidateSession(claims *Claims) error {
+	if claims.SessionID == "" {
+		return ErrSessionRevoked
+	}
+
+	var active bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM user_sessions s
+			JOIN user u ON s.user_id = u.id
+			WHERE s.session_id = ? AND s.user_id = ? AND s.revoked_at IS NULL AND s.expires_at > ? AND u.status = 'ACTIVE'
+		)
+	`
+	if err := db.DB.QueryRow(query, claims.SessionID, claims.UserID, time.Now()).Scan(&active); err != nil {
+		return err
+	}
+	if !active {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// RevokeSession 吊销会话，会话的访问令牌和刷新令牌立即失效
+func RevokeSession(sessionID string) error {
+	_, err := db.DB.Exec("UPDATE user_sessions SET revoked_at = ? WHERE session_id = ? AND revoked_at IS NULL", time.Now(), sessionID)
+	return err
+}
+
+// RevokeUserSessions 吊销用户的全部会话，exceptSessionID 不为空时保留该会话
+func RevokeUserSessions(userID int, exceptSessionID string) error {
+	_, err := db.DB.Exec(
+		"UPDATE user_sessions SET revoked_at = ? WHERE user_id = ? AND session_id <> ? AND revoked_at IS NULL",
+		time.Now(), userID, exceptSessionID,
+	)
+	return err
+}
+
+// newTokenPair 为会话签发访问令牌
+func newTokenPair(userID int, username, sessionID, refreshToken string) (*TokenPair, error) {
+	accessToken, err := GenerateToken(userID, username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		UserID:       userID,
+		Username:     username,
+		SessionID:    sessionID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// newRefreshToken 生成会话的刷新令牌和保存到数据库的哈希
+func newRefreshToken(sessionID string) (token, hash string, err error) {
+	secret, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	token = RefreshTokenPrefix + sessionID + "." + secret
+	return token, HashToken(token), nil
+}
+
+// parseRefreshToken 获取刷新令牌所属的会话ID
+func parseRefreshToken(token string) (string, bool) {
+	if !strings.HasPrefix(token, RefreshTokenPrefix) {
+		return "", false
+	}
+	sessionID, secret, ok := strings.Cut(strings.TrimPrefix(token, RefreshTokenPrefix), ".")
+	if !ok || sessionID == "" || secret == "" {
+		return "", false
+	}
+	return sessionID, true
+}
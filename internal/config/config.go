@@ -2,19 +2,27 @@ package config
 
 // Config 应用程序配置
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Auth     AuthConfig     `mapstructure:"auth"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Email    EmailConfig    `mapstructure:"email"`    // 新增邮件配置
-	SMS      SMSConfig      `mapstructure:"sms"`      // 新增短信配置
-	Alert    AlertConfig    `mapstructure:"alert"`    // 新增告警配置
+	Server       ServerConfig            `mapstructure:"server"`
+	Database     DatabaseConfig          `mapstructure:"database"`
+	Auth         AuthConfig              `mapstructure:"auth"`
+	Logging      LoggingConfig           `mapstructure:"logging"`
+	Email        EmailConfig             `mapstructure:"email"`        // 新增邮件配置
+	SMS          SMSConfig               `mapstructure:"sms"`          // 新增短信配置
+	Alert        AlertConfig             `mapstructure:"alert"`        // 新增告警配置
+	Notification NotificationQueueConfig `mapstructure:"notification"` // 通知发送队列配置
+	LogIndex     LogIndexConfig          `mapstructure:"log_index"`    // 日志全文检索索引配置
+	LogPattern   LogPatternConfig        `mapstructure:"log_pattern"`  // 日志模式聚类配置
+	Monitor      MonitorConfig           `mapstructure:"monitor"`      // 监控服务配置
+	Log          LogConfig               `mapstructure:"log"`          // 日志服务配置
+	Retention    RetentionConfig         `mapstructure:"retention"`    // 数据保留配置
+	Export       ExportConfig            `mapstructure:"export"`       // 日志和指标导出配置
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port      int    `mapstructure:"port"`
-	JWTSecret string `mapstructure:"jwt_secret"`
+	Port        int    `mapstructure:"port"`
+	JWTSecret   string `mapstructure:"jwt_secret"`
+	ExternalURL string `mapstructure:"external_url"` // 平台对外访问地址，用于通知中的跳转链接
 }
 
 // DatabaseConfig 数据库配置
@@ -30,7 +38,59 @@ type DatabaseConfig struct {
 
 // AuthConfig 认证配置
 type AuthConfig struct {
-	TokenExpiration int `mapstructure:"token_expiration"` // 单位分钟
+	TokenExpiration        int        `mapstructure:"token_expiration"`         // 访问令牌有效期，单位分钟
+	RefreshTokenExpiration int        `mapstructure:"refresh_token_expiration"` // 刷新令牌有效期，单位小时
+	Providers              []string   `mapstructure:"providers"`                // 认证方式及尝试顺序: local、ldap，为空时只使用本地账号
+	LDAP                   LDAPConfig `mapstructure:"ldap"`                     // LDAP/AD 认证配置
+	OIDC                   OIDCConfig `mapstructure:"oidc"`                     // OIDC 单点登录配置
+}
+
+// LDAPConfig LDAP/AD 认证配置，先用服务账号查找用户，再以用户DN和密码绑定验证密码
+type LDAPConfig struct {
+	URL                string             `mapstructure:"url"`                  // 如 ldap://ad.example.com:389、ldaps://ad.example.com:636
+	StartTLS           bool               `mapstructure:"start_tls"`            // ldap:// 连接后是否升级为TLS
+	InsecureSkipVerify bool               `mapstructure:"insecure_skip_verify"` // 是否跳过服务器证书校验，仅用于测试
+	CACertFile         string             `mapstructure:"ca_cert_file"`         // 校验服务器证书的CA证书文件，为空时使用系统证书
+	Timeout            int                `mapstructure:"timeout"`              // 连接和查询超时，单位秒
+	BindDN             string             `mapstructure:"bind_dn"`              // 查找用户的服务账号，为空时匿名查找
+	BindPassword       string             `mapstructure:"bind_password"`
+	BaseDN             string             `mapstructure:"base_dn"`              // 查找用户的起始DN
+	UserFilter         string             `mapstructure:"user_filter"`          // 查找用户的过滤条件，{username} 替换为登录用户名
+	EmailAttribute     string             `mapstructure:"email_attribute"`      // 同步到本地用户的邮箱属性
+	PhoneAttribute     string             `mapstructure:"phone_attribute"`      // 同步到本地用户的手机号属性
+	MemberOfAttribute  string             `mapstructure:"member_of_attribute"`  // 用户条目中记录所属组DN的属性，如 AD 的 memberOf
+	GroupBaseDN        string             `mapstructure:"group_base_dn"`        // 查找组的起始DN，为空时使用 base_dn
+	GroupFilter        string             `mapstructure:"group_filter"`         // 查找用户所属组的过滤条件，{dn} 替换为用户DN，{username} 替换为用户名
+	GroupNameAttribute string             `mapstructure:"group_name_attribute"` // 组名属性，默认为 cn
+	GroupMappings      []LDAPGroupMapping `mapstructure:"group_mappings"`       // 组与角色的映射，登录时同步用户的角色
+}
+
+// LDAPGroupMapping LDAP组与平台角色的映射
+type LDAPGroupMapping struct {
+	Group string   `mapstructure:"group"` // 组名或组DN，不区分大小写
+	Roles []string `mapstructure:"roles"` // 角色名
+}
+
+// OIDCConfig OIDC 单点登录配置，使用授权码模式和 PKCE，通过身份提供者的 JWKS 校验 ID Token
+type OIDCConfig struct {
+	Enabled         bool               `mapstructure:"enabled"`
+	Issuer          string             `mapstructure:"issuer"` // 身份提供者地址，从 {issuer}/.well-known/openid-configuration 获取端点
+	ClientID        string             `mapstructure:"client_id"`
+	ClientSecret    string             `mapstructure:"client_secret"`    // 公共客户端为空
+	RedirectURL     string             `mapstructure:"redirect_url"`     // 平台的回调地址，需在身份提供者中登记
+	Scopes          []string           `mapstructure:"scopes"`           // 默认为 openid profile email
+	UsernameClaim   string             `mapstructure:"username_claim"`   // 作为平台用户名的声明，默认为 preferred_username
+	EmailClaim      string             `mapstructure:"email_claim"`      // 默认为 email
+	PhoneClaim      string             `mapstructure:"phone_claim"`      // 默认为 phone_number
+	ClaimMappings   []OIDCClaimMapping `mapstructure:"claim_mappings"`   // 声明与角色的映射，登录时同步用户的角色
+	SuccessRedirect string             `mapstructure:"success_redirect"` // 登录成功后跳转的前端地址，令牌放在URL片段中；为空时回调返回JSON
+}
+
+// OIDCClaimMapping ID Token 声明值与平台角色的映射
+type OIDCClaimMapping struct {
+	Claim string   `mapstructure:"claim"` // 声明名，嵌套声明用点号分隔，如 realm_access.roles
+	Value string   `mapstructure:"value"` // 声明值，声明为数组时包含该值即匹配
+	Roles []string `mapstructure:"roles"` // 角色名
 }
 
 // LoggingConfig 日志配置
@@ -45,19 +105,141 @@ type EmailConfig struct {
 	SMTPPort   int    `mapstructure:"smtp_port"`
 	Username   string `mapstructure:"username"`
 	Password   string `mapstructure:"password"`
-	From       string `mapstructure:"from"`
+	From       string `mapstructure:"from"` // 发件人，可带显示名称，如 "告警中心 <alerts@example.com>"
+
+	Security   string `mapstructure:"security"`    // 加密方式: tls(隐式TLS)、starttls、none，为空时465端口使用tls，其余端口在服务器支持时使用starttls
+	SkipVerify bool   `mapstructure:"skip_verify"` // 跳过服务器证书校验
+	Timeout    int    `mapstructure:"timeout"`     // 连接和发送超时，单位秒
 }
 
 // SMSConfig 短信配置
 type SMSConfig struct {
-	Provider string `mapstructure:"provider"`
-	APIKey   string `mapstructure:"api_key"`
-	Secret   string `mapstructure:"secret"`
+	Provider string `mapstructure:"provider"` // aliyun、tencent 或 http
+	APIKey   string `mapstructure:"api_key"`  // 阿里云 AccessKeyId / 腾讯云 SecretId / 网关令牌
+	Secret   string `mapstructure:"secret"`   // 阿里云 AccessKeySecret / 腾讯云 SecretKey / 网关签名密钥
+
+	Endpoint       string   `mapstructure:"endpoint"`        // 接口地址，为空时使用服务商默认地址，http 网关必填
+	Region         string   `mapstructure:"region"`          // 服务商地域
+	SignName       string   `mapstructure:"sign_name"`       // 短信签名
+	TemplateCode   string   `mapstructure:"template_code"`   // 阿里云 TemplateCode / 腾讯云 TemplateId
+	SDKAppID       string   `mapstructure:"sdk_app_id"`      // 腾讯云 SmsSdkAppId
+	TemplateParams []string `mapstructure:"template_params"` // 按顺序传给短信模板的变量名
+	Timeout        int      `mapstructure:"timeout"`         // 请求超时，单位秒
 }
 
 // AlertConfig 告警配置
 type AlertConfig struct {
 	ProcessInterval  int `mapstructure:"process_interval"`  // 告警处理间隔，单位秒
 	RetentionDays    int `mapstructure:"retention_days"`    // 告警保留天数
-	MaxNotifications int `mapstructure:"max_notifications"` // 每个告警最大通知次数
-} 
\ No newline at end of file
+	MaxNotifications int `mapstructure:"max_notifications"` // 每个告警最大通知次数，0表示不限制
+
+	GroupBy        []string `mapstructure:"group_by"`        // 默认分组标签，同一规则下标签值相同的告警合并通知
+	GroupWait      int      `mapstructure:"group_wait"`      // 新分组首次通知前的等待时间，单位秒
+	GroupInterval  int      `mapstructure:"group_interval"`  // 分组内有新告警时两次通知的最小间隔，单位秒
+	RepeatInterval int      `mapstructure:"repeat_interval"` // 未解决告警的重复通知间隔，单位秒
+} 
+
+// NotificationQueueConfig 通知发送队列配置
+type NotificationQueueConfig struct {
+	Workers      int `mapstructure:"workers"`       // 并发发送数
+	PollInterval int `mapstructure:"poll_interval"` // 扫描待发送任务的间隔，单位秒
+	MaxAttempts  int `mapstructure:"max_attempts"`  // 最大发送次数，超过后进入死信状态
+	RetryBackoff int `mapstructure:"retry_backoff"` // 首次重试等待时间，之后每次翻倍，单位秒
+	MaxBackoff   int `mapstructure:"max_backoff"`   // 重试等待时间上限，单位秒
+
+	// 各通知类型每分钟最多发送的通知数，键为小写的通知类型，如 dingtalk
+	RateLimits map[string]int `mapstructure:"rate_limits"`
+}
+
+// MonitorConfig 监控服务配置
+type MonitorConfig struct {
+	CollectionInterval int `mapstructure:"collection_interval"` // 指标收集间隔，单位秒
+	RetentionDays      int `mapstructure:"retention_days"`      // 指标保留天数，0表示不删除
+	BatchSize          int `mapstructure:"batch_size"`          // 指标上传批量大小
+}
+
+// LogConfig 日志服务配置
+type LogConfig struct {
+	RetentionDays int    `mapstructure:"retention_days"` // 日志保留天数，0表示不删除
+	Path          string `mapstructure:"path"`
+	Level         string `mapstructure:"level"`
+}
+
+// RetentionConfig 数据保留配置，log_record、metric、alert_events、log_pattern_counts 表的默认保留天数来自对应模块的配置
+type RetentionConfig struct {
+	Enabled       bool                              `mapstructure:"enabled"`
+	CheckInterval int                               `mapstructure:"check_interval"` // 清理间隔，单位秒
+	BatchSize     int                               `mapstructure:"batch_size"`     // 按行删除时每批删除的行数
+	Tables        map[string]int                    `mapstructure:"tables"`         // 各表的保留天数，覆盖默认值，0表示不删除
+	Services      map[string]ServiceRetentionConfig `mapstructure:"services"`       // 按服务名配置日志和指标的保留天数
+	Partition     PartitionConfig                   `mapstructure:"partition"`
+	Archive       ArchiveConfig                     `mapstructure:"archive"`
+}
+
+// ServiceRetentionConfig 单个服务的日志和指标保留天数，0表示使用表的保留天数
+type ServiceRetentionConfig struct {
+	LogDays    int `mapstructure:"log_days"`
+	MetricDays int `mapstructure:"metric_days"`
+}
+
+// PartitionConfig 按天分区配置，过期数据整个分区删除
+type PartitionConfig struct {
+	Tables    []string `mapstructure:"tables"`     // 按天分区的表，启动时将未分区的表转换为分区表，支持 log_record、metric
+	AheadDays int      `mapstructure:"ahead_days"` // 提前创建的分区天数
+}
+
+// ArchiveConfig 过期数据归档配置，删除前将数据写入 gzip 压缩的 NDJSON 文件
+type ArchiveConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Tables  []string `mapstructure:"tables"` // 需要归档的表，默认 log_record
+	Type    string   `mapstructure:"type"`   // local 或 s3
+	Dir     string   `mapstructure:"dir"`    // 本地归档目录
+	S3      S3Config `mapstructure:"s3"`
+}
+
+// S3Config S3 兼容存储配置
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"` // 如 https://s3.amazonaws.com、http://minio:9000
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	Prefix    string `mapstructure:"prefix"` // 对象名前缀
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+}
+
+// LogIndexConfig 日志全文检索索引配置
+type LogIndexConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`           // 是否启用，关闭时日志查询使用数据库模糊匹配
+	Dir              string `mapstructure:"dir"`               // 索引文件目录
+	PartitionMinutes int    `mapstructure:"partition_minutes"` // 每个索引段覆盖的时间跨度，单位分钟
+	PollInterval     int    `mapstructure:"poll_interval"`     // 扫描新日志的间隔，单位秒
+	BatchSize        int    `mapstructure:"batch_size"`        // 每批索引的日志数
+	FlushInterval    int    `mapstructure:"flush_interval"`    // 索引写入磁盘的间隔，单位秒
+	BackfillDays     int    `mapstructure:"backfill_days"`     // 首次启动时索引最近多少天的已有日志
+	RetentionDays    int    `mapstructure:"retention_days"`    // 索引保留天数，0表示不删除
+}
+
+// LogPatternConfig 日志模式聚类配置，按服务和组件将日志聚类为模板并统计各模板的日志数
+type LogPatternConfig struct {
+	Enabled         bool    `mapstructure:"enabled"`
+	PollInterval    int     `mapstructure:"poll_interval"`    // 扫描新日志的间隔，单位秒
+	BatchSize       int     `mapstructure:"batch_size"`       // 每批处理的日志数
+	BucketMinutes   int     `mapstructure:"bucket_minutes"`   // 日志数统计的时间粒度，单位分钟
+	Similarity      float64 `mapstructure:"similarity"`       // 日志归入已有模式所需的相同词比例
+	MaxPatterns     int     `mapstructure:"max_patterns"`     // 每个服务组件最多的模式数
+	BackfillHours   int     `mapstructure:"backfill_hours"`   // 首次启动时处理最近多少小时的已有日志
+	RetentionDays   int     `mapstructure:"retention_days"`   // 模式日志数统计的保留天数，0表示不删除
+	WindowMinutes   int     `mapstructure:"window_minutes"`   // 异常检测的默认观察窗口，单位分钟
+	BaselineMinutes int     `mapstructure:"baseline_minutes"` // 异常检测的默认基线时长，单位分钟
+	SpikeRatio      float64 `mapstructure:"spike_ratio"`      // 观察窗口日志数达到基线预期的多少倍视为突增
+	MinCount        int     `mapstructure:"min_count"`        // 视为突增的最少日志数
+}
+
+// ExportConfig 日志和指标导出配置，导出任务在后台执行，结果文件过期后删除
+type ExportConfig struct {
+	Dir         string `mapstructure:"dir"`          // 导出文件目录
+	Workers     int    `mapstructure:"workers"`      // 同时执行的导出任务数
+	BatchSize   int    `mapstructure:"batch_size"`   // 每次从数据库读取的行数
+	MaxRows     int64  `mapstructure:"max_rows"`     // 单个导出任务最多导出的行数，0表示不限制
+	ExpireHours int    `mapstructure:"expire_hours"` // 导出文件保留小时数，过期后删除文件和任务
+}
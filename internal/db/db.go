@@ -8,11 +8,15 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/spf13/viper"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 )
 
 var (
 	// DB 全局数据库连接池
 	DB *sql.DB
+	// GormDB 基于同一连接池的GORM实例，供告警、通知等服务使用
+	GormDB *gorm.DB
 )
 
 // InitDB 初始化数据库连接
@@ -46,6 +50,12 @@ func InitDB() error {
 		return fmt.Errorf("数据库Ping失败: %v", err)
 	}
 
+	// 复用连接池创建GORM实例
+	GormDB, err = gorm.Open(mysql.New(mysql.Config{Conn: DB}), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("初始化GORM失败: %v", err)
+	}
+
 	log.Println("数据库连接成功")
 	return nil
 }
@@ -0,0 +1,200 @@
+package logparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFormat %d 转换符的日期格式
+type dateFormat struct {
+	regex   string
+	layout  string // Go 时间格式，为空时按 Unix 时间戳解析
+	unit    time.Duration
+	iso     bool // ISO8601，日期和时间之间可以是空格或T
+	hasYear bool
+	hasDate bool // 包含月和日，只有时间时使用当天日期
+}
+
+// 日志中常见的 ISO8601 时间，秒的小数部分可以用逗号或点分隔
+const isoRegex = `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[,.]\d{1,9})?`
+
+// parseDateOption 解析 %d{} 中的日期格式，支持 log4j 的命名格式和 SimpleDateFormat 格式
+func parseDateOption(option string) (*dateFormat, error) {
+	switch option {
+	case "", "ISO8601", "DEFAULT":
+		return &dateFormat{regex: isoRegex, layout: "2006-01-02T15:04:05", iso: true, hasYear: true, hasDate: true}, nil
+	case "ABSOLUTE":
+		option = "HH:mm:ss,SSS"
+	case "DATE":
+		option = "dd MMM yyyy HH:mm:ss,SSS"
+	case "UNIX":
+		return &dateFormat{regex: `\d+`, unit: time.Second, hasYear: true, hasDate: true}, nil
+	case "UNIX_MILLIS":
+		return &dateFormat{regex: `\d+`, unit: time.Millisecond, hasYear: true, hasDate: true}, nil
+	}
+	return convertSimpleDateFormat(option)
+}
+
+// convertSimpleDateFormat 将 Java SimpleDateFormat 格式转换为正则表达式和 Go 时间格式
+func convertSimpleDateFormat(format string) (*dateFormat, error) {
+	f := &dateFormat{}
+	var regex, layout strings.Builder
+
+	for i := 0; i < len(format); {
+		c := format[i]
+
+		// 单引号内为普通文本，两个单引号表示单引号本身
+		if c == '\'' {
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("日期格式中的引号未闭合: %s", format)
+			}
+			text := format[i+1 : i+1+end]
+			if text == "" {
+				text = "'"
+			}
+			regex.WriteString(quoteRegex(text))
+			layout.WriteString(text)
+			i += end + 2
+			continue
+		}
+		if !isLetter(c) {
+			regex.WriteString(quoteRegex(string(c)))
+			layout.WriteByte(c)
+			i++
+			continue
+		}
+
+		n := 1
+		for i+n < len(format) && format[i+n] == c {
+			n++
+		}
+		i += n
+
+		var r, l string
+		switch c {
+		case 'y':
+			f.hasYear = true
+			if n == 2 {
+				r, l = `\d{2}`, "06"
+			} else {
+				r, l = `\d{4}`, "2006"
+			}
+		case 'M':
+			switch n {
+			case 1:
+				r, l = `\d{1,2}`, "1"
+			case 2:
+				r, l = `\d{2}`, "01"
+			case 3:
+				r, l = `[A-Za-z]{3}`, "Jan"
+			default:
+				r, l = `[A-Za-z]+`, "January"
+			}
+		case 'd':
+			f.hasDate = true
+			r, l = digits(n), pick(n, "2", "02")
+		case 'H':
+			r, l = digits(n), "15"
+		case 'h':
+			r, l = digits(n), pick(n, "3", "03")
+		case 'm':
+			r, l = digits(n), pick(n, "4", "04")
+		case 's':
+			r, l = digits(n), pick(n, "5", "05")
+		case 'S':
+			if s := layout.String(); s == "" || (s[len(s)-1] != '.' && s[len(s)-1] != ',') {
+				return nil, fmt.Errorf("不支持的日期格式，毫秒前必须是点或逗号: %s", format)
+			}
+			r, l = fmt.Sprintf(`\d{%d}`, n), strings.Repeat("0", n)
+		case 'a':
+			r, l = `[AaPp][Mm]`, "PM"
+		case 'E':
+			r, l = `[A-Za-z]+`, pick(n-2, "Mon", "Monday")
+		case 'Z':
+			r, l = `[+-]\d{4}`, "-0700"
+		case 'X':
+			switch n {
+			case 1:
+				r, l = `(?:Z|[+-]\d{2})`, "Z07"
+			case 2:
+				r, l = `(?:Z|[+-]\d{4})`, "Z0700"
+			default:
+				r, l = `(?:Z|[+-]\d{2}:\d{2})`, "Z07:00"
+			}
+		case 'z':
+			r, l = `[A-Za-z]+`, "MST"
+		default:
+			return nil, fmt.Errorf("不支持的日期格式 %c: %s", c, format)
+		}
+		regex.WriteString(r)
+		layout.WriteString(l)
+	}
+
+	f.regex = regex.String()
+	f.layout = layout.String()
+	return f, nil
+}
+
+// digits 一位格式匹配1到2位数字，两位格式匹配2位数字
+func digits(n int) string {
+	if n == 1 {
+		return `\d{1,2}`
+	}
+	return `\d{2}`
+}
+
+// pick 一位格式使用 short，否则使用 long
+func pick(n int, short, long string) string {
+	if n <= 1 {
+		return short
+	}
+	return long
+}
+
+// quoteRegex 转义正则表达式中的特殊字符，空白匹配任意个数的空白
+func quoteRegex(text string) string {
+	var b strings.Builder
+	writeLiteral(&b, text)
+	return b.String()
+}
+
+// parse 解析日志中的时间，没有时区的时间按本地时区解析
+func (f *dateFormat) parse(value string) (time.Time, error) {
+	if f.layout == "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, 0).Add(time.Duration(n) * f.unit), nil
+	}
+	if f.iso && len(value) > 10 {
+		value = value[:10] + "T" + value[11:]
+	}
+
+	t, err := time.ParseInLocation(f.layout, value, time.Local)
+	if err != nil {
+		return t, err
+	}
+	return completeDate(t, f.hasYear, f.hasDate), nil
+}
+
+// completeDate 补全日志时间中缺少的年份或日期，补全后晚于当前时间的视为去年（前一天）的日志
+func completeDate(t time.Time, hasYear, hasDate bool) time.Time {
+	now := time.Now()
+	switch {
+	case !hasDate:
+		t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		if t.After(now.Add(time.Hour)) {
+			t = t.AddDate(0, 0, -1)
+		}
+	case !hasYear:
+		t = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+	}
+	return t
+}
@@ -0,0 +1,141 @@
+package logparse
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+// JSON 日志中各字段的常见名称，支持 logstash、ECS、log4j2 JsonLayout 等格式，按顺序查找
+var (
+	jsonTimestampKeys = []string{"@timestamp", "timestamp", "time", "ts", "date", "timeMillis", "instant"}
+	jsonLevelKeys     = []string{"level", "log.level", "severity", "levelname", "lvl"}
+	jsonLoggerKeys    = []string{"logger", "logger_name", "loggerName", "log.logger", "name"}
+	jsonThreadKeys    = []string{"thread", "thread_name", "threadName", "process.thread.name"}
+	jsonMessageKeys   = []string{"message", "msg", "@message"}
+	jsonStackKeys     = []string{"stack_trace", "stackTrace", "error.stack_trace", "exception", "stack"}
+)
+
+// jsonParser 每行一个JSON对象，不是JSON对象的行为续行
+type jsonParser struct{}
+
+func (jsonParser) Parse(line string) (Entry, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+		return Entry{Message: line}, false
+	}
+
+	var fields map[string]any
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return Entry{Message: line}, false
+	}
+
+	entry := Entry{
+		Level:   NormalizeLevel(jsonString(fields, jsonLevelKeys)),
+		Logger:  jsonString(fields, jsonLoggerKeys),
+		Thread:  jsonString(fields, jsonThreadKeys),
+		Message: jsonString(fields, jsonMessageKeys),
+	}
+	if entry.Message == "" {
+		entry.Message = line
+	}
+	if stack := jsonString(fields, jsonStackKeys); stack != "" {
+		entry.Message += "\n" + stack
+	}
+	for _, key := range jsonTimestampKeys {
+		if ts, ok := jsonTime(jsonLookup(fields, key)); ok {
+			entry.Timestamp = ts
+			break
+		}
+	}
+	return entry, true
+}
+
+// jsonLookup 查找字段，先按完整名称查找，再按点号分隔的路径查找嵌套对象
+func jsonLookup(fields map[string]any, key string) any {
+	if value, ok := fields[key]; ok {
+		return value
+	}
+	parts := strings.Split(key, ".")
+	if len(parts) == 1 {
+		return nil
+	}
+	var current any = fields
+	for _, part := range parts {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if current, ok = object[part]; !ok {
+			return nil
+		}
+	}
+	return current
+}
+
+// jsonString 返回第一个字符串或数字类型的字段值
+func jsonString(fields map[string]any, keys []string) string {
+	for _, key := range keys {
+		switch value := jsonLookup(fields, key).(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case json.Number:
+			return value.String()
+		}
+	}
+	return ""
+}
+
+// jsonTime 解析时间字段，支持 RFC3339 字符串、Unix 时间戳（根据大小判断单位）
+// 和 log4j2 的 {"epochSecond":..., "nanoOfSecond":...}
+func jsonTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+		if len(v) > 10 && (v[10] == ' ' || v[10] == 'T') {
+			if t, err := time.ParseInLocation("2006-01-02T15:04:05", v[:10]+"T"+v[11:], time.Local); err == nil {
+				return t, true
+			}
+		}
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil || f <= 0 {
+			return time.Time{}, false
+		}
+		switch {
+		case f >= 1e17:
+			return time.Unix(0, int64(f)), true
+		case f >= 1e14:
+			return time.UnixMicro(int64(f)), true
+		case f >= 1e11:
+			return time.UnixMilli(int64(f)), true
+		default:
+			sec, frac := math.Modf(f)
+			return time.Unix(int64(sec), int64(frac*1e9)), true
+		}
+	case map[string]any:
+		sec, ok := v["epochSecond"].(json.Number)
+		if !ok {
+			return time.Time{}, false
+		}
+		s, err := sec.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		var nanos int64
+		if n, ok := v["nanoOfSecond"].(json.Number); ok {
+			nanos, _ = n.Int64()
+		}
+		return time.Unix(s, nanos), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,205 @@
+package logparse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// levelRegex 日志级别，限定为常见级别名称以减少误匹配
+const levelRegex = `(?i:TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|SEVERE|FATAL|CRITICAL|FINE|FINER|FINEST)`
+
+// Log4jParser 按 log4j/logback 转换模式解析日志，匹配模式的行为新日志的开头
+type Log4jParser struct {
+	pattern string
+	re      *regexp.Regexp
+	date    *dateFormat // 模式中没有 %d 时为nil
+}
+
+// CompileLog4j 将 log4j/logback 转换模式编译为解析器
+//
+// 支持 %d{格式}、%p/%level、%c/%logger、%C/%class、%t/%thread、%m/%msg、%n 及宽度修饰符，如 %-5p、%.30c；
+// 其他转换符（如 %X、%L、%M）匹配任意内容。
+func CompileLog4j(pattern string) (*Log4jParser, error) {
+	p := &Log4jParser{pattern: pattern}
+	var b strings.Builder
+	b.WriteString("^")
+	captured := make(map[string]bool)
+
+	for i := 0; i < len(pattern); {
+		if pattern[i] != '%' {
+			end := strings.IndexByte(pattern[i:], '%')
+			if end < 0 {
+				end = len(pattern) - i
+			}
+			writeLiteral(&b, pattern[i:i+end])
+			i += end
+			continue
+		}
+
+		i++
+		if i >= len(pattern) {
+			return nil, fmt.Errorf("转换模式以 %% 结尾: %s", pattern)
+		}
+		if pattern[i] == '%' {
+			b.WriteString("%")
+			i++
+			continue
+		}
+
+		// 宽度修饰符，如 -5、.30、20.30
+		start := i
+		for i < len(pattern) && (pattern[i] == '-' || pattern[i] == '.' || isDigit(pattern[i])) {
+			i++
+		}
+		modifier := pattern[start:i]
+
+		start = i
+		for i < len(pattern) && isLetter(pattern[i]) {
+			i++
+		}
+		word := pattern[start:i]
+		if word == "" {
+			return nil, fmt.Errorf("无效的转换模式: %s", pattern)
+		}
+
+		var options []string
+		for i < len(pattern) && pattern[i] == '{' {
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("转换模式中的 { 未闭合: %s", pattern)
+			}
+			options = append(options, pattern[i+1:i+end])
+			i += end + 1
+		}
+
+		var piece string
+		switch word {
+		case "d", "date":
+			option := ""
+			if len(options) > 0 {
+				option = options[0]
+			}
+			format, err := parseDateOption(option)
+			if err != nil {
+				return nil, err
+			}
+			piece = capture(captured, "timestamp", format.regex)
+			if p.date == nil {
+				p.date = format
+			}
+		case "p", "le", "level":
+			piece = capture(captured, "level", levelRegex)
+		case "c", "lo", "logger", "C", "class":
+			piece = capture(captured, "logger", `\S+?`)
+		case "t", "thread", "tn", "threadName":
+			piece = capture(captured, "thread", `.*?`)
+		case "m", "msg", "message":
+			piece = capture(captured, "message", `.*`)
+		case "n":
+			continue
+		default:
+			piece = `.*?`
+		}
+
+		// 有最小宽度时不足的部分以空格填充，- 表示左对齐
+		minWidth := modifier
+		if dot := strings.IndexByte(minWidth, '.'); dot >= 0 {
+			minWidth = minWidth[:dot]
+		}
+		switch {
+		case strings.HasPrefix(minWidth, "-") && len(minWidth) > 1:
+			piece += `\s*`
+		case minWidth != "" && minWidth != "-":
+			piece = `\s*` + piece
+		}
+		b.WriteString(piece)
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("无效的转换模式 %s: %v", pattern, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+// mustCompileLog4j 编译内置的转换模式
+func mustCompileLog4j(pattern string) *Log4jParser {
+	p, err := CompileLog4j(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// capture 字段第一次出现时作为命名分组，再次出现时只匹配不提取
+func capture(captured map[string]bool, name, regex string) string {
+	if captured[name] {
+		return "(?:" + regex + ")"
+	}
+	captured[name] = true
+	return "(?P<" + name + ">" + regex + ")"
+}
+
+// writeLiteral 写入模式中的普通文本，连续空白匹配任意个数的空白
+func writeLiteral(b *strings.Builder, literal string) {
+	start := 0
+	for i := 0; i < len(literal); {
+		if !isSpace(literal[i]) {
+			i++
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(literal[start:i]))
+		for i < len(literal) && isSpace(literal[i]) {
+			i++
+		}
+		b.WriteString(`\s+`)
+		start = i
+	}
+	b.WriteString(regexp.QuoteMeta(literal[start:]))
+}
+
+// Parse 解析一行，不匹配转换模式的行为续行
+func (p *Log4jParser) Parse(line string) (Entry, bool) {
+	match := p.re.FindStringSubmatch(line)
+	if match == nil {
+		return Entry{Message: line}, false
+	}
+
+	group := func(name string) string {
+		if index := p.re.SubexpIndex(name); index >= 0 {
+			return match[index]
+		}
+		return ""
+	}
+
+	entry := Entry{
+		Level:   NormalizeLevel(group("level")),
+		Logger:  group("logger"),
+		Thread:  strings.TrimSpace(group("thread")),
+		Message: line,
+	}
+	if p.re.SubexpIndex("message") >= 0 {
+		entry.Message = group("message")
+	}
+	if p.date != nil {
+		if ts, err := p.date.parse(group("timestamp")); err == nil {
+			entry.Timestamp = ts
+		}
+	}
+	return entry, true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
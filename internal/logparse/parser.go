@@ -0,0 +1,167 @@
+// Package logparse 解析 log4j/logback、JSON、syslog 格式的日志，识别多行日志（如Java异常堆栈）的开头，
+// 提取时间、级别、logger 和线程
+package logparse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 日志格式
+const (
+	FormatAuto   = "auto"   // 自动识别常见格式，识别出格式后不匹配该格式的行作为续行
+	FormatLog4j  = "log4j"  // log4j/logback 转换模式，如 %d{ISO8601} %p %c: %m%n
+	FormatJSON   = "json"   // 每行一个JSON对象
+	FormatSyslog = "syslog" // RFC3164/RFC5424
+	FormatPlain  = "plain"  // 每行一条日志，只识别日志级别
+)
+
+// Entry 解析出的日志字段，未识别的字段为零值
+type Entry struct {
+	Timestamp time.Time
+	Level     string
+	Logger    string
+	Thread    string
+	Message   string
+}
+
+// Parser 日志行解析器，同一个文件使用同一个解析器
+type Parser interface {
+	// Parse 解析一行，该行是一条新日志的开头时返回true，否则为上一条日志的续行
+	Parse(line string) (Entry, bool)
+}
+
+// New 创建指定格式的解析器，pattern 为 log4j 格式的转换模式
+func New(format, pattern string) (Parser, error) {
+	switch format {
+	case "", FormatAuto:
+		return &autoParser{}, nil
+	case FormatLog4j:
+		if pattern == "" {
+			return nil, fmt.Errorf("log4j 格式必须配置转换模式")
+		}
+		return CompileLog4j(pattern)
+	case FormatJSON:
+		return jsonParser{}, nil
+	case FormatSyslog:
+		return syslogParser{}, nil
+	case FormatPlain:
+		return plainParser{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的日志格式: %s", format)
+	}
+}
+
+// 常见大数据组件的 log4j 转换模式，自动识别时按顺序尝试
+var presetPatterns = []string{
+	// Hadoop、Spark、Flink
+	"%d{ISO8601} %p %c: %m%n",
+	// Kafka
+	"[%d] %p %m (%c)%n",
+	// HBase、Hive
+	"%d{ISO8601} %-5p [%t] %c: %m%n",
+	// ZooKeeper
+	"%d{ISO8601} [myid:%X{myid}] - %-5p [%t:%C{1}@%L] - %m%n",
+	// logback 默认格式、Spring Boot
+	"%d{yyyy-MM-dd HH:mm:ss.SSS} [%thread] %-5level %logger{36} - %msg%n",
+}
+
+var presets = func() []*Log4jParser {
+	parsers := make([]*Log4jParser, len(presetPatterns))
+	for i, pattern := range presetPatterns {
+		parsers[i] = mustCompileLog4j(pattern)
+	}
+	return parsers
+}()
+
+// Detect 依次尝试 JSON、常见 log4j 格式和 syslog 解析单行日志
+func Detect(line string) (Entry, bool) {
+	if entry, ok := (jsonParser{}).Parse(line); ok {
+		return entry, true
+	}
+	for _, parser := range presets {
+		if entry, ok := parser.Parse(line); ok {
+			return entry, true
+		}
+	}
+	return (syslogParser{}).Parse(line)
+}
+
+// autoParser 自动识别格式，识别后不匹配该格式的行作为续行；尚未识别格式时，缩进行和异常堆栈行作为续行
+type autoParser struct {
+	detected Parser
+}
+
+func (p *autoParser) Parse(line string) (Entry, bool) {
+	if p.detected != nil {
+		if entry, ok := p.detected.Parse(line); ok {
+			return entry, true
+		}
+	}
+
+	if entry, ok := (jsonParser{}).Parse(line); ok {
+		p.detected = jsonParser{}
+		return entry, true
+	}
+	for _, parser := range presets {
+		if entry, ok := parser.Parse(line); ok {
+			p.detected = parser
+			return entry, true
+		}
+	}
+	if entry, ok := (syslogParser{}).Parse(line); ok {
+		p.detected = syslogParser{}
+		return entry, true
+	}
+
+	if p.detected != nil || isContinuation(line) {
+		return Entry{Message: line}, false
+	}
+	return Entry{Level: guessLevel(line), Message: line}, true
+}
+
+// continuationPattern Java异常堆栈中的续行
+var continuationPattern = regexp.MustCompile(`^(?:\s|at |Caused by:|Suppressed:|\.\.\. \d+ (?:more|common frames omitted))`)
+
+// isContinuation 是否为缩进行或异常堆栈行
+func isContinuation(line string) bool {
+	return line == "" || continuationPattern.MatchString(line)
+}
+
+// plainParser 每行一条日志
+type plainParser struct{}
+
+func (plainParser) Parse(line string) (Entry, bool) {
+	return Entry{Level: guessLevel(line), Message: line}, true
+}
+
+// levelPattern 未结构化的日志中出现的日志级别
+var levelPattern = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|SEVERE|FATAL|CRITICAL)\b`)
+
+// guessLevel 从日志开头识别日志级别
+func guessLevel(line string) string {
+	if len(line) > 128 {
+		line = line[:128]
+	}
+	return NormalizeLevel(levelPattern.FindString(line))
+}
+
+// NormalizeLevel 统一日志级别名称为 TRACE、DEBUG、INFO、WARN、ERROR、FATAL
+func NormalizeLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	switch level {
+	case "WARNING":
+		return "WARN"
+	case "ERR", "SEVERE":
+		return "ERROR"
+	case "CRITICAL", "CRIT", "EMERG", "EMERGENCY", "ALERT", "PANIC":
+		return "FATAL"
+	case "NOTICE", "INFORMATION":
+		return "INFO"
+	case "FINE", "FINER", "FINEST":
+		return "DEBUG"
+	}
+	return level
+}
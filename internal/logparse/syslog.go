@@ -0,0 +1,69 @@
+package logparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// RFC5424: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	rfc5424Pattern = regexp.MustCompile(`^<(\d{1,3})>1 (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[(?:[^\]\\]|\\.)*\])+)(?: (.*))?$`)
+	// RFC3164 及 rsyslog 写入文件的格式: [<PRI>]TIMESTAMP HOSTNAME TAG[PID]: MSG
+	rfc3164Pattern = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\S+) (\S+) ([^\s:\[]+)(?:\[([^\]]*)\])?: ?(.*)$`)
+)
+
+// syslogLevels syslog severity 对应的日志级别
+var syslogLevels = []string{"FATAL", "FATAL", "FATAL", "ERROR", "WARN", "INFO", "INFO", "DEBUG"}
+
+// syslogParser 解析 syslog，APP-NAME（TAG）作为 logger，PROCID（PID）作为线程
+type syslogParser struct{}
+
+func (syslogParser) Parse(line string) (Entry, bool) {
+	if match := rfc5424Pattern.FindStringSubmatch(line); match != nil {
+		entry := Entry{
+			Level:   syslogLevel(match[1], ""),
+			Logger:  nilValue(match[4]),
+			Thread:  nilValue(match[5]),
+			Message: strings.TrimPrefix(match[8], "\uFEFF"),
+		}
+		if ts, err := time.Parse(time.RFC3339, match[2]); err == nil {
+			entry.Timestamp = ts
+		}
+		return entry, true
+	}
+
+	if match := rfc3164Pattern.FindStringSubmatch(line); match != nil {
+		entry := Entry{
+			Level:   syslogLevel(match[1], match[6]),
+			Logger:  match[4],
+			Thread:  match[5],
+			Message: match[6],
+		}
+		if ts, err := time.Parse(time.RFC3339, match[2]); err == nil {
+			entry.Timestamp = ts
+		} else if ts, err := time.ParseInLocation(time.Stamp, match[2], time.Local); err == nil {
+			entry.Timestamp = completeDate(ts, false, true)
+		}
+		return entry, true
+	}
+
+	return Entry{Message: line}, false
+}
+
+// syslogLevel 根据 PRI 的 severity 确定日志级别，没有 PRI 时从日志内容识别
+func syslogLevel(pri, message string) string {
+	if n, err := strconv.Atoi(pri); err == nil && n <= 191 {
+		return syslogLevels[n%8]
+	}
+	return guessLevel(message)
+}
+
+// nilValue RFC5424 中 - 表示空值
+func nilValue(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,55 @@
+package logpattern
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Wildcard 模板中的变量部分
+const Wildcard = "<*>"
+
+// maxTokens 参与聚类的最大词数，超出部分忽略
+const maxTokens = 64
+
+// mask 日志中常见的变量及替换后的占位符，按顺序替换
+type mask struct {
+	re          *regexp.Regexp
+	placeholder string
+}
+
+var masks = []mask{
+	// HDFS 数据块，如 blk_1073741825_1001
+	{regexp.MustCompile(`blk_-?\d+(?:_\d+)?`), "<BLK>"},
+	// YARN、MapReduce 的应用、容器和任务ID，如 container_e17_1410901177871_0001_01_000005
+	{regexp.MustCompile(`\b(?:application|appattempt|container|job|task|attempt)_\w*\d\w*`), "<ID>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<UUID>"},
+	// IPv4 地址，可带端口
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<IP>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<HEX>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{16,}\b`), "<HEX>"},
+	{regexp.MustCompile(`\b\d+(?:\.\d+)?\b`), "<NUM>"},
+}
+
+// Tokenize 将日志内容的第一行替换变量后按空白切分，替换后仍包含数字的词也作为变量
+//
+// 多行日志（如异常堆栈）只使用第一行，堆栈中的行号不影响聚类。
+func Tokenize(message string) []string {
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		message = message[:i]
+	}
+	for _, m := range masks {
+		message = m.re.ReplaceAllString(message, m.placeholder)
+	}
+
+	fields := strings.Fields(message)
+	if len(fields) > maxTokens {
+		fields = fields[:maxTokens]
+	}
+	for i, field := range fields {
+		if strings.IndexFunc(field, unicode.IsDigit) >= 0 {
+			fields[i] = Wildcard
+		}
+	}
+	return fields
+}
@@ -0,0 +1,112 @@
+// Package logpattern 将日志内容聚类为模板，模板中的变量部分用占位符表示
+//
+// 聚类方法参考 Drain：词数和第一个词相同的日志分为一组，组内与已有模板逐词比较，
+// 相同词的比例达到阈值时归入该模板，不同的词替换为 <*>，否则新建模板。
+package logpattern
+
+import "strings"
+
+// Cluster 日志模板
+type Cluster struct {
+	Tokens []string
+}
+
+// Template 模板文本
+func (c *Cluster) Template() string {
+	return strings.Join(c.Tokens, " ")
+}
+
+// similarity 与日志各词相同的比例及模板中的变量数，变量不计为相同
+func (c *Cluster) similarity(tokens []string) (float64, int) {
+	same, wildcards := 0, 0
+	for i, token := range c.Tokens {
+		if token == Wildcard {
+			wildcards++
+		} else if token == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens)), wildcards
+}
+
+// merge 将与日志不同的词替换为变量，返回模板是否变化
+func (c *Cluster) merge(tokens []string) bool {
+	changed := false
+	for i, token := range c.Tokens {
+		if token != Wildcard && token != tokens[i] {
+			c.Tokens[i] = Wildcard
+			changed = true
+		}
+	}
+	return changed
+}
+
+// groupKey 日志分组：词数和第一个词
+type groupKey struct {
+	length int
+	first  string
+}
+
+func keyOf(tokens []string) groupKey {
+	key := groupKey{length: len(tokens)}
+	if len(tokens) > 0 {
+		key.first = tokens[0]
+	}
+	return key
+}
+
+// Miner 日志模板聚类，非并发安全
+type Miner struct {
+	similarity  float64
+	maxClusters int
+	groups      map[groupKey][]*Cluster
+	size        int
+}
+
+// NewMiner 创建模板聚类，similarity 为归入模板所需的相同词比例，maxClusters 为最多的模板数
+//
+// 模板数达到上限后，新日志归入同组中最相似的模板，同组没有模板时仍然新建。
+func NewMiner(similarity float64, maxClusters int) *Miner {
+	return &Miner{
+		similarity:  similarity,
+		maxClusters: maxClusters,
+		groups:      make(map[groupKey][]*Cluster),
+	}
+}
+
+// Add 添加已有的模板，用于恢复聚类状态
+func (m *Miner) Add(c *Cluster) {
+	key := keyOf(c.Tokens)
+	m.groups[key] = append(m.groups[key], c)
+	m.size++
+}
+
+// Len 模板数
+func (m *Miner) Len() int {
+	return m.size
+}
+
+// Match 将日志归入模板，created 表示新建了模板，changed 表示已有模板的变量部分增加
+func (m *Miner) Match(tokens []string) (c *Cluster, created, changed bool) {
+	key := keyOf(tokens)
+	group := m.groups[key]
+
+	var best *Cluster
+	bestSim, bestWildcards := -1.0, -1
+	for _, candidate := range group {
+		sim, wildcards := candidate.similarity(tokens)
+		if sim > bestSim || (sim == bestSim && wildcards > bestWildcards) {
+			best, bestSim, bestWildcards = candidate, sim, wildcards
+		}
+	}
+
+	full := m.maxClusters > 0 && m.size >= m.maxClusters
+	if best != nil && (bestSim >= m.similarity || full || len(tokens) == 0) {
+		return best, false, best.merge(tokens)
+	}
+
+	c = &Cluster{Tokens: append([]string(nil), tokens...)}
+	m.groups[key] = append(group, c)
+	m.size++
+	return c, true, false
+}
@@ -0,0 +1,25 @@
+package logstore
+
+// Batch 一批日志的内存索引，用于判断新写入的日志是否匹配查询（如实时日志流），不写入磁盘
+type Batch struct {
+	seg *segment
+}
+
+// NewBatch 为一批日志建立内存索引，日志需按ID递增排列
+func NewBatch(docs []*Document) *Batch {
+	seg := newSegment(0)
+	for _, doc := range docs {
+		seg.add(doc)
+	}
+	return &Batch{seg: seg}
+}
+
+// Match 返回匹配查询的日志ID，按ID递增排列
+func (b *Batch) Match(q Query) []int64 {
+	docs := q.eval(b.seg)
+	ids := make([]int64, len(docs))
+	for i, doc := range docs {
+		ids[i] = b.seg.IDs[doc]
+	}
+	return ids
+}
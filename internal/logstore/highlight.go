@@ -0,0 +1,143 @@
+package logstore
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Span 高亮片段在日志内容中的位置，按字符（而非字节）计算，End 不包含在内
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// highlighter 查询中用于高亮的正向条件，NOT 中的条件不参与高亮
+type highlighter struct {
+	terms    map[string]bool
+	prefixes []string
+	phrases  [][]string
+}
+
+// collect 收集高亮条件
+func (q *TermQuery) collect(h *highlighter) { h.terms[q.Term] = true }
+
+// collect 收集高亮条件
+func (q *PhraseQuery) collect(h *highlighter) { h.phrases = append(h.phrases, q.Terms) }
+
+// collect 收集高亮条件
+func (q *PrefixQuery) collect(h *highlighter) { h.prefixes = append(h.prefixes, q.Prefix) }
+
+// collect 字段条件不出现在日志内容中，不参与高亮
+func (q *FieldQuery) collect(h *highlighter) {}
+
+// collect 收集高亮条件
+func (q *AndQuery) collect(h *highlighter) {
+	for _, clause := range q.Must {
+		clause.collect(h)
+	}
+}
+
+// collect 收集高亮条件
+func (q *OrQuery) collect(h *highlighter) {
+	for _, clause := range q.Should {
+		clause.collect(h)
+	}
+}
+
+// collect 匹配全部时没有可高亮的内容
+func (q *MatchAllQuery) collect(h *highlighter) {}
+
+// collect 不匹配任何日志时没有可高亮的内容
+func (q *MatchNoneQuery) collect(h *highlighter) {}
+
+// Highlighter 根据查询计算日志内容中命中的位置
+type Highlighter struct {
+	h highlighter
+}
+
+// NewHighlighter 创建查询的高亮器，可重复用于多条日志
+func NewHighlighter(q Query) *Highlighter {
+	hl := &Highlighter{h: highlighter{terms: make(map[string]bool)}}
+	if q != nil {
+		q.collect(&hl.h)
+	}
+	return hl
+}
+
+// Highlight 返回日志内容中与查询匹配的片段，重叠或相邻的片段会合并
+func (hl *Highlighter) Highlight(message string) []Span {
+	h := &hl.h
+	if len(h.terms) == 0 && len(h.prefixes) == 0 && len(h.phrases) == 0 {
+		return nil
+	}
+
+	tokens := Tokenize(message)
+	var spans []Span
+	for _, token := range tokens {
+		if h.terms[token.Term] || h.matchPrefix(token.Term) {
+			spans = append(spans, Span{Start: token.Start, End: token.End})
+		}
+	}
+	for _, phrase := range h.phrases {
+		for i := 0; i+len(phrase) <= len(tokens); i++ {
+			matched := true
+			for j, term := range phrase {
+				if tokens[i+j].Term != term {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				spans = append(spans, Span{Start: tokens[i].Start, End: tokens[i+len(phrase)-1].End})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	return toRuneSpans(message, mergeSpans(spans))
+}
+
+// matchPrefix 词是否以任意一个前缀开头
+func (h *highlighter) matchPrefix(term string) bool {
+	for _, prefix := range h.prefixes {
+		if strings.HasPrefix(term, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSpans 排序并合并重叠的片段
+func mergeSpans(spans []Span) []Span {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span.Start <= last.End {
+			if span.End > last.End {
+				last.End = span.End
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// toRuneSpans 将字节偏移转换为字符偏移
+func toRuneSpans(text string, spans []Span) []Span {
+	result := make([]Span, len(spans))
+	byteOffset, runeOffset := 0, 0
+	advance := func(target int) int {
+		runeOffset += utf8.RuneCountInString(text[byteOffset:target])
+		byteOffset = target
+		return runeOffset
+	}
+	for i, span := range spans {
+		result[i] = Span{Start: advance(span.Start), End: advance(span.End)}
+	}
+	return result
+}
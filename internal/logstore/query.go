@@ -0,0 +1,518 @@
+package logstore
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query 日志查询条件
+//
+// 查询语法:
+//
+//	error timeout            同时包含 error 和 timeout（AND 可省略）
+//	error OR warn            包含任意一个
+//	error AND NOT timeout    包含 error 且不包含 timeout，NOT 也可写作 -timeout
+//	"connection refused"     短语，词需相邻且顺序一致
+//	OutOfMemory*             前缀匹配
+//	level:ERROR              字段匹配，字段值整体比较且不区分大小写
+//	host:(node-01 OR node-02) 对同一字段的多个值
+//	(a OR b) AND c           使用括号分组
+//
+// 优先级从高到低为 NOT、AND、OR，运算符需大写。
+type Query interface {
+	// eval 在段内求值，返回按升序排列的匹配文档编号
+	eval(seg *segment) []uint32
+	// collect 收集用于高亮的正向匹配条件
+	collect(h *highlighter)
+	// String 返回查询的规范形式
+	String() string
+}
+
+// TermQuery 匹配包含某个词的日志
+type TermQuery struct {
+	Term string
+}
+
+// PhraseQuery 匹配按顺序相邻出现的多个词
+type PhraseQuery struct {
+	Terms []string
+}
+
+// PrefixQuery 匹配包含以某前缀开头的词的日志
+type PrefixQuery struct {
+	Prefix string
+}
+
+// FieldQuery 匹配字段值，Prefix 为true时按前缀匹配
+type FieldQuery struct {
+	Field  string
+	Value  string
+	Prefix bool
+}
+
+// AndQuery 同时满足 Must 中的全部条件且不满足 MustNot 中的任何条件
+type AndQuery struct {
+	Must    []Query
+	MustNot []Query
+}
+
+// OrQuery 满足任意一个条件
+type OrQuery struct {
+	Should []Query
+}
+
+// MatchAllQuery 匹配全部日志
+type MatchAllQuery struct{}
+
+// MatchNoneQuery 不匹配任何日志
+type MatchNoneQuery struct{}
+
+// Term 创建词查询，词会按分词规则处理，切分为多个词时为短语查询
+func Term(text string) Query {
+	terms := Terms(text)
+	switch len(terms) {
+	case 0:
+		return nil
+	case 1:
+		return &TermQuery{Term: terms[0]}
+	default:
+		return &PhraseQuery{Terms: terms}
+	}
+}
+
+// Field 创建字段查询
+func Field(field, value string) Query {
+	return &FieldQuery{Field: strings.ToLower(field), Value: strings.ToLower(strings.TrimSpace(value))}
+}
+
+// And 组合多个条件，忽略nil条件，全部为nil时返回nil
+func And(clauses ...Query) Query {
+	and := &AndQuery{}
+	matchAll := false
+	for _, clause := range clauses {
+		if clause == nil {
+			continue
+		}
+		if _, ok := clause.(*MatchAllQuery); ok {
+			matchAll = true
+			continue
+		}
+		if inner, ok := clause.(*AndQuery); ok {
+			and.Must = append(and.Must, inner.Must...)
+			and.MustNot = append(and.MustNot, inner.MustNot...)
+			continue
+		}
+		and.Must = append(and.Must, clause)
+	}
+	if len(and.Must) == 1 && len(and.MustNot) == 0 {
+		return and.Must[0]
+	}
+	if len(and.Must) == 0 && len(and.MustNot) == 0 {
+		if matchAll {
+			return &MatchAllQuery{}
+		}
+		return nil
+	}
+	return and
+}
+
+// Or 组合多个可选条件，忽略nil条件，全部为nil时返回nil
+func Or(clauses ...Query) Query {
+	or := &OrQuery{}
+	for _, clause := range clauses {
+		if clause == nil {
+			continue
+		}
+		if inner, ok := clause.(*OrQuery); ok {
+			or.Should = append(or.Should, inner.Should...)
+			continue
+		}
+		or.Should = append(or.Should, clause)
+	}
+	switch len(or.Should) {
+	case 0:
+		return nil
+	case 1:
+		return or.Should[0]
+	}
+	return or
+}
+
+// Not 创建取反条件
+func Not(clause Query) Query {
+	if clause == nil {
+		return nil
+	}
+	return &AndQuery{MustNot: []Query{clause}}
+}
+
+// String 返回查询的规范形式
+func (q *TermQuery) String() string { return q.Term }
+
+// String 返回查询的规范形式
+func (q *PhraseQuery) String() string { return `"` + strings.Join(q.Terms, " ") + `"` }
+
+// String 返回查询的规范形式
+func (q *PrefixQuery) String() string { return q.Prefix + "*" }
+
+// String 返回查询的规范形式
+func (q *FieldQuery) String() string {
+	value := q.Value
+	if strings.ContainsAny(value, " ()\"") {
+		value = `"` + value + `"`
+	}
+	if q.Prefix {
+		value += "*"
+	}
+	return q.Field + ":" + value
+}
+
+// String 返回查询的规范形式
+func (q *AndQuery) String() string {
+	parts := make([]string, 0, len(q.Must)+len(q.MustNot))
+	for _, clause := range q.Must {
+		parts = append(parts, wrap(clause))
+	}
+	for _, clause := range q.MustNot {
+		parts = append(parts, "NOT "+wrap(clause))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// String 返回查询的规范形式
+func (q *OrQuery) String() string {
+	parts := make([]string, 0, len(q.Should))
+	for _, clause := range q.Should {
+		parts = append(parts, wrap(clause))
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// String 返回查询的规范形式
+func (q *MatchAllQuery) String() string { return "*" }
+
+// String 返回查询的规范形式
+func (q *MatchNoneQuery) String() string { return "-*" }
+
+// wrap 组合条件加括号
+func wrap(q Query) string {
+	switch q.(type) {
+	case *AndQuery, *OrQuery:
+		return "(" + q.String() + ")"
+	}
+	return q.String()
+}
+
+// SyntaxError 查询语法错误
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// ParseQuery 解析查询字符串，fields 为允许按字段查询的字段名，其余 name:value 形式按普通文本处理；
+// 查询为空时返回 MatchAllQuery
+func ParseQuery(input string, fields ...string) (Query, error) {
+	p := &parser{fields: make(map[string]bool, len(fields))}
+	for _, field := range fields {
+		p.fields[strings.ToLower(field)] = true
+	}
+	if err := p.lex(input); err != nil {
+		return nil, err
+	}
+
+	if len(p.tokens) == 0 {
+		return &MatchAllQuery{}, nil
+	}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	if q == nil {
+		return &MatchAllQuery{}, nil
+	}
+	return q, nil
+}
+
+// queryTokenKind 查询词法单元类型
+type queryTokenKind int
+
+const (
+	tokWord queryTokenKind = iota
+	tokPhrase
+	tokField
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+// queryToken 查询词法单元
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+// parser 查询语法分析器
+type parser struct {
+	fields map[string]bool
+	tokens []queryToken
+	pos    int
+	field  string // 当前所在的字段分组，如 host:(a OR b)
+}
+
+// lex 将查询字符串切分为词法单元
+func (p *parser) lex(input string) error {
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			p.tokens = append(p.tokens, queryToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			p.tokens = append(p.tokens, queryToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return &SyntaxError{Pos: i, Msg: "unterminated quoted phrase"}
+			}
+			p.tokens = append(p.tokens, queryToken{kind: tokPhrase, text: string(runes[i+1 : end]), pos: i})
+			i = end + 1
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			// 词首的减号表示取反，词中的连字符属于词本身
+			p.tokens = append(p.tokens, queryToken{kind: tokNot, text: "-", pos: i})
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			word := string(runes[i:end])
+			p.tokens = append(p.tokens, p.classify(word, i))
+			i = end
+		}
+	}
+	return nil
+}
+
+// classify 识别运算符和字段前缀
+func (p *parser) classify(word string, pos int) queryToken {
+	switch word {
+	case "AND", "&&":
+		return queryToken{kind: tokAnd, text: word, pos: pos}
+	case "OR", "||":
+		return queryToken{kind: tokOr, text: word, pos: pos}
+	case "NOT", "!":
+		return queryToken{kind: tokNot, text: word, pos: pos}
+	}
+	if idx := strings.IndexByte(word, ':'); idx > 0 && p.fields[strings.ToLower(word[:idx])] {
+		// 字段名作为单独的单元，字段值作为后续单元
+		p.tokens = append(p.tokens, queryToken{kind: tokField, text: strings.ToLower(word[:idx]), pos: pos})
+		value := word[idx+1:]
+		if value == "" {
+			// 字段值为短语或分组，由后续单元提供
+			return queryToken{kind: tokWord, text: "", pos: pos + idx + 1}
+		}
+		return queryToken{kind: tokWord, text: value, pos: pos + idx + 1}
+	}
+	return queryToken{kind: tokWord, text: word, pos: pos}
+}
+
+// peek 返回当前词法单元
+func (p *parser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// parseOr 解析 OR 表达式
+func (p *parser) parseOr() (Query, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	clauses := []Query{left}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokOr {
+			break
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, right)
+	}
+	if len(clauses) == 1 {
+		return left, nil
+	}
+	// OR 的任意一边为空（如只有标点的词）时整体按另一边匹配
+	return Or(clauses...), nil
+}
+
+// parseAnd 解析 AND 表达式，相邻条件之间默认为 AND
+func (p *parser) parseAnd() (Query, error) {
+	var clauses []Query
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokOr || tok.kind == tokRParen {
+			break
+		}
+		if tok.kind == tokAnd {
+			if len(clauses) == 0 {
+				return nil, &SyntaxError{Pos: tok.pos, Msg: "AND requires a left operand"}
+			}
+			p.pos++
+			if next, ok := p.peek(); !ok || next.kind == tokOr || next.kind == tokRParen || next.kind == tokAnd {
+				return nil, &SyntaxError{Pos: tok.pos, Msg: "AND requires a right operand"}
+			}
+			continue
+		}
+		clause, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) == 0 {
+		tok, ok := p.peek()
+		if !ok {
+			return nil, &SyntaxError{Pos: p.endPos(), Msg: "unexpected end of query"}
+		}
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return And(clauses...), nil
+}
+
+// parseUnary 解析 NOT 表达式
+func (p *parser) parseUnary() (Query, error) {
+	tok, _ := p.peek()
+	if tok.kind != tokNot {
+		return p.parsePrimary()
+	}
+	p.pos++
+	if _, ok := p.peek(); !ok {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "NOT requires an operand"}
+	}
+	clause, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return Not(clause), nil
+}
+
+// parsePrimary 解析词、短语、字段条件和括号分组
+func (p *parser) parsePrimary() (Query, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, &SyntaxError{Pos: p.endPos(), Msg: "unexpected end of query"}
+	}
+
+	switch tok.kind {
+	case tokLParen:
+		p.pos++
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.kind != tokRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "unbalanced parenthesis"}
+		}
+		p.pos++
+		return q, nil
+	case tokField:
+		return p.parseField()
+	case tokPhrase:
+		p.pos++
+		if p.field != "" {
+			return Field(p.field, tok.text), nil
+		}
+		return Term(tok.text), nil
+	case tokWord:
+		p.pos++
+		return p.word(tok.text), nil
+	}
+	return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+// parseField 解析 field:value、field:"value" 和 field:(a OR b)
+func (p *parser) parseField() (Query, error) {
+	fieldTok := p.tokens[p.pos]
+	if p.field != "" {
+		return nil, &SyntaxError{Pos: fieldTok.pos, Msg: "nested field query"}
+	}
+	p.pos++
+
+	valueTok, _ := p.peek()
+	p.pos++ // 紧随字段名的词（可能为空）
+	if valueTok.text != "" {
+		return p.fieldValue(fieldTok.text, valueTok.text), nil
+	}
+
+	next, ok := p.peek()
+	if !ok {
+		return nil, &SyntaxError{Pos: fieldTok.pos, Msg: "missing value for field " + fieldTok.text}
+	}
+	switch next.kind {
+	case tokPhrase:
+		p.pos++
+		return Field(fieldTok.text, next.text), nil
+	case tokLParen:
+		p.field = fieldTok.text
+		defer func() { p.field = "" }()
+		return p.parsePrimary()
+	}
+	return nil, &SyntaxError{Pos: fieldTok.pos, Msg: "missing value for field " + fieldTok.text}
+}
+
+// word 处理普通词，位于字段分组中时作为字段值
+func (p *parser) word(text string) Query {
+	if p.field != "" {
+		return p.fieldValue(p.field, text)
+	}
+	if strings.HasSuffix(text, "*") {
+		terms := Terms(strings.TrimSuffix(text, "*"))
+		if len(terms) == 1 {
+			return &PrefixQuery{Prefix: terms[0]}
+		}
+	}
+	return Term(text)
+}
+
+// fieldValue 创建字段条件，以 * 结尾时按前缀匹配
+func (p *parser) fieldValue(field, value string) Query {
+	if strings.HasSuffix(value, "*") && len(value) > 1 {
+		q := Field(field, strings.TrimSuffix(value, "*")).(*FieldQuery)
+		q.Prefix = true
+		return q
+	}
+	return Field(field, value)
+}
+
+// endPos 查询结尾位置
+func (p *parser) endPos() int {
+	if len(p.tokens) == 0 {
+		return 0
+	}
+	last := p.tokens[len(p.tokens)-1]
+	return last.pos + len([]rune(last.text))
+}
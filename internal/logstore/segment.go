@@ -0,0 +1,336 @@
+package logstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// postings 词的倒排列表，Docs 为升序的文档编号，Positions 为词在对应文档中出现的位置
+type postings struct {
+	Docs      []uint32
+	Positions [][]uint32
+}
+
+// segment 一个时间分区内的索引段
+//
+// 文档按加入顺序编号，编号即 IDs、Times 中的下标。同一分区内的日志按日志ID递增的顺序加入，
+// 因此可以用 MaxID 判断日志是否已经索引。
+type segment struct {
+	Start int64 // 分区起始时间，unix秒
+	MaxID int64 // 已索引的最大日志ID
+
+	IDs   []int64 // 日志ID
+	Times []int64 // 日志时间，unix纳秒
+
+	Terms  map[string]*postings // 日志内容的倒排索引
+	Fields map[string][]uint32  // 字段值的倒排索引，键为 field=value
+
+	dirty bool // 是否有未写入磁盘的修改
+
+	mu          sync.Mutex // 保护下面的有序词表缓存
+	sortedTerms []string
+	sortedField []string
+}
+
+// newSegment 创建空的索引段
+func newSegment(start int64) *segment {
+	return &segment{
+		Start:  start,
+		Terms:  make(map[string]*postings),
+		Fields: make(map[string][]uint32),
+	}
+}
+
+// add 将日志加入索引段，日志ID不大于 MaxID 时视为已索引并忽略
+func (s *segment) add(doc *Document) bool {
+	if doc.ID <= s.MaxID {
+		return false
+	}
+
+	docNum := uint32(len(s.IDs))
+	s.IDs = append(s.IDs, doc.ID)
+	s.Times = append(s.Times, doc.Timestamp.UnixNano())
+	s.MaxID = doc.ID
+
+	for _, token := range Tokenize(doc.Message) {
+		p, ok := s.Terms[token.Term]
+		if !ok {
+			p = &postings{}
+			s.Terms[token.Term] = p
+		}
+		last := len(p.Docs) - 1
+		if last >= 0 && p.Docs[last] == docNum {
+			p.Positions[last] = append(p.Positions[last], uint32(token.Position))
+		} else {
+			p.Docs = append(p.Docs, docNum)
+			p.Positions = append(p.Positions, []uint32{uint32(token.Position)})
+		}
+	}
+
+	for field, value := range doc.Fields {
+		if value == "" {
+			continue
+		}
+		key := fieldTerm(field, value)
+		s.Fields[key] = append(s.Fields[key], docNum)
+	}
+
+	s.dirty = true
+	s.mu.Lock()
+	s.sortedTerms, s.sortedField = nil, nil
+	s.mu.Unlock()
+	return true
+}
+
+// size 索引段中的日志数
+func (s *segment) size() int {
+	return len(s.IDs)
+}
+
+// allDocs 段内全部文档编号
+func (s *segment) allDocs() []uint32 {
+	docs := make([]uint32, len(s.IDs))
+	for i := range docs {
+		docs[i] = uint32(i)
+	}
+	return docs
+}
+
+// termsWithPrefix 返回以 prefix 开头的日志内容词
+func (s *segment) termsWithPrefix(prefix string) []string {
+	s.mu.Lock()
+	if s.sortedTerms == nil {
+		s.sortedTerms = sortedKeys(s.Terms)
+	}
+	terms := s.sortedTerms
+	s.mu.Unlock()
+	return rangePrefix(terms, prefix)
+}
+
+// fieldsWithPrefix 返回以 prefix 开头的字段索引键
+func (s *segment) fieldsWithPrefix(prefix string) []string {
+	s.mu.Lock()
+	if s.sortedField == nil {
+		s.sortedField = sortedKeys(s.Fields)
+	}
+	keys := s.sortedField
+	s.mu.Unlock()
+	return rangePrefix(keys, prefix)
+}
+
+// sortedKeys 返回 map 中按字典序排列的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// rangePrefix 在有序列表中查找以 prefix 开头的元素
+func rangePrefix(sorted []string, prefix string) []string {
+	start := sort.SearchStrings(sorted, prefix)
+	end := start
+	for end < len(sorted) && strings.HasPrefix(sorted[end], prefix) {
+		end++
+	}
+	return sorted[start:end]
+}
+
+// eval 在段内求值
+func (q *TermQuery) eval(seg *segment) []uint32 {
+	if p, ok := seg.Terms[q.Term]; ok {
+		return p.Docs
+	}
+	return nil
+}
+
+// eval 在段内求值，先求所有词的交集，再检查词的位置是否相邻
+func (q *PhraseQuery) eval(seg *segment) []uint32 {
+	lists := make([]*postings, len(q.Terms))
+	for i, term := range q.Terms {
+		p, ok := seg.Terms[term]
+		if !ok {
+			return nil
+		}
+		lists[i] = p
+	}
+
+	var result []uint32
+	cursors := make([]int, len(lists))
+	for first, doc := range lists[0].Docs {
+		cursors[0] = first
+		matched := true
+		for i := 1; i < len(lists); i++ {
+			docs := lists[i].Docs
+			for cursors[i] < len(docs) && docs[cursors[i]] < doc {
+				cursors[i]++
+			}
+			if cursors[i] >= len(docs) {
+				return result
+			}
+			if docs[cursors[i]] != doc {
+				matched = false
+				break
+			}
+		}
+		if matched && phraseAt(lists, cursors) {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+// phraseAt 检查当前文档中各词是否依次出现在相邻位置
+func phraseAt(lists []*postings, cursors []int) bool {
+	for _, start := range lists[0].Positions[cursors[0]] {
+		found := true
+		for i := 1; i < len(lists); i++ {
+			if !containsPosition(lists[i].Positions[cursors[i]], start+uint32(i)) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPosition 在升序的位置列表中查找位置
+func containsPosition(positions []uint32, pos uint32) bool {
+	i := sort.Search(len(positions), func(i int) bool { return positions[i] >= pos })
+	return i < len(positions) && positions[i] == pos
+}
+
+// eval 在段内求值
+func (q *PrefixQuery) eval(seg *segment) []uint32 {
+	var result []uint32
+	for _, term := range seg.termsWithPrefix(q.Prefix) {
+		result = union(result, seg.Terms[term].Docs)
+	}
+	return result
+}
+
+// eval 在段内求值
+func (q *FieldQuery) eval(seg *segment) []uint32 {
+	key := fieldTerm(q.Field, q.Value)
+	if !q.Prefix {
+		return seg.Fields[key]
+	}
+	var result []uint32
+	for _, k := range seg.fieldsWithPrefix(key) {
+		result = union(result, seg.Fields[k])
+	}
+	return result
+}
+
+// eval 在段内求值，没有正向条件时从全部文档中排除
+func (q *AndQuery) eval(seg *segment) []uint32 {
+	var result []uint32
+	if len(q.Must) == 0 {
+		result = seg.allDocs()
+	} else {
+		result = q.Must[0].eval(seg)
+		for _, clause := range q.Must[1:] {
+			if len(result) == 0 {
+				return nil
+			}
+			result = intersect(result, clause.eval(seg))
+		}
+	}
+	for _, clause := range q.MustNot {
+		if len(result) == 0 {
+			return nil
+		}
+		result = difference(result, clause.eval(seg))
+	}
+	return result
+}
+
+// eval 在段内求值
+func (q *OrQuery) eval(seg *segment) []uint32 {
+	var result []uint32
+	for _, clause := range q.Should {
+		result = union(result, clause.eval(seg))
+	}
+	return result
+}
+
+// eval 在段内求值
+func (q *MatchAllQuery) eval(seg *segment) []uint32 {
+	return seg.allDocs()
+}
+
+// eval 在段内求值
+func (q *MatchNoneQuery) eval(seg *segment) []uint32 {
+	return nil
+}
+
+// intersect 求两个升序列表的交集
+func intersect(a, b []uint32) []uint32 {
+	var result []uint32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// union 求两个升序列表的并集
+func union(a, b []uint32) []uint32 {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	result := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+// difference 求 a 中不在 b 中的元素
+func difference(a, b []uint32) []uint32 {
+	if len(b) == 0 {
+		return a
+	}
+	var result []uint32
+	j := 0
+	for _, v := range a {
+		for j < len(b) && b[j] < v {
+			j++
+		}
+		if j < len(b) && b[j] == v {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,405 @@
+package logstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 索引文件命名
+const (
+	segmentFilePrefix = "segment-"
+	segmentFileSuffix = ".gob"
+	checkpointFile    = "checkpoint.json"
+)
+
+// defaultPartition 默认的索引段时间跨度
+const defaultPartition = time.Hour
+
+// Document 待索引的日志
+type Document struct {
+	ID        int64
+	Timestamp time.Time
+	Message   string
+	Fields    map[string]string // 可按字段查询的值，如 host、service、level
+}
+
+// Options 索引配置
+type Options struct {
+	Dir       string        // 索引文件目录，为空时只保存在内存中
+	Partition time.Duration // 每个索引段覆盖的时间跨度，默认1小时
+}
+
+// SearchRequest 检索条件，Start、End 为零值时不限制
+type SearchRequest struct {
+	Query  Query
+	Start  time.Time
+	End    time.Time
+	Offset int
+	Limit  int
+}
+
+// SearchResult 检索结果，IDs 为当前页的日志ID，按日志时间倒序排列
+type SearchResult struct {
+	Total int
+	IDs   []int64
+}
+
+// Stats 索引统计信息
+type Stats struct {
+	Segments  int       `json:"segments"`
+	Documents int       `json:"documents"`
+	Terms     int       `json:"terms"`
+	LastID    int64     `json:"last_id"`
+	Oldest    time.Time `json:"oldest"` // 最早的索引段起始时间
+	Newest    time.Time `json:"newest"` // 最新的索引段结束时间
+}
+
+// checkpoint 已写入磁盘的索引进度
+type checkpoint struct {
+	LastID int64 `json:"last_id"`
+}
+
+// Store 按时间分区的日志倒排索引
+//
+// 日志按时间落入固定跨度的索引段，检索时只扫描与时间范围重叠的索引段。
+// 日志原文不保存在索引中，检索结果为日志ID，由调用方从日志表读取。
+type Store struct {
+	opts Options
+
+	mu       sync.RWMutex
+	segments map[int64]*segment
+	lastID   int64
+}
+
+// Open 打开索引，加载目录中已保存的索引段
+func Open(opts Options) (*Store, error) {
+	if opts.Partition <= 0 {
+		opts.Partition = defaultPartition
+	}
+	s := &Store{
+		opts:     opts,
+		segments: make(map[int64]*segment),
+	}
+	if opts.Dir == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %v", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load 加载索引段和进度
+func (s *Store) load() error {
+	data, err := os.ReadFile(filepath.Join(s.opts.Dir, checkpointFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read index checkpoint: %v", err)
+	}
+	if err == nil {
+		var cp checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return fmt.Errorf("failed to parse index checkpoint: %v", err)
+		}
+		s.lastID = cp.LastID
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.opts.Dir, segmentFilePrefix+"*"+segmentFileSuffix))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		seg, err := readSegment(file)
+		if err != nil {
+			return fmt.Errorf("failed to load index segment %s: %v", filepath.Base(file), err)
+		}
+		s.segments[seg.Start] = seg
+	}
+	return nil
+}
+
+// LastID 已索引的最大日志ID，重新打开索引后为最近一次写入磁盘时的进度
+func (s *Store) LastID() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastID
+}
+
+// Index 将日志加入索引，docs 需按日志ID递增排列，已索引的日志会被忽略；返回新加入的日志数
+func (s *Store) Index(docs []*Document) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := 0
+	for _, doc := range docs {
+		start := s.partitionStart(doc.Timestamp)
+		seg, ok := s.segments[start]
+		if !ok {
+			seg = newSegment(start)
+			s.segments[start] = seg
+		}
+		if seg.add(doc) {
+			added++
+		}
+		if doc.ID > s.lastID {
+			s.lastID = doc.ID
+		}
+	}
+	return added
+}
+
+// partitionStart 计算时间所属索引段的起始时间
+func (s *Store) partitionStart(t time.Time) int64 {
+	return t.Truncate(s.opts.Partition).Unix()
+}
+
+// Search 检索日志，结果按日志时间倒序、同一时间按日志ID倒序排列
+func (s *Store) Search(req *SearchRequest) *SearchResult {
+	query := req.Query
+	if query == nil {
+		query = &MatchAllQuery{}
+	}
+	startNano, endNano := timeBounds(req.Start, req.End)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	partition := int64(s.opts.Partition)
+	result := &SearchResult{}
+	for _, seg := range s.overlapping(req.Start, req.End) {
+		docs := query.eval(seg)
+		// 索引段部分落在时间范围外时逐条过滤
+		if segStart := seg.Start * int64(time.Second); segStart < startNano || segStart+partition > endNano {
+			docs = filterTime(seg, docs, startNano, endNano)
+		}
+		if len(docs) == 0 {
+			continue
+		}
+
+		// 当前页所在的索引段才需要排序取出日志ID，求值结果可能直接引用倒排列表，排序前先复制
+		pageStart := req.Offset - result.Total
+		if req.Limit > 0 && len(result.IDs) < req.Limit && pageStart < len(docs) {
+			docs = append([]uint32(nil), docs...)
+			sortByTimeDesc(seg, docs)
+			if pageStart < 0 {
+				pageStart = 0
+			}
+			for _, doc := range docs[pageStart:] {
+				if len(result.IDs) >= req.Limit {
+					break
+				}
+				result.IDs = append(result.IDs, seg.IDs[doc])
+			}
+		}
+		result.Total += len(docs)
+	}
+	return result
+}
+
+// Count 统计时间范围内匹配查询的日志数
+func (s *Store) Count(q Query, start, end time.Time) int {
+	result := s.Search(&SearchRequest{Query: q, Start: start, End: end})
+	return result.Total
+}
+
+// overlapping 返回与时间范围重叠的索引段，按时间倒序排列
+func (s *Store) overlapping(start, end time.Time) []*segment {
+	partition := int64(s.opts.Partition / time.Second)
+	segments := make([]*segment, 0, len(s.segments))
+	for _, seg := range s.segments {
+		if !start.IsZero() && seg.Start+partition <= start.Unix() {
+			continue
+		}
+		if !end.IsZero() && seg.Start > end.Unix() {
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Start > segments[j].Start })
+	return segments
+}
+
+// timeBounds 将时间范围转换为unix纳秒，零值表示不限制
+func timeBounds(start, end time.Time) (int64, int64) {
+	startNano, endNano := int64(-1<<63), int64(1<<63-1)
+	if !start.IsZero() {
+		startNano = start.UnixNano()
+	}
+	if !end.IsZero() {
+		endNano = end.UnixNano()
+	}
+	return startNano, endNano
+}
+
+// filterTime 过滤时间范围外的文档
+func filterTime(seg *segment, docs []uint32, startNano, endNano int64) []uint32 {
+	result := make([]uint32, 0, len(docs))
+	for _, doc := range docs {
+		if t := seg.Times[doc]; t >= startNano && t <= endNano {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+// sortByTimeDesc 按日志时间倒序排列，时间相同时按日志ID倒序
+func sortByTimeDesc(seg *segment, docs []uint32) {
+	sort.Slice(docs, func(i, j int) bool {
+		ti, tj := seg.Times[docs[i]], seg.Times[docs[j]]
+		if ti != tj {
+			return ti > tj
+		}
+		return seg.IDs[docs[i]] > seg.IDs[docs[j]]
+	})
+}
+
+// DeleteBefore 删除结束时间不晚于 t 的索引段，返回删除的索引段数
+func (s *Store) DeleteBefore(t time.Time) (int, error) {
+	partition := int64(s.opts.Partition / time.Second)
+
+	s.mu.Lock()
+	var expired []int64
+	for start := range s.segments {
+		if start+partition <= t.Unix() {
+			expired = append(expired, start)
+			delete(s.segments, start)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.opts.Dir == "" {
+		return len(expired), nil
+	}
+	for _, start := range expired {
+		if err := os.Remove(s.segmentPath(start)); err != nil && !os.IsNotExist(err) {
+			return len(expired), err
+		}
+	}
+	return len(expired), nil
+}
+
+// Stats 返回索引统计信息
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := Stats{Segments: len(s.segments), LastID: s.lastID}
+	var oldest, newest int64
+	for start, seg := range s.segments {
+		stats.Documents += seg.size()
+		stats.Terms += len(seg.Terms)
+		if oldest == 0 || start < oldest {
+			oldest = start
+		}
+		if start > newest {
+			newest = start
+		}
+	}
+	if len(s.segments) > 0 {
+		stats.Oldest = time.Unix(oldest, 0)
+		stats.Newest = time.Unix(newest, 0).Add(s.opts.Partition)
+	}
+	return stats
+}
+
+// Flush 将有修改的索引段和索引进度写入磁盘
+//
+// 先写索引段再写进度，中途失败时下次启动会从较早的进度重新索引，已在索引段中的日志按ID去重。
+func (s *Store) Flush() error {
+	if s.opts.Dir == "" {
+		return nil
+	}
+
+	// 在写锁内编码，保证写入的索引段与进度一致
+	s.mu.Lock()
+	encoded := make(map[int64][]byte)
+	for start, seg := range s.segments {
+		if !seg.dirty {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(seg); err != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("failed to encode index segment: %v", err)
+		}
+		encoded[start] = buf.Bytes()
+		seg.dirty = false
+	}
+	lastID := s.lastID
+	s.mu.Unlock()
+
+	for start, data := range encoded {
+		if err := writeFileAtomic(s.segmentPath(start), data); err != nil {
+			s.markDirty(encoded)
+			return err
+		}
+	}
+
+	data, err := json.Marshal(checkpoint{LastID: lastID})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.opts.Dir, checkpointFile), data); err != nil {
+		s.markDirty(encoded)
+		return err
+	}
+	return nil
+}
+
+// markDirty 写入失败后重新标记索引段，等待下次写入
+func (s *Store) markDirty(encoded map[int64][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for start := range encoded {
+		if seg, ok := s.segments[start]; ok {
+			seg.dirty = true
+		}
+	}
+}
+
+// segmentPath 索引段文件路径
+func (s *Store) segmentPath(start int64) string {
+	return filepath.Join(s.opts.Dir, segmentFilePrefix+strconv.FormatInt(start, 10)+segmentFileSuffix)
+}
+
+// readSegment 读取索引段文件
+func readSegment(path string) (*segment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	seg := newSegment(0)
+	if err := gob.NewDecoder(file).Decode(seg); err != nil {
+		return nil, err
+	}
+
+	// 文件名中的起始时间与内容不一致时以文件名为准
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), segmentFilePrefix), segmentFileSuffix)
+	if start, err := strconv.ParseInt(name, 10, 64); err == nil {
+		seg.Start = start
+	}
+	return seg, nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写入中断留下不完整的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,89 @@
+package logstore
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTermLength 索引词的最大长度（字符数），更长的词会被截断
+const maxTermLength = 64
+
+// Token 分词结果，Start、End 为词在原文中的字节偏移
+type Token struct {
+	Term     string
+	Position int
+	Start    int
+	End      int
+}
+
+// Tokenize 将文本切分为小写的索引词
+//
+// 字母、数字和下划线组成的连续片段为一个词，如 blk_1073741825；其余字符均作为分隔符，
+// 因此 java.lang.OutOfMemoryError 会切分为 java、lang、outofmemoryerror 三个相邻的词，
+// 可通过短语查询整体匹配。中日韩文字没有空格分隔，每个字单独作为一个词。
+func Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+
+	emit := func(end int) {
+		if start < 0 {
+			return
+		}
+		tokens = append(tokens, Token{
+			Term:     normalizeTerm(text[start:end]),
+			Position: len(tokens),
+			Start:    start,
+			End:      end,
+		})
+		start = -1
+	}
+
+	for i, r := range text {
+		switch {
+		case isIdeograph(r):
+			emit(i)
+			start = i
+			emit(i + utf8.RuneLen(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if start < 0 {
+				start = i
+			}
+		default:
+			emit(i)
+		}
+	}
+	emit(len(text))
+	return tokens
+}
+
+// Terms 返回文本的索引词列表
+func Terms(text string) []string {
+	tokens := Tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.Term
+	}
+	return terms
+}
+
+// normalizeTerm 将词转为小写并截断到最大长度
+func normalizeTerm(term string) string {
+	term = strings.ToLower(term)
+	if utf8.RuneCountInString(term) <= maxTermLength {
+		return term
+	}
+	runes := []rune(term)
+	return string(runes[:maxTermLength])
+}
+
+// isIdeograph 是否为需要逐字切分的中日韩文字
+func isIdeograph(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// fieldTerm 字段值对应的索引词，字段值整体作为一个词且不区分大小写
+func fieldTerm(field, value string) string {
+	return strings.ToLower(field) + "=" + strings.ToLower(strings.TrimSpace(value))
+}
@@ -0,0 +1,111 @@
+package logtail
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/TejParker/bigdata-manager/internal/logparse"
+)
+
+// 默认参数
+const (
+	DefaultBatchSize     = 1000 // 服务端单次上传的日志条数上限
+	defaultPollInterval  = time.Second
+	defaultScanInterval  = 10 * time.Second
+	defaultFlushInterval = 2 * time.Second
+	defaultCloseInactive = 5 * time.Minute
+	defaultMaxLineBytes  = 64 * 1024
+	defaultMultilineWait = time.Second
+	defaultMaxLines      = 500
+	defaultMaxRetryDelay = time.Minute
+)
+
+// 首次启动时已存在文件的读取位置
+const (
+	StartAtEnd       = "end"       // 从文件末尾开始，只采集启动后新写入的日志
+	StartAtBeginning = "beginning" // 从文件开头开始
+)
+
+// Source 一个组件的日志文件
+type Source struct {
+	ServiceID   int      `mapstructure:"service_id"`
+	ComponentID int      `mapstructure:"component_id"`
+	Paths       []string `mapstructure:"paths"`   // 文件路径，支持通配符，如 /opt/hadoop/logs/*.log
+	Exclude     []string `mapstructure:"exclude"` // 排除的文件名通配符，如 *.out
+
+	Format  string `mapstructure:"format"`  // 日志格式: auto、log4j、json、syslog、plain，默认 auto
+	Pattern string `mapstructure:"pattern"` // log4j 格式的转换模式，如 %d{ISO8601} %p %c: %m%n
+}
+
+// Config 日志采集配置
+type Config struct {
+	Sources   []Source `mapstructure:"sources"`
+	StateFile string   `mapstructure:"state_file"` // 保存文件读取位置的文件，重启后从该位置继续读取
+
+	StartPosition string `mapstructure:"start_position"` // 首次启动时已存在文件的读取位置: end、beginning，默认 end
+	BatchSize     int    `mapstructure:"batch_size"`     // 每次上传的最大日志条数，不超过1000
+	PollInterval  int    `mapstructure:"poll_interval"`  // 读取新内容的间隔，单位秒
+	ScanInterval  int    `mapstructure:"scan_interval"`  // 重新匹配文件路径的间隔，单位秒
+	FlushInterval int    `mapstructure:"flush_interval"` // 不足一批的日志最长等待时间，单位秒
+	CloseInactive int    `mapstructure:"close_inactive"` // 文件无新内容多久后关闭文件句柄，单位秒
+	MaxLineBytes  int    `mapstructure:"max_line_bytes"` // 单条日志（含续行）最大字节数，超出部分被截断
+
+	MultilineTimeout int `mapstructure:"multiline_timeout"` // 多行日志最后一行之后等待续行的时间，单位秒
+	MaxLines         int `mapstructure:"max_lines"`         // 单条日志最多合并的行数，超出后作为新日志
+}
+
+// batchSize 每次上传的最大日志条数
+func (c *Config) batchSize() int {
+	if c.BatchSize <= 0 || c.BatchSize > DefaultBatchSize {
+		return DefaultBatchSize
+	}
+	return c.BatchSize
+}
+
+// maxLineBytes 单行最大字节数
+func (c *Config) maxLineBytes() int {
+	if c.MaxLineBytes <= 0 {
+		return defaultMaxLineBytes
+	}
+	return c.MaxLineBytes
+}
+
+// maxLines 单条日志最多合并的行数
+func (c *Config) maxLines() int {
+	if c.MaxLines <= 0 {
+		return defaultMaxLines
+	}
+	return c.MaxLines
+}
+
+// seconds 将以秒为单位的配置转换为时间，未配置时使用默认值
+func seconds(value int, def time.Duration) time.Duration {
+	if value <= 0 {
+		return def
+	}
+	return time.Duration(value) * time.Second
+}
+
+// LoadConfig 读取日志采集配置文件
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return cfg, fmt.Errorf("读取日志采集配置失败: %v", err)
+	}
+	if err := v.Unmarshal(&cfg); err != nil {
+		return cfg, fmt.Errorf("解析日志采集配置失败: %v", err)
+	}
+	if cfg.StartPosition != "" && cfg.StartPosition != StartAtEnd && cfg.StartPosition != StartAtBeginning {
+		return cfg, fmt.Errorf("无效的起始位置: %s", cfg.StartPosition)
+	}
+	for _, source := range cfg.Sources {
+		if _, err := logparse.New(source.Format, source.Pattern); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package logtail
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileID 文件标识，使用设备号和inode，文件被改名（如日志轮转）后标识不变
+func fileID(path string, info os.FileInfo) string {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", stat.Dev, stat.Ino)
+	}
+	return path
+}
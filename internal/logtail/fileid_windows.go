@@ -0,0 +1,12 @@
+//go:build windows
+
+package logtail
+
+import (
+	"os"
+)
+
+// fileID 文件标识，Windows 上使用文件路径，改名轮转的文件会被当作新文件
+func fileID(path string, info os.FileInfo) string {
+	return path
+}
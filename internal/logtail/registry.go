@@ -0,0 +1,75 @@
+package logtail
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fileState 文件的已上传位置
+type fileState struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+// registry 各文件已成功上传的位置，键为文件标识，文件改名后仍能找到原来的位置
+type registry struct {
+	path  string
+	files map[string]*fileState
+}
+
+// loadRegistry 读取位置文件，文件不存在时 exists 为false
+func loadRegistry(path string) (reg *registry, exists bool, err error) {
+	reg = &registry{path: path, files: make(map[string]*fileState)}
+	if path == "" {
+		return reg, false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return reg, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("读取日志采集位置文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &reg.files); err != nil {
+		return nil, false, fmt.Errorf("解析日志采集位置文件失败: %v", err)
+	}
+	return reg, true, nil
+}
+
+// get 获取文件的已上传位置
+func (r *registry) get(key string) (*fileState, bool) {
+	state, ok := r.files[key]
+	return state, ok
+}
+
+// set 记录文件的已上传位置
+func (r *registry) set(key, path string, offset int64) {
+	r.files[key] = &fileState{Path: path, Offset: offset}
+}
+
+// remove 删除不再采集的文件
+func (r *registry) remove(key string) {
+	delete(r.files, key)
+}
+
+// save 写入位置文件，先写临时文件再重命名
+func (r *registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.files, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
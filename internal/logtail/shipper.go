@@ -0,0 +1,94 @@
+package logtail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/pkg/model"
+)
+
+// Shipper 将一批日志发送到服务端
+type Shipper interface {
+	Ship(ctx context.Context, records []model.LogRecord) error
+}
+
+// RejectedError 服务端拒绝了这批日志，重试也不会成功
+type RejectedError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("服务端拒绝上传日志 (状态码: %d): %s", e.StatusCode, e.Message)
+}
+
+// isRejected 是否为不需要重试的错误
+func isRejected(err error) bool {
+	var rejected *RejectedError
+	return errors.As(err, &rejected)
+}
+
+// HTTPShipper 通过 POST /agent/logs 上传日志
+type HTTPShipper struct {
+	url    string
+	hostID int
+	client *http.Client
+}
+
+// NewHTTPShipper 创建日志上传器，serverAddr 为管理服务器地址
+func NewHTTPShipper(serverAddr string, hostID int) *HTTPShipper {
+	return &HTTPShipper{
+		url:    serverAddr + "/api/v1/agent/logs",
+		hostID: hostID,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Ship 上传日志，服务端返回4xx时返回 *RejectedError；认证失败、超时和限流可能在服务端恢复后成功，按普通错误重试
+func (s *HTTPShipper) Ship(ctx context.Context, records []model.LogRecord) error {
+	body, err := json.Marshal(map[string]any{
+		"host_id": s.hostID,
+		"logs":    records,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &result) != nil || result.Message == "" {
+		result.Message = string(data)
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+	default:
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return &RejectedError{StatusCode: resp.StatusCode, Message: result.Message}
+		}
+	}
+	return fmt.Errorf("上传日志失败 (状态码: %d): %s", resp.StatusCode, result.Message)
+}
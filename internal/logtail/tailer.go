@@ -0,0 +1,506 @@
+package logtail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/TejParker/bigdata-manager/internal/logparse"
+	"github.com/TejParker/bigdata-manager/pkg/model"
+)
+
+// tailedFile 正在采集的文件
+type tailedFile struct {
+	key    string
+	path   string
+	source *Source
+
+	file   *os.File // 因长时间无新内容关闭后为nil
+	reader *bufio.Reader
+	offset int64 // 已读取的完整行末尾位置
+
+	partial    []byte // 尚未读到换行符的行，最多保留 max_line_bytes
+	partialLen int64  // 尚未读到换行符的行的实际字节数
+
+	parser  logparse.Parser
+	current *pendingLine // 正在合并续行的日志，读到下一条日志的开头或等待超时后加入上传批次
+	lines   int          // current 已合并的行数
+
+	generation int       // 文件被截断的次数，截断前读取的日志不再更新位置
+	lastRead   time.Time // 最近一次读到新内容的时间
+	removed    bool      // 路径已不再指向该文件（轮转或删除），读完剩余内容后停止采集
+}
+
+// pendingLine 等待上传的日志及其在文件中的结束位置
+type pendingLine struct {
+	key        string
+	path       string
+	end        int64
+	generation int
+	record     model.LogRecord
+}
+
+// Tailer 持续读取配置的日志文件并分批上传
+//
+// 每行按文件的日志格式解析，不是日志开头的行（如Java异常堆栈）合并到上一条日志，
+// 并提取日志时间、级别、logger 和线程。
+//
+// 文件按设备号和inode识别，改名轮转后继续读完旧文件，再从头读取新文件；
+// 文件变小时视为被截断（copytruncate 轮转），从头重新读取。
+// 读取位置在日志上传成功后才保存，重启后从上次上传成功的位置继续，网络异常时日志可能重复但不会丢失。
+type Tailer struct {
+	cfg     Config
+	shipper Shipper
+	reg     *registry
+
+	firstRun    bool // 位置文件不存在，首次启动
+	initialScan bool
+	files       map[string]*tailedFile
+	regDirty    bool
+
+	batch      []pendingLine
+	batchStart time.Time
+	retryAt    time.Time
+	retryDelay time.Duration
+}
+
+// New 创建日志采集器
+func New(cfg Config, shipper Shipper) (*Tailer, error) {
+	if len(cfg.Sources) == 0 {
+		return nil, errors.New("未配置日志文件")
+	}
+	for _, source := range cfg.Sources {
+		if len(source.Paths) == 0 {
+			return nil, errors.New("日志文件路径不能为空")
+		}
+		for _, pattern := range append(source.Paths, source.Exclude...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, errors.New("无效的文件通配符: " + pattern)
+			}
+		}
+		if _, err := logparse.New(source.Format, source.Pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	reg, exists, err := loadRegistry(cfg.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Tailer{
+		cfg:      cfg,
+		shipper:  shipper,
+		reg:      reg,
+		firstRun: !exists,
+		files:    make(map[string]*tailedFile),
+	}, nil
+}
+
+// Run 开始采集，ctx 取消后保存读取位置并返回
+func (t *Tailer) Run(ctx context.Context) {
+	pollTicker := time.NewTicker(seconds(t.cfg.PollInterval, defaultPollInterval))
+	defer pollTicker.Stop()
+	scanTicker := time.NewTicker(seconds(t.cfg.ScanInterval, defaultScanInterval))
+	defer scanTicker.Stop()
+
+	t.initialScan = true
+	t.scan()
+	t.initialScan = false
+
+	for {
+		// 读满一批并上传成功后继续读取，直到没有新内容或上传失败
+		for t.read() && t.ship(ctx) {
+		}
+		t.ship(ctx)
+		t.saveRegistry()
+
+		select {
+		case <-ctx.Done():
+			t.close()
+			return
+		case <-pollTicker.C:
+		case <-scanTicker.C:
+			t.scan()
+		}
+	}
+}
+
+// scan 重新匹配文件路径，发现新文件，标记已轮转或删除的文件
+func (t *Tailer) scan() {
+	seen := make(map[string]bool)
+	for i := range t.cfg.Sources {
+		source := &t.cfg.Sources[i]
+		for _, pattern := range source.Paths {
+			matches, _ := filepath.Glob(pattern)
+			for _, path := range matches {
+				if excluded(source, path) {
+					continue
+				}
+				info, err := os.Stat(path)
+				if err != nil || !info.Mode().IsRegular() {
+					continue
+				}
+				key := fileID(path, info)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				if tf, ok := t.files[key]; ok {
+					tf.path = path
+					tf.removed = false
+					continue
+				}
+				t.files[key] = t.newFile(key, path, source, info)
+			}
+		}
+	}
+
+	for key, tf := range t.files {
+		if !seen[key] {
+			tf.removed = true
+		}
+	}
+	for key := range t.reg.files {
+		if _, ok := t.files[key]; !ok && !seen[key] {
+			t.reg.remove(key)
+			t.regDirty = true
+		}
+	}
+}
+
+// excluded 文件名是否匹配排除规则
+func excluded(source *Source, path string) bool {
+	name := filepath.Base(path)
+	for _, pattern := range source.Exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newFile 开始采集新发现的文件，确定起始读取位置
+func (t *Tailer) newFile(key, path string, source *Source, info os.FileInfo) *tailedFile {
+	var offset int64
+	if state, ok := t.reg.get(key); ok {
+		offset = state.Offset
+		if offset > info.Size() {
+			offset = 0
+		}
+	} else if t.initialScan && t.firstRun && t.cfg.StartPosition != StartAtBeginning {
+		offset = info.Size()
+	}
+
+	// 格式已在创建采集器时校验
+	parser, _ := logparse.New(source.Format, source.Pattern)
+
+	t.reg.set(key, path, offset)
+	t.regDirty = true
+	log.Printf("开始采集日志文件 %s (位置: %d)", path, offset)
+	return &tailedFile{
+		key:      key,
+		path:     path,
+		source:   source,
+		offset:   offset,
+		parser:   parser,
+		lastRead: time.Now(),
+	}
+}
+
+// read 从各文件读取新内容加入待上传批次，批次已满时返回true
+func (t *Tailer) read() bool {
+	batchSize := t.cfg.batchSize()
+	for key, tf := range t.files {
+		if len(t.batch) >= batchSize {
+			return true
+		}
+		if !t.readFile(tf, batchSize) {
+			t.flushCurrent(tf)
+			t.closeFile(tf)
+			delete(t.files, key)
+		}
+	}
+	return len(t.batch) >= batchSize
+}
+
+// readFile 读取文件的新内容，文件已轮转或删除且读完时返回false
+func (t *Tailer) readFile(tf *tailedFile, batchSize int) bool {
+	if tf.file == nil && !t.open(tf) {
+		return !tf.removed
+	}
+
+	info, err := tf.file.Stat()
+	if err != nil {
+		log.Printf("读取日志文件信息失败 %s: %v", tf.path, err)
+		t.closeFile(tf)
+		return !tf.removed
+	}
+	if info.Size() < tf.offset+tf.partialLen {
+		t.truncated(tf)
+	}
+
+	maxLine := t.cfg.maxLineBytes()
+	for len(t.batch) < batchSize {
+		chunk, err := tf.reader.ReadSlice('\n')
+		tf.appendPartial(chunk, maxLine)
+		if err == nil {
+			t.emit(tf, tf.partial)
+			continue
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			log.Printf("读取日志文件失败 %s: %v", tf.path, err)
+			t.closeFile(tf)
+			return !tf.removed
+		}
+
+		// 已读到文件末尾
+		if tf.removed {
+			// 轮转后的旧文件不会再写入，最后一行没有换行符时也作为一条日志
+			if tf.partialLen > 0 {
+				t.emit(tf, tf.partial)
+			}
+			log.Printf("日志文件已轮转或删除，停止采集 %s", tf.path)
+			return false
+		}
+		// 一段时间没有续行时认为多行日志已结束
+		if time.Since(tf.lastRead) >= seconds(t.cfg.MultilineTimeout, defaultMultilineWait) {
+			t.flushCurrent(tf)
+		}
+		if tf.current == nil && time.Since(tf.lastRead) > seconds(t.cfg.CloseInactive, defaultCloseInactive) {
+			t.closeFile(tf)
+		}
+		return true
+	}
+	return true
+}
+
+// open 打开文件并定位到已读取的位置，文件没有新内容时不打开
+func (t *Tailer) open(tf *tailedFile) bool {
+	if tf.removed {
+		return false
+	}
+
+	info, err := os.Stat(tf.path)
+	if err != nil || fileID(tf.path, info) != tf.key {
+		// 路径已指向其他文件，等待下次扫描确认
+		return false
+	}
+	if info.Size() == tf.offset {
+		return false
+	}
+	if info.Size() < tf.offset {
+		t.flushCurrent(tf)
+		tf.offset = 0
+		tf.generation++
+		t.reg.set(tf.key, tf.path, 0)
+		t.regDirty = true
+	}
+
+	file, err := os.Open(tf.path)
+	if err != nil {
+		log.Printf("打开日志文件失败 %s: %v", tf.path, err)
+		return false
+	}
+	if _, err := file.Seek(tf.offset, io.SeekStart); err != nil {
+		log.Printf("定位日志文件失败 %s: %v", tf.path, err)
+		file.Close()
+		return false
+	}
+
+	tf.file = file
+	tf.reader = bufio.NewReaderSize(file, 64*1024)
+	tf.partial, tf.partialLen = nil, 0
+	tf.lastRead = time.Now()
+	return true
+}
+
+// truncated 文件被截断，从头重新读取
+func (t *Tailer) truncated(tf *tailedFile) {
+	log.Printf("日志文件被截断，从头读取 %s", tf.path)
+	if _, err := tf.file.Seek(0, io.SeekStart); err != nil {
+		log.Printf("定位日志文件失败 %s: %v", tf.path, err)
+		t.closeFile(tf)
+		return
+	}
+	tf.reader.Reset(tf.file)
+	t.flushCurrent(tf)
+	tf.offset = 0
+	tf.partial, tf.partialLen = nil, 0
+	tf.generation++
+	t.reg.set(tf.key, tf.path, 0)
+	t.regDirty = true
+}
+
+// appendPartial 累积未结束的行，超过最大长度的部分丢弃但计入位置
+func (tf *tailedFile) appendPartial(chunk []byte, maxLine int) {
+	if len(chunk) == 0 {
+		return
+	}
+	tf.partialLen += int64(len(chunk))
+	if room := maxLine - len(tf.partial); room > 0 {
+		if len(chunk) > room {
+			chunk = chunk[:room]
+		}
+		tf.partial = append(tf.partial, chunk...)
+	}
+	tf.lastRead = time.Now()
+}
+
+// emit 解析读到的一行，日志开头的行作为新日志，其余行合并到当前日志
+func (t *Tailer) emit(tf *tailedFile, line []byte) {
+	tf.offset += tf.partialLen
+	text := strings.ToValidUTF8(string(bytes.TrimRight(line, "\r\n")), "\uFFFD")
+	tf.partial, tf.partialLen = tf.partial[:0], 0
+
+	entry, start := tf.parser.Parse(text)
+	if !start && tf.current != nil && tf.lines < t.cfg.maxLines() {
+		tf.current.record.Message = truncateMessage(tf.current.record.Message+"\n"+text, t.cfg.maxLineBytes())
+		tf.current.end = tf.offset
+		tf.lines++
+		return
+	}
+
+	t.flushCurrent(tf)
+	if strings.TrimSpace(entry.Message) == "" && strings.TrimSpace(text) == "" {
+		return
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	tf.current = &pendingLine{
+		key:        tf.key,
+		path:       tf.path,
+		end:        tf.offset,
+		generation: tf.generation,
+		record: model.LogRecord{
+			ServiceID:   tf.source.ServiceID,
+			ComponentID: tf.source.ComponentID,
+			LogLevel:    entry.Level,
+			Logger:      entry.Logger,
+			Thread:      entry.Thread,
+			Timestamp:   entry.Timestamp,
+			Message:     truncateMessage(entry.Message, t.cfg.maxLineBytes()),
+		},
+	}
+	tf.lines = 1
+}
+
+// flushCurrent 将正在合并的日志加入待上传批次
+func (t *Tailer) flushCurrent(tf *tailedFile) {
+	if tf.current == nil {
+		return
+	}
+	tf.current.record.Message = strings.TrimRightFunc(tf.current.record.Message, unicode.IsSpace)
+	if len(t.batch) == 0 {
+		t.batchStart = time.Now()
+	}
+	t.batch = append(t.batch, *tf.current)
+	tf.current, tf.lines = nil, 0
+}
+
+// truncateMessage 截断超过最大长度的日志，不截断多字节字符
+func truncateMessage(message string, maxBytes int) string {
+	if len(message) <= maxBytes {
+		return message
+	}
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
+
+// ship 上传待发送的日志，批次未满且等待时间未到时不上传；上传成功或被服务端拒绝时返回true
+func (t *Tailer) ship(ctx context.Context) bool {
+	if len(t.batch) == 0 || time.Now().Before(t.retryAt) {
+		return false
+	}
+	if len(t.batch) < t.cfg.batchSize() && time.Since(t.batchStart) < seconds(t.cfg.FlushInterval, defaultFlushInterval) {
+		return false
+	}
+
+	records := make([]model.LogRecord, len(t.batch))
+	for i, line := range t.batch {
+		records[i] = line.record
+	}
+
+	err := t.shipper.Ship(ctx, records)
+	if err != nil && !isRejected(err) {
+		// 网络或服务端异常，按指数退避重试同一批日志
+		t.retryDelay *= 2
+		if t.retryDelay == 0 {
+			t.retryDelay = time.Second
+		}
+		if t.retryDelay > defaultMaxRetryDelay {
+			t.retryDelay = defaultMaxRetryDelay
+		}
+		t.retryAt = time.Now().Add(t.retryDelay)
+		log.Printf("上传日志失败，%v 后重试: %v", t.retryDelay, err)
+		return false
+	}
+	if err != nil {
+		log.Printf("%v，丢弃 %d 条日志", err, len(records))
+	}
+
+	t.commit()
+	t.batch = t.batch[:0]
+	t.retryDelay = 0
+	t.retryAt = time.Time{}
+	return true
+}
+
+// commit 记录已上传日志的文件位置
+func (t *Tailer) commit() {
+	for _, line := range t.batch {
+		if tf, ok := t.files[line.key]; ok && tf.generation != line.generation {
+			continue
+		}
+		if state, ok := t.reg.get(line.key); ok && state.Offset >= line.end {
+			continue
+		}
+		t.reg.set(line.key, line.path, line.end)
+	}
+	t.regDirty = true
+	t.saveRegistry()
+}
+
+// saveRegistry 有修改时保存读取位置
+func (t *Tailer) saveRegistry() {
+	if !t.regDirty {
+		return
+	}
+	if err := t.reg.save(); err != nil {
+		log.Printf("保存日志采集位置失败: %v", err)
+		return
+	}
+	t.regDirty = false
+}
+
+// closeFile 关闭文件句柄，之后有新内容时重新打开
+func (t *Tailer) closeFile(tf *tailedFile) {
+	if tf.file != nil {
+		tf.file.Close()
+		tf.file, tf.reader = nil, nil
+	}
+}
+
+// close 关闭全部文件并保存读取位置，未上传的日志在下次启动时重新读取
+func (t *Tailer) close() {
+	for _, tf := range t.files {
+		t.closeFile(tf)
+	}
+	t.saveRegistry()
+}
@@ -34,21 +34,44 @@ const (
 	OpNotEqual           ComparisonOperator = "!="
 )
 
+// AlertRuleType 告警规则类型
+type AlertRuleType string
+
+const (
+	AlertRuleTypeMetric AlertRuleType = "METRIC" // 指标规则，按指标阈值或表达式告警
+	AlertRuleTypeLog    AlertRuleType = "LOG"    // 日志规则，按时间窗口内匹配的日志数告警
+)
+
+// 日志规则的分组方式，分组后每组单独计数和告警
+const (
+	LogGroupByHost      = "host"
+	LogGroupByService   = "service"
+	LogGroupByComponent = "component"
+)
+
 // 告警规则
 type AlertRule struct {
 	ID               uint          `json:"id" gorm:"primaryKey"`
 	Name             string        `json:"name" gorm:"size:100;not null"`
 	Description      string        `json:"description" gorm:"size:500"`
 	MetricName       string        `json:"metric_name" gorm:"size:100;not null"`
+	Expression       string        `json:"expression" gorm:"size:1000"` // 告警表达式（类PromQL），结果中每个序列单独告警；设置了 Operator 时再与 Threshold 比较
 	ClusterID        *uint         `json:"cluster_id" gorm:"index"`
 	ServiceID        *uint         `json:"service_id" gorm:"index"`
 	HostID           *uint         `json:"host_id" gorm:"index"`
-	Operator         ComparisonOperator `json:"operator" gorm:"size:10;not null"`
+	Operator         ComparisonOperator `json:"operator" gorm:"size:10"`
 	Threshold        float64       `json:"threshold" gorm:"not null"`
 	Duration         int           `json:"duration" gorm:"default:0"` // 持续时间，单位为秒，0表示立即触发
 	Severity         AlertSeverity `json:"severity" gorm:"size:20;not null;default:'WARNING'"`
 	Enabled          bool          `json:"enabled" gorm:"default:true"`
 	NotificationIDs  string        `json:"notification_ids" gorm:"size:255"` // 逗号分隔的通知ID
+	GroupBy          string        `json:"group_by" gorm:"size:255"` // 逗号分隔的分组标签，为空时使用 alert.group_by 配置
+	EscalationPolicyID *uint       `json:"escalation_policy_id" gorm:"index"` // 未确认告警的升级策略
+	RuleType         AlertRuleType `json:"rule_type" gorm:"size:20;not null;default:'METRIC'"`
+	LogQuery         string        `json:"log_query" gorm:"size:1000"`  // 日志规则的检索语法，与日志查询相同，为空时匹配全部日志
+	LogLevels        string        `json:"log_levels" gorm:"size:100"`  // 日志规则匹配的日志级别，逗号分隔，为空时不限制
+	LogWindow        int           `json:"log_window" gorm:"default:0"` // 日志规则的统计时间窗口，单位为秒
+	LogGroupBy       string        `json:"log_group_by" gorm:"size:20"` // 日志规则的分组方式：host、service、component，为空时不分组
 	CreatedBy        uint          `json:"created_by"`
 	CreatedAt        time.Time     `json:"created_at"`
 	UpdatedAt        time.Time     `json:"updated_at"`
@@ -65,12 +88,19 @@ type AlertEvent struct {
 	Hostname     string        `json:"hostname" gorm:"size:100"`
 	ServiceName  string        `json:"service_name" gorm:"size:100"`
 	MetricName   string        `json:"metric_name" gorm:"size:100;not null"`
-	MetricValue  float64       `json:"metric_value"`
+	Labels       string        `json:"labels" gorm:"size:1000"`     // 表达式规则结果序列的标签（JSON）
+	Fingerprint  string        `json:"fingerprint" gorm:"size:64;index"` // 表达式规则结果序列的标识
+	MetricValue  float64       `json:"metric_value"` // 日志规则为时间窗口内匹配的日志数
 	Threshold    float64       `json:"threshold"`
 	Operator     string        `json:"operator" gorm:"size:10"`
 	Message      string        `json:"message" gorm:"size:500"`
 	Severity     AlertSeverity `json:"severity" gorm:"size:20;not null"`
 	Status       AlertStatus   `json:"status" gorm:"size:20;not null;default:'OPEN'"`
+	SilenceID    *uint         `json:"silence_id"` // 命中的静默，命中时不发送通知
+	GroupKey     string        `json:"group_key" gorm:"size:64;index"` // 通知分组标识，同组告警合并发送
+	NotificationCount int      `json:"notification_count" gorm:"default:0"` // 已发送通知次数
+	LastNotifiedAt *time.Time   `json:"last_notified_at"`
+	EscalationLevel int         `json:"escalation_level" gorm:"default:0"` // 已执行的升级步骤数
 	TriggeredAt  time.Time     `json:"triggered_at"`
 	AcknowledgedAt *time.Time   `json:"acknowledged_at"`
 	AcknowledgedBy *uint        `json:"acknowledged_by"`
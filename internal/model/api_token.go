@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// APIToken 用户或服务账号的API令牌，供CI流水线和脚本调用接口
+//
+// 令牌只在创建时返回一次，数据库中只保存哈希；Privileges 不为空时令牌只拥有其中的权限，
+// 且不超过所属用户当前的权限。
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     int        `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	TokenHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // 令牌的 SHA-256 哈希
+	Prefix     string     `json:"prefix" gorm:"size:16"`                 // 令牌的前几位，用于识别令牌
+	Privileges []string   `json:"privileges" gorm:"type:text;serializer:json"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 为空时不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"size:64"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"index"`
+	CreatedBy  int        `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	Username string `json:"username,omitempty" gorm:"->;-:migration"`
+}
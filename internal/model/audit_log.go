@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// AuditResult 审计记录的操作结果
+type AuditResult string
+
+const (
+	AuditResultSuccess AuditResult = "SUCCESS"
+	AuditResultFailure AuditResult = "FAILURE"
+)
+
+// 审计记录的认证方式
+const (
+	AuditAuthSession  = "SESSION"   // 登录令牌
+	AuditAuthAPIToken = "API_TOKEN" // API令牌
+)
+
+// AuditActionLogin 登录操作，包括用户名密码登录和 OIDC 单点登录
+const AuditActionLogin = "LOGIN"
+
+// AuditLog 审计记录，记录修改类接口调用和登录
+//
+// 每条记录的 Hash 由上一条记录的 Hash 和本条记录的内容计算，修改或删除中间的记录会使之后的校验失败。
+type AuditLog struct {
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	CreatedAt    time.Time   `json:"created_at" gorm:"index"`
+	UserID       int         `json:"user_id" gorm:"index"` // 未认证的请求为0，如登录失败
+	Username     string      `json:"username" gorm:"size:64;index"`
+	AuthMethod   string      `json:"auth_method" gorm:"size:20"`
+	ClientIP     string      `json:"client_ip" gorm:"size:64"`
+	Method       string      `json:"method" gorm:"size:10"`
+	Path         string      `json:"path" gorm:"size:255"`
+	Action       string      `json:"action" gorm:"size:255;index"` // 请求方法和路由，如 POST /services/:id/stop
+	ResourceType string      `json:"resource_type" gorm:"size:64;index"`
+	ResourceID   string      `json:"resource_id" gorm:"size:64;index"`
+	Params       string      `json:"params" gorm:"type:text"` // 请求参数（JSON），密码、密钥、令牌等已脱敏
+	Result       AuditResult `json:"result" gorm:"size:20;index"`
+	StatusCode   int         `json:"status_code"`
+	Message      string      `json:"message" gorm:"size:1000"` // 失败时的错误信息
+	PrevHash     string      `json:"prev_hash" gorm:"size:64"`
+	Hash         string      `json:"hash" gorm:"size:64;uniqueIndex"`
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// 告警升级策略
+type EscalationPolicy struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	Name        string           `json:"name" gorm:"size:100;not null"`
+	Description string           `json:"description" gorm:"size:500"`
+	Steps       []EscalationStep `json:"steps" gorm:"foreignKey:PolicyID;constraint:OnDelete:CASCADE"`
+	CreatedBy   uint             `json:"created_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// 升级策略步骤，告警触发后超过 Delay 分钟仍未确认时执行
+type EscalationStep struct {
+	ID               uint   `json:"id" gorm:"primaryKey"`
+	PolicyID         uint   `json:"policy_id" gorm:"index;not null"`
+	Position         int    `json:"position" gorm:"not null"`                  // 步骤顺序，从0开始
+	Delay            int    `json:"delay" gorm:"not null;default:0"`           // 距告警触发的分钟数，0表示立即通知
+	NotificationIDs  string `json:"notification_ids" gorm:"size:255;not null"` // 逗号分隔的通知ID
+	OnCallScheduleID *uint  `json:"on_call_schedule_id"`                       // 设置时通知发送给当前值班人员
+}
+
+// SortSteps 按步骤顺序排序
+func (p *EscalationPolicy) SortSteps() {
+	sort.SliceStable(p.Steps, func(i, j int) bool {
+		return p.Steps[i].Position < p.Steps[j].Position
+	})
+}
+
+// 值班联系人
+type OnCallContact struct {
+	Name  string `json:"name" gorm:"size:100;not null"`
+	Email string `json:"email" gorm:"size:100"`
+	Phone string `json:"phone" gorm:"size:20"`
+}
+
+// 值班表，参与人按 Position 顺序每 RotationHours 小时轮换一次
+type OnCallSchedule struct {
+	ID            uint                `json:"id" gorm:"primaryKey"`
+	Name          string              `json:"name" gorm:"size:100;not null"`
+	Description   string              `json:"description" gorm:"size:500"`
+	RotationStart time.Time           `json:"rotation_start" gorm:"not null"` // 第一位参与人开始值班的时间
+	RotationHours int                 `json:"rotation_hours" gorm:"not null"` // 轮换周期，单位为小时
+	Participants  []OnCallParticipant `json:"participants" gorm:"foreignKey:ScheduleID;constraint:OnDelete:CASCADE"`
+	Overrides     []OnCallOverride    `json:"overrides,omitempty" gorm:"foreignKey:ScheduleID;constraint:OnDelete:CASCADE"`
+	CreatedBy     uint                `json:"created_by"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// 值班表参与人
+type OnCallParticipant struct {
+	ID            uint `json:"id" gorm:"primaryKey"`
+	ScheduleID    uint `json:"schedule_id" gorm:"index;not null"`
+	Position      int  `json:"position" gorm:"not null"` // 轮换顺序，从0开始
+	OnCallContact `gorm:"embedded"`
+}
+
+// 值班替换，生效期间由替换人代替轮换中的值班人员
+type OnCallOverride struct {
+	ID            uint `json:"id" gorm:"primaryKey"`
+	ScheduleID    uint `json:"schedule_id" gorm:"index;not null"`
+	OnCallContact `gorm:"embedded"`
+	StartsAt      time.Time `json:"starts_at" gorm:"not null"`
+	EndsAt        time.Time `json:"ends_at" gorm:"not null"`
+	CreatedBy     uint      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CurrentOnCall 返回指定时间的值班人员，替换优先于轮换，无人值班时返回nil
+func (s *OnCallSchedule) CurrentOnCall(now time.Time) *OnCallContact {
+	// 多个替换同时生效时以最后创建的为准
+	var override *OnCallOverride
+	for i := range s.Overrides {
+		o := &s.Overrides[i]
+		if now.Before(o.StartsAt) || !now.Before(o.EndsAt) {
+			continue
+		}
+		if override == nil || o.CreatedAt.After(override.CreatedAt) || (o.CreatedAt.Equal(override.CreatedAt) && o.ID > override.ID) {
+			override = o
+		}
+	}
+	if override != nil {
+		return &override.OnCallContact
+	}
+
+	if len(s.Participants) == 0 || s.RotationHours <= 0 {
+		return nil
+	}
+
+	participants := make([]OnCallParticipant, len(s.Participants))
+	copy(participants, s.Participants)
+	sort.SliceStable(participants, func(i, j int) bool {
+		return participants[i].Position < participants[j].Position
+	})
+
+	period := time.Duration(s.RotationHours) * time.Hour
+	elapsed := now.Sub(s.RotationStart)
+	shift := int64(elapsed / period)
+	if elapsed < 0 && elapsed%period != 0 {
+		shift--
+	}
+	n := int64(len(participants))
+	index := ((shift % n) + n) % n
+	return &participants[index].OnCallContact
+}
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// ExportSource 导出的数据
+type ExportSource string
+
+const (
+	ExportSourceLog    ExportSource = "LOG"
+	ExportSourceMetric ExportSource = "METRIC"
+)
+
+// ExportFormat 导出文件格式
+type ExportFormat string
+
+const (
+	ExportFormatCSV    ExportFormat = "csv"
+	ExportFormatNDJSON ExportFormat = "ndjson" // 每行一个JSON对象
+)
+
+// ExportJobStatus 导出任务状态
+type ExportJobStatus string
+
+const (
+	ExportJobPending   ExportJobStatus = "PENDING"   // 等待执行
+	ExportJobRunning   ExportJobStatus = "RUNNING"   // 导出中
+	ExportJobSucceeded ExportJobStatus = "SUCCEEDED" // 导出完成，可以下载
+	ExportJobFailed    ExportJobStatus = "FAILED"    // 导出失败
+)
+
+// ExportJob 日志或指标的异步导出任务，导出完成后生成文件供下载，过期后删除
+type ExportJob struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	Source     ExportSource    `json:"source" gorm:"size:20;not null"`
+	Format     ExportFormat    `json:"format" gorm:"size:10;not null"`
+	Gzip       bool            `json:"gzip" gorm:"default:false"`
+	Params     string          `json:"params" gorm:"type:text"` // JSON格式的查询条件
+	Status     ExportJobStatus `json:"status" gorm:"size:20;not null;index"`
+	RowCount   int64           `json:"row_count" gorm:"default:0"`     // 已导出的行数，导出中时为当前进度
+	Truncated  bool            `json:"truncated" gorm:"default:false"` // 达到最大行数，导出结果不完整
+	FileName   string          `json:"file_name" gorm:"size:255"`
+	FileSize   int64           `json:"file_size" gorm:"default:0"`
+	Error      string          `json:"error" gorm:"size:1000"`
+	CreatedBy  uint            `json:"created_by" gorm:"index"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+	ExpiresAt  *time.Time      `json:"expires_at" gorm:"index"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+
+	DownloadURL string `json:"download_url,omitempty" gorm:"-"` // 导出完成后的下载地址
+}
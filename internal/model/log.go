@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// LogRecord 日志记录，对应 log_record 表；Hostname、ServiceName、ComponentType 由关联查询得到
+type LogRecord struct {
+	ID            int64     `json:"id"`
+	HostID        int       `json:"host_id,omitempty"`
+	ServiceID     int       `json:"service_id,omitempty"`
+	ComponentID   int       `json:"component_id,omitempty"`
+	LogLevel      string    `json:"log_level"`
+	Logger        string    `json:"logger,omitempty" gorm:"size:255"`
+	Thread        string    `json:"thread,omitempty" gorm:"size:255"`
+	Timestamp     time.Time `json:"timestamp"`
+	Message       string    `json:"message"`
+	CreatedAt     time.Time `json:"created_at"`
+	Hostname      string    `json:"hostname,omitempty" gorm:"->;-:migration"`
+	ServiceName   string    `json:"service_name,omitempty" gorm:"->;-:migration"`
+	ComponentType string    `json:"component_type,omitempty" gorm:"->;-:migration"`
+}
+
+// TableName 日志表名
+func (LogRecord) TableName() string {
+	return "log_record"
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// LogPattern 日志模式，同一服务组件中只有变量部分不同的日志归为一个模式
+type LogPattern struct {
+	ID            int64     `json:"id" gorm:"primaryKey"`
+	ServiceID     int       `json:"service_id" gorm:"index:idx_log_pattern_source"`
+	ComponentID   int       `json:"component_id" gorm:"index:idx_log_pattern_source"`
+	Template      string    `json:"template" gorm:"type:text;not null"` // 变量部分替换为占位符的日志模板
+	Level         string    `json:"level" gorm:"size:20"`               // 最近一条日志的级别
+	Sample        string    `json:"sample" gorm:"type:text"`            // 最近一条日志的内容
+	Count         int64     `json:"count"`                              // 累计日志数
+	LastLogID     int64     `json:"last_log_id" gorm:"index"`           // 最近一条日志的ID
+	FirstSeen     time.Time `json:"first_seen" gorm:"index"`
+	LastSeen      time.Time `json:"last_seen" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ServiceName   string    `json:"service_name,omitempty" gorm:"->;-:migration"`
+	ComponentType string    `json:"component_type,omitempty" gorm:"->;-:migration"`
+}
+
+// LogPatternCount 日志模式在一个时间桶内的日志数
+type LogPatternCount struct {
+	ID        int64     `json:"-" gorm:"primaryKey"`
+	PatternID int64     `json:"pattern_id" gorm:"uniqueIndex:idx_log_pattern_bucket;not null"`
+	Bucket    time.Time `json:"bucket" gorm:"uniqueIndex:idx_log_pattern_bucket;index;not null"` // 时间桶的开始时间
+	Count     int64     `json:"count"`
+}
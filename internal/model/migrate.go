@@ -0,0 +1,94 @@
+package model
+
+import "gorm.io/gorm"
+
+// AutoMigrate 自动创建或更新告警、通知、日志模式、导出任务、角色绑定、API令牌、登录会话、审计记录相关的数据表，
+// 并为日志表和用户表补充新增的列、补充新增的权限
+func AutoMigrate(db *gorm.DB) error {
+	if err := migrateLogRecord(db); err != nil {
+		return err
+	}
+	if err := migrateUser(db); err != nil {
+		return err
+	}
+	if err := migratePrivileges(db); err != nil {
+		return err
+	}
+	return db.AutoMigrate(
+		&AlertRule{},
+		&AlertEvent{},
+		&NotificationConfig{},
+		&NotificationHistory{},
+		&NotificationJob{},
+		&NotificationTemplate{},
+		&Silence{},
+		&MaintenanceWindow{},
+		&EscalationPolicy{},
+		&EscalationStep{},
+		&OnCallSchedule{},
+		&OnCallParticipant{},
+		&OnCallOverride{},
+		&LogPattern{},
+		&LogPatternCount{},
+		&ExportJob{},
+		&RoleBinding{},
+		&APIToken{},
+		&UserSession{},
+		&AuditLog{},
+	)
+}
+
+// migrateLogRecord 为 schema.sql 创建的日志表添加 logger、thread 列；日志表数据量大，不使用 AutoMigrate 修改已有列
+func migrateLogRecord(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&LogRecord{}) {
+		return nil
+	}
+	for _, column := range []string{"Logger", "Thread"} {
+		if migrator.HasColumn(&LogRecord{}, column) {
+			continue
+		}
+		if err := migrator.AddColumn(&LogRecord{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateUser 为 schema.sql 创建的用户表添加 auth_source 列，已有用户为本地账号
+func migrateUser(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&User{}) || migrator.HasColumn(&User{}, "AuthSource") {
+		return nil
+	}
+	return migrator.AddColumn(&User{}, "AuthSource")
+}
+
+// addedPrivileges schema.sql 中后来新增的权限，已有数据库在迁移时补充
+var addedPrivileges = []Privilege{
+	{Name: PrivilegeViewAudit, Description: "查看和导出审计日志"},
+}
+
+// migratePrivileges 为已有数据库补充新增的权限，新增的权限只授予 ADMIN 角色
+func migratePrivileges(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Privilege{}) {
+		return nil
+	}
+	for _, privilege := range addedPrivileges {
+		var count int64
+		if err := db.Model(&Privilege{}).Where("name = ?", privilege.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := db.Create(&privilege).Error; err != nil {
+			return err
+		}
+		err := db.Exec("INSERT INTO role_privilege (role_id, privilege_id) SELECT id, ? FROM role WHERE name = 'ADMIN'", privilege.ID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,6 +11,10 @@ const (
 	NotificationTypeEmail    NotificationType = "EMAIL"
 	NotificationTypeSMS      NotificationType = "SMS"
 	NotificationTypeWebhook  NotificationType = "WEBHOOK"
+	NotificationTypeDingTalk NotificationType = "DINGTALK"
+	NotificationTypeWeCom    NotificationType = "WECOM"
+	NotificationTypeFeishu   NotificationType = "FEISHU"
+	NotificationTypeSlack    NotificationType = "SLACK"
 )
 
 // 通知配置
@@ -32,6 +36,12 @@ type NotificationConfig struct {
 	WebhookMethod string `json:"webhook_method" gorm:"size:10;default:'POST'"`
 	WebhookHeaders string `json:"webhook_headers" gorm:"size:1000"` // JSON格式的Headers
 	WebhookTemplate string `json:"webhook_template" gorm:"size:2000"` // 自定义webhook内容模板
+
+	// 群机器人配置，机器人地址使用 WebhookURL
+	BotSecret string `json:"bot_secret" gorm:"size:255"` // 钉钉加签密钥、飞书签名校验密钥
+
+	// 发送限流，每分钟最多发送的通知数，0表示使用该通知类型的默认限制
+	RateLimit int `json:"rate_limit" gorm:"default:0"`
 	
 	// 通用配置
 	CreatedBy    uint      `json:"created_by"`
@@ -45,9 +55,38 @@ type NotificationHistory struct {
 	AlertEventID      uint             `json:"alert_event_id" gorm:"index;not null"`
 	NotificationConfigID uint             `json:"notification_config_id" gorm:"index;not null"`
 	Type              NotificationType `json:"type" gorm:"size:20;not null"`
-	Status            string           `json:"status" gorm:"size:20;not null"` // SUCCESS, FAILED
+	Status            string           `json:"status" gorm:"size:20;not null"` // SUCCESS, FAILED, SUPPRESSED
 	Message           string           `json:"message" gorm:"size:500"`
 	Recipient         string           `json:"recipient" gorm:"size:255"`
+	JobID             uint             `json:"job_id" gorm:"index"`  // 对应的通知发送任务，0表示未经过发送队列
+	Attempt           int              `json:"attempt"`              // 第几次发送尝试
 	SentAt            time.Time        `json:"sent_at"`
 	CreatedAt         time.Time        `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// NotificationJobStatus 通知发送任务状态
+type NotificationJobStatus string
+
+const (
+	NotificationJobPending    NotificationJobStatus = "PENDING"    // 等待发送或等待重试
+	NotificationJobProcessing NotificationJobStatus = "PROCESSING" // 发送中
+	NotificationJobSucceeded  NotificationJobStatus = "SUCCEEDED"  // 全部接收人发送成功
+	NotificationJobDead       NotificationJobStatus = "DEAD"       // 重试次数耗尽，需要人工处理
+)
+
+// NotificationJob 持久化的通知发送任务，失败后按指数退避重试，重试耗尽后进入死信状态
+type NotificationJob struct {
+	ID                   uint                  `json:"id" gorm:"primaryKey"`
+	NotificationConfigID uint                  `json:"notification_config_id" gorm:"index;not null"`
+	Kind                 string                `json:"kind" gorm:"size:20;not null"`              // FIRING、ACKNOWLEDGED、RESOLVED
+	AlertEventIDs        string                `json:"alert_event_ids" gorm:"size:1000;not null"` // 逗号分隔的告警事件ID
+	Recipients           string                `json:"recipients" gorm:"size:1000"`               // 逗号分隔的接收人，为空时使用通知配置中的接收人
+	Status               NotificationJobStatus `json:"status" gorm:"size:20;not null;index:idx_notification_job_due,priority:1"`
+	Attempts             int                   `json:"attempts" gorm:"default:0"`
+	MaxAttempts          int                   `json:"max_attempts" gorm:"default:0"`
+	NextAttemptAt        time.Time             `json:"next_attempt_at" gorm:"index:idx_notification_job_due,priority:2"`
+	LastError            string                `json:"last_error" gorm:"size:1000"`
+	SentAt               *time.Time            `json:"sent_at"`
+	CreatedAt            time.Time             `json:"created_at"`
+	UpdatedAt            time.Time             `json:"updated_at"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+// NotificationTemplate 用户自定义的通知模板
+//
+// 发送时按 通知配置+告警级别、通知配置、通知类型+告警级别、通知类型 的顺序选择第一个启用的模板，
+// 都没有时使用内置模板。模板使用 Go text/template 语法。
+type NotificationTemplate struct {
+	ID                   uint             `json:"id" gorm:"primaryKey"`
+	Name                 string           `json:"name" gorm:"size:100;not null"`
+	Description          string           `json:"description" gorm:"size:500"`
+	Type                 NotificationType `json:"type" gorm:"size:20;not null;index"`  // 适用的通知类型
+	NotificationConfigID *uint            `json:"notification_config_id" gorm:"index"` // 适用的通知配置，为空表示该类型的全部通知配置
+	Severity             AlertSeverity    `json:"severity" gorm:"size:20"`             // 适用的告警级别，为空表示全部级别
+	Subject              string           `json:"subject" gorm:"size:500"`             // 邮件主题、群消息标题，为空时使用内置格式
+	Content              string           `json:"content" gorm:"type:text;not null"`
+	Enabled              bool             `json:"enabled" gorm:"default:true"`
+	CreatedBy            uint             `json:"created_by"`
+	CreatedAt            time.Time        `json:"created_at"`
+	UpdatedAt            time.Time        `json:"updated_at"`
+}
+
+// HostInfo 通知模板可用的主机信息，对应 host 表
+type HostInfo struct {
+	ID           uint   `json:"id"`
+	Hostname     string `json:"hostname"`
+	IP           string `json:"ip" gorm:"column:ip"`
+	ClusterID    uint   `json:"cluster_id"`
+	CPUCores     int    `json:"cpu_cores" gorm:"column:cpu_cores"`
+	MemorySize   int64  `json:"memory_size"`
+	Status       string `json:"status"`
+	AgentVersion string `json:"agent_version"`
+}
+
+// TableName 主机表名
+func (HostInfo) TableName() string {
+	return "host"
+}
+
+// ClusterInfo 通知模板可用的集群信息，对应 cluster 表
+type ClusterInfo struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TableName 集群表名
+func (ClusterInfo) TableName() string {
+	return "cluster"
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"time"
+)
+
+// AlertMatcher 告警匹配条件，未设置的字段不参与匹配
+type AlertMatcher struct {
+	AlertRuleID *uint         `json:"alert_rule_id" gorm:"index"`
+	ClusterID   *uint         `json:"cluster_id" gorm:"index"`
+	ServiceID   *uint         `json:"service_id" gorm:"index"`
+	HostID      *uint         `json:"host_id" gorm:"index"`
+	Severity    AlertSeverity `json:"severity" gorm:"size:20"`
+}
+
+// IsEmpty 是否未设置任何匹配条件
+func (m AlertMatcher) IsEmpty() bool {
+	return m.AlertRuleID == nil && m.ClusterID == nil && m.ServiceID == nil && m.HostID == nil && m.Severity == ""
+}
+
+// Matches 判断告警事件是否满足全部匹配条件
+func (m AlertMatcher) Matches(event *AlertEvent) bool {
+	if m.AlertRuleID != nil && *m.AlertRuleID != event.AlertRuleID {
+		return false
+	}
+	if !matchID(m.ClusterID, event.ClusterID) || !matchID(m.ServiceID, event.ServiceID) || !matchID(m.HostID, event.HostID) {
+		return false
+	}
+	if m.Severity != "" && m.Severity != event.Severity {
+		return false
+	}
+	return true
+}
+
+// matchID 匹配条件设置时，事件必须带有相同的ID
+func matchID(want, got *uint) bool {
+	if want == nil {
+		return true
+	}
+	return got != nil && *got == *want
+}
+
+// 告警静默
+type Silence struct {
+	ID           uint `json:"id" gorm:"primaryKey"`
+	AlertMatcher `gorm:"embedded"`
+	StartsAt     time.Time `json:"starts_at" gorm:"index;not null"`
+	EndsAt       time.Time `json:"ends_at" gorm:"index;not null"`
+	Comment      string    `json:"comment" gorm:"size:500;not null"`
+	CreatedBy    uint      `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// IsActive 静默在指定时间是否生效
+func (s *Silence) IsActive(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// 维护窗口
+type MaintenanceWindow struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Name         string `json:"name" gorm:"size:100;not null"`
+	Description  string `json:"description" gorm:"size:500"`
+	AlertMatcher `gorm:"embedded"`
+	// 周期性窗口的开始时间，标准5段cron表达式，可加 CRON_TZ= 前缀指定时区；为空表示一次性窗口
+	CronExpression string     `json:"cron_expression" gorm:"size:100"`
+	Duration       int        `json:"duration"`  // 周期性窗口每次持续时间，单位为分钟
+	StartsAt       *time.Time `json:"starts_at"` // 窗口生效起始时间，一次性窗口的开始时间
+	EndsAt         *time.Time `json:"ends_at"`   // 窗口生效截止时间，一次性窗口的结束时间
+	Enabled        bool       `json:"enabled"`   // 是否启用；不设 gorm 默认值，否则创建时 false 会被忽略
+	CreatedBy      uint       `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,86 @@
+package model
+
+import "time"
+
+// UserStatus 用户状态
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "ACTIVE"
+	UserStatusDisabled UserStatus = "DISABLED" // 禁用后不能登录，登录会话被吊销，API令牌也不能使用
+)
+
+// 用户的认证来源
+const (
+	UserAuthSourceLocal   = "LOCAL"   // 本地账号，使用 user 表中的密码登录
+	UserAuthSourceLDAP    = "LDAP"    // LDAP/AD 用户，首次登录时创建，角色按组映射同步
+	UserAuthSourceOIDC    = "OIDC"    // OIDC 单点登录用户，首次登录时创建，角色按声明映射同步
+	UserAuthSourceService = "SERVICE" // 服务账号，供自动化脚本使用，不能登录，只能通过API令牌访问
+)
+
+// User 用户，对应 schema.sql 创建的 user 表
+type User struct {
+	ID           int        `json:"id"`
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"`
+	Email        string     `json:"email"`
+	Phone        string     `json:"phone"`
+	Status       UserStatus `json:"status"`
+	AuthSource   string     `json:"auth_source" gorm:"size:20;not null;default:'LOCAL'"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Roles        []*Role    `json:"roles,omitempty" gorm:"many2many:user_role;"`
+}
+
+// TableName 用户表名
+func (User) TableName() string {
+	return "user"
+}
+
+// Role 角色，对应 role 表，用户通过角色获得权限
+type Role struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Privileges  []*Privilege `json:"privileges,omitempty" gorm:"many2many:role_privilege;"`
+}
+
+// TableName 角色表名
+func (Role) TableName() string {
+	return "role"
+}
+
+// PrivilegeViewAudit 查看和导出审计日志的权限，只授予管理员角色
+const PrivilegeViewAudit = "VIEW_AUDIT"
+
+// Privilege 权限，对应 privilege 表，权限由 schema.sql 预置
+type Privilege struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 权限表名
+func (Privilege) TableName() string {
+	return "privilege"
+}
+
+// RoleBinding 在集群或服务范围内授予用户的角色，角色中的权限只作用于该集群或服务
+//
+// 全局角色仍通过 user_role 表授予；ServiceID 为空时作用于整个集群。
+type RoleBinding struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"not null;uniqueIndex:idx_role_binding,priority:1"`
+	RoleID    int       `json:"role_id" gorm:"not null;uniqueIndex:idx_role_binding,priority:2"`
+	ClusterID int       `json:"cluster_id" gorm:"not null;uniqueIndex:idx_role_binding,priority:3"`
+	ServiceID *int      `json:"service_id" gorm:"uniqueIndex:idx_role_binding,priority:4"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RoleName    string `json:"role_name,omitempty" gorm:"->;-:migration"`
+	ClusterName string `json:"cluster_name,omitempty" gorm:"->;-:migration"`
+	ServiceName string `json:"service_name,omitempty" gorm:"->;-:migration"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// UserSession 用户的登录会话，登录时创建，访问令牌和刷新令牌都属于一个会话
+//
+// 访问令牌有效期短，每个请求都检查其会话未被吊销；刷新令牌每次使用后轮换，数据库中只保存哈希。
+// 退出登录、禁用用户、重置密码时吊销会话。
+type UserSession struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	SessionID        string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	UserID           int        `json:"user_id" gorm:"not null;index"`
+	RefreshTokenHash string     `json:"-" gorm:"size:64;not null"` // 当前刷新令牌的 SHA-256 哈希
+	ClientIP         string     `json:"client_ip" gorm:"size:64"`
+	UserAgent        string     `json:"user_agent" gorm:"size:255"`
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"index"` // 刷新令牌的过期时间，每次刷新后顺延
+	LastRefreshedAt  *time.Time `json:"last_refreshed_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+
+	Current bool `json:"current,omitempty" gorm:"-"` // 是否为发起请求的会话
+}
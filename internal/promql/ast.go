@@ -0,0 +1,290 @@
+package promql
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MatchType 标签匹配方式
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// MetricNameLabel 指标名称对应的保留标签
+const MetricNameLabel = "__name__"
+
+// LabelMatcher 标签匹配器
+type LabelMatcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+	re    *regexp.Regexp
+}
+
+// NewLabelMatcher 创建标签匹配器，正则匹配器会被预编译并锚定首尾
+func NewLabelMatcher(typ MatchType, name, value string) (*LabelMatcher, error) {
+	m := &LabelMatcher{Name: name, Type: typ, Value: value}
+	if typ == MatchRegexp || typ == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %v", value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches 判断标签值是否满足匹配器
+func (m *LabelMatcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+func (m *LabelMatcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// Labels 序列标签集合
+type Labels map[string]string
+
+// Fingerprint 返回标签集合的稳定标识（SHA1十六进制），用于区分不同序列
+func (l Labels) Fingerprint() string {
+	sum := sha1.Sum([]byte(l.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// String 按标签名排序输出，例如 {host_id="1", service="hdfs"}
+func (l Labels) String() string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%q", name, l[name]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// Copy 复制标签集合
+func (l Labels) Copy() Labels {
+	c := make(Labels, len(l))
+	for k, v := range l {
+		c[k] = v
+	}
+	return c
+}
+
+// withoutName 返回去掉指标名称的标签集合
+func (l Labels) withoutName() Labels {
+	c := l.Copy()
+	delete(c, MetricNameLabel)
+	return c
+}
+
+// Expr 表达式语法树节点
+type Expr interface {
+	String() string
+}
+
+// NumberLiteral 数值字面量
+type NumberLiteral struct {
+	Value float64
+}
+
+func (e *NumberLiteral) String() string {
+	return fmt.Sprintf("%g", e.Value)
+}
+
+// VectorSelector 瞬时向量选择器，例如 cpu_usage{host_id="1"}
+type VectorSelector struct {
+	Name     string
+	Matchers []*LabelMatcher
+}
+
+func (e *VectorSelector) String() string {
+	var parts []string
+	for _, m := range e.Matchers {
+		if m.Name == MetricNameLabel {
+			continue
+		}
+		parts = append(parts, m.String())
+	}
+	if len(parts) == 0 {
+		return e.Name
+	}
+	return e.Name + "{" + strings.Join(parts, ", ") + "}"
+}
+
+// MatrixSelector 区间向量选择器，例如 cpu_usage[5m]
+type MatrixSelector struct {
+	Vector *VectorSelector
+	Range  time.Duration
+}
+
+func (e *MatrixSelector) String() string {
+	return fmt.Sprintf("%s[%s]", e.Vector.String(), e.Range)
+}
+
+// Call 函数调用
+type Call struct {
+	Func string
+	Args []Expr
+}
+
+func (e *Call) String() string {
+	args := make([]string, 0, len(e.Args))
+	for _, a := range e.Args {
+		args = append(args, a.String())
+	}
+	return fmt.Sprintf("%s(%s)", e.Func, strings.Join(args, ", "))
+}
+
+// AggregateExpr 聚合表达式，例如 sum by (cluster_id) (...)
+type AggregateExpr struct {
+	Op       string
+	Expr     Expr
+	Grouping []string
+	Without  bool
+}
+
+func (e *AggregateExpr) String() string {
+	s := e.Op
+	if len(e.Grouping) > 0 || e.Without {
+		kw := "by"
+		if e.Without {
+			kw = "without"
+		}
+		s += fmt.Sprintf(" %s (%s)", kw, strings.Join(e.Grouping, ", "))
+	}
+	return fmt.Sprintf("%s (%s)", s, e.Expr.String())
+}
+
+// BinaryExpr 二元运算表达式
+type BinaryExpr struct {
+	Op       tokenType
+	LHS, RHS Expr
+	// On 为 true 时仅按 MatchingLabels 匹配，否则忽略 MatchingLabels 后匹配
+	On             bool
+	MatchingLabels []string
+}
+
+func (e *BinaryExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS.String(), binaryOpString(e.Op), e.RHS.String())
+}
+
+// UnaryExpr 一元取负表达式
+type UnaryExpr struct {
+	Expr Expr
+}
+
+func (e *UnaryExpr) String() string {
+	return "-" + e.Expr.String()
+}
+
+// ParenExpr 括号表达式
+type ParenExpr struct {
+	Expr Expr
+}
+
+func (e *ParenExpr) String() string {
+	return "(" + e.Expr.String() + ")"
+}
+
+func binaryOpString(op tokenType) string {
+	switch op {
+	case tokAdd:
+		return "+"
+	case tokSub:
+		return "-"
+	case tokMul:
+		return "*"
+	case tokDiv:
+		return "/"
+	case tokMod:
+		return "%"
+	case tokPow:
+		return "^"
+	case tokEql:
+		return "=="
+	case tokNeq:
+		return "!="
+	case tokGtr:
+		return ">"
+	case tokGte:
+		return ">="
+	case tokLss:
+		return "<"
+	case tokLte:
+		return "<="
+	case tokAnd:
+		return "and"
+	case tokOr:
+		return "or"
+	case tokUnless:
+		return "unless"
+	}
+	return "?"
+}
+
+func isComparisonOp(op tokenType) bool {
+	switch op {
+	case tokEql, tokNeq, tokGtr, tokGte, tokLss, tokLte:
+		return true
+	}
+	return false
+}
+
+func isSetOp(op tokenType) bool {
+	return op == tokAnd || op == tokOr || op == tokUnless
+}
+
+// Selectors 返回表达式中引用的所有向量选择器
+func Selectors(expr Expr) []*VectorSelector {
+	var result []*VectorSelector
+	var walk func(Expr)
+	walk = func(e Expr) {
+		switch n := e.(type) {
+		case *VectorSelector:
+			result = append(result, n)
+		case *MatrixSelector:
+			result = append(result, n.Vector)
+		case *Call:
+			for _, a := range n.Args {
+				walk(a)
+			}
+		case *AggregateExpr:
+			walk(n.Expr)
+		case *BinaryExpr:
+			walk(n.LHS)
+			walk(n.RHS)
+		case *UnaryExpr:
+			walk(n.Expr)
+		case *ParenExpr:
+			walk(n.Expr)
+		}
+	}
+	walk(expr)
+	return result
+}
@@ -0,0 +1,475 @@
+package promql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLookback 瞬时向量选择器向前查找最新样本的时间窗口
+const DefaultLookback = 5 * time.Minute
+
+// Sample 单个样本点
+type Sample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Series 带标签的时间序列
+type Series struct {
+	Labels  Labels
+	Samples []Sample
+}
+
+// Querier 指标查询接口，由指标存储层实现
+type Querier interface {
+	// Select 返回满足所有匹配器、且时间戳在 (start, end] 区间内的序列，样本按时间升序
+	Select(ctx context.Context, matchers []*LabelMatcher, start, end time.Time) ([]Series, error)
+}
+
+// VectorElement 瞬时向量中的一个元素
+type VectorElement struct {
+	Labels Labels  `json:"labels"`
+	Value  float64 `json:"value"`
+}
+
+// Vector 瞬时向量
+type Vector []VectorElement
+
+// Engine 表达式求值引擎
+type Engine struct {
+	querier  Querier
+	lookback time.Duration
+}
+
+// NewEngine 创建求值引擎
+func NewEngine(querier Querier) *Engine {
+	return &Engine{
+		querier:  querier,
+		lookback: DefaultLookback,
+	}
+}
+
+// Query 解析并在指定时间点对表达式求值
+func (e *Engine) Query(ctx context.Context, query string, ts time.Time) (Vector, error) {
+	expr, err := ParseExpr(query)
+	if err != nil {
+		return nil, err
+	}
+	return e.Eval(ctx, expr, ts)
+}
+
+// Eval 在指定时间点对表达式求值，标量结果以不带标签的单元素向量返回
+func (e *Engine) Eval(ctx context.Context, expr Expr, ts time.Time) (Vector, error) {
+	if typeOf(expr) == valueMatrix {
+		return nil, fmt.Errorf("表达式结果不能是区间向量")
+	}
+
+	ev := &evaluator{ctx: ctx, engine: e, ts: ts}
+	val, err := ev.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := val.(type) {
+	case float64:
+		return Vector{{Labels: Labels{}, Value: v}}, nil
+	case Vector:
+		sort.Slice(v, func(i, j int) bool {
+			return v[i].Labels.String() < v[j].Labels.String()
+		})
+		return v, nil
+	}
+	return nil, fmt.Errorf("不支持的结果类型 %T", val)
+}
+
+// evaluator 单次求值的上下文
+type evaluator struct {
+	ctx    context.Context
+	engine *Engine
+	ts     time.Time
+}
+
+func (ev *evaluator) eval(expr Expr) (interface{}, error) {
+	if err := ev.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	switch n := expr.(type) {
+	case *NumberLiteral:
+		return n.Value, nil
+	case *ParenExpr:
+		return ev.eval(n.Expr)
+	case *UnaryExpr:
+		val, err := ev.eval(n.Expr)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := val.(float64); ok {
+			return -f, nil
+		}
+		vec := val.(Vector)
+		result := make(Vector, 0, len(vec))
+		for _, el := range vec {
+			result = append(result, VectorElement{Labels: el.Labels.withoutName(), Value: -el.Value})
+		}
+		return result, nil
+	case *VectorSelector:
+		return ev.evalVectorSelector(n)
+	case *MatrixSelector:
+		return ev.evalMatrixSelector(n)
+	case *Call:
+		return ev.evalCall(n)
+	case *AggregateExpr:
+		return ev.evalAggregate(n)
+	case *BinaryExpr:
+		return ev.evalBinary(n)
+	}
+	return nil, fmt.Errorf("不支持的表达式 %T", expr)
+}
+
+// evalVectorSelector 取每个序列在回看窗口内的最新样本
+func (ev *evaluator) evalVectorSelector(vs *VectorSelector) (Vector, error) {
+	series, err := ev.engine.querier.Select(ev.ctx, vs.Matchers, ev.ts.Add(-ev.engine.lookback), ev.ts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(Vector, 0, len(series))
+	for _, s := range series {
+		if len(s.Samples) == 0 {
+			continue
+		}
+		last := s.Samples[len(s.Samples)-1]
+		result = append(result, VectorElement{Labels: s.Labels, Value: last.Value})
+	}
+	return result, nil
+}
+
+// evalMatrixSelector 取每个序列在区间内的全部样本
+func (ev *evaluator) evalMatrixSelector(ms *MatrixSelector) ([]Series, error) {
+	series, err := ev.engine.querier.Select(ev.ctx, ms.Vector.Matchers, ev.ts.Add(-ms.Range), ev.ts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Series, 0, len(series))
+	for _, s := range series {
+		if len(s.Samples) > 0 {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (ev *evaluator) evalCall(call *Call) (interface{}, error) {
+	fn := functions[call.Func]
+	args := make([]interface{}, 0, len(call.Args))
+	for _, a := range call.Args {
+		val, err := ev.eval(a)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, val)
+	}
+	return fn.call(args, call.Args)
+}
+
+// evalAggregate 按分组标签聚合
+func (ev *evaluator) evalAggregate(agg *AggregateExpr) (Vector, error) {
+	val, err := ev.eval(agg.Expr)
+	if err != nil {
+		return nil, err
+	}
+	vec := val.(Vector)
+
+	type group struct {
+		labels Labels
+		values []float64
+	}
+	groups := make(map[string]*group)
+	var order []string
+
+	for _, el := range vec {
+		gl := groupingLabels(el.Labels, agg.Grouping, agg.Without)
+		key := gl.String()
+		g, ok := groups[key]
+		if !ok {
+			g = &group{labels: gl}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.values = append(g.values, el.Value)
+	}
+
+	result := make(Vector, 0, len(groups))
+	for _, key := range order {
+		g := groups[key]
+		result = append(result, VectorElement{Labels: g.labels, Value: aggregate(agg.Op, g.values)})
+	}
+	return result, nil
+}
+
+// groupingLabels 计算元素所属分组的标签
+func groupingLabels(l Labels, grouping []string, without bool) Labels {
+	result := Labels{}
+	if without {
+		drop := map[string]bool{MetricNameLabel: true}
+		for _, name := range grouping {
+			drop[name] = true
+		}
+		for k, v := range l {
+			if !drop[k] {
+				result[k] = v
+			}
+		}
+		return result
+	}
+	for _, name := range grouping {
+		if v, ok := l[name]; ok {
+			result[name] = v
+		}
+	}
+	return result
+}
+
+func aggregate(op string, values []float64) float64 {
+	switch op {
+	case "sum":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	case "avg":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	case "min":
+		min := values[0]
+		for _, v := range values[1:] {
+			if v < min || math.IsNaN(min) {
+				min = v
+			}
+		}
+		return min
+	case "max":
+		max := values[0]
+		for _, v := range values[1:] {
+			if v > max || math.IsNaN(max) {
+				max = v
+			}
+		}
+		return max
+	case "count":
+		return float64(len(values))
+	}
+	return math.NaN()
+}
+
+func (ev *evaluator) evalBinary(be *BinaryExpr) (interface{}, error) {
+	lhs, err := ev.eval(be.LHS)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := ev.eval(be.RHS)
+	if err != nil {
+		return nil, err
+	}
+
+	lf, lIsScalar := lhs.(float64)
+	rf, rIsScalar := rhs.(float64)
+
+	switch {
+	case lIsScalar && rIsScalar:
+		if isComparisonOp(be.Op) {
+			return nil, fmt.Errorf("比较运算至少需要一个向量操作数")
+		}
+		return arith(be.Op, lf, rf), nil
+
+	case lIsScalar:
+		return scalarVectorOp(be.Op, rhs.(Vector), lf, true), nil
+
+	case rIsScalar:
+		return scalarVectorOp(be.Op, lhs.(Vector), rf, false), nil
+	}
+
+	lv, rv := lhs.(Vector), rhs.(Vector)
+	if isSetOp(be.Op) {
+		return setOp(be, lv, rv), nil
+	}
+	return vectorVectorOp(be, lv, rv)
+}
+
+// scalarVectorOp 标量与向量的运算，scalarLeft 表示标量位于运算符左侧
+func scalarVectorOp(op tokenType, vec Vector, scalar float64, scalarLeft bool) Vector {
+	result := make(Vector, 0, len(vec))
+	for _, el := range vec {
+		l, r := el.Value, scalar
+		if scalarLeft {
+			l, r = scalar, el.Value
+		}
+
+		if isComparisonOp(op) {
+			if compare(op, l, r) {
+				result = append(result, el)
+			}
+			continue
+		}
+		result = append(result, VectorElement{Labels: el.Labels.withoutName(), Value: arith(op, l, r)})
+	}
+	return result
+}
+
+// matchSignature 计算向量匹配使用的标签签名
+func matchSignature(l Labels, on bool, matching []string) string {
+	sig := Labels{}
+	if on {
+		for _, name := range matching {
+			sig[name] = l[name]
+		}
+		return sig.String()
+	}
+
+	ignore := map[string]bool{MetricNameLabel: true}
+	for _, name := range matching {
+		ignore[name] = true
+	}
+	for k, v := range l {
+		if !ignore[k] {
+			sig[k] = v
+		}
+	}
+	return sig.String()
+}
+
+// vectorVectorOp 向量之间的一对一运算
+func vectorVectorOp(be *BinaryExpr, lhs, rhs Vector) (Vector, error) {
+	rightBySig := make(map[string]VectorElement, len(rhs))
+	for _, el := range rhs {
+		sig := matchSignature(el.Labels, be.On, be.MatchingLabels)
+		if _, dup := rightBySig[sig]; dup {
+			return nil, fmt.Errorf("运算 %s 右侧存在多个标签相同的序列 %s，请使用 on/ignoring 或先聚合", binaryOpString(be.Op), el.Labels)
+		}
+		rightBySig[sig] = el
+	}
+
+	result := make(Vector, 0, len(lhs))
+	seen := make(map[string]bool, len(lhs))
+	for _, l := range lhs {
+		sig := matchSignature(l.Labels, be.On, be.MatchingLabels)
+		r, ok := rightBySig[sig]
+		if !ok {
+			continue
+		}
+		if seen[sig] {
+			return nil, fmt.Errorf("运算 %s 左侧存在多个标签相同的序列 %s，请使用 on/ignoring 或先聚合", binaryOpString(be.Op), l.Labels)
+		}
+		seen[sig] = true
+
+		if isComparisonOp(be.Op) {
+			if compare(be.Op, l.Value, r.Value) {
+				result = append(result, l)
+			}
+			continue
+		}
+
+		labels := l.Labels.withoutName()
+		if be.On {
+			labels = Labels{}
+			for _, name := range be.MatchingLabels {
+				if v, ok := l.Labels[name]; ok {
+					labels[name] = v
+				}
+			}
+		}
+		result = append(result, VectorElement{Labels: labels, Value: arith(be.Op, l.Value, r.Value)})
+	}
+	return result, nil
+}
+
+// setOp and / or / unless 集合运算
+func setOp(be *BinaryExpr, lhs, rhs Vector) Vector {
+	rightSigs := make(map[string]bool, len(rhs))
+	for _, el := range rhs {
+		rightSigs[matchSignature(el.Labels, be.On, be.MatchingLabels)] = true
+	}
+
+	var result Vector
+	switch be.Op {
+	case tokAnd:
+		for _, el := range lhs {
+			if rightSigs[matchSignature(el.Labels, be.On, be.MatchingLabels)] {
+				result = append(result, el)
+			}
+		}
+	case tokUnless:
+		for _, el := range lhs {
+			if !rightSigs[matchSignature(el.Labels, be.On, be.MatchingLabels)] {
+				result = append(result, el)
+			}
+		}
+	case tokOr:
+		leftSigs := make(map[string]bool, len(lhs))
+		for _, el := range lhs {
+			leftSigs[matchSignature(el.Labels, be.On, be.MatchingLabels)] = true
+			result = append(result, el)
+		}
+		for _, el := range rhs {
+			if !leftSigs[matchSignature(el.Labels, be.On, be.MatchingLabels)] {
+				result = append(result, el)
+			}
+		}
+	}
+	return result
+}
+
+func arith(op tokenType, l, r float64) float64 {
+	switch op {
+	case tokAdd:
+		return l + r
+	case tokSub:
+		return l - r
+	case tokMul:
+		return l * r
+	case tokDiv:
+		return l / r
+	case tokMod:
+		return math.Mod(l, r)
+	case tokPow:
+		return math.Pow(l, r)
+	}
+	return math.NaN()
+}
+
+func compare(op tokenType, l, r float64) bool {
+	switch op {
+	case tokEql:
+		return l == r
+	case tokNeq:
+		return l != r
+	case tokGtr:
+		return l > r
+	case tokGte:
+		return l >= r
+	case tokLss:
+		return l < r
+	case tokLte:
+		return l <= r
+	}
+	return false
+}
+
+// FormatVector 将结果向量格式化为可读文本，用于告警消息
+func FormatVector(vec Vector) string {
+	parts := make([]string, 0, len(vec))
+	for _, el := range vec {
+		parts = append(parts, fmt.Sprintf("%s => %g", el.Labels, el.Value))
+	}
+	return strings.Join(parts, "\n")
+}
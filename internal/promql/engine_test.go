@@ -0,0 +1,329 @@
+package promql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubQuerier 内存中的指标存储，按 Querier 约定返回 (start, end] 区间内的样本
+type stubQuerier struct {
+	series []Series
+	err    error
+}
+
+func (q *stubQuerier) Select(_ context.Context, matchers []*LabelMatcher, start, end time.Time) ([]Series, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	var result []Series
+	for _, s := range q.series {
+		matched := true
+		for _, m := range matchers {
+			if !m.Matches(s.Labels[m.Name]) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		var samples []Sample
+		for _, sample := range s.Samples {
+			if sample.Timestamp.After(start) && !sample.Timestamp.After(end) {
+				samples = append(samples, sample)
+			}
+		}
+		result = append(result, Series{Labels: s.Labels, Samples: samples})
+	}
+	return result, nil
+}
+
+var testEvalTime = time.Unix(1700000000, 0)
+
+// instant 生成在求值时间前 ago 的单个样本
+func instant(labels Labels, ago time.Duration, value float64) Series {
+	return Series{Labels: labels, Samples: []Sample{{Timestamp: testEvalTime.Add(-ago), Value: value}}}
+}
+
+func newTestEngine() *Engine {
+	return NewEngine(&stubQuerier{series: []Series{
+		instant(Labels{MetricNameLabel: "cpu_usage", "cluster_id": "1", "host_id": "1", "role": "master"}, 30*time.Second, 80),
+		instant(Labels{MetricNameLabel: "cpu_usage", "cluster_id": "1", "host_id": "2", "role": "worker"}, time.Minute, 40),
+		instant(Labels{MetricNameLabel: "cpu_usage", "cluster_id": "2", "host_id": "3", "role": "worker"}, 0, 60),
+		// 超出回看窗口，瞬时查询不返回
+		instant(Labels{MetricNameLabel: "cpu_usage", "cluster_id": "2", "host_id": "4", "role": "worker"}, 10*time.Minute, 90),
+		instant(Labels{MetricNameLabel: "mem_usage", "cluster_id": "1", "host_id": "1"}, 0, 50),
+		instant(Labels{MetricNameLabel: "mem_usage", "cluster_id": "1", "host_id": "2"}, 0, 20),
+		instant(Labels{MetricNameLabel: "mem_usage", "cluster_id": "2", "host_id": "3"}, 0, 30),
+		{
+			// 第三个样本计数器重置，第一个样本在 5m 区间之外
+			Labels:  Labels{MetricNameLabel: "requests_total", "host_id": "1"},
+			Samples: samplesEvery(testEvalTime.Add(-5*time.Minute), time.Minute, 0, 100, 220, 60, 180, 360),
+		},
+		{
+			Labels:  Labels{MetricNameLabel: "temperature", "host_id": "1"},
+			Samples: samplesEvery(testEvalTime.Add(-9*time.Minute), 3*time.Minute, 3, 1, 4, 2),
+		},
+	}})
+}
+
+func TestEngineQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		// 标签匹配
+		{
+			name:  "equal matcher",
+			query: `cpu_usage{role="worker"}`,
+			want: []string{
+				`{__name__="cpu_usage", cluster_id="1", host_id="2", role="worker"} => 40`,
+				`{__name__="cpu_usage", cluster_id="2", host_id="3", role="worker"} => 60`,
+			},
+		},
+		{
+			name:  "not equal matcher",
+			query: `cpu_usage{role!="worker"}`,
+			want:  []string{`{__name__="cpu_usage", cluster_id="1", host_id="1", role="master"} => 80`},
+		},
+		{
+			name:  "regexp matcher",
+			query: `cpu_usage{host_id=~"1|3"}`,
+			want: []string{
+				`{__name__="cpu_usage", cluster_id="1", host_id="1", role="master"} => 80`,
+				`{__name__="cpu_usage", cluster_id="2", host_id="3", role="worker"} => 60`,
+			},
+		},
+		{
+			name:  "not regexp matcher",
+			query: `cpu_usage{host_id!~"1|3", cluster_id="1"}`,
+			want:  []string{`{__name__="cpu_usage", cluster_id="1", host_id="2", role="worker"} => 40`},
+		},
+		{
+			name:  "missing label matches empty value",
+			query: `mem_usage{role="", host_id="3"}`,
+			want:  []string{`{__name__="mem_usage", cluster_id="2", host_id="3"} => 30`},
+		},
+		{
+			name:  "no match",
+			query: `cpu_usage{role="gateway"}`,
+		},
+
+		// 聚合
+		{
+			name:  "sum by",
+			query: `sum by (cluster_id) (cpu_usage)`,
+			want:  []string{`{cluster_id="1"} => 120`, `{cluster_id="2"} => 60`},
+		},
+		{
+			name:  "grouping after expression",
+			query: `count(cpu_usage) by (role)`,
+			want:  []string{`{role="master"} => 1`, `{role="worker"} => 2`},
+		},
+		{
+			name:  "without drops metric name",
+			query: `avg without (host_id, role) (cpu_usage)`,
+			want:  []string{`{cluster_id="1"} => 60`, `{cluster_id="2"} => 60`},
+		},
+		{
+			name:  "without nothing",
+			query: `max without () (mem_usage{host_id="1"})`,
+			want:  []string{`{cluster_id="1", host_id="1"} => 50`},
+		},
+		{
+			name:  "by missing label",
+			query: `min by (rack) (cpu_usage)`,
+			want:  []string{`{} => 40`},
+		},
+		{
+			name:  "without grouping",
+			query: `max(cpu_usage)`,
+			want:  []string{`{} => 80`},
+		},
+
+		// 向量匹配
+		{
+			name:  "default matching requires identical labels",
+			query: `cpu_usage + mem_usage`,
+		},
+		{
+			name:  "ignoring",
+			query: `cpu_usage - ignoring(role) mem_usage`,
+			want: []string{
+				`{cluster_id="1", host_id="1", role="master"} => 30`,
+				`{cluster_id="1", host_id="2", role="worker"} => 20`,
+				`{cluster_id="2", host_id="3", role="worker"} => 30`,
+			},
+		},
+		{
+			name:  "on",
+			query: `cpu_usage / on(host_id) mem_usage`,
+			want:  []string{`{host_id="1"} => 1.6`, `{host_id="2"} => 2`, `{host_id="3"} => 2`},
+		},
+		{
+			name:  "comparison keeps left labels",
+			query: `cpu_usage >= on(host_id) (mem_usage * 2)`,
+			want: []string{
+				`{__name__="cpu_usage", cluster_id="1", host_id="2", role="worker"} => 40`,
+				`{__name__="cpu_usage", cluster_id="2", host_id="3", role="worker"} => 60`,
+			},
+		},
+		{
+			name:  "and",
+			query: `cpu_usage and on(host_id) mem_usage{cluster_id="2"}`,
+			want:  []string{`{__name__="cpu_usage", cluster_id="2", host_id="3", role="worker"} => 60`},
+		},
+		{
+			name:  "unless",
+			query: `cpu_usage unless on(cluster_id) mem_usage{host_id="3"}`,
+			want: []string{
+				`{__name__="cpu_usage", cluster_id="1", host_id="1", role="master"} => 80`,
+				`{__name__="cpu_usage", cluster_id="1", host_id="2", role="worker"} => 40`,
+			},
+		},
+		{
+			name:  "or",
+			query: `mem_usage{cluster_id="1"} or on(host_id) cpu_usage`,
+			want: []string{
+				`{__name__="cpu_usage", cluster_id="2", host_id="3", role="worker"} => 60`,
+				`{__name__="mem_usage", cluster_id="1", host_id="1"} => 50`,
+				`{__name__="mem_usage", cluster_id="1", host_id="2"} => 20`,
+			},
+		},
+
+		// 标量运算
+		{
+			name:  "vector scalar arithmetic",
+			query: `100 - cpu_usage{host_id="1"}`,
+			want:  []string{`{cluster_id="1", host_id="1", role="master"} => 20`},
+		},
+		{
+			name:  "vector scalar comparison",
+			query: `cpu_usage > 50`,
+			want: []string{
+				`{__name__="cpu_usage", cluster_id="1", host_id="1", role="master"} => 80`,
+				`{__name__="cpu_usage", cluster_id="2", host_id="3", role="worker"} => 60`,
+			},
+		},
+		{
+			name:  "scalar left comparison",
+			query: `50 > cpu_usage`,
+			want:  []string{`{__name__="cpu_usage", cluster_id="1", host_id="2", role="worker"} => 40`},
+		},
+		{
+			name:  "negation",
+			query: `-mem_usage{host_id="2"}`,
+			want:  []string{`{cluster_id="1", host_id="2"} => -20`},
+		},
+		{
+			name:  "scalar",
+			query: `(1 + 2) * 3 ^ 2`,
+			want:  []string{`{} => 27`},
+		},
+
+		// 区间函数
+		{
+			name:  "rate with counter reset",
+			query: `rate(requests_total[5m])`,
+			want:  []string{`{host_id="1"} => 2`},
+		},
+		{
+			name:  "increase with counter reset",
+			query: `increase(requests_total[5m])`,
+			want:  []string{`{host_id="1"} => 480`},
+		},
+		{
+			name:  "irate",
+			query: `irate(requests_total[5m])`,
+			want:  []string{`{host_id="1"} => 3`},
+		},
+		{
+			name:  "avg_over_time",
+			query: `avg_over_time(temperature[10m])`,
+			want:  []string{`{host_id="1"} => 2.5`},
+		},
+		{
+			name:  "avg_over_time excludes samples outside range",
+			query: `avg_over_time(temperature[5m])`,
+			want:  []string{`{host_id="1"} => 3`},
+		},
+		{
+			name:  "max_over_time",
+			query: `max_over_time(temperature[10m])`,
+			want:  []string{`{host_id="1"} => 4`},
+		},
+		{
+			name:  "count_over_time",
+			query: `count_over_time(temperature[10m])`,
+			want:  []string{`{host_id="1"} => 4`},
+		},
+		{
+			name:  "over_time without samples in range",
+			query: `sum_over_time(cpu_usage{host_id="4"}[5m])`,
+		},
+		{
+			name:  "aggregate over range function",
+			query: `sum(min_over_time(temperature[10m])) + delta(temperature[10m])`,
+		},
+		{
+			name:  "aggregate over range function with matching",
+			query: `sum by (host_id) (min_over_time(temperature[10m])) + delta(temperature[10m])`,
+			want:  []string{`{host_id="1"} => 0`},
+		},
+	}
+	engine := newTestEngine()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vec, err := engine.Query(context.Background(), tt.query, testEvalTime)
+			if err != nil {
+				t.Fatalf("Query(%q) error: %v", tt.query, err)
+			}
+			if got, want := FormatVector(vec), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("Query(%q) =\n%s\nwant\n%s", tt.query, got, want)
+			}
+		})
+	}
+}
+
+func TestEngineQueryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{name: "duplicate right series", query: `mem_usage / on(cluster_id) cpu_usage`, wantErr: "右侧存在多个标签相同的序列"},
+		{name: "duplicate left series", query: `cpu_usage / on(cluster_id) sum by (cluster_id) (mem_usage)`, wantErr: "左侧存在多个标签相同的序列"},
+		{name: "scalar comparison", query: `1 > 2`, wantErr: "比较运算至少需要一个向量操作数"},
+		{name: "range vector result", query: `cpu_usage[5m]`, wantErr: "表达式结果不能是区间向量"},
+		{name: "parse error", query: `sum(`, wantErr: "位置"},
+	}
+	engine := newTestEngine()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vec, err := engine.Query(context.Background(), tt.query, testEvalTime)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Query(%q) = %v, %v, want error containing %q", tt.query, vec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEngineQuerierError(t *testing.T) {
+	wantErr := errors.New("storage unavailable")
+	engine := NewEngine(&stubQuerier{err: wantErr})
+	if _, err := engine.Query(context.Background(), `sum(rate(requests_total[5m]))`, testEvalTime); !errors.Is(err, wantErr) {
+		t.Errorf("Query() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestEngineQueryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := newTestEngine().Query(ctx, `cpu_usage`, testEvalTime); !errors.Is(err, context.Canceled) {
+		t.Errorf("Query() error = %v, want context.Canceled", err)
+	}
+}
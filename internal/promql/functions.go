@@ -0,0 +1,140 @@
+package promql
+
+import (
+	"math"
+)
+
+// function 内置函数定义
+type function struct {
+	name     string
+	argTypes []valueType
+	call     func(args []interface{}, exprs []Expr) (interface{}, error)
+}
+
+var functions map[string]*function
+
+func init() {
+	functions = map[string]*function{
+		"rate":            rangeFunction("rate", rate),
+		"irate":           rangeFunction("irate", irate),
+		"increase":        rangeFunction("increase", increase),
+		"delta":           rangeFunction("delta", delta),
+		"avg_over_time":   rangeFunction("avg_over_time", overTime("avg")),
+		"sum_over_time":   rangeFunction("sum_over_time", overTime("sum")),
+		"min_over_time":   rangeFunction("min_over_time", overTime("min")),
+		"max_over_time":   rangeFunction("max_over_time", overTime("max")),
+		"count_over_time": rangeFunction("count_over_time", overTime("count")),
+		"last_over_time": rangeFunction("last_over_time", func(samples []Sample) (float64, bool) {
+			return samples[len(samples)-1].Value, true
+		}),
+		"abs":   mathFunction("abs", math.Abs),
+		"ceil":  mathFunction("ceil", math.Ceil),
+		"floor": mathFunction("floor", math.Floor),
+		"round": mathFunction("round", math.Round),
+	}
+}
+
+// rangeFunction 构造作用于区间向量的函数，结果去掉指标名称
+func rangeFunction(name string, fn func(samples []Sample) (float64, bool)) *function {
+	return &function{
+		name:     name,
+		argTypes: []valueType{valueMatrix},
+		call: func(args []interface{}, _ []Expr) (interface{}, error) {
+			series := args[0].([]Series)
+			result := make(Vector, 0, len(series))
+			for _, s := range series {
+				v, ok := fn(s.Samples)
+				if !ok {
+					continue
+				}
+				result = append(result, VectorElement{Labels: s.Labels.withoutName(), Value: v})
+			}
+			return result, nil
+		},
+	}
+}
+
+// mathFunction 构造逐元素计算的数学函数
+func mathFunction(name string, fn func(float64) float64) *function {
+	return &function{
+		name:     name,
+		argTypes: []valueType{valueVector},
+		call: func(args []interface{}, _ []Expr) (interface{}, error) {
+			vec := args[0].(Vector)
+			result := make(Vector, 0, len(vec))
+			for _, el := range vec {
+				result = append(result, VectorElement{Labels: el.Labels.withoutName(), Value: fn(el.Value)})
+			}
+			return result, nil
+		},
+	}
+}
+
+// counterIncrease 计算计数器在样本区间内的增长量，计数器重置时累加重置前的值
+func counterIncrease(samples []Sample) float64 {
+	var inc float64
+	prev := samples[0].Value
+	for _, s := range samples[1:] {
+		if s.Value < prev {
+			inc += prev
+		}
+		prev = s.Value
+	}
+	return inc + samples[len(samples)-1].Value - samples[0].Value
+}
+
+// rate 计数器每秒平均增长率
+func rate(samples []Sample) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	elapsed := samples[len(samples)-1].Timestamp.Sub(samples[0].Timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return counterIncrease(samples) / elapsed, true
+}
+
+// irate 基于最后两个样本的瞬时增长率
+func irate(samples []Sample) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	last, prev := samples[len(samples)-1], samples[len(samples)-2]
+	elapsed := last.Timestamp.Sub(prev.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	diff := last.Value - prev.Value
+	if last.Value < prev.Value {
+		diff = last.Value
+	}
+	return diff / elapsed, true
+}
+
+// increase 计数器在区间内的增长量
+func increase(samples []Sample) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	return counterIncrease(samples), true
+}
+
+// delta 仪表类指标在区间内首尾样本的差值
+func delta(samples []Sample) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	return samples[len(samples)-1].Value - samples[0].Value, true
+}
+
+// overTime 构造 *_over_time 系列函数
+func overTime(op string) func(samples []Sample) (float64, bool) {
+	return func(samples []Sample) (float64, bool) {
+		values := make([]float64, 0, len(samples))
+		for _, s := range samples {
+			values = append(values, s.Value)
+		}
+		return aggregate(op, values), true
+	}
+}
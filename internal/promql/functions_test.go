@@ -0,0 +1,106 @@
+package promql
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// samplesEvery 从 start 开始每隔 step 生成一个样本
+func samplesEvery(start time.Time, step time.Duration, values ...float64) []Sample {
+	samples := make([]Sample, 0, len(values))
+	for i, v := range values {
+		samples = append(samples, Sample{Timestamp: start.Add(time.Duration(i) * step), Value: v})
+	}
+	return samples
+}
+
+func TestRangeFunctions(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		fn      string
+		samples []Sample
+		want    float64
+		wantOK  bool
+	}{
+		{name: "rate", fn: "rate", samples: samplesEvery(start, 10*time.Second, 10, 20, 40), want: 1.5, wantOK: true},
+		// 20 -> 5 视为计数器重置，重置前的 20 计入增长量：20 + (15 - 10) = 25
+		{name: "rate with reset", fn: "rate", samples: samplesEvery(start, 10*time.Second, 10, 20, 5, 15), want: 25.0 / 30, wantOK: true},
+		{name: "rate with resets", fn: "rate", samples: samplesEvery(start, 10*time.Second, 5, 10, 2, 8, 1, 4), want: 17.0 / 50, wantOK: true},
+		{name: "rate reset to zero", fn: "rate", samples: samplesEvery(start, time.Minute, 100, 0, 30), want: 30.0 / 120, wantOK: true},
+		{name: "rate single sample", fn: "rate", samples: samplesEvery(start, time.Minute, 10)},
+		{name: "rate same timestamp", fn: "rate", samples: samplesEvery(start, 0, 10, 20)},
+		{name: "increase", fn: "increase", samples: samplesEvery(start, time.Minute, 1, 2, 4), want: 3, wantOK: true},
+		{name: "increase with reset", fn: "increase", samples: samplesEvery(start, 10*time.Second, 5, 10, 2, 8, 1, 4), want: 17, wantOK: true},
+		{name: "increase single sample", fn: "increase", samples: samplesEvery(start, time.Minute, 1)},
+		{name: "irate", fn: "irate", samples: samplesEvery(start, 10*time.Second, 0, 100, 130), want: 3, wantOK: true},
+		{name: "irate with reset", fn: "irate", samples: samplesEvery(start, 10*time.Second, 0, 100, 30), want: 3, wantOK: true},
+		{name: "irate single sample", fn: "irate", samples: samplesEvery(start, time.Minute, 1)},
+		// delta 用于仪表类指标，不处理重置
+		{name: "delta", fn: "delta", samples: samplesEvery(start, time.Minute, 10, 20, 3), want: -7, wantOK: true},
+		{name: "delta single sample", fn: "delta", samples: samplesEvery(start, time.Minute, 10)},
+		{name: "avg_over_time", fn: "avg_over_time", samples: samplesEvery(start, time.Minute, 3, 1, 4, 1, 5), want: 2.8, wantOK: true},
+		{name: "sum_over_time", fn: "sum_over_time", samples: samplesEvery(start, time.Minute, 3, 1, 4, 1, 5), want: 14, wantOK: true},
+		{name: "min_over_time", fn: "min_over_time", samples: samplesEvery(start, time.Minute, 3, 1, 4, 1, 5), want: 1, wantOK: true},
+		{name: "max_over_time", fn: "max_over_time", samples: samplesEvery(start, time.Minute, 3, 1, 4, 1, 5), want: 5, wantOK: true},
+		{name: "count_over_time", fn: "count_over_time", samples: samplesEvery(start, time.Minute, 3, 1, 4, 1, 5), want: 5, wantOK: true},
+		{name: "last_over_time", fn: "last_over_time", samples: samplesEvery(start, time.Minute, 3, 1, 4, 1, 5), want: 5, wantOK: true},
+		{name: "avg_over_time single sample", fn: "avg_over_time", samples: samplesEvery(start, time.Minute, 7), want: 7, wantOK: true},
+		{name: "min_over_time with NaN", fn: "min_over_time", samples: samplesEvery(start, time.Minute, math.NaN(), 2, 1), want: 1, wantOK: true},
+		{name: "max_over_time with NaN", fn: "max_over_time", samples: samplesEvery(start, time.Minute, math.NaN(), 2, 1), want: 2, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := []Series{{
+				Labels:  Labels{MetricNameLabel: "requests_total", "host_id": "1"},
+				Samples: tt.samples,
+			}}
+			val, err := functions[tt.fn].call([]interface{}{series}, nil)
+			if err != nil {
+				t.Fatalf("%s() error: %v", tt.fn, err)
+			}
+			vec := val.(Vector)
+			if !tt.wantOK {
+				if len(vec) != 0 {
+					t.Errorf("%s() = %v, want no result", tt.fn, vec)
+				}
+				return
+			}
+			if len(vec) != 1 {
+				t.Fatalf("%s() = %v, want one element", tt.fn, vec)
+			}
+			if math.Abs(vec[0].Value-tt.want) > 1e-9 {
+				t.Errorf("%s() = %g, want %g", tt.fn, vec[0].Value, tt.want)
+			}
+			// 函数结果去掉指标名称，保留其他标签
+			if got := vec[0].Labels.String(); got != `{host_id="1"}` {
+				t.Errorf("%s() labels = %s, want {host_id=\"1\"}", tt.fn, got)
+			}
+		})
+	}
+}
+
+func TestMathFunctions(t *testing.T) {
+	tests := []struct {
+		fn    string
+		input float64
+		want  float64
+	}{
+		{"abs", -2.5, 2.5},
+		{"ceil", 1.2, 2},
+		{"floor", -1.2, -2},
+		{"round", 2.5, 3},
+	}
+	for _, tt := range tests {
+		vec := Vector{{Labels: Labels{MetricNameLabel: "temp", "host_id": "1"}, Value: tt.input}}
+		val, err := functions[tt.fn].call([]interface{}{vec}, nil)
+		if err != nil {
+			t.Fatalf("%s() error: %v", tt.fn, err)
+		}
+		got := val.(Vector)
+		if len(got) != 1 || got[0].Value != tt.want || got[0].Labels.String() != `{host_id="1"}` {
+			t.Errorf("%s(%g) = %v, want {host_id=\"1\"} => %g", tt.fn, tt.input, got, tt.want)
+		}
+	}
+}
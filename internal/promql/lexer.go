@@ -0,0 +1,251 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// tokenType 词法单元类型
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIdentifier
+	tokNumber
+	tokString
+	tokDuration
+	tokLeftParen
+	tokRightParen
+	tokLeftBrace
+	tokRightBrace
+	tokLeftBracket
+	tokRightBracket
+	tokComma
+	tokAdd
+	tokSub
+	tokMul
+	tokDiv
+	tokMod
+	tokPow
+	tokEql
+	tokNeq
+	tokGtr
+	tokGte
+	tokLss
+	tokLte
+	tokAssign
+	tokRegexMatch
+	tokRegexNoMatch
+	tokAnd
+	tokOr
+	tokUnless
+)
+
+// token 词法单元
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokEOF {
+		return "EOF"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+// lex 将表达式切分为词法单元
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+
+	for i < len(runes) {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{tokLeftParen, "(", i})
+		case r == ')':
+			tokens = append(tokens, token{tokRightParen, ")", i})
+		case r == '{':
+			tokens = append(tokens, token{tokLeftBrace, "{", i})
+		case r == '}':
+			tokens = append(tokens, token{tokRightBrace, "}", i})
+		case r == ']':
+			tokens = append(tokens, token{tokRightBracket, "]", i})
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+		case r == '+':
+			tokens = append(tokens, token{tokAdd, "+", i})
+		case r == '-':
+			tokens = append(tokens, token{tokSub, "-", i})
+		case r == '*':
+			tokens = append(tokens, token{tokMul, "*", i})
+		case r == '/':
+			tokens = append(tokens, token{tokDiv, "/", i})
+		case r == '%':
+			tokens = append(tokens, token{tokMod, "%", i})
+		case r == '^':
+			tokens = append(tokens, token{tokPow, "^", i})
+		case r == '[':
+			// 区间选择器，例如 [5m]
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("位置 %d: 区间选择器缺少 ']'", i)
+			}
+			tokens = append(tokens, token{tokLeftBracket, "[", i})
+			tokens = append(tokens, token{tokDuration, strings.TrimSpace(string(runes[i+1 : end])), i + 1})
+			i = end
+			continue
+		case r == '=':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{tokEql, "==", i})
+				i += 2
+				continue
+			}
+			if i+1 < len(runes) && runes[i+1] == '~' {
+				tokens = append(tokens, token{tokRegexMatch, "=~", i})
+				i += 2
+				continue
+			}
+			tokens = append(tokens, token{tokAssign, "=", i})
+		case r == '!':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{tokNeq, "!=", i})
+				i += 2
+				continue
+			}
+			if i+1 < len(runes) && runes[i+1] == '~' {
+				tokens = append(tokens, token{tokRegexNoMatch, "!~", i})
+				i += 2
+				continue
+			}
+			return nil, fmt.Errorf("位置 %d: 非法字符 '!'", i)
+		case r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{tokGte, ">=", i})
+				i += 2
+				continue
+			}
+			tokens = append(tokens, token{tokGtr, ">", i})
+		case r == '<':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{tokLte, "<=", i})
+				i += 2
+				continue
+			}
+			tokens = append(tokens, token{tokLss, "<", i})
+		case r == '"' || r == '\'':
+			// 字符串字面量
+			quote := r
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != quote {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("位置 %d: 字符串未闭合", start)
+			}
+			tokens = append(tokens, token{tokString, sb.String(), start})
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[start:i]), start})
+			continue
+		case isIdentStart(r):
+			start := i
+			for i < len(runes) && isIdentChar(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, token{tokAnd, word, start})
+			case "or":
+				tokens = append(tokens, token{tokOr, word, start})
+			case "unless":
+				tokens = append(tokens, token{tokUnless, word, start})
+			default:
+				tokens = append(tokens, token{tokIdentifier, word, start})
+			}
+			continue
+		default:
+			return nil, fmt.Errorf("位置 %d: 非法字符 %q", i, r)
+		}
+		i++
+	}
+
+	tokens = append(tokens, token{tokEOF, "", len(runes)})
+	return tokens, nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == ':' || unicode.IsLetter(r)
+}
+
+func isIdentChar(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || r == '.'
+}
+
+// ParseDuration 解析区间时长，支持 s、m、h、d、w 单位，例如 30s、5m、1h30m
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("时长不能为空")
+	}
+
+	var total time.Duration
+	num := 0
+	hasNum := false
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			num = num*10 + int(r-'0')
+			hasNum = true
+			continue
+		}
+		if !hasNum {
+			return 0, fmt.Errorf("无效的时长: %s", s)
+		}
+		var unit time.Duration
+		switch r {
+		case 's':
+			unit = time.Second
+		case 'm':
+			unit = time.Minute
+		case 'h':
+			unit = time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		default:
+			return 0, fmt.Errorf("无效的时长单位: %s", s)
+		}
+		total += time.Duration(num) * unit
+		num = 0
+		hasNum = false
+	}
+	if hasNum {
+		return 0, fmt.Errorf("时长缺少单位: %s", s)
+	}
+	if total <= 0 {
+		return 0, fmt.Errorf("时长必须大于0: %s", s)
+	}
+	return total, nil
+}
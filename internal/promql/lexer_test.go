@@ -0,0 +1,152 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []token
+	}{
+		{
+			input: `cpu_usage{host_id="1", role!~'data.*'}[5m]`,
+			want: []token{
+				{tokIdentifier, "cpu_usage", 0},
+				{tokLeftBrace, "{", 9},
+				{tokIdentifier, "host_id", 10},
+				{tokAssign, "=", 17},
+				{tokString, "1", 18},
+				{tokComma, ",", 21},
+				{tokIdentifier, "role", 23},
+				{tokRegexNoMatch, "!~", 27},
+				{tokString, "data.*", 29},
+				{tokRightBrace, "}", 37},
+				{tokLeftBracket, "[", 38},
+				{tokDuration, "5m", 39},
+				{tokRightBracket, "]", 41},
+				{tokEOF, "", 42},
+			},
+		},
+		{
+			input: `a>=1.5e3 AND b!=.5 or c==2 unless d<=0`,
+			want: []token{
+				{tokIdentifier, "a", 0},
+				{tokGte, ">=", 1},
+				{tokNumber, "1.5e3", 3},
+				{tokAnd, "AND", 9},
+				{tokIdentifier, "b", 13},
+				{tokNeq, "!=", 14},
+				{tokNumber, ".5", 16},
+				{tokOr, "or", 19},
+				{tokIdentifier, "c", 22},
+				{tokEql, "==", 23},
+				{tokNumber, "2", 25},
+				{tokUnless, "unless", 27},
+				{tokIdentifier, "d", 34},
+				{tokLte, "<=", 35},
+				{tokNumber, "0", 37},
+				{tokEOF, "", 38},
+			},
+		},
+		{
+			// 字符串中的转义字符和多字节字符，位置按字符计算
+			input: `x{path=~"a\"b", 名称="值"}`,
+			want: []token{
+				{tokIdentifier, "x", 0},
+				{tokLeftBrace, "{", 1},
+				{tokIdentifier, "path", 2},
+				{tokRegexMatch, "=~", 6},
+				{tokString, `a"b`, 8},
+				{tokComma, ",", 14},
+				{tokIdentifier, "名称", 16},
+				{tokAssign, "=", 18},
+				{tokString, "值", 19},
+				{tokRightBrace, "}", 22},
+				{tokEOF, "", 23},
+			},
+		},
+		{
+			input: `-a % 2 ^ 3 / (b * c) + d < e > f`,
+			want: []token{
+				{tokSub, "-", 0},
+				{tokIdentifier, "a", 1},
+				{tokMod, "%", 3},
+				{tokNumber, "2", 5},
+				{tokPow, "^", 7},
+				{tokNumber, "3", 9},
+				{tokDiv, "/", 11},
+				{tokLeftParen, "(", 13},
+				{tokIdentifier, "b", 14},
+				{tokMul, "*", 16},
+				{tokIdentifier, "c", 18},
+				{tokRightParen, ")", 19},
+				{tokAdd, "+", 21},
+				{tokIdentifier, "d", 23},
+				{tokLss, "<", 25},
+				{tokIdentifier, "e", 27},
+				{tokGtr, ">", 29},
+				{tokIdentifier, "f", 31},
+				{tokEOF, "", 32},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := lex(tt.input)
+			if err != nil {
+				t.Fatalf("lex() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lex() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	for _, input := range []string{
+		`a{b="c}`,
+		`a[5m`,
+		`a ! b`,
+		`a # b`,
+	} {
+		if tokens, err := lex(input); err == nil {
+			t.Errorf("lex(%q) = %v, want error", input, tokens)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30s", want: 30 * time.Second},
+		{input: "5m", want: 5 * time.Minute},
+		{input: "1h30m", want: 90 * time.Minute},
+		{input: "2d", want: 48 * time.Hour},
+		{input: "1w", want: 7 * 24 * time.Hour},
+		{input: "", wantErr: true},
+		{input: "5", wantErr: true},
+		{input: "m", wantErr: true},
+		{input: "5ms", wantErr: true},
+		{input: "0s", wantErr: true},
+		{input: "1y", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %s, want error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %s, %v, want %s", tt.input, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package promql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 聚合操作符
+var aggregateOps = map[string]bool{
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+	"count": true,
+}
+
+// parser 表达式语法分析器
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseExpr 解析告警表达式，返回语法树
+func ParseExpr(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.typ != tokEOF {
+		return nil, p.errorf(tok, "多余的内容 %s", tok)
+	}
+	if err := checkTypes(expr); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.typ != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(typ tokenType, desc string) (token, error) {
+	tok := p.next()
+	if tok.typ != typ {
+		return tok, p.errorf(tok, "期望 %s，实际为 %s", desc, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("位置 %d: %s", tok.pos, fmt.Sprintf(format, args...))
+}
+
+// precedence 返回二元运算符优先级，非二元运算符返回 -1
+func precedence(typ tokenType) int {
+	switch typ {
+	case tokOr:
+		return 1
+	case tokAnd, tokUnless:
+		return 2
+	case tokEql, tokNeq, tokGtr, tokGte, tokLss, tokLte:
+		return 3
+	case tokAdd, tokSub:
+		return 4
+	case tokMul, tokDiv, tokMod:
+		return 5
+	case tokPow:
+		return 6
+	}
+	return -1
+}
+
+// parseBinary 按运算符优先级解析二元表达式
+func (p *parser) parseBinary(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := p.peek()
+		prec := precedence(op.typ)
+		if prec < 0 || prec < minPrec {
+			return lhs, nil
+		}
+		p.next()
+
+		expr := &BinaryExpr{Op: op.typ, LHS: lhs}
+
+		// 向量匹配修饰符 on(...) / ignoring(...)
+		if tok := p.peek(); tok.typ == tokIdentifier && (tok.val == "on" || tok.val == "ignoring") {
+			p.next()
+			expr.On = tok.val == "on"
+			labels, err := p.parseLabelList()
+			if err != nil {
+				return nil, err
+			}
+			expr.MatchingLabels = labels
+		}
+
+		// 幂运算为右结合
+		nextPrec := prec + 1
+		if op.typ == tokPow {
+			nextPrec = prec
+		}
+		rhs, err := p.parseBinary(nextPrec)
+		if err != nil {
+			return nil, err
+		}
+		expr.RHS = rhs
+		lhs = expr
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	tok := p.peek()
+	if tok.typ == tokSub || tok.typ == tokAdd {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if tok.typ == tokAdd {
+			return expr, nil
+		}
+		if num, ok := expr.(*NumberLiteral); ok {
+			return &NumberLiteral{Value: -num.Value}, nil
+		}
+		return &UnaryExpr{Expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.peek()
+
+	switch tok.typ {
+	case tokNumber:
+		p.next()
+		v, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "无效的数值 %s", tok.val)
+		}
+		return &NumberLiteral{Value: v}, nil
+
+	case tokLeftParen:
+		p.next()
+		expr, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRightParen, "')'"); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: expr}, nil
+
+	case tokLeftBrace:
+		return p.parseSelector("")
+
+	case tokIdentifier:
+		p.next()
+		if aggregateOps[tok.val] {
+			if next := p.peek(); next.typ == tokLeftParen || (next.typ == tokIdentifier && (next.val == "by" || next.val == "without")) {
+				return p.parseAggregate(tok.val)
+			}
+		}
+		if p.peek().typ == tokLeftParen {
+			return p.parseCall(tok)
+		}
+		return p.parseSelector(tok.val)
+	}
+
+	return nil, p.errorf(tok, "意外的 %s", tok)
+}
+
+// parseSelector 解析 name{label="value"}[5m]
+func (p *parser) parseSelector(name string) (Expr, error) {
+	vs := &VectorSelector{Name: name}
+	if name != "" {
+		m, _ := NewLabelMatcher(MatchEqual, MetricNameLabel, name)
+		vs.Matchers = append(vs.Matchers, m)
+	}
+
+	if p.peek().typ == tokLeftBrace {
+		p.next()
+		for p.peek().typ != tokRightBrace {
+			labelTok, err := p.expect(tokIdentifier, "标签名")
+			if err != nil {
+				return nil, err
+			}
+
+			opTok := p.next()
+			var typ MatchType
+			switch opTok.typ {
+			case tokAssign:
+				typ = MatchEqual
+			case tokNeq:
+				typ = MatchNotEqual
+			case tokRegexMatch:
+				typ = MatchRegexp
+			case tokRegexNoMatch:
+				typ = MatchNotRegexp
+			default:
+				return nil, p.errorf(opTok, "期望标签匹配符，实际为 %s", opTok)
+			}
+
+			valueTok, err := p.expect(tokString, "标签值字符串")
+			if err != nil {
+				return nil, err
+			}
+
+			m, err := NewLabelMatcher(typ, labelTok.val, valueTok.val)
+			if err != nil {
+				return nil, p.errorf(valueTok, "%v", err)
+			}
+			if m.Name == MetricNameLabel && m.Type == MatchEqual {
+				vs.Name = m.Value
+			}
+			vs.Matchers = append(vs.Matchers, m)
+
+			if p.peek().typ == tokComma {
+				p.next()
+				continue
+			}
+			if p.peek().typ != tokRightBrace {
+				return nil, p.errorf(p.peek(), "期望 ',' 或 '}'，实际为 %s", p.peek())
+			}
+		}
+		p.next()
+	}
+
+	if vs.Name == "" {
+		return nil, fmt.Errorf("向量选择器必须指定指标名称")
+	}
+
+	if p.peek().typ == tokLeftBracket {
+		p.next()
+		durTok, err := p.expect(tokDuration, "区间时长")
+		if err != nil {
+			return nil, err
+		}
+		rng, err := ParseDuration(durTok.val)
+		if err != nil {
+			return nil, p.errorf(durTok, "%v", err)
+		}
+		if _, err := p.expect(tokRightBracket, "']'"); err != nil {
+			return nil, err
+		}
+		return &MatrixSelector{Vector: vs, Range: rng}, nil
+	}
+
+	return vs, nil
+}
+
+// parseAggregate 解析 sum by (a, b) (expr) 或 sum (expr) by (a, b)
+func (p *parser) parseAggregate(op string) (Expr, error) {
+	agg := &AggregateExpr{Op: op}
+
+	parseGrouping := func() error {
+		tok := p.next()
+		agg.Without = tok.val == "without"
+		labels, err := p.parseLabelList()
+		if err != nil {
+			return err
+		}
+		agg.Grouping = labels
+		return nil
+	}
+
+	if tok := p.peek(); tok.typ == tokIdentifier {
+		if err := parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.expect(tokLeftParen, "'('"); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokRightParen, "')'"); err != nil {
+		return nil, err
+	}
+	agg.Expr = expr
+
+	if tok := p.peek(); tok.typ == tokIdentifier && (tok.val == "by" || tok.val == "without") {
+		if agg.Grouping != nil || agg.Without {
+			return nil, p.errorf(tok, "聚合分组子句重复")
+		}
+		if err := parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+
+	return agg, nil
+}
+
+// parseCall 解析函数调用
+func (p *parser) parseCall(nameTok token) (Expr, error) {
+	fn, ok := functions[nameTok.val]
+	if !ok {
+		return nil, p.errorf(nameTok, "未知函数 %s", nameTok.val)
+	}
+
+	p.next() // '('
+	call := &Call{Func: nameTok.val}
+	for p.peek().typ != tokRightParen {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		if p.peek().typ == tokComma {
+			p.next()
+			continue
+		}
+		if p.peek().typ != tokRightParen {
+			return nil, p.errorf(p.peek(), "期望 ',' 或 ')'，实际为 %s", p.peek())
+		}
+	}
+	p.next()
+
+	if len(call.Args) != len(fn.argTypes) {
+		return nil, p.errorf(nameTok, "函数 %s 需要 %d 个参数，实际为 %d 个", fn.name, len(fn.argTypes), len(call.Args))
+	}
+	return call, nil
+}
+
+// parseLabelList 解析 (a, b, c)
+func (p *parser) parseLabelList() ([]string, error) {
+	if _, err := p.expect(tokLeftParen, "'('"); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	for p.peek().typ != tokRightParen {
+		tok, err := p.expect(tokIdentifier, "标签名")
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, tok.val)
+		if p.peek().typ == tokComma {
+			p.next()
+			continue
+		}
+		if p.peek().typ != tokRightParen {
+			return nil, p.errorf(p.peek(), "期望 ',' 或 ')'，实际为 %s", p.peek())
+		}
+	}
+	p.next()
+	return labels, nil
+}
+
+// valueType 表达式结果类型
+type valueType int
+
+const (
+	valueScalar valueType = iota
+	valueVector
+	valueMatrix
+)
+
+// typeOf 推导表达式结果类型
+func typeOf(expr Expr) valueType {
+	switch n := expr.(type) {
+	case *NumberLiteral:
+		return valueScalar
+	case *MatrixSelector:
+		return valueMatrix
+	case *ParenExpr:
+		return typeOf(n.Expr)
+	case *UnaryExpr:
+		return typeOf(n.Expr)
+	case *BinaryExpr:
+		if typeOf(n.LHS) == valueScalar && typeOf(n.RHS) == valueScalar {
+			return valueScalar
+		}
+		return valueVector
+	}
+	return valueVector
+}
+
+// checkTypes 检查函数参数和运算对象的类型
+func checkTypes(expr Expr) error {
+	switch n := expr.(type) {
+	case *MatrixSelector:
+		return nil
+	case *Call:
+		fn := functions[n.Func]
+		for i, arg := range n.Args {
+			if err := checkTypes(arg); err != nil {
+				return err
+			}
+			if typeOf(arg) != fn.argTypes[i] {
+				return fmt.Errorf("函数 %s 的第 %d 个参数类型不正确", n.Func, i+1)
+			}
+		}
+	case *AggregateExpr:
+		if err := checkTypes(n.Expr); err != nil {
+			return err
+		}
+		if typeOf(n.Expr) != valueVector {
+			return fmt.Errorf("聚合 %s 的参数必须是瞬时向量", n.Op)
+		}
+	case *BinaryExpr:
+		if err := checkTypes(n.LHS); err != nil {
+			return err
+		}
+		if err := checkTypes(n.RHS); err != nil {
+			return err
+		}
+		if typeOf(n.LHS) == valueMatrix || typeOf(n.RHS) == valueMatrix {
+			return fmt.Errorf("区间向量不能直接参与运算，请使用 rate、avg_over_time 等函数")
+		}
+		if isSetOp(n.Op) && (typeOf(n.LHS) != valueVector || typeOf(n.RHS) != valueVector) {
+			return fmt.Errorf("集合运算 %s 只能作用于瞬时向量", binaryOpString(n.Op))
+		}
+	case *UnaryExpr:
+		if err := checkTypes(n.Expr); err != nil {
+			return err
+		}
+		if typeOf(n.Expr) == valueMatrix {
+			return fmt.Errorf("区间向量不能取负")
+		}
+	case *ParenExpr:
+		return checkTypes(n.Expr)
+	}
+	return nil
+}
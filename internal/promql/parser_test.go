@@ -0,0 +1,144 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabelMatcher(t *testing.T) {
+	tests := []struct {
+		typ   MatchType
+		value string
+		input string
+		want  bool
+	}{
+		{MatchEqual, "hdfs", "hdfs", true},
+		{MatchEqual, "hdfs", "yarn", false},
+		{MatchEqual, "", "", true},
+		{MatchNotEqual, "hdfs", "yarn", true},
+		{MatchNotEqual, "hdfs", "hdfs", false},
+		{MatchRegexp, "data.*", "datanode", true},
+		{MatchRegexp, "data", "datanode", false}, // 正则匹配锚定首尾
+		{MatchRegexp, "hdfs|yarn", "yarn", true},
+		{MatchRegexp, "hdfs|yarn", "hdfs-yarn", false},
+		{MatchRegexp, ".*", "", true},
+		{MatchNotRegexp, "data.*", "namenode", true},
+		{MatchNotRegexp, "data.*", "datanode", false},
+		{MatchNotRegexp, ".+", "", true},
+	}
+	for _, tt := range tests {
+		m, err := NewLabelMatcher(tt.typ, "role", tt.value)
+		if err != nil {
+			t.Fatalf("NewLabelMatcher(%s, %q) error: %v", tt.typ, tt.value, err)
+		}
+		if got := m.Matches(tt.input); got != tt.want {
+			t.Errorf("%s.Matches(%q) = %v, want %v", m, tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := NewLabelMatcher(MatchRegexp, "role", "data("); err == nil {
+		t.Error("NewLabelMatcher() with invalid regexp, want error")
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`cpu_usage`, `cpu_usage`},
+		{`cpu_usage{host_id="1", role!="master"}`, `cpu_usage{host_id="1", role!="master"}`},
+		{`{__name__="up", job=~'hdfs|yarn'}`, `up{job=~"hdfs|yarn"}`},
+		{`rate(http_requests_total[5m])`, `rate(http_requests_total[5m0s])`},
+		{`avg_over_time(mem_used{host_id!~"1|2"}[1h30m])`, `avg_over_time(mem_used{host_id!~"1|2"}[1h30m0s])`},
+		{`sum by (cluster_id) (cpu)`, `sum by (cluster_id) (cpu)`},
+		{`sum(cpu) without (host_id, role)`, `sum without (host_id, role) (cpu)`},
+		{`count(cpu)`, `count (cpu)`},
+		{`1 + 2 * 3`, `(1 + (2 * 3))`},
+		{`a - b - c`, `((a - b) - c)`},
+		{`2 ^ 3 ^ 2`, `(2 ^ (3 ^ 2))`},
+		{`a > 1 and b or c unless d`, `(((a > 1) and b) or (c unless d))`},
+		{`-cpu`, `-cpu`},
+		{`-1`, `-1`},
+		{`+(cpu)`, `(cpu)`},
+		{`abs(a - b) >= 0.5`, `(abs((a - b)) >= 0.5)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := ParseExpr(tt.input)
+			if err != nil {
+				t.Fatalf("ParseExpr() error: %v", err)
+			}
+			if got := expr.String(); got != tt.want {
+				t.Errorf("ParseExpr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVectorMatching(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantOn   bool
+		wantList []string
+	}{
+		{`a / on(host_id, role) b`, true, []string{"host_id", "role"}},
+		{`a / ignoring(mode) b`, false, []string{"mode"}},
+		{`a and on() b`, true, []string{}},
+		{`a + b`, false, nil},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.input)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q) error: %v", tt.input, err)
+		}
+		be, ok := expr.(*BinaryExpr)
+		if !ok {
+			t.Fatalf("ParseExpr(%q) = %T, want *BinaryExpr", tt.input, expr)
+		}
+		if be.On != tt.wantOn || !reflect.DeepEqual(be.MatchingLabels, tt.wantList) {
+			t.Errorf("ParseExpr(%q) on = %v, matching = %#v, want %v, %#v", tt.input, be.On, be.MatchingLabels, tt.wantOn, tt.wantList)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, input := range []string{
+		``,
+		`cpu mem`,
+		`{job="hdfs"}`,
+		`cpu{job=1}`,
+		`cpu{job="a" role="b"}`,
+		`cpu{job=~"("}`,
+		`cpu[5x]`,
+		`unknown_func(cpu)`,
+		`rate(cpu)`,
+		`rate(cpu[5m], 1)`,
+		`abs(cpu[5m])`,
+		`sum(cpu[5m])`,
+		`sum by (a) cpu`,
+		`sum by (a) (cpu) by (b)`,
+		`cpu[5m] + 1`,
+		`-cpu[5m]`,
+		`1 and 2`,
+		`(cpu`,
+	} {
+		if expr, err := ParseExpr(input); err == nil {
+			t.Errorf("ParseExpr(%q) = %s, want error", input, expr)
+		}
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	expr, err := ParseExpr(`sum by (host_id) (rate(a[5m])) / -(b + abs(c)) > 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, vs := range Selectors(expr) {
+		names = append(names, vs.Name)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Selectors() = %v, want %v", names, want)
+	}
+}
@@ -81,7 +81,8 @@ func (r *AlertRuleRepository) List(page, pageSize int, filters map[string]interf
 func (r *AlertRuleRepository) FindApplicableRules(hostID uint, serviceID *uint, metricName string) ([]*model.AlertRule, error) {
 	var rules []*model.AlertRule
 
-	query := r.db.Model(&model.AlertRule{}).Where("metric_name = ? AND enabled = ?", metricName, true)
+	query := r.db.Model(&model.AlertRule{}).Where("metric_name = ? AND enabled = ? AND (expression IS NULL OR expression = '') AND rule_type = ?",
+		metricName, true, model.AlertRuleTypeMetric)
 
 	// 策略1: 针对特定主机的规则
 	query1 := query.Where("host_id = ?", hostID)
@@ -117,6 +118,31 @@ func (r *AlertRuleRepository) FindApplicableRules(hostID uint, serviceID *uint,
 	return rules, nil
 }
 
+// FindExpressionRules 查找所有启用的指标规则，包括表达式规则和只设置了指标名的阈值规则
+func (r *AlertRuleRepository) FindExpressionRules() ([]*model.AlertRule, error) {
+	var rules []*model.AlertRule
+	err := r.db.Where("enabled = ? AND rule_type = ? AND ((expression IS NOT NULL AND expression != '') OR metric_name != '')", true, model.AlertRuleTypeMetric).
+		Order("id ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// FindLogRules 查找所有启用的日志规则
+func (r *AlertRuleRepository) FindLogRules() ([]*model.AlertRule, error) {
+	var rules []*model.AlertRule
+	err := r.db.Where("enabled = ? AND rule_type = ?", true, model.AlertRuleTypeLog).
+		Order("id ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// ListClusterHostIDs 列出集群中的主机ID
+func (r *AlertRuleRepository) ListClusterHostIDs(clusterID uint) ([]int, error) {
+	var ids []int
+	err := r.db.Table("host").Where("cluster_id = ?", clusterID).Pluck("id", &ids).Error
+	return ids, err
+}
+
 // AlertEventRepository 告警事件仓库
 type AlertEventRepository struct {
 	db *gorm.DB
@@ -207,6 +233,95 @@ func (r *AlertEventRepository) FindOpenAlertByRule(ruleID, hostID uint, serviceI
 	return &event, nil
 }
 
+// FindOpenAlertByFingerprint 查找表达式规则指定序列的未解决告警
+func (r *AlertEventRepository) FindOpenAlertByFingerprint(ruleID uint, fingerprint string) (*model.AlertEvent, error) {
+	var event model.AlertEvent
+	err := r.db.Where(
+		"alert_rule_id = ? AND fingerprint = ? AND status != ?",
+		ruleID,
+		fingerprint,
+		model.AlertStatusResolved,
+	).First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// ListOpenAlertsByRule 列出指定规则的所有未解决告警
+func (r *AlertEventRepository) ListOpenAlertsByRule(ruleID uint) ([]*model.AlertEvent, error) {
+	var events []*model.AlertEvent
+	err := r.db.Where("alert_rule_id = ? AND status != ?", ruleID, model.AlertStatusResolved).
+		Find(&events).Error
+	return events, err
+}
+
+// ListByIDs 根据ID列出告警事件，按触发时间排序
+func (r *AlertEventRepository) ListByIDs(ids []uint) ([]*model.AlertEvent, error) {
+	var events []*model.AlertEvent
+	if len(ids) == 0 {
+		return events, nil
+	}
+	err := r.db.Where("id IN ?", ids).
+		Order("triggered_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// ListOpenAlertsByGroupKey 列出指定通知分组中处于OPEN状态的告警
+func (r *AlertEventRepository) ListOpenAlertsByGroupKey(groupKey string) ([]*model.AlertEvent, error) {
+	var events []*model.AlertEvent
+	err := r.db.Where("group_key = ? AND status = ?", groupKey, model.AlertStatusOpen).
+		Order("triggered_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// ListOpenGroupedAlerts 列出所有已分组且处于OPEN状态的告警，用于服务重启后恢复通知分组
+func (r *AlertEventRepository) ListOpenGroupedAlerts() ([]*model.AlertEvent, error) {
+	var events []*model.AlertEvent
+	err := r.db.Where("group_key != '' AND status = ? AND silence_id IS NULL", model.AlertStatusOpen).
+		Find(&events).Error
+	return events, err
+}
+
+// MarkNotified 累加告警的通知次数并记录通知时间
+func (r *AlertEventRepository) MarkNotified(ids []uint, notifiedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.AlertEvent{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"notification_count": gorm.Expr("notification_count + 1"),
+			"last_notified_at":   notifiedAt,
+		}).Error
+}
+
+// ListOpenEscalatingAlerts 列出所属规则配置了升级策略、处于OPEN状态且未被静默的告警
+func (r *AlertEventRepository) ListOpenEscalatingAlerts() ([]*model.AlertEvent, error) {
+	var events []*model.AlertEvent
+	err := r.db.Model(&model.AlertEvent{}).
+		Select("alert_events.*").
+		Joins("JOIN alert_rules ON alert_rules.id = alert_events.alert_rule_id").
+		Where("alert_rules.escalation_policy_id IS NOT NULL AND alert_rules.enabled = ?", true).
+		Where("alert_events.status = ? AND alert_events.silence_id IS NULL", model.AlertStatusOpen).
+		Order("alert_events.triggered_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// UpdateEscalationLevel 更新告警已执行的升级步骤数
+func (r *AlertEventRepository) UpdateEscalationLevel(ids []uint, level int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.AlertEvent{}).
+		Where("id IN ?", ids).
+		UpdateColumn("escalation_level", level).Error
+}
+
 // CountBySeverity 按告警级别统计
 func (r *AlertEventRepository) CountBySeverity() (map[string]int64, error) {
 	type Result struct {
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// APITokenRepository API令牌仓库
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAPITokenRepository 创建API令牌仓库
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+// Create 创建API令牌
+func (r *APITokenRepository) Create(token *model.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByID 根据ID获取API令牌
+func (r *APITokenRepository) GetByID(id uint) (*model.APIToken, error) {
+	var token model.APIToken
+	err := r.db.First(&token, id).Error
+	return &token, err
+}
+
+// List 列出API令牌及其所属用户名，按创建时间倒序
+func (r *APITokenRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.APIToken, int64, error) {
+	var tokens []*model.APIToken
+	var total int64
+
+	query := applyFilters(r.db.Model(&model.APIToken{}), filters)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	err := query.Select("api_tokens.*, u.username AS username").
+		Joins("LEFT JOIN user u ON api_tokens.user_id = u.id").
+		Order("api_tokens.id DESC").
+		Find(&tokens).Error
+	return tokens, total, err
+}
+
+// CountPrivileges 统计 names 中存在的权限数
+func (r *APITokenRepository) CountPrivileges(names []string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Privilege{}).Where("name IN ?", names).Count(&count).Error
+	return count, err
+}
+
+// Revoke 吊销API令牌，已吊销的令牌不受影响
+func (r *APITokenRepository) Revoke(id uint) error {
+	return r.db.Model(&model.APIToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
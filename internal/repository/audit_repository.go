@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository 审计记录仓库，审计记录只能追加，不提供修改和删除
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 创建审计记录仓库
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// Create 追加审计记录
+func (r *AuditLogRepository) Create(entry *model.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// LastHash 获取最后一条审计记录的哈希，没有记录时返回空字符串
+func (r *AuditLogRepository) LastHash() (string, error) {
+	var hashes []string
+	err := r.db.Model(&model.AuditLog{}).Order("id DESC").Limit(1).Pluck("hash", &hashes).Error
+	if err != nil || len(hashes) == 0 {
+		return "", err
+	}
+	return hashes[0], nil
+}
+
+// GetByID 根据ID获取审计记录
+func (r *AuditLogRepository) GetByID(id uint) (*model.AuditLog, error) {
+	var entry model.AuditLog
+	err := r.db.First(&entry, id).Error
+	return &entry, err
+}
+
+// List 列出审计记录，按时间倒序
+func (r *AuditLogRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.AuditLog, int64, error) {
+	var entries []*model.AuditLog
+	var total int64
+
+	query := applyFilters(r.db.Model(&model.AuditLog{}), filters)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	err := query.Order("id DESC").Find(&entries).Error
+	return entries, total, err
+}
+
+// ListAfter 按ID顺序获取 afterID 之后的一批审计记录，用于导出和校验哈希链
+func (r *AuditLogRepository) ListAfter(afterID uint, limit int, filters map[string]interface{}) ([]*model.AuditLog, error) {
+	var entries []*model.AuditLog
+	err := applyFilters(r.db.Model(&model.AuditLog{}), filters).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
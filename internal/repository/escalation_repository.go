@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// EscalationPolicyRepository 升级策略仓库
+type EscalationPolicyRepository struct {
+	db *gorm.DB
+}
+
+// NewEscalationPolicyRepository 创建升级策略仓库
+func NewEscalationPolicyRepository(db *gorm.DB) *EscalationPolicyRepository {
+	return &EscalationPolicyRepository{db: db}
+}
+
+// Create 创建升级策略及其步骤
+func (r *EscalationPolicyRepository) Create(policy *model.EscalationPolicy) error {
+	return r.db.Create(policy).Error
+}
+
+// Update 更新升级策略，并用新的步骤替换原有步骤
+func (r *EscalationPolicyRepository) Update(policy *model.EscalationPolicy) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&model.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		for i := range policy.Steps {
+			policy.Steps[i].ID = 0
+			policy.Steps[i].PolicyID = policy.ID
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(policy).Error
+	})
+}
+
+// Delete 删除升级策略及其步骤
+func (r *EscalationPolicyRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", id).Delete(&model.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.EscalationPolicy{}, id).Error
+	})
+}
+
+// GetByID 根据ID获取升级策略，步骤按顺序排列
+func (r *EscalationPolicyRepository) GetByID(id uint) (*model.EscalationPolicy, error) {
+	var policy model.EscalationPolicy
+	err := r.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&policy, id).Error
+	return &policy, err
+}
+
+// List 列出升级策略
+func (r *EscalationPolicyRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.EscalationPolicy, int64, error) {
+	var policies []*model.EscalationPolicy
+	var total int64
+
+	query := r.db.Model(&model.EscalationPolicy{})
+
+	// 应用过滤条件
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+
+	// 统计总数
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	if page > 0 && pageSize > 0 {
+		offset := (page - 1) * pageSize
+		query = query.Offset(offset).Limit(pageSize)
+	}
+
+	// 执行查询
+	err = query.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Order("id DESC").Find(&policies).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return policies, total, nil
+}
+
+// OnCallScheduleRepository 值班表仓库
+type OnCallScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewOnCallScheduleRepository 创建值班表仓库
+func NewOnCallScheduleRepository(db *gorm.DB) *OnCallScheduleRepository {
+	return &OnCallScheduleRepository{db: db}
+}
+
+// Create 创建值班表及其参与人
+func (r *OnCallScheduleRepository) Create(schedule *model.OnCallSchedule) error {
+	return r.db.Omit("Overrides").Create(schedule).Error
+}
+
+// Update 更新值班表，并用新的参与人替换原有参与人，替换记录保持不变
+func (r *OnCallScheduleRepository) Update(schedule *model.OnCallSchedule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&model.OnCallParticipant{}).Error; err != nil {
+			return err
+		}
+		for i := range schedule.Participants {
+			schedule.Participants[i].ID = 0
+			schedule.Participants[i].ScheduleID = schedule.ID
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Omit("Overrides").Save(schedule).Error
+	})
+}
+
+// Delete 删除值班表及其参与人和替换记录
+func (r *OnCallScheduleRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", id).Delete(&model.OnCallParticipant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("schedule_id = ?", id).Delete(&model.OnCallOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.OnCallSchedule{}, id).Error
+	})
+}
+
+// GetByID 根据ID获取值班表，包含参与人和替换记录
+func (r *OnCallScheduleRepository) GetByID(id uint) (*model.OnCallSchedule, error) {
+	var schedule model.OnCallSchedule
+	err := r.db.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Overrides", func(db *gorm.DB) *gorm.DB {
+		return db.Order("starts_at ASC")
+	}).First(&schedule, id).Error
+	return &schedule, err
+}
+
+// List 列出值班表
+func (r *OnCallScheduleRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.OnCallSchedule, int64, error) {
+	var schedules []*model.OnCallSchedule
+	var total int64
+
+	query := r.db.Model(&model.OnCallSchedule{})
+
+	// 应用过滤条件
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+
+	// 统计总数
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	if page > 0 && pageSize > 0 {
+		offset := (page - 1) * pageSize
+		query = query.Offset(offset).Limit(pageSize)
+	}
+
+	// 执行查询
+	err = query.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Order("id DESC").Find(&schedules).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return schedules, total, nil
+}
+
+// CreateOverride 创建值班替换
+func (r *OnCallScheduleRepository) CreateOverride(override *model.OnCallOverride) error {
+	return r.db.Create(override).Error
+}
+
+// DeleteOverride 删除值班表中的替换记录
+func (r *OnCallScheduleRepository) DeleteOverride(scheduleID, overrideID uint) error {
+	return r.db.Where("schedule_id = ?", scheduleID).Delete(&model.OnCallOverride{}, overrideID).Error
+}
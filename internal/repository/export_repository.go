@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// ExportJobRepository 导出任务仓库
+type ExportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewExportJobRepository 创建导出任务仓库
+func NewExportJobRepository(db *gorm.DB) *ExportJobRepository {
+	return &ExportJobRepository{db: db}
+}
+
+// Create 创建导出任务
+func (r *ExportJobRepository) Create(job *model.ExportJob) error {
+	return r.db.Create(job).Error
+}
+
+// Update 更新导出任务
+func (r *ExportJobRepository) Update(job *model.ExportJob) error {
+	return r.db.Save(job).Error
+}
+
+// UpdateProgress 更新导出中任务的已导出行数
+func (r *ExportJobRepository) UpdateProgress(id uint, rowCount int64) error {
+	return r.db.Model(&model.ExportJob{}).
+		Where("id = ? AND status = ?", id, model.ExportJobRunning).
+		Updates(map[string]interface{}{
+			"row_count":  rowCount,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// Delete 删除导出任务
+func (r *ExportJobRepository) Delete(id uint) error {
+	return r.db.Delete(&model.ExportJob{}, id).Error
+}
+
+// GetByID 根据ID获取导出任务
+func (r *ExportJobRepository) GetByID(id uint) (*model.ExportJob, error) {
+	var job model.ExportJob
+	err := r.db.First(&job, id).Error
+	return &job, err
+}
+
+// List 列出导出任务
+func (r *ExportJobRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.ExportJob, int64, error) {
+	var jobs []*model.ExportJob
+	var total int64
+
+	query := applyFilters(r.db.Model(&model.ExportJob{}), filters)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := query.Order("id DESC").Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// ClaimNext 领取最早创建的待执行任务并置为导出中，没有待执行任务时返回nil
+func (r *ExportJobRepository) ClaimNext() (*model.ExportJob, error) {
+	for {
+		var job model.ExportJob
+		err := r.db.Where("status = ?", model.ExportJobPending).Order("id").Take(&job).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		result := r.db.Model(&model.ExportJob{}).
+			Where("id = ? AND status = ?", job.ID, model.ExportJobPending).
+			Updates(map[string]interface{}{
+				"status":     model.ExportJobRunning,
+				"started_at": now,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		// 已被其他工作协程领取时继续领取下一个
+		if result.RowsAffected == 1 {
+			job.Status = model.ExportJobRunning
+			job.StartedAt = &now
+			job.UpdatedAt = now
+			return &job, nil
+		}
+	}
+}
+
+// RequeueRunning 将导出中的任务重新置为待执行，用于恢复进程退出时中断的任务
+func (r *ExportJobRepository) RequeueRunning() (int64, error) {
+	result := r.db.Model(&model.ExportJob{}).
+		Where("status = ?", model.ExportJobRunning).
+		Updates(map[string]interface{}{
+			"status":    model.ExportJobPending,
+			"row_count": 0,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// ListExpired 列出过期时间早于 now 的任务
+func (r *ExportJobRepository) ListExpired(now time.Time) ([]*model.ExportJob, error) {
+	var jobs []*model.ExportJob
+	err := r.db.Where("expires_at < ?", now).Find(&jobs).Error
+	return jobs, err
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LogPatternWindowCount 日志模式在观察窗口和基线时段内的日志数
+type LogPatternWindowCount struct {
+	PatternID     int64
+	WindowCount   int64
+	BaselineCount int64
+}
+
+// LogPatternRepository 日志模式仓库
+type LogPatternRepository struct {
+	db *gorm.DB
+}
+
+// NewLogPatternRepository 创建日志模式仓库
+func NewLogPatternRepository(db *gorm.DB) *LogPatternRepository {
+	return &LogPatternRepository{db: db}
+}
+
+// withNames 关联服务和组件表
+func (r *LogPatternRepository) withNames() *gorm.DB {
+	return r.db.Table("log_patterns AS lp").
+		Select("lp.*, IFNULL(s.service_name, '') AS service_name, IFNULL(sc.component_type, '') AS component_type").
+		Joins("LEFT JOIN service s ON lp.service_id = s.id").
+		Joins("LEFT JOIN service_component sc ON lp.component_id = sc.id")
+}
+
+// ListAll 列出全部模式的聚类状态，用于恢复模式聚类
+func (r *LogPatternRepository) ListAll() ([]*model.LogPattern, error) {
+	var patterns []*model.LogPattern
+	err := r.db.Select("id, service_id, component_id, template, count, first_seen, last_seen, last_log_id").
+		Order("id").
+		Find(&patterns).Error
+	return patterns, err
+}
+
+// MaxLastLogID 已处理到的最大日志ID，没有模式时为0
+func (r *LogPatternRepository) MaxLastLogID() (int64, error) {
+	var id int64
+	err := r.db.Model(&model.LogPattern{}).
+		Select("IFNULL(MAX(last_log_id), 0)").
+		Scan(&id).Error
+	return id, err
+}
+
+// Save 在一个事务中保存模式和各模式按时间桶新增的日志数，新模式创建后得到ID
+func (r *LogPatternRepository) Save(patterns []*model.LogPattern, counts map[*model.LogPattern]map[time.Time]int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, pattern := range patterns {
+			if pattern.ID == 0 {
+				if err := tx.Create(pattern).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			err := tx.Model(pattern).
+				Select("template", "level", "sample", "count", "last_log_id", "last_seen", "updated_at").
+				Updates(pattern).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var rows []*model.LogPatternCount
+		for pattern, buckets := range counts {
+			for bucket, count := range buckets {
+				rows = append(rows, &model.LogPatternCount{PatternID: pattern.ID, Bucket: bucket, Count: count})
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("`count` + VALUES(`count`)")}),
+		}).CreateInBatches(rows, 500).Error
+	})
+}
+
+// GetByID 根据ID获取模式
+func (r *LogPatternRepository) GetByID(id int64) (*model.LogPattern, error) {
+	var pattern model.LogPattern
+	err := r.withNames().Where("lp.id = ?", id).Take(&pattern).Error
+	return &pattern, err
+}
+
+// ListByIDs 根据ID列出模式，不保证顺序
+func (r *LogPatternRepository) ListByIDs(ids []int64) ([]*model.LogPattern, error) {
+	var patterns []*model.LogPattern
+	if len(ids) == 0 {
+		return patterns, nil
+	}
+	err := r.withNames().Where("lp.id IN ?", ids).Find(&patterns).Error
+	return patterns, err
+}
+
+// List 列出模式，orderBy 为排序字段
+func (r *LogPatternRepository) List(page, pageSize int, filters map[string]interface{}, orderBy string) ([]*model.LogPattern, int64, error) {
+	var patterns []*model.LogPattern
+	var total int64
+
+	if err := applyFilters(r.db.Table("log_patterns AS lp"), filters).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := applyFilters(r.withNames(), filters)
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	err := query.Order(orderBy).Order("lp.id DESC").Find(&patterns).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return patterns, total, nil
+}
+
+// WindowCounts 统计 baselineStart 之后各模式的日志数，windowStart 之前的计入基线，只返回观察窗口内有日志的模式
+func (r *LogPatternRepository) WindowCounts(baselineStart, windowStart time.Time, filters map[string]interface{}) ([]*LogPatternWindowCount, error) {
+	var counts []*LogPatternWindowCount
+	query := r.db.Table("log_pattern_counts AS c").
+		Select(`c.pattern_id, SUM(IF(c.bucket >= ?, c.count, 0)) AS window_count,
+			SUM(IF(c.bucket < ?, c.count, 0)) AS baseline_count`, windowStart, windowStart).
+		Joins("JOIN log_patterns lp ON lp.id = c.pattern_id").
+		Where("c.bucket >= ?", baselineStart)
+	err := applyFilters(query, filters).Group("c.pattern_id").
+		Having("window_count > 0").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// ListCounts 按时间顺序列出模式在 since 之后各时间桶的日志数
+func (r *LogPatternRepository) ListCounts(patternID int64, since time.Time) ([]*model.LogPatternCount, error) {
+	var counts []*model.LogPatternCount
+	err := r.db.Where("pattern_id = ? AND bucket >= ?", patternID, since).
+		Order("bucket").
+		Find(&counts).Error
+	return counts, err
+}
+
+// applyFilters 应用过滤条件，键为带占位符的条件
+func applyFilters(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+	return query
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// logRecordColumns 日志及关联的主机名、服务名、组件类型，空值转换为零值
+const logRecordColumns = `lr.id, IFNULL(lr.host_id, 0) AS host_id, IFNULL(lr.service_id, 0) AS service_id,
+	IFNULL(lr.component_id, 0) AS component_id, IFNULL(lr.log_level, '') AS log_level,
+	IFNULL(lr.logger, '') AS logger, IFNULL(lr.thread, '') AS thread, lr.timestamp, IFNULL(lr.message, '') AS message, lr.created_at,
+	IFNULL(h.hostname, '') AS hostname, IFNULL(s.service_name, '') AS service_name,
+	IFNULL(sc.component_type, '') AS component_type`
+
+// LogRepository 日志仓库
+type LogRepository struct {
+	db *gorm.DB
+}
+
+// NewLogRepository 创建日志仓库
+func NewLogRepository(db *gorm.DB) *LogRepository {
+	return &LogRepository{db: db}
+}
+
+// withNames 关联主机、服务和组件表
+func (r *LogRepository) withNames() *gorm.DB {
+	return r.db.Table("log_record AS lr").
+		Select(logRecordColumns).
+		Joins("LEFT JOIN host h ON lr.host_id = h.id").
+		Joins("LEFT JOIN service s ON lr.service_id = s.id").
+		Joins("LEFT JOIN service_component sc ON lr.component_id = sc.id")
+}
+
+// ListAfter 按ID递增顺序列出ID大于 lastID 的日志
+func (r *LogRepository) ListAfter(lastID int64, limit int) ([]*model.LogRecord, error) {
+	var records []*model.LogRecord
+	err := r.withNames().
+		Where("lr.id > ?", lastID).
+		Order("lr.id").
+		Limit(limit).
+		Scan(&records).Error
+	return records, err
+}
+
+// ListByIDs 根据ID列出日志，不保证顺序
+func (r *LogRepository) ListByIDs(ids []int64) ([]*model.LogRecord, error) {
+	var records []*model.LogRecord
+	if len(ids) == 0 {
+		return records, nil
+	}
+	err := r.withNames().
+		Where("lr.id IN ?", ids).
+		Scan(&records).Error
+	return records, err
+}
+
+// ListRange 按ID递增顺序列出ID大于 afterID 且满足过滤条件的日志
+func (r *LogRepository) ListRange(afterID int64, filters map[string]interface{}, limit int) ([]*model.LogRecord, error) {
+	var records []*model.LogRecord
+	err := applyFilters(r.withNames().Where("lr.id > ?", afterID), filters).
+		Order("lr.id").
+		Limit(limit).
+		Scan(&records).Error
+	return records, err
+}
+
+// ListBetween 按ID递减顺序列出ID在 (afterID, beforeID) 之间且满足过滤条件的日志
+func (r *LogRepository) ListBetween(afterID, beforeID int64, filters map[string]interface{}, limit int) ([]*model.LogRecord, error) {
+	var records []*model.LogRecord
+	query := r.withNames().Where("lr.id > ? AND lr.id < ?", afterID, beforeID)
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+	err := query.Order("lr.id DESC").
+		Limit(limit).
+		Scan(&records).Error
+	return records, err
+}
+
+// MaxID 获取最大的日志ID，没有日志时为0
+func (r *LogRepository) MaxID() (int64, error) {
+	var maxID int64
+	err := r.db.Table("log_record").
+		Select("IFNULL(MAX(id), 0)").
+		Scan(&maxID).Error
+	return maxID, err
+}
+
+// FirstIDSince 获取指定时间之后写入的第一条日志ID，没有时为0
+func (r *LogRepository) FirstIDSince(since time.Time) (int64, error) {
+	var id int64
+	err := r.db.Table("log_record").
+		Select("IFNULL(MIN(id), 0)").
+		Where("created_at >= ?", since).
+		Scan(&id).Error
+	return id, err
+}
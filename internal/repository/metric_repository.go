@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/promql"
+	"gorm.io/gorm"
+)
+
+// 指标序列支持的标签
+const (
+	LabelHostID    = "host_id"
+	LabelHostname  = "hostname"
+	LabelServiceID = "service_id"
+	LabelService   = "service"
+	LabelClusterID = "cluster_id"
+)
+
+// MetricRepository 指标数据仓库，实现告警表达式的查询接口
+type MetricRepository struct {
+	db *gorm.DB
+}
+
+// NewMetricRepository 创建指标数据仓库
+func NewMetricRepository(db *gorm.DB) *MetricRepository {
+	return &MetricRepository{db: db}
+}
+
+// MetricRecord 指标数据及关联的主机名、集群ID、服务名，空值转换为零值
+type MetricRecord struct {
+	ID          int64     `json:"id"`
+	HostID      int       `json:"host_id,omitempty"`
+	Hostname    string    `json:"hostname,omitempty"`
+	ClusterID   int       `json:"cluster_id,omitempty"`
+	ServiceID   int       `json:"service_id,omitempty"`
+	ServiceName string    `json:"service_name,omitempty"`
+	MetricName  string    `json:"metric_name"`
+	Timestamp   time.Time `json:"timestamp"`
+	Value       float64   `json:"value"`
+}
+
+// metricRow 指标查询结果行
+type metricRow struct {
+	HostID      sql.NullInt64
+	ServiceID   sql.NullInt64
+	MetricName  string
+	Timestamp   time.Time
+	Value       float64
+	Hostname    sql.NullString
+	ClusterID   sql.NullInt64
+	ServiceName sql.NullString
+}
+
+// Select 按标签匹配器查询时间区间 (start, end] 内的指标序列
+func (r *MetricRepository) Select(ctx context.Context, matchers []*promql.LabelMatcher, start, end time.Time) ([]promql.Series, error) {
+	query := r.db.WithContext(ctx).
+		Table("metric m").
+		Select("m.host_id, m.service_id, m.metric_name, m.timestamp, m.value, h.hostname, h.cluster_id, s.service_name").
+		Joins("LEFT JOIN host h ON m.host_id = h.id").
+		Joins("LEFT JOIN service s ON m.service_id = s.id").
+		Where("m.timestamp > ? AND m.timestamp <= ?", start, end)
+
+	// 等值匹配下推到SQL，其余匹配器在内存中过滤
+	for _, m := range matchers {
+		if m.Type != promql.MatchEqual {
+			continue
+		}
+		switch m.Name {
+		case promql.MetricNameLabel:
+			query = query.Where("m.metric_name = ?", m.Value)
+		case LabelHostID:
+			query = query.Where("m.host_id = ?", m.Value)
+		case LabelServiceID:
+			query = query.Where("m.service_id = ?", m.Value)
+		case LabelClusterID:
+			query = query.Where("h.cluster_id = ?", m.Value)
+		}
+	}
+
+	var rows []metricRow
+	if err := query.Order("m.timestamp ASC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	seriesByKey := make(map[string]*promql.Series)
+	var keys []string
+	for _, row := range rows {
+		labels := rowLabels(row)
+		if !matchesAll(labels, matchers) {
+			continue
+		}
+
+		key := labels.String()
+		s, ok := seriesByKey[key]
+		if !ok {
+			s = &promql.Series{Labels: labels}
+			seriesByKey[key] = s
+			keys = append(keys, key)
+		}
+		s.Samples = append(s.Samples, promql.Sample{Timestamp: row.Timestamp, Value: row.Value})
+	}
+
+	sort.Strings(keys)
+	result := make([]promql.Series, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *seriesByKey[key])
+	}
+	return result, nil
+}
+
+// rowLabels 根据查询行构造序列标签
+func rowLabels(row metricRow) promql.Labels {
+	labels := promql.Labels{promql.MetricNameLabel: row.MetricName}
+	if row.HostID.Valid {
+		labels[LabelHostID] = strconv.FormatInt(row.HostID.Int64, 10)
+	}
+	if row.Hostname.Valid {
+		labels[LabelHostname] = row.Hostname.String
+	}
+	if row.ClusterID.Valid {
+		labels[LabelClusterID] = strconv.FormatInt(row.ClusterID.Int64, 10)
+	}
+	if row.ServiceID.Valid {
+		labels[LabelServiceID] = strconv.FormatInt(row.ServiceID.Int64, 10)
+	}
+	if row.ServiceName.Valid {
+		labels[LabelService] = row.ServiceName.String
+	}
+	return labels
+}
+
+// matchesAll 判断标签是否满足全部匹配器，缺失的标签视为空字符串
+func matchesAll(labels promql.Labels, matchers []*promql.LabelMatcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ListRange 按ID递增顺序列出ID大于 afterID 且满足过滤条件的指标数据
+func (r *MetricRepository) ListRange(afterID int64, filters map[string]interface{}, limit int) ([]*MetricRecord, error) {
+	var records []*MetricRecord
+	query := r.db.Table("metric m").
+		Select(`m.id, IFNULL(m.host_id, 0) AS host_id, IFNULL(h.hostname, '') AS hostname,
+			IFNULL(h.cluster_id, 0) AS cluster_id, IFNULL(m.service_id, 0) AS service_id,
+			IFNULL(s.service_name, '') AS service_name, m.metric_name, m.timestamp, m.value`).
+		Joins("LEFT JOIN host h ON m.host_id = h.id").
+		Joins("LEFT JOIN service s ON m.service_id = s.id").
+		Where("m.id > ?", afterID)
+	err := applyFilters(query, filters).
+		Order("m.id").
+		Limit(limit).
+		Scan(&records).Error
+	return records, err
+}
+
+// MaxID 获取最大的指标数据ID，没有数据时为0
+func (r *MetricRepository) MaxID() (int64, error) {
+	var maxID int64
+	err := r.db.Table("metric").
+		Select("IFNULL(MAX(id), 0)").
+		Scan(&maxID).Error
+	return maxID, err
+}
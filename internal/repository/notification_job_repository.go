@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// NotificationJobRepository 通知发送任务仓库
+type NotificationJobRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationJobRepository 创建通知发送任务仓库
+func NewNotificationJobRepository(db *gorm.DB) *NotificationJobRepository {
+	return &NotificationJobRepository{db: db}
+}
+
+// Create 创建通知发送任务
+func (r *NotificationJobRepository) Create(job *model.NotificationJob) error {
+	return r.db.Create(job).Error
+}
+
+// Update 更新通知发送任务
+func (r *NotificationJobRepository) Update(job *model.NotificationJob) error {
+	return r.db.Save(job).Error
+}
+
+// GetByID 根据ID获取通知发送任务
+func (r *NotificationJobRepository) GetByID(id uint) (*model.NotificationJob, error) {
+	var job model.NotificationJob
+	err := r.db.First(&job, id).Error
+	return &job, err
+}
+
+// List 列出通知发送任务
+func (r *NotificationJobRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.NotificationJob, int64, error) {
+	var jobs []*model.NotificationJob
+	var total int64
+
+	query := r.db.Model(&model.NotificationJob{})
+
+	// 应用过滤条件
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+
+	// 统计总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	if page > 0 && pageSize > 0 {
+		offset := (page - 1) * pageSize
+		query = query.Offset(offset).Limit(pageSize)
+	}
+
+	if err := query.Order("id DESC").Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// ClaimDue 领取到期的待发送任务并置为发送中，已被其他进程领取的任务会被跳过
+func (r *NotificationJobRepository) ClaimDue(now time.Time, limit int) ([]*model.NotificationJob, error) {
+	var candidates []*model.NotificationJob
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", model.NotificationJobPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]*model.NotificationJob, 0, len(candidates))
+	for _, job := range candidates {
+		result := r.db.Model(&model.NotificationJob{}).
+			Where("id = ? AND status = ?", job.ID, model.NotificationJobPending).
+			Updates(map[string]interface{}{
+				"status":     model.NotificationJobProcessing,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = model.NotificationJobProcessing
+			job.UpdatedAt = now
+			claimed = append(claimed, job)
+		}
+	}
+	return claimed, nil
+}
+
+// RequeueProcessing 将指定时间之前进入发送中的任务重新置为待发送，用于恢复进程退出时中断的任务
+func (r *NotificationJobRepository) RequeueProcessing(before time.Time) (int64, error) {
+	result := r.db.Model(&model.NotificationJob{}).
+		Where("status = ? AND updated_at < ?", model.NotificationJobProcessing, before).
+		Updates(map[string]interface{}{
+			"status":          model.NotificationJobPending,
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
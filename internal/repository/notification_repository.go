@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/TejParker/bigdata-manager/internal/model"
 	"gorm.io/gorm"
 )
@@ -132,3 +134,56 @@ func (r *NotificationHistoryRepository) CountFailureByConfig(configID uint) (int
 		Count(&count).Error
 	return count, err
 }
+
+// List 列出通知历史
+func (r *NotificationHistoryRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.NotificationHistory, int64, error) {
+	var histories []*model.NotificationHistory
+	var total int64
+
+	query := r.db.Model(&model.NotificationHistory{})
+
+	// 应用过滤条件
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+
+	// 统计总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	if page > 0 && pageSize > 0 {
+		offset := (page - 1) * pageSize
+		query = query.Offset(offset).Limit(pageSize)
+	}
+
+	if err := query.Order("sent_at DESC, id DESC").Find(&histories).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return histories, total, nil
+}
+
+// NotificationStatusCount 通知配置某一发送状态的记录数
+type NotificationStatusCount struct {
+	NotificationConfigID uint
+	Status               string
+	Count                int64
+}
+
+// CountByConfigAndStatus 按通知配置和发送状态统计时间范围内的通知历史，configID 为0时统计全部配置
+func (r *NotificationHistoryRepository) CountByConfigAndStatus(configID uint, start, end time.Time) ([]*NotificationStatusCount, error) {
+	var counts []*NotificationStatusCount
+	query := r.db.Model(&model.NotificationHistory{}).
+		Select("notification_config_id, status, COUNT(*) AS count").
+		Where("sent_at BETWEEN ? AND ?", start, end)
+	if configID > 0 {
+		query = query.Where("notification_config_id = ?", configID)
+	}
+	err := query.Group("notification_config_id, status").
+		Scan(&counts).Error
+	return counts, err
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// NotificationTemplateRepository 通知模板仓库
+type NotificationTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationTemplateRepository 创建通知模板仓库
+func NewNotificationTemplateRepository(db *gorm.DB) *NotificationTemplateRepository {
+	return &NotificationTemplateRepository{db: db}
+}
+
+// Create 创建通知模板
+func (r *NotificationTemplateRepository) Create(template *model.NotificationTemplate) error {
+	return r.db.Create(template).Error
+}
+
+// Update 更新通知模板
+func (r *NotificationTemplateRepository) Update(template *model.NotificationTemplate) error {
+	return r.db.Save(template).Error
+}
+
+// Delete 删除通知模板
+func (r *NotificationTemplateRepository) Delete(id uint) error {
+	return r.db.Delete(&model.NotificationTemplate{}, id).Error
+}
+
+// GetByID 根据ID获取通知模板
+func (r *NotificationTemplateRepository) GetByID(id uint) (*model.NotificationTemplate, error) {
+	var template model.NotificationTemplate
+	err := r.db.First(&template, id).Error
+	return &template, err
+}
+
+// List 列出通知模板
+func (r *NotificationTemplateRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.NotificationTemplate, int64, error) {
+	var templates []*model.NotificationTemplate
+	var total int64
+
+	query := r.db.Model(&model.NotificationTemplate{})
+
+	// 应用过滤条件
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+
+	// 统计总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	if page > 0 && pageSize > 0 {
+		offset := (page - 1) * pageSize
+		query = query.Offset(offset).Limit(pageSize)
+	}
+
+	if err := query.Order("id DESC").Find(&templates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return templates, total, nil
+}
+
+// ListCandidates 列出可能适用于通知配置的启用模板，包括该配置专用和该通知类型通用的模板
+func (r *NotificationTemplateRepository) ListCandidates(notificationType model.NotificationType, configID uint) ([]*model.NotificationTemplate, error) {
+	var templates []*model.NotificationTemplate
+	err := r.db.Where("type = ? AND enabled = ?", notificationType, true).
+		Where("notification_config_id = ? OR notification_config_id IS NULL", configID).
+		Order("id DESC").
+		Find(&templates).Error
+	return templates, err
+}
+
+// InventoryRepository 主机、集群信息查询
+type InventoryRepository struct {
+	db *gorm.DB
+}
+
+// NewInventoryRepository 创建主机、集群信息查询仓库
+func NewInventoryRepository(db *gorm.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// GetHost 根据ID获取主机信息
+func (r *InventoryRepository) GetHost(id uint) (*model.HostInfo, error) {
+	var host model.HostInfo
+	err := r.db.First(&host, id).Error
+	return &host, err
+}
+
+// GetCluster 根据ID获取集群信息
+func (r *InventoryRepository) GetCluster(id uint) (*model.ClusterInfo, error) {
+	var cluster model.ClusterInfo
+	err := r.db.First(&cluster, id).Error
+	return &cluster, err
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TablePartition 按时间范围分区的表的分区
+type TablePartition struct {
+	Name     string
+	LessThan int64 // 分区上界，unix秒；MAXVALUE 分区为 math.MaxInt64
+}
+
+// RetentionRepository 过期数据清理，表名、列名和条件由调用方从固定列表中提供，不来自用户输入
+type RetentionRepository struct {
+	db *gorm.DB
+}
+
+// NewRetentionRepository 创建过期数据清理仓库
+func NewRetentionRepository(db *gorm.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// ListPartitions 按顺序列出表的分区，未分区的表返回空列表
+func (r *RetentionRepository) ListPartitions(table string) ([]TablePartition, error) {
+	var rows []struct {
+		Name        string
+		Description string
+	}
+	err := r.db.Raw(`SELECT PARTITION_NAME AS name, IFNULL(PARTITION_DESCRIPTION, '') AS description
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION`, table).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]TablePartition, 0, len(rows))
+	for _, row := range rows {
+		lessThan := int64(math.MaxInt64)
+		if row.Description != "MAXVALUE" {
+			if lessThan, err = strconv.ParseInt(row.Description, 10, 64); err != nil {
+				return nil, fmt.Errorf("table %s is not partitioned by time range: %s", table, row.Description)
+			}
+		}
+		partitions = append(partitions, TablePartition{Name: row.Name, LessThan: lessThan})
+	}
+	return partitions, nil
+}
+
+// partitionDefinitions 每天一个分区的定义，days 为各分区所在日期的零点
+func partitionDefinitions(days []time.Time, withMax bool) string {
+	defs := make([]string, 0, len(days)+1)
+	for _, day := range days {
+		defs = append(defs, fmt.Sprintf("PARTITION p%s VALUES LESS THAN (%d)",
+			day.Format("20060102"), day.AddDate(0, 0, 1).Unix()))
+	}
+	if withMax {
+		defs = append(defs, "PARTITION pmax VALUES LESS THAN MAXVALUE")
+	}
+	return strings.Join(defs, ", ")
+}
+
+// PartitionByDay 将表转换为按天分区的表，分区键需要加入主键；数据量大时耗时较长
+func (r *RetentionRepository) PartitionByDay(table, column string, days []time.Time) error {
+	return r.db.Exec(fmt.Sprintf(
+		"ALTER TABLE %s DROP PRIMARY KEY, ADD PRIMARY KEY (id, %s) PARTITION BY RANGE (UNIX_TIMESTAMP(%s)) (%s)",
+		table, column, column, partitionDefinitions(days, true))).Error
+}
+
+// AddPartitions 添加按天的分区，有 MAXVALUE 分区时从中拆分
+func (r *RetentionRepository) AddPartitions(table string, days []time.Time, hasMax bool) error {
+	if len(days) == 0 {
+		return nil
+	}
+	if hasMax {
+		return r.db.Exec(fmt.Sprintf("ALTER TABLE %s REORGANIZE PARTITION pmax INTO (%s)",
+			table, partitionDefinitions(days, true))).Error
+	}
+	return r.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD PARTITION (%s)",
+		table, partitionDefinitions(days, false))).Error
+}
+
+// DropPartitions 删除分区及其中的数据
+func (r *RetentionRepository) DropPartitions(table string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return r.db.Exec(fmt.Sprintf("ALTER TABLE %s DROP PARTITION %s", table, strings.Join(names, ", "))).Error
+}
+
+// MinTime 表中最早的时间，表为空时 ok 为false
+func (r *RetentionRepository) MinTime(table, column string) (t time.Time, ok bool, err error) {
+	var min sql.NullTime
+	if err := r.db.Table(table).Select("MIN(" + column + ")").Row().Scan(&min); err != nil {
+		return t, false, err
+	}
+	return min.Time, min.Valid, nil
+}
+
+// ListRows 按ID递增顺序列出ID大于 afterID 且满足条件的行，返回各行的列值和最后一行的ID
+func (r *RetentionRepository) ListRows(table, where string, args []interface{}, afterID int64, limit int) ([]map[string]interface{}, int64, error) {
+	rows, err := r.db.Table(table).
+		Where(where, args...).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Rows()
+	if err != nil {
+		return nil, afterID, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, afterID, err
+	}
+
+	var result []map[string]interface{}
+	lastID := afterID
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, afterID, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			value := values[i]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			row[column] = value
+		}
+		if id, err := strconv.ParseInt(fmt.Sprint(row["id"]), 10, 64); err == nil {
+			lastID = id
+		}
+		result = append(result, row)
+	}
+	return result, lastID, rows.Err()
+}
+
+// DeleteRows 删除满足条件且ID不大于 maxID 的行，每次最多删除 limit 行，返回删除的行数
+func (r *RetentionRepository) DeleteRows(table, where string, args []interface{}, maxID int64, limit int) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE (%s) AND id <= ? LIMIT ?", table, where)
+	result := r.db.Exec(query, append(append([]interface{}{}, args...), maxID, limit)...)
+	return result.RowsAffected, result.Error
+}
+
+// ServiceIDsByName 按服务名（小写）分组的服务ID，不同集群中可能有同名服务
+func (r *RetentionRepository) ServiceIDsByName() (map[string][]int, error) {
+	var services []struct {
+		ID          int
+		ServiceName string
+	}
+	if err := r.db.Table("service").Select("id, service_name").Scan(&services).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string][]int)
+	for _, service := range services {
+		name := strings.ToLower(service.ServiceName)
+		result[name] = append(result[name], service.ID)
+	}
+	return result, nil
+}
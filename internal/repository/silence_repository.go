@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// SilenceRepository 告警静默仓库
+type SilenceRepository struct {
+	db *gorm.DB
+}
+
+// NewSilenceRepository 创建告警静默仓库
+func NewSilenceRepository(db *gorm.DB) *SilenceRepository {
+	return &SilenceRepository{db: db}
+}
+
+// Create 创建静默
+func (r *SilenceRepository) Create(silence *model.Silence) error {
+	return r.db.Create(silence).Error
+}
+
+// Update 更新静默
+func (r *SilenceRepository) Update(silence *model.Silence) error {
+	return r.db.Save(silence).Error
+}
+
+// GetByID 根据ID获取静默
+func (r *SilenceRepository) GetByID(id uint) (*model.Silence, error) {
+	var silence model.Silence
+	err := r.db.First(&silence, id).Error
+	return &silence, err
+}
+
+// List 列出静默
+func (r *SilenceRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.Silence, int64, error) {
+	var silences []*model.Silence
+	var total int64
+
+	query := r.db.Model(&model.Silence{})
+
+	// 应用过滤条件
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+
+	// 统计总数
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	if page > 0 && pageSize > 0 {
+		offset := (page - 1) * pageSize
+		query = query.Offset(offset).Limit(pageSize)
+	}
+
+	// 排序
+	query = query.Order("id DESC")
+
+	// 执行查询
+	err = query.Find(&silences).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return silences, total, nil
+}
+
+// FindActive 查找在指定时间生效的静默
+func (r *SilenceRepository) FindActive(now time.Time) ([]*model.Silence, error) {
+	var silences []*model.Silence
+	err := r.db.Where("starts_at <= ? AND ends_at > ?", now, now).
+		Order("id ASC").
+		Find(&silences).Error
+	return silences, err
+}
+
+// MaintenanceWindowRepository 维护窗口仓库
+type MaintenanceWindowRepository struct {
+	db *gorm.DB
+}
+
+// NewMaintenanceWindowRepository 创建维护窗口仓库
+func NewMaintenanceWindowRepository(db *gorm.DB) *MaintenanceWindowRepository {
+	return &MaintenanceWindowRepository{db: db}
+}
+
+// Create 创建维护窗口
+func (r *MaintenanceWindowRepository) Create(window *model.MaintenanceWindow) error {
+	return r.db.Create(window).Error
+}
+
+// Update 更新维护窗口
+func (r *MaintenanceWindowRepository) Update(window *model.MaintenanceWindow) error {
+	return r.db.Save(window).Error
+}
+
+// Delete 删除维护窗口
+func (r *MaintenanceWindowRepository) Delete(id uint) error {
+	return r.db.Delete(&model.MaintenanceWindow{}, id).Error
+}
+
+// GetByID 根据ID获取维护窗口
+func (r *MaintenanceWindowRepository) GetByID(id uint) (*model.MaintenanceWindow, error) {
+	var window model.MaintenanceWindow
+	err := r.db.First(&window, id).Error
+	return &window, err
+}
+
+// List 列出维护窗口
+func (r *MaintenanceWindowRepository) List(page, pageSize int, filters map[string]interface{}) ([]*model.MaintenanceWindow, int64, error) {
+	var windows []*model.MaintenanceWindow
+	var total int64
+
+	query := r.db.Model(&model.MaintenanceWindow{})
+
+	// 应用过滤条件
+	for key, value := range filters {
+		if value != nil && value != "" {
+			query = query.Where(key, value)
+		}
+	}
+
+	// 统计总数
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 分页查询
+	if page > 0 && pageSize > 0 {
+		offset := (page - 1) * pageSize
+		query = query.Offset(offset).Limit(pageSize)
+	}
+
+	// 排序
+	query = query.Order("id DESC")
+
+	// 执行查询
+	err = query.Find(&windows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return windows, total, nil
+}
+
+// FindEnabled 查找所有启用的维护窗口
+func (r *MaintenanceWindowRepository) FindEnabled() ([]*model.MaintenanceWindow, error) {
+	var windows []*model.MaintenanceWindow
+	err := r.db.Where("enabled = ?", true).Order("id ASC").Find(&windows).Error
+	return windows, err
+}
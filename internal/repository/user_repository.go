@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"gorm.io/gorm"
+)
+
+// UserRepository 用户、角色和权限仓库
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository 创建用户仓库
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Transaction 在事务中执行 fn，fn 返回错误时回滚
+func (r *UserRepository) Transaction(fn func(tx *UserRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&UserRepository{db: tx})
+	})
+}
+
+// ListUsers 列出用户及其角色
+func (r *UserRepository) ListUsers(page, pageSize int, filters map[string]interface{}) ([]*model.User, int64, error) {
+	var users []*model.User
+	var total int64
+
+	query := applyFilters(r.db.Model(&model.User{}), filters)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page > 0 && pageSize > 0 {
+		query = query.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := query.Preload("Roles").Order("id").Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// GetUser 根据ID获取用户及其角色
+func (r *UserRepository) GetUser(id int) (*model.User, error) {
+	var user model.User
+	err := r.db.Preload("Roles").First(&user, id).Error
+	return &user, err
+}
+
+// UsernameExists 用户名是否已被其他用户使用
+func (r *UserRepository) UsernameExists(username string, excludeID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).
+		Where("username = ? AND id <> ?", username, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateUser 创建用户
+func (r *UserRepository) CreateUser(user *model.User) error {
+	return r.db.Omit("Roles").Create(user).Error
+}
+
+// UpdateUser 更新用户的邮箱和手机号
+func (r *UserRepository) UpdateUser(user *model.User) error {
+	return r.db.Model(user).Select("email", "phone", "updated_at").Updates(user).Error
+}
+
+// UpdateStatus 更新用户状态
+func (r *UserRepository) UpdateStatus(id int, status model.UserStatus) error {
+	return r.db.Model(&model.User{ID: id}).Update("status", status).Error
+}
+
+// UpdatePassword 更新用户密码哈希
+func (r *UserRepository) UpdatePassword(id int, passwordHash string) error {
+	return r.db.Model(&model.User{ID: id}).Update("password_hash", passwordHash).Error
+}
+
+// DeleteUser 删除用户及其角色绑定、API令牌和登录会话，角色关联由外键级联删除
+func (r *UserRepository) DeleteUser(id int) error {
+	if err := r.db.Where("user_id = ?", id).Delete(&model.UserSession{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("user_id = ?", id).Delete(&model.RoleBinding{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("user_id = ?", id).Delete(&model.APIToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&model.User{}, id).Error
+}
+
+// SetUserRoles 将用户的角色替换为 roleIDs
+func (r *UserRepository) SetUserRoles(userID int, roleIDs []int) error {
+	if err := r.db.Exec("DELETE FROM user_role WHERE user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	for _, roleID := range roleIDs {
+		if err := r.db.Exec("INSERT INTO user_role (user_id, role_id) VALUES (?, ?)", userID, roleID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountActiveUsersWithPrivilege 统计拥有指定权限的启用用户数，不包括服务账号
+func (r *UserRepository) CountActiveUsersWithPrivilege(privilege string) (int64, error) {
+	var count int64
+	err := r.db.Table("user u").
+		Joins("JOIN user_role ur ON ur.user_id = u.id").
+		Joins("JOIN role_privilege rp ON rp.role_id = ur.role_id").
+		Joins("JOIN privilege p ON p.id = rp.privilege_id").
+		Where("u.status = ? AND p.name = ? AND u.auth_source <> ?", model.UserStatusActive, privilege, model.UserAuthSourceService).
+		Distinct("u.id").
+		Count(&count).Error
+	return count, err
+}
+
+// ListRoles 列出全部角色及其权限
+func (r *UserRepository) ListRoles() ([]*model.Role, error) {
+	var roles []*model.Role
+	err := r.db.Preload("Privileges").Order("id").Find(&roles).Error
+	return roles, err
+}
+
+// GetRole 根据ID获取角色及其权限
+func (r *UserRepository) GetRole(id int) (*model.Role, error) {
+	var role model.Role
+	err := r.db.Preload("Privileges").First(&role, id).Error
+	return &role, err
+}
+
+// CountRoles 统计 ids 中存在的角色数
+func (r *UserRepository) CountRoles(ids []int) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Role{}).Where("id IN ?", ids).Count(&count).Error
+	return count, err
+}
+
+// RoleNameExists 角色名是否已被其他角色使用
+func (r *UserRepository) RoleNameExists(name string, excludeID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Role{}).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateRole 创建角色
+func (r *UserRepository) CreateRole(role *model.Role) error {
+	return r.db.Omit("Privileges").Create(role).Error
+}
+
+// UpdateRole 更新角色名称和描述
+func (r *UserRepository) UpdateRole(role *model.Role) error {
+	return r.db.Model(role).Select("name", "description", "updated_at").Updates(role).Error
+}
+
+// DeleteRole 删除角色及其角色绑定，用户和权限关联由外键级联删除
+func (r *UserRepository) DeleteRole(id int) error {
+	if err := r.db.Where("role_id = ?", id).Delete(&model.RoleBinding{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&model.Role{}, id).Error
+}
+
+// SetRolePrivileges 将角色的权限替换为 privilegeIDs
+func (r *UserRepository) SetRolePrivileges(roleID int, privilegeIDs []int) error {
+	if err := r.db.Exec("DELETE FROM role_privilege WHERE role_id = ?", roleID).Error; err != nil {
+		return err
+	}
+	for _, privilegeID := range privilegeIDs {
+		if err := r.db.Exec("INSERT INTO role_privilege (role_id, privilege_id) VALUES (?, ?)", roleID, privilegeID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListPrivileges 列出全部权限
+func (r *UserRepository) ListPrivileges() ([]*model.Privilege, error) {
+	var privileges []*model.Privilege
+	err := r.db.Order("id").Find(&privileges).Error
+	return privileges, err
+}
+
+// CountPrivileges 统计 ids 中存在的权限数
+func (r *UserRepository) CountPrivileges(ids []int) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Privilege{}).Where("id IN ?", ids).Count(&count).Error
+	return count, err
+}
+
+// ListRoleBindings 列出用户的角色绑定，包含角色、集群和服务名称
+func (r *UserRepository) ListRoleBindings(userID int) ([]*model.RoleBinding, error) {
+	var bindings []*model.RoleBinding
+	err := r.db.Table("role_bindings AS rb").
+		Select("rb.*, r.name AS role_name, c.name AS cluster_name, s.service_name AS service_name").
+		Joins("LEFT JOIN role r ON rb.role_id = r.id").
+		Joins("LEFT JOIN cluster c ON rb.cluster_id = c.id").
+		Joins("LEFT JOIN service s ON rb.service_id = s.id").
+		Where("rb.user_id = ?", userID).
+		Order("rb.id").
+		Scan(&bindings).Error
+	return bindings, err
+}
+
+// GetRoleBinding 根据ID获取角色绑定
+func (r *UserRepository) GetRoleBinding(id int) (*model.RoleBinding, error) {
+	var binding model.RoleBinding
+	err := r.db.First(&binding, id).Error
+	return &binding, err
+}
+
+// RoleBindingExists 用户是否已在同一范围绑定了该角色
+func (r *UserRepository) RoleBindingExists(binding *model.RoleBinding) (bool, error) {
+	query := r.db.Model(&model.RoleBinding{}).
+		Where("user_id = ? AND role_id = ? AND cluster_id = ?", binding.UserID, binding.RoleID, binding.ClusterID)
+	if binding.ServiceID == nil {
+		query = query.Where("service_id IS NULL")
+	} else {
+		query = query.Where("service_id = ?", *binding.ServiceID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// CreateRoleBinding 创建角色绑定
+func (r *UserRepository) CreateRoleBinding(binding *model.RoleBinding) error {
+	return r.db.Create(binding).Error
+}
+
+// DeleteRoleBinding 删除角色绑定
+func (r *UserRepository) DeleteRoleBinding(id int) error {
+	return r.db.Delete(&model.RoleBinding{}, id).Error
+}
+
+// ClusterExists 集群是否存在
+func (r *UserRepository) ClusterExists(id int) (bool, error) {
+	var count int64
+	err := r.db.Table("cluster").Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// ServiceClusterID 获取服务所属的集群ID，服务不存在时返回 gorm.ErrRecordNotFound
+func (r *UserRepository) ServiceClusterID(serviceID int) (int, error) {
+	var clusterIDs []int
+	if err := r.db.Table("service").Where("id = ?", serviceID).Pluck("cluster_id", &clusterIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(clusterIDs) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return clusterIDs[0], nil
+}
+
+// ListSessions 列出用户未吊销、未过期的登录会话，按创建时间倒序
+func (r *UserRepository) ListSessions(userID int) ([]*model.UserSession, error) {
+	var sessions []*model.UserSession
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("id DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// GetSession 根据ID获取登录会话
+func (r *UserRepository) GetSession(id uint) (*model.UserSession, error) {
+	var session model.UserSession
+	err := r.db.First(&session, id).Error
+	return &session, err
+}
+
+// RevokeSessions 吊销用户的全部登录会话
+func (r *UserRepository) RevokeSessions(userID int) error {
+	return r.db.Model(&model.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
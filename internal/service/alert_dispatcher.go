@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/promql"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// 通知分组的默认时间参数
+const (
+	defaultGroupWait      = 30 * time.Second
+	defaultGroupInterval  = 5 * time.Minute
+	defaultRepeatInterval = 4 * time.Hour
+	dispatchTick          = 5 * time.Second
+)
+
+// groupLabelRuleID 分组标签中的规则ID，分组总是限定在同一规则内
+const groupLabelRuleID = "alert_rule_id"
+
+// defaultGroupBy 未配置 alert.group_by 时的默认分组标签
+var defaultGroupBy = []string{repository.LabelClusterID}
+
+// groupLabelPattern 分组标签名的格式，与表达式结果的标签名相同
+var groupLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// maxGroupByLength 规则分组标签的最大长度，与 alert_rule.group_by 列一致
+const maxGroupByLength = 255
+
+// alertGroup 通知分组的调度状态
+type alertGroup struct {
+	key            string
+	ruleID         uint
+	version        uint64 // 每加入一个新告警加一，用于判断通知期间是否有新告警
+	flushed        uint64 // 上次通知时的 version
+	notified       bool   // 是否已发送过首次通知
+	lastNotifiedAt time.Time
+	nextFlush      time.Time
+}
+
+// hasNew 自上次通知后是否有新告警加入
+func (g *alertGroup) hasNew() bool {
+	return g.version != g.flushed
+}
+
+// AlertDispatcher 对告警按规则和标签分组，合并发送通知并控制重复通知频率
+type AlertDispatcher struct {
+	alertRuleRepo   *repository.AlertRuleRepository
+	alertEventRepo  *repository.AlertEventRepository
+	notificationSvc *NotificationService
+	silenceSvc      *SilenceService
+
+	groupBy          []string
+	groupWait        time.Duration
+	groupInterval    time.Duration
+	repeatInterval   time.Duration
+	maxNotifications int
+
+	mu     sync.Mutex
+	groups map[string]*alertGroup
+}
+
+// NewAlertDispatcher 创建告警通知分组调度器
+func NewAlertDispatcher(db *gorm.DB, cfg config.AlertConfig, notificationSvc *NotificationService, silenceSvc *SilenceService) *AlertDispatcher {
+	d := &AlertDispatcher{
+		alertRuleRepo:    repository.NewAlertRuleRepository(db),
+		alertEventRepo:   repository.NewAlertEventRepository(db),
+		notificationSvc:  notificationSvc,
+		silenceSvc:       silenceSvc,
+		groupBy:          cfg.GroupBy,
+		groupWait:        secondsOrDefault(cfg.GroupWait, defaultGroupWait),
+		groupInterval:    secondsOrDefault(cfg.GroupInterval, defaultGroupInterval),
+		repeatInterval:   secondsOrDefault(cfg.RepeatInterval, defaultRepeatInterval),
+		maxNotifications: cfg.MaxNotifications,
+		groups:           make(map[string]*alertGroup),
+	}
+	if len(d.groupBy) == 0 {
+		d.groupBy = defaultGroupBy
+	}
+	return d
+}
+
+// secondsOrDefault 将秒数配置转换为时间间隔，未配置时使用默认值
+func secondsOrDefault(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GroupKey 计算告警所属的通知分组标识
+func (d *AlertDispatcher) GroupKey(rule *model.AlertRule, event *model.AlertEvent) string {
+	groupBy := d.groupBy
+	if rule.GroupBy != "" {
+		groupBy = strings.Split(rule.GroupBy, ",")
+	}
+
+	var eventLabels map[string]string
+	if event.Labels != "" {
+		_ = json.Unmarshal([]byte(event.Labels), &eventLabels)
+	}
+
+	labels := promql.Labels{groupLabelRuleID: strconv.FormatUint(uint64(rule.ID), 10)}
+	for _, name := range groupBy {
+		name = strings.TrimSpace(name)
+		if name == "" || name == groupLabelRuleID {
+			continue
+		}
+		labels[name] = alertLabelValue(event, eventLabels, name)
+	}
+	return labels.Fingerprint()
+}
+
+// normalizeGroupBy 规范化规则的分组标签：去除空白、空项和重复项，规则ID总是参与分组，无需配置
+func normalizeGroupBy(groupBy string) (string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(groupBy, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == groupLabelRuleID || seen[name] {
+			continue
+		}
+		if !groupLabelPattern.MatchString(name) {
+			return "", fmt.Errorf("invalid group by label: %s", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	normalized := strings.Join(names, ",")
+	if len(normalized) > maxGroupByLength {
+		return "", fmt.Errorf("group by must be at most %d characters", maxGroupByLength)
+	}
+	return normalized, nil
+}
+
+// alertLabelValue 读取告警事件的分组标签值，非内置标签从表达式结果标签中查找
+func alertLabelValue(event *model.AlertEvent, eventLabels map[string]string, name string) string {
+	formatID := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*id), 10)
+	}
+
+	switch name {
+	case repository.LabelClusterID:
+		return formatID(event.ClusterID)
+	case repository.LabelServiceID:
+		return formatID(event.ServiceID)
+	case repository.LabelHostID:
+		return formatID(event.HostID)
+	case repository.LabelHostname:
+		return event.Hostname
+	case repository.LabelService:
+		return event.ServiceName
+	case "severity":
+		return string(event.Severity)
+	case "metric_name":
+		return event.MetricName
+	default:
+		return eventLabels[name]
+	}
+}
+
+// Add 将新告警加入其通知分组，新分组在 group_wait 后发送首次通知
+func (d *AlertDispatcher) Add(event *model.AlertEvent) {
+	if event.GroupKey == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	g, ok := d.groups[event.GroupKey]
+	if !ok {
+		g = &alertGroup{
+			key:       event.GroupKey,
+			ruleID:    event.AlertRuleID,
+			nextFlush: now.Add(d.groupWait),
+		}
+		d.groups[event.GroupKey] = g
+	}
+	g.version++
+
+	// 已通知过的分组出现新告警时，最早在 group_interval 后再次通知
+	if g.notified {
+		next := g.lastNotifiedAt.Add(d.groupInterval)
+		if next.Before(g.nextFlush) {
+			g.nextFlush = next
+		}
+	}
+}
+
+// Start 恢复未解决告警的分组并启动调度，ctx 取消后停止
+func (d *AlertDispatcher) Start(ctx context.Context) {
+	if err := d.restore(); err != nil {
+		log.Printf("恢复告警通知分组失败: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(dispatchTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				d.Flush(ctx, now)
+			}
+		}
+	}()
+}
+
+// restore 根据数据库中未解决的告警重建分组，避免重启后重复发送首次通知
+func (d *AlertDispatcher) restore() error {
+	events, err := d.alertEventRepo.ListOpenGroupedAlerts()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for _, event := range events {
+		g, ok := d.groups[event.GroupKey]
+		if !ok {
+			g = &alertGroup{
+				key:       event.GroupKey,
+				ruleID:    event.AlertRuleID,
+				nextFlush: now.Add(d.groupWait),
+			}
+			d.groups[event.GroupKey] = g
+		}
+
+		if event.LastNotifiedAt == nil {
+			g.version++
+			continue
+		}
+		if !g.notified || event.LastNotifiedAt.After(g.lastNotifiedAt) {
+			g.notified = true
+			g.lastNotifiedAt = *event.LastNotifiedAt
+		}
+	}
+
+	// 已通知过且没有新告警的分组按重复通知间隔调度
+	for _, g := range d.groups {
+		if g.notified && !g.hasNew() {
+			g.nextFlush = g.lastNotifiedAt.Add(d.repeatInterval)
+		}
+	}
+	return nil
+}
+
+// Flush 对到期的分组发送合并通知
+func (d *AlertDispatcher) Flush(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	var due []*alertGroup
+	for _, g := range d.groups {
+		if !g.nextFlush.After(now) {
+			due = append(due, g)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, g := range due {
+		if err := d.flushGroup(ctx, g, now); err != nil {
+			log.Printf("发送告警分组 %s 通知失败: %v", g.key, err)
+		}
+	}
+}
+
+// flushGroup 发送一个分组的合并通知，分组内已无未解决告警时将其移除
+func (d *AlertDispatcher) flushGroup(ctx context.Context, g *alertGroup, now time.Time) error {
+	d.mu.Lock()
+	version := g.version
+	d.mu.Unlock()
+
+	rule, err := d.alertRuleRepo.GetByID(g.ruleID)
+	if err != nil {
+		d.removeGroup(g, version)
+		return err
+	}
+
+	events, err := d.alertEventRepo.ListOpenAlertsByGroupKey(g.key)
+	if err != nil {
+		d.reschedule(g, now.Add(d.groupInterval))
+		return err
+	}
+	if len(events) == 0 || !rule.Enabled {
+		d.removeGroup(g, version)
+		return nil
+	}
+
+	events, err = d.silenceSvc.FilterUnsilenced(events, now)
+	if err != nil {
+		d.reschedule(g, now.Add(d.groupInterval))
+		return err
+	}
+
+	// 超过最大通知次数的告警不再通知
+	pending := make([]*model.AlertEvent, 0, len(events))
+	for _, event := range events {
+		if d.maxNotifications > 0 && event.NotificationCount >= d.maxNotifications {
+			continue
+		}
+		pending = append(pending, event)
+	}
+
+	sent := false
+	if len(pending) > 0 {
+		for _, id := range parseNotificationIDs(rule.NotificationIDs) {
+			d.notificationSvc.SendGroupNotification(ctx, id, pending)
+			sent = true
+		}
+	}
+
+	if sent {
+		ids := make([]uint, 0, len(pending))
+		for _, event := range pending {
+			ids = append(ids, event.ID)
+		}
+		if err := d.alertEventRepo.MarkNotified(ids, now); err != nil {
+			log.Printf("更新告警通知次数失败: %v", err)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	g.flushed = version
+	if sent {
+		g.notified = true
+		g.lastNotifiedAt = now
+	}
+	if g.hasNew() {
+		g.nextFlush = now.Add(d.groupInterval)
+	} else {
+		g.nextFlush = now.Add(d.repeatInterval)
+	}
+	return nil
+}
+
+// reschedule 调整分组的下次通知时间
+func (d *AlertDispatcher) reschedule(g *alertGroup, next time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g.nextFlush = next
+}
+
+// removeGroup 移除分组，version 之后有新告警加入时保留分组并尽快重新处理
+func (d *AlertDispatcher) removeGroup(g *alertGroup, version uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if g.version != version {
+		g.nextFlush = time.Now()
+		return
+	}
+	if current, ok := d.groups[g.key]; ok && current == g {
+		delete(d.groups, g.key)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestNormalizeGroupBy(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		want    string
+		wantErr bool
+	}{
+		{groupBy: "", want: ""},
+		{groupBy: "cluster_id", want: "cluster_id"},
+		{groupBy: " cluster_id , hostname,,cluster_id ", want: "cluster_id,hostname"},
+		{groupBy: "alert_rule_id,service_id", want: "service_id"},
+		{groupBy: "instance,__name__", want: "instance,__name__"},
+		{groupBy: "cluster-id", wantErr: true},
+		{groupBy: "1st", wantErr: true},
+		{groupBy: "host name", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeGroupBy(tt.groupBy)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeGroupBy(%q) = %q, want error", tt.groupBy, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeGroupBy(%q) = %q, %v, want %q", tt.groupBy, got, err, tt.want)
+		}
+	}
+}
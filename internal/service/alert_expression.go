@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/promql"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// defaultEvaluationInterval 未配置 alert.process_interval 时的表达式规则评估间隔
+const defaultEvaluationInterval = 30 * time.Second
+
+// StartExpressionEvaluator 启动表达式规则的定时评估，ctx 取消后停止
+func (s *AlertService) StartExpressionEvaluator(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultEvaluationInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.EvaluateExpressionRules(ctx, now)
+			}
+		}
+	}()
+}
+
+// EvaluateExpressionRules 在指定时间点对所有启用的指标规则（表达式规则和阈值规则）求值一次
+func (s *AlertService) EvaluateExpressionRules(ctx context.Context, ts time.Time) {
+	rules, err := s.alertRuleRepo.FindExpressionRules()
+	if err != nil {
+		log.Printf("查询表达式告警规则失败: %v", err)
+		return
+	}
+
+	active := make(map[uint]bool, len(rules))
+	for _, rule := range rules {
+		active[rule.ID] = true
+		if err := s.evaluateExpressionRule(ctx, rule, ts); err != nil {
+			log.Printf("评估告警规则 %d(%s) 失败: %v", rule.ID, rule.Name, err)
+		}
+	}
+
+	// 清理已删除或已禁用规则的待定状态
+	s.pendingMu.Lock()
+	for ruleID := range s.pending {
+		if !active[ruleID] {
+			delete(s.pending, ruleID)
+		}
+	}
+	s.pendingMu.Unlock()
+}
+
+// QueryExpression 对表达式即时求值，用于规则调试
+func (s *AlertService) QueryExpression(ctx context.Context, expression string, ts time.Time) (promql.Vector, error) {
+	return s.queryEngine.Query(ctx, expression, ts)
+}
+
+// ruleExpression 规则求值使用的表达式，未设置表达式的阈值规则按指标名查询该指标的全部序列
+func ruleExpression(rule *model.AlertRule) string {
+	if rule.Expression != "" {
+		return rule.Expression
+	}
+	return rule.MetricName
+}
+
+// evaluateExpressionRule 评估单条表达式规则，为每个满足条件的序列创建告警，并解决不再满足条件的告警
+func (s *AlertService) evaluateExpressionRule(ctx context.Context, rule *model.AlertRule, ts time.Time) error {
+	expr, err := promql.ParseExpr(ruleExpression(rule))
+	if err != nil {
+		return err
+	}
+
+	vector, err := s.queryEngine.Eval(ctx, expr, ts)
+	if err != nil {
+		return err
+	}
+
+	firing := make(map[string]bool, len(vector))
+	var ready []promql.VectorElement
+
+	s.pendingMu.Lock()
+	previous := s.pending[rule.ID]
+	current := make(map[string]time.Time, len(vector))
+	for _, el := range vector {
+		if !ruleScopeMatches(rule, el.Labels) {
+			continue
+		}
+		if rule.Operator != "" && !s.evaluateRule(rule, el.Value) {
+			continue
+		}
+
+		fingerprint := el.Labels.Fingerprint()
+		since, ok := previous[fingerprint]
+		if !ok {
+			since = ts
+		}
+		current[fingerprint] = since
+		firing[fingerprint] = true
+
+		// 持续时间未达到要求时保持待定
+		if rule.Duration > 0 && ts.Sub(since) < time.Duration(rule.Duration)*time.Second {
+			continue
+		}
+		ready = append(ready, el)
+	}
+	s.pending[rule.ID] = current
+	s.pendingMu.Unlock()
+
+	for _, el := range ready {
+		if err := s.createExpressionAlertEvent(ctx, rule, el, ts); err != nil {
+			return err
+		}
+	}
+
+	// 自动解决不再满足条件的序列
+	return s.resolveInactiveAlerts(ctx, rule, firing)
+}
+
+// resolveInactiveAlerts 自动解决规则下标识不在 firing 中的未解决告警，并发送恢复通知
+func (s *AlertService) resolveInactiveAlerts(ctx context.Context, rule *model.AlertRule, firing map[string]bool) error {
+	openEvents, err := s.alertEventRepo.ListOpenAlertsByRule(rule.ID)
+	if err != nil {
+		return err
+	}
+	var resolved []*model.AlertEvent
+	for _, event := range openEvents {
+		if event.Fingerprint == "" || firing[event.Fingerprint] {
+			continue
+		}
+		now := time.Now()
+		event.Status = model.AlertStatusResolved
+		event.ResolvedAt = &now
+		event.UpdatedAt = now
+		if err := s.alertEventRepo.Update(event); err != nil {
+			return err
+		}
+		resolved = append(resolved, event)
+	}
+	s.notifyFollowUp(ctx, rule, NotificationKindResolved, resolved)
+
+	return nil
+}
+
+// ruleScopeMatches 判断序列是否在规则限定的集群、服务、主机范围内，序列缺少对应标签时不做限制
+func ruleScopeMatches(rule *model.AlertRule, labels promql.Labels) bool {
+	scopes := []struct {
+		id    *uint
+		label string
+	}{
+		{rule.ClusterID, repository.LabelClusterID},
+		{rule.ServiceID, repository.LabelServiceID},
+		{rule.HostID, repository.LabelHostID},
+	}
+
+	for _, scope := range scopes {
+		if scope.id == nil {
+			continue
+		}
+		value, ok := labels[scope.label]
+		if ok && value != strconv.FormatUint(uint64(*scope.id), 10) {
+			return false
+		}
+	}
+	return true
+}
+
+// createExpressionAlertEvent 为表达式结果中的一个序列创建告警事件并发送通知
+func (s *AlertService) createExpressionAlertEvent(ctx context.Context, rule *model.AlertRule, el promql.VectorElement, ts time.Time) error {
+	fingerprint := el.Labels.Fingerprint()
+
+	// 同一序列已有未解决的告警时不重复创建
+	existingAlert, err := s.alertEventRepo.FindOpenAlertByFingerprint(rule.ID, fingerprint)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if existingAlert != nil {
+		return nil
+	}
+
+	labelsJSON, err := json.Marshal(el.Labels)
+	if err != nil {
+		return err
+	}
+
+	metricName := el.Labels[promql.MetricNameLabel]
+	if metricName == "" {
+		metricName = rule.MetricName
+	}
+	if metricName == "" {
+		metricName = "expression"
+	}
+
+	now := time.Now()
+	alertEvent := &model.AlertEvent{
+		AlertRuleID: rule.ID,
+		AlertName:   rule.Name,
+		ClusterID:   labelUint(el.Labels, repository.LabelClusterID),
+		ServiceID:   labelUint(el.Labels, repository.LabelServiceID),
+		HostID:      labelUint(el.Labels, repository.LabelHostID),
+		Hostname:    el.Labels[repository.LabelHostname],
+		ServiceName: el.Labels[repository.LabelService],
+		MetricName:  metricName,
+		Labels:      string(labelsJSON),
+		Fingerprint: fingerprint,
+		MetricValue: el.Value,
+		Threshold:   rule.Threshold,
+		Operator:    string(rule.Operator),
+		Message:     truncate(fmt.Sprintf("%s: %s = %.2f", rule.Name, el.Labels.String(), el.Value), 500),
+		Severity:    rule.Severity,
+		Status:      model.AlertStatusOpen,
+		TriggeredAt: ts,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if alertEvent.ClusterID == nil && rule.ClusterID != nil {
+		alertEvent.ClusterID = rule.ClusterID
+	}
+
+	s.applySilence(alertEvent)
+	alertEvent.GroupKey = s.dispatcher.GroupKey(rule, alertEvent)
+
+	if err := s.alertEventRepo.Create(alertEvent); err != nil {
+		return err
+	}
+
+	s.notifyAlertEvent(ctx, rule, alertEvent)
+	return nil
+}
+
+// labelUint 读取数值型标签，标签不存在或无法解析时返回nil
+func labelUint(labels promql.Labels, name string) *uint {
+	value, ok := labels[name]
+	if !ok {
+		return nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil
+	}
+	result := uint(id)
+	return &result
+}
+
+// truncate 按字符数截断字符串
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
@@ -0,0 +1,476 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/logparse"
+	"github.com/TejParker/bigdata-manager/internal/logstore"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/promql"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// 日志规则参数
+const (
+	defaultLogRuleWindow = 5 * time.Minute
+	maxLogRuleWindow     = 24 * time.Hour
+	logRuleBatchSize     = 5000
+	logRuleBucket        = 10 * time.Second // 时间窗口内按桶计数，窗口边界的精度
+
+	// LogCountMetric 日志规则告警事件的指标名称
+	LogCountMetric = "log_count"
+	// LabelComponent 日志规则按组件分组时的组件标签
+	LabelComponent = "component"
+)
+
+// logRuleState 日志规则的匹配条件和各分组在时间窗口内的计数
+type logRuleState struct {
+	rule    *model.AlertRule
+	query   logstore.Query // 集群内没有主机时为nil，不匹配任何日志
+	window  time.Duration
+	groups  map[string]*logRuleGroup
+	version time.Time // 规则的更新时间，规则修改后重新计数
+}
+
+// logRuleGroup 日志规则的一个分组
+type logRuleGroup struct {
+	labels  promql.Labels
+	buckets []logCountBucket // 按时间递增
+	since   time.Time        // 开始满足条件的时间，用于 Duration 判断，零值表示不满足
+	sample  string           // 最近一条匹配日志的首行
+}
+
+// logCountBucket 时间桶内的匹配日志数
+type logCountBucket struct {
+	start time.Time
+	count int64
+}
+
+// logRuleEvaluator 按ID顺序读取新写入的日志，匹配各日志规则并计数；只由评估协程访问
+type logRuleEvaluator struct {
+	logRepo *repository.LogRepository
+	wake    chan struct{}
+	cursor  int64
+	gap     idGap
+	started bool
+	rules   map[uint]*logRuleState
+}
+
+// validateLogRule 校验日志规则，检索语法需能被正确解析；未设置比较操作符时匹配到日志即告警
+func validateLogRule(rule *model.AlertRule) error {
+	if _, err := logstore.ParseQuery(rule.LogQuery, LogSearchFields...); err != nil {
+		return fmt.Errorf("invalid log query: %v", err)
+	}
+
+	var levels []string
+	for _, level := range strings.Split(rule.LogLevels, ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, logparse.NormalizeLevel(level))
+		}
+	}
+	rule.LogLevels = strings.Join(levels, ",")
+
+	if rule.LogWindow < 0 || time.Duration(rule.LogWindow)*time.Second > maxLogRuleWindow {
+		return fmt.Errorf("log window must be between 0 and %d seconds", int(maxLogRuleWindow/time.Second))
+	}
+	switch rule.LogGroupBy {
+	case "", model.LogGroupByHost, model.LogGroupByService, model.LogGroupByComponent:
+	default:
+		return fmt.Errorf("invalid log group by: %s", rule.LogGroupBy)
+	}
+
+	if rule.Operator == "" {
+		rule.Operator = model.OpGreaterThan
+		rule.Threshold = 0
+	}
+	if !isValidOperator(rule.Operator) {
+		return fmt.Errorf("invalid operator: %s", rule.Operator)
+	}
+	if rule.MetricName == "" {
+		rule.MetricName = LogCountMetric
+	}
+	return nil
+}
+
+// StartLogEvaluator 启动日志规则评估：持续匹配新写入的日志，并定期按时间窗口内的日志数触发或解决告警
+func (s *AlertService) StartLogEvaluator(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultEvaluationInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.EvaluateLogRules(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.logEvaluator.wake:
+			}
+		}
+	}()
+}
+
+// NotifyLogsIngested 通知有新日志写入，立即评估日志规则
+func (s *AlertService) NotifyLogsIngested() {
+	select {
+	case s.logEvaluator.wake <- struct{}{}:
+	default:
+	}
+}
+
+// EvaluateLogRules 加载日志规则，匹配尚未处理的日志后评估全部日志规则
+func (s *AlertService) EvaluateLogRules(ctx context.Context) {
+	e := s.logEvaluator
+	if err := s.loadLogRules(); err != nil {
+		log.Printf("加载日志告警规则失败: %v", err)
+		return
+	}
+	if len(e.rules) == 0 {
+		// 没有日志规则时不读取日志，新增规则后从当前位置开始
+		e.started = false
+		return
+	}
+
+	for ctx.Err() == nil {
+		fetched, err := e.logRepo.ListAfter(e.cursor, logRuleBatchSize)
+		if err != nil {
+			log.Printf("读取待评估日志失败: %v", err)
+			break
+		}
+		records := e.gap.until(e.cursor, fetched)
+		if len(records) == 0 {
+			break
+		}
+		e.match(records, e.rules)
+		e.cursor = records[len(records)-1].ID
+		if len(records) < len(fetched) || len(fetched) < logRuleBatchSize {
+			break
+		}
+	}
+
+	now := time.Now()
+	for _, state := range e.rules {
+		if err := s.evaluateLogRule(ctx, state, now); err != nil {
+			log.Printf("评估告警规则 %d(%s) 失败: %v", state.rule.ID, state.rule.Name, err)
+		}
+	}
+}
+
+// loadLogRules 加载启用的日志规则；新增或修改的规则从时间窗口开始处重新统计已写入的日志
+func (s *AlertService) loadLogRules() error {
+	e := s.logEvaluator
+	rules, err := s.alertRuleRepo.FindLogRules()
+	if err != nil {
+		return err
+	}
+	if !e.started {
+		if e.cursor, err = e.logRepo.MaxID(); err != nil {
+			return err
+		}
+		e.started = true
+	}
+
+	active := make(map[uint]bool, len(rules))
+	for _, rule := range rules {
+		active[rule.ID] = true
+		// 每次重新构建匹配条件，集群内新增的主机随之生效
+		query, err := s.logRuleQuery(rule)
+		if err != nil {
+			log.Printf("加载日志告警规则 %d(%s) 失败: %v", rule.ID, rule.Name, err)
+			delete(e.rules, rule.ID)
+			continue
+		}
+		if state, ok := e.rules[rule.ID]; ok && state.version.Equal(rule.UpdatedAt) {
+			state.rule, state.query = rule, query
+			continue
+		}
+
+		window := time.Duration(rule.LogWindow) * time.Second
+		if window <= 0 {
+			window = defaultLogRuleWindow
+		}
+		state := &logRuleState{
+			rule:    rule,
+			query:   query,
+			window:  window,
+			groups:  make(map[string]*logRuleGroup),
+			version: rule.UpdatedAt,
+		}
+		if err := e.backfill(state); err != nil {
+			return err
+		}
+		e.rules[rule.ID] = state
+	}
+
+	for id := range e.rules {
+		if !active[id] {
+			delete(e.rules, id)
+		}
+	}
+	return nil
+}
+
+// logRuleQuery 构建日志规则的匹配条件，限定集群时只匹配集群内主机的日志，集群内没有主机时返回nil
+func (s *AlertService) logRuleQuery(rule *model.AlertRule) (logstore.Query, error) {
+	req := &LogSearchRequest{Query: rule.LogQuery}
+	if rule.ServiceID != nil {
+		req.ServiceID = int(*rule.ServiceID)
+	}
+	if rule.HostID != nil {
+		req.HostID = int(*rule.HostID)
+	}
+	if rule.LogLevels != "" {
+		req.Levels = strings.Split(rule.LogLevels, ",")
+	}
+	query, err := buildLogQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		query = &logstore.MatchAllQuery{}
+	}
+	if rule.ClusterID == nil {
+		return query, nil
+	}
+
+	hostIDs, err := s.alertRuleRepo.ListClusterHostIDs(*rule.ClusterID)
+	if err != nil || len(hostIDs) == 0 {
+		return nil, err
+	}
+	hosts := make([]logstore.Query, len(hostIDs))
+	for i, id := range hostIDs {
+		hosts[i] = logstore.Field("host_id", strconv.Itoa(id))
+	}
+	return logstore.And(query, logstore.Or(hosts...)), nil
+}
+
+// backfill 统计规则时间窗口内已处理过的日志
+func (e *logRuleEvaluator) backfill(state *logRuleState) error {
+	firstID, err := e.logRepo.FirstIDSince(time.Now().Add(-state.window))
+	if err != nil || firstID == 0 {
+		return err
+	}
+
+	rules := map[uint]*logRuleState{state.rule.ID: state}
+	cursor := firstID - 1
+	for cursor < e.cursor {
+		records, err := e.logRepo.ListAfter(cursor, logRuleBatchSize)
+		if err != nil {
+			return err
+		}
+		for i, record := range records {
+			if record.ID > e.cursor {
+				records = records[:i]
+				break
+			}
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		e.match(records, rules)
+		cursor = records[len(records)-1].ID
+	}
+	return nil
+}
+
+// match 将一批日志与各规则匹配，按日志时间计入对应分组的时间桶
+func (e *logRuleEvaluator) match(records []*model.LogRecord, rules map[uint]*logRuleState) {
+	docs := make([]*logstore.Document, len(records))
+	recordByID := make(map[int64]*model.LogRecord, len(records))
+	for i, record := range records {
+		docs[i] = logDocument(record)
+		recordByID[record.ID] = record
+	}
+	batch := logstore.NewBatch(docs)
+
+	for _, state := range rules {
+		if state.query == nil {
+			continue
+		}
+		for _, id := range batch.Match(state.query) {
+			record := recordByID[id]
+			labels := logRuleLabels(state.rule.LogGroupBy, record)
+			fingerprint := labels.Fingerprint()
+			group, ok := state.groups[fingerprint]
+			if !ok {
+				group = &logRuleGroup{labels: labels}
+				state.groups[fingerprint] = group
+			}
+			group.add(record.Timestamp)
+			group.sample, _, _ = strings.Cut(record.Message, "\n")
+		}
+	}
+}
+
+// logRuleLabels 日志所属分组的标签，不分组时为空；日志没有主机或服务时不设置对应的ID标签
+func logRuleLabels(groupBy string, record *model.LogRecord) promql.Labels {
+	labels := promql.Labels{}
+	setID := func(name string, id int) {
+		if id > 0 {
+			labels[name] = strconv.Itoa(id)
+		}
+	}
+	switch groupBy {
+	case model.LogGroupByHost:
+		setID(repository.LabelHostID, record.HostID)
+		labels[repository.LabelHostname] = record.Hostname
+	case model.LogGroupByService:
+		setID(repository.LabelServiceID, record.ServiceID)
+		labels[repository.LabelService] = record.ServiceName
+	case model.LogGroupByComponent:
+		setID(repository.LabelServiceID, record.ServiceID)
+		labels[repository.LabelService] = record.ServiceName
+		labels[LabelComponent] = record.ComponentType
+	}
+	return labels
+}
+
+// add 计入一条日志，日志时间乱序时插入对应的时间桶
+func (g *logRuleGroup) add(ts time.Time) {
+	start := ts.Truncate(logRuleBucket)
+	i := len(g.buckets)
+	for i > 0 && g.buckets[i-1].start.After(start) {
+		i--
+	}
+	if i > 0 && g.buckets[i-1].start.Equal(start) {
+		g.buckets[i-1].count++
+		return
+	}
+	g.buckets = append(g.buckets, logCountBucket{})
+	copy(g.buckets[i+1:], g.buckets[i:])
+	g.buckets[i] = logCountBucket{start: start, count: 1}
+}
+
+// count 删除时间窗口之前的时间桶，返回窗口内的日志数
+func (g *logRuleGroup) count(since time.Time) int64 {
+	expired := 0
+	for expired < len(g.buckets) && g.buckets[expired].start.Add(logRuleBucket).Before(since) {
+		expired++
+	}
+	g.buckets = g.buckets[expired:]
+
+	var total int64
+	for _, bucket := range g.buckets {
+		total += bucket.count
+	}
+	return total
+}
+
+// evaluateLogRule 按各分组时间窗口内的日志数触发告警，并解决不再满足条件的告警
+//
+// 不分组的规则始终按全部匹配日志计数，可使用 < 等操作符在日志数过少时告警；分组的规则只统计有匹配日志的分组。
+func (s *AlertService) evaluateLogRule(ctx context.Context, state *logRuleState, now time.Time) error {
+	rule := state.rule
+	if rule.LogGroupBy == "" && len(state.groups) == 0 && state.query != nil {
+		labels := promql.Labels{}
+		state.groups[labels.Fingerprint()] = &logRuleGroup{labels: labels}
+	}
+
+	firing := make(map[string]bool, len(state.groups))
+	for fingerprint, group := range state.groups {
+		count := group.count(now.Add(-state.window))
+		if count == 0 && rule.LogGroupBy != "" {
+			delete(state.groups, fingerprint)
+			continue
+		}
+		if !s.evaluateRule(rule, float64(count)) {
+			group.since = time.Time{}
+			continue
+		}
+
+		firing[fingerprint] = true
+		if group.since.IsZero() {
+			group.since = now
+		}
+		// 持续时间未达到要求时保持待定
+		if rule.Duration > 0 && now.Sub(group.since) < time.Duration(rule.Duration)*time.Second {
+			continue
+		}
+		if err := s.createLogAlertEvent(ctx, state, group, fingerprint, count, now); err != nil {
+			return err
+		}
+	}
+
+	return s.resolveInactiveAlerts(ctx, rule, firing)
+}
+
+// createLogAlertEvent 为日志规则的一个分组创建告警事件并发送通知，告警消息附带最近一条匹配日志的首行
+func (s *AlertService) createLogAlertEvent(ctx context.Context, state *logRuleState, group *logRuleGroup, fingerprint string, count int64, ts time.Time) error {
+	rule := state.rule
+
+	// 同一分组已有未解决的告警时不重复创建
+	existingAlert, err := s.alertEventRepo.FindOpenAlertByFingerprint(rule.ID, fingerprint)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if existingAlert != nil {
+		return nil
+	}
+
+	labelsJSON, err := json.Marshal(group.labels)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%s: %d matching logs in %s %s %.0f", rule.Name, count, state.window, rule.Operator, rule.Threshold)
+	if len(group.labels) > 0 {
+		message = fmt.Sprintf("%s: %s %d matching logs in %s %s %.0f",
+			rule.Name, group.labels.String(), count, state.window, rule.Operator, rule.Threshold)
+	}
+	if group.sample != "" {
+		message += ": " + group.sample
+	}
+
+	now := time.Now()
+	alertEvent := &model.AlertEvent{
+		AlertRuleID: rule.ID,
+		AlertName:   rule.Name,
+		ClusterID:   rule.ClusterID,
+		ServiceID:   labelUint(group.labels, repository.LabelServiceID),
+		HostID:      labelUint(group.labels, repository.LabelHostID),
+		Hostname:    group.labels[repository.LabelHostname],
+		ServiceName: group.labels[repository.LabelService],
+		MetricName:  LogCountMetric,
+		Labels:      string(labelsJSON),
+		Fingerprint: fingerprint,
+		MetricValue: float64(count),
+		Threshold:   rule.Threshold,
+		Operator:    string(rule.Operator),
+		Message:     truncate(message, 500),
+		Severity:    rule.Severity,
+		Status:      model.AlertStatusOpen,
+		TriggeredAt: ts,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if alertEvent.ServiceID == nil {
+		alertEvent.ServiceID = rule.ServiceID
+	}
+	if alertEvent.HostID == nil {
+		alertEvent.HostID = rule.HostID
+	}
+
+	s.applySilence(alertEvent)
+	alertEvent.GroupKey = s.dispatcher.GroupKey(rule, alertEvent)
+
+	if err := s.alertEventRepo.Create(alertEvent); err != nil {
+		return err
+	}
+
+	s.notifyAlertEvent(ctx, rule, alertEvent)
+	return nil
+}
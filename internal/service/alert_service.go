@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/TejParker/bigdata-manager/internal/config"
 	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/promql"
 	"github.com/TejParker/bigdata-manager/internal/repository"
-	"strconv"
-	"strings"
+	"log"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
@@ -18,21 +20,107 @@ type AlertService struct {
 	db              *gorm.DB
 	alertRuleRepo   *repository.AlertRuleRepository
 	alertEventRepo  *repository.AlertEventRepository
+	policyRepo      *repository.EscalationPolicyRepository
 	notificationSvc *NotificationService
+	silenceSvc      *SilenceService
+	dispatcher      *AlertDispatcher
+	queryEngine     *promql.Engine
+
+	// 表达式规则各序列首次满足条件的时间，用于 Duration 判断
+	pending   map[uint]map[string]time.Time
+	pendingMu sync.Mutex
+
+	logEvaluator *logRuleEvaluator
 }
 
 // NewAlertService 创建新的告警服务
-func NewAlertService(db *gorm.DB, notificationSvc *NotificationService) *AlertService {
+func NewAlertService(db *gorm.DB, cfg *config.Config, notificationSvc *NotificationService) *AlertService {
+	silenceSvc := NewSilenceService(db)
 	return &AlertService{
 		db:              db,
 		alertRuleRepo:   repository.NewAlertRuleRepository(db),
 		alertEventRepo:  repository.NewAlertEventRepository(db),
+		policyRepo:      repository.NewEscalationPolicyRepository(db),
 		notificationSvc: notificationSvc,
+		silenceSvc:      silenceSvc,
+		dispatcher:      NewAlertDispatcher(db, cfg.Alert, notificationSvc, silenceSvc),
+		queryEngine:     promql.NewEngine(repository.NewMetricRepository(db)),
+		pending:         make(map[uint]map[string]time.Time),
+		logEvaluator: &logRuleEvaluator{
+			logRepo: repository.NewLogRepository(db),
+			wake:    make(chan struct{}, 1),
+			rules:   make(map[uint]*logRuleState),
+		},
 	}
 }
 
+// validateAlertRule 校验告警规则并规范化分组标签，表达式规则需能被正确解析，引用的升级策略需存在
+func (s *AlertService) validateAlertRule(rule *model.AlertRule) error {
+	groupBy, err := normalizeGroupBy(rule.GroupBy)
+	if err != nil {
+		return err
+	}
+	rule.GroupBy = groupBy
+
+	if rule.EscalationPolicyID != nil && *rule.EscalationPolicyID == 0 {
+		rule.EscalationPolicyID = nil
+	}
+	if rule.EscalationPolicyID != nil {
+		if _, err := s.policyRepo.GetByID(*rule.EscalationPolicyID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("escalation policy %d not found", *rule.EscalationPolicyID)
+			}
+			return err
+		}
+	}
+
+	switch rule.RuleType {
+	case "":
+		rule.RuleType = model.AlertRuleTypeMetric
+	case model.AlertRuleTypeLog:
+		return validateLogRule(rule)
+	case model.AlertRuleTypeMetric:
+	default:
+		return fmt.Errorf("invalid rule type: %s", rule.RuleType)
+	}
+
+	if rule.Expression != "" {
+		if _, err := promql.ParseExpr(rule.Expression); err != nil {
+			return fmt.Errorf("invalid expression: %v", err)
+		}
+		if rule.Operator != "" && !isValidOperator(rule.Operator) {
+			return fmt.Errorf("invalid operator: %s", rule.Operator)
+		}
+		return nil
+	}
+
+	if rule.MetricName == "" {
+		return errors.New("metric name or expression is required")
+	}
+	if _, err := promql.ParseExpr(rule.MetricName); err != nil {
+		return fmt.Errorf("invalid metric name: %v", err)
+	}
+	if !isValidOperator(rule.Operator) {
+		return fmt.Errorf("invalid operator: %s", rule.Operator)
+	}
+	return nil
+}
+
+// isValidOperator 判断比较操作符是否受支持
+func isValidOperator(op model.ComparisonOperator) bool {
+	switch op {
+	case model.OpGreaterThan, model.OpGreaterThanOrEqual, model.OpLessThan,
+		model.OpLessThanOrEqual, model.OpEqual, model.OpNotEqual:
+		return true
+	}
+	return false
+}
+
 // CreateAlertRule 创建告警规则
 func (s *AlertService) CreateAlertRule(rule *model.AlertRule) error {
+	if err := s.validateAlertRule(rule); err != nil {
+		return err
+	}
 	rule.CreatedAt = time.Now()
 	rule.UpdatedAt = time.Now()
 	return s.alertRuleRepo.Create(rule)
@@ -40,6 +128,9 @@ func (s *AlertService) CreateAlertRule(rule *model.AlertRule) error {
 
 // UpdateAlertRule 更新告警规则
 func (s *AlertService) UpdateAlertRule(rule *model.AlertRule) error {
+	if err := s.validateAlertRule(rule); err != nil {
+		return err
+	}
 	rule.UpdatedAt = time.Now()
 	return s.alertRuleRepo.Update(rule)
 }
@@ -144,6 +235,10 @@ func (s *AlertService) createAlertEvent(ctx context.Context, rule *model.AlertRu
 		alertEvent.ClusterID = rule.ClusterID
 	}
 
+	// 命中静默的告警照常记录，但不发送通知
+	s.applySilence(alertEvent)
+	alertEvent.GroupKey = s.dispatcher.GroupKey(rule, alertEvent)
+
 	// 保存告警事件
 	err = s.alertEventRepo.Create(alertEvent)
 	if err != nil {
@@ -151,20 +246,36 @@ func (s *AlertService) createAlertEvent(ctx context.Context, rule *model.AlertRu
 	}
 
 	// 发送通知
-	if rule.NotificationIDs != "" {
-		notificationIDs := strings.Split(rule.NotificationIDs, ",")
-		for _, idStr := range notificationIDs {
-			id, err := strconv.ParseUint(idStr, 10, 32)
-			if err != nil {
-				continue
-			}
-			s.notificationSvc.SendAlertNotification(ctx, uint(id), alertEvent)
-		}
-	}
+	s.notifyAlertEvent(ctx, rule, alertEvent)
 
 	return nil
 }
 
+// notifyAlertEvent 将告警加入通知分组，由分组调度器合并发送
+func (s *AlertService) notifyAlertEvent(ctx context.Context, rule *model.AlertRule, alertEvent *model.AlertEvent) {
+	if rule.NotificationIDs == "" || alertEvent.SilenceID != nil {
+		return
+	}
+	s.dispatcher.Add(alertEvent)
+}
+
+// StartNotificationDispatcher 启动告警通知分组调度，ctx 取消后停止
+func (s *AlertService) StartNotificationDispatcher(ctx context.Context) {
+	s.dispatcher.Start(ctx)
+}
+
+// applySilence 检查告警事件是否命中生效中的静默，命中时记录静默ID
+func (s *AlertService) applySilence(alertEvent *model.AlertEvent) {
+	silence, err := s.silenceSvc.FindMatchingSilence(alertEvent, time.Now())
+	if err != nil {
+		log.Printf("查询告警静默失败: %v", err)
+		return
+	}
+	if silence != nil {
+		alertEvent.SilenceID = &silence.ID
+	}
+}
+
 // GetAlertEvent 获取告警事件
 func (s *AlertService) GetAlertEvent(id uint) (*model.AlertEvent, error) {
 	return s.alertEventRepo.GetByID(id)
@@ -192,7 +303,12 @@ func (s *AlertService) AcknowledgeAlertEvent(id uint, userID uint) error {
 	event.AcknowledgedBy = &userID
 	event.UpdatedAt = now
 
-	return s.alertEventRepo.Update(event)
+	if err := s.alertEventRepo.Update(event); err != nil {
+		return err
+	}
+
+	s.notifyFollowUp(context.Background(), nil, NotificationKindAcknowledged, []*model.AlertEvent{event})
+	return nil
 }
 
 // ResolveAlertEvent 解决告警事件
@@ -211,7 +327,39 @@ func (s *AlertService) ResolveAlertEvent(id uint) error {
 	event.ResolvedAt = &now
 	event.UpdatedAt = now
 
-	return s.alertEventRepo.Update(event)
+	if err := s.alertEventRepo.Update(event); err != nil {
+		return err
+	}
+
+	s.notifyFollowUp(context.Background(), nil, NotificationKindResolved, []*model.AlertEvent{event})
+	return nil
+}
+
+// notifyFollowUp 向已收到告警通知的渠道发送确认、恢复后续消息，rule 为nil时按事件加载规则
+func (s *AlertService) notifyFollowUp(ctx context.Context, rule *model.AlertRule, kind NotificationKind, events []*model.AlertEvent) {
+	// 只通知已经发出过告警的事件，静默或尚在分组等待中的事件不发送后续消息
+	notified := make([]*model.AlertEvent, 0, len(events))
+	for _, event := range events {
+		if event.SilenceID == nil && (event.NotificationCount > 0 || event.EscalationLevel > 0) {
+			notified = append(notified, event)
+		}
+	}
+	if len(notified) == 0 {
+		return
+	}
+
+	if rule == nil {
+		var err error
+		rule, err = s.alertRuleRepo.GetByID(notified[0].AlertRuleID)
+		if err != nil {
+			log.Printf("加载告警规则失败 (规则ID: %d): %v", notified[0].AlertRuleID, err)
+			return
+		}
+	}
+
+	for _, notificationID := range parseNotificationIDs(rule.NotificationIDs) {
+		s.notificationSvc.SendFollowUpNotification(ctx, notificationID, kind, notified)
+	}
 }
 
 // GetAlertStatistics 获取告警统计信息
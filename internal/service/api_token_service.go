@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/auth"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// apiTokenPrefixLength 保存的令牌前缀长度，包含 auth.APITokenPrefix
+const apiTokenPrefixLength = 12
+
+// API令牌错误
+var (
+	ErrTokenNameRequired  = errors.New("token name is required")
+	ErrTokenExpiresInPast = errors.New("token expiration must be in the future")
+	ErrNotServiceAccount  = errors.New("user is not a service account")
+)
+
+// APITokenService 用户和服务账号的API令牌管理服务
+type APITokenService struct {
+	repo     *repository.APITokenRepository
+	userRepo *repository.UserRepository
+}
+
+// NewAPITokenService 创建API令牌管理服务
+func NewAPITokenService(db *gorm.DB) *APITokenService {
+	return &APITokenService{
+		repo:     repository.NewAPITokenRepository(db),
+		userRepo: repository.NewUserRepository(db),
+	}
+}
+
+// CreateToken 为用户创建API令牌，返回令牌明文，令牌明文只在创建时返回一次
+//
+// token.Privileges 为空时令牌拥有用户的全部权限，否则只能使用其中的权限。
+func (s *APITokenService) CreateToken(token *model.APIToken) (string, error) {
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return "", ErrTokenNameRequired
+	}
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now()) {
+		return "", ErrTokenExpiresInPast
+	}
+
+	token.Privileges = uniqueNames(token.Privileges)
+	if len(token.Privileges) > 0 {
+		count, err := s.repo.CountPrivileges(token.Privileges)
+		if err != nil {
+			return "", err
+		}
+		if count != int64(len(token.Privileges)) {
+			return "", ErrPrivilegeNotFound
+		}
+	}
+
+	raw, hash, err := auth.GenerateAPIToken()
+	if err != nil {
+		return "", err
+	}
+	token.TokenHash = hash
+	token.Prefix = raw[:apiTokenPrefixLength]
+	token.LastUsedAt = nil
+	token.RevokedAt = nil
+	if err := s.repo.Create(token); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// CreateServiceAccountToken 为服务账号创建API令牌，不能为普通用户创建
+func (s *APITokenService) CreateServiceAccountToken(token *model.APIToken) (string, error) {
+	user, err := s.userRepo.GetUser(token.UserID)
+	if err != nil {
+		return "", err
+	}
+	if user.AuthSource != model.UserAuthSourceService {
+		return "", ErrNotServiceAccount
+	}
+	return s.CreateToken(token)
+}
+
+// ListTokens 列出API令牌，包括已吊销的令牌
+func (s *APITokenService) ListTokens(page, pageSize int, filters map[string]interface{}) ([]*model.APIToken, int64, error) {
+	return s.repo.List(page, pageSize, filters)
+}
+
+// GetToken 获取API令牌
+func (s *APITokenService) GetToken(id uint) (*model.APIToken, error) {
+	return s.repo.GetByID(id)
+}
+
+// RevokeToken 吊销API令牌，吊销后立即失效
+func (s *APITokenService) RevokeToken(id uint) error {
+	return s.repo.Revoke(id)
+}
+
+// uniqueNames 去除空白、重复的名称
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// auditBatchSize 导出和校验审计记录时每批读取的行数
+const auditBatchSize = 1000
+
+// 审计记录各字段的最大长度，超出部分截断后再计算哈希
+const (
+	auditMaxFieldLength   = 255
+	auditMaxParamsLength  = 16000
+	auditMaxMessageLength = 1000
+)
+
+// auditExportColumns 导出审计记录的列，NDJSON 格式中为对象的字段
+var auditExportColumns = []string{
+	"id", "created_at", "user_id", "username", "auth_method", "client_ip", "method", "path", "action",
+	"resource_type", "resource_id", "params", "result", "status_code", "message", "prev_hash", "hash",
+}
+
+// AuditVerifyResult 审计记录哈希链的校验结果
+type AuditVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`             // 已校验的记录数
+	BrokenID uint   `json:"broken_id,omitempty"` // 第一条校验失败的记录
+	Reason   string `json:"reason,omitempty"`
+}
+
+// AuditService 审计记录服务，记录按写入顺序组成哈希链
+//
+// 哈希链的末尾缓存在内存中，写入由互斥锁串行化，要求只有一个服务实例写入审计记录。
+type AuditService struct {
+	cfg  *config.Config
+	repo *repository.AuditLogRepository
+
+	mu       sync.Mutex
+	lastHash string
+	loaded   bool // lastHash 是否已从数据库加载
+}
+
+// NewAuditService 创建审计记录服务
+func NewAuditService(db *gorm.DB, cfg *config.Config) *AuditService {
+	return &AuditService{
+		cfg:  cfg,
+		repo: repository.NewAuditLogRepository(db),
+	}
+}
+
+// Record 追加审计记录，以上一条记录的哈希和本条记录的内容计算哈希
+func (s *AuditService) Record(entry *model.AuditLog) error {
+	entry.ID = 0
+	// 数据库中的时间精确到毫秒，截断后读出的记录才能重新计算出相同的哈希
+	entry.CreatedAt = time.Now().Truncate(time.Millisecond)
+	entry.Username = truncate(entry.Username, 64)
+	entry.ClientIP = truncate(entry.ClientIP, 64)
+	entry.Path = truncate(entry.Path, auditMaxFieldLength)
+	entry.Action = truncate(entry.Action, auditMaxFieldLength)
+	entry.ResourceType = truncate(entry.ResourceType, 64)
+	entry.ResourceID = truncate(entry.ResourceID, 64)
+	entry.Params = truncate(entry.Params, auditMaxParamsLength)
+	entry.Message = truncate(entry.Message, auditMaxMessageLength)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		hash, err := s.repo.LastHash()
+		if err != nil {
+			return err
+		}
+		s.lastHash, s.loaded = hash, true
+	}
+
+	entry.PrevHash = s.lastHash
+	entry.Hash = auditHash(entry)
+	if err := s.repo.Create(entry); err != nil {
+		// 写入结果不确定，下次写入前重新从数据库加载链尾
+		s.loaded = false
+		return err
+	}
+	s.lastHash = entry.Hash
+	return nil
+}
+
+// ListAuditLogs 列出审计记录
+func (s *AuditService) ListAuditLogs(page, pageSize int, filters map[string]interface{}) ([]*model.AuditLog, int64, error) {
+	return s.repo.List(page, pageSize, filters)
+}
+
+// GetAuditLog 获取审计记录
+func (s *AuditService) GetAuditLog(id uint) (*model.AuditLog, error) {
+	return s.repo.GetByID(id)
+}
+
+// ExportAuditLogs 按ID顺序分批导出符合条件的审计记录，最多导出 export.max_rows 行，返回导出的行数
+func (s *AuditService) ExportAuditLogs(w io.Writer, format model.ExportFormat, filters map[string]interface{}) (int64, error) {
+	enc, err := newExportEncoder(format, w, auditExportColumns)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	var afterID uint
+	for {
+		entries, err := s.repo.ListAfter(afterID, auditBatchSize, filters)
+		if err != nil {
+			return rows, err
+		}
+		for _, entry := range entries {
+			if s.cfg.Export.MaxRows > 0 && rows >= s.cfg.Export.MaxRows {
+				return rows, enc.flush()
+			}
+			err := enc.encode([]interface{}{
+				int64(entry.ID), entry.CreatedAt, entry.UserID, entry.Username, entry.AuthMethod, entry.ClientIP,
+				entry.Method, entry.Path, entry.Action, entry.ResourceType, entry.ResourceID, entry.Params,
+				string(entry.Result), entry.StatusCode, entry.Message, entry.PrevHash, entry.Hash,
+			})
+			if err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if len(entries) < auditBatchSize {
+			return rows, enc.flush()
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+}
+
+// VerifyAuditLogs 从第一条记录开始校验哈希链，记录被修改、删除或插入时返回第一条校验失败的记录
+func (s *AuditService) VerifyAuditLogs() (*AuditVerifyResult, error) {
+	result := &AuditVerifyResult{Valid: true}
+	prevHash := ""
+	var afterID uint
+	for {
+		entries, err := s.repo.ListAfter(afterID, auditBatchSize, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch {
+			case entry.PrevHash != prevHash:
+				result.Valid, result.BrokenID = false, entry.ID
+				result.Reason = "prev_hash does not match the previous entry, entries were deleted or reordered"
+				return result, nil
+			case auditHash(entry) != entry.Hash:
+				result.Valid, result.BrokenID = false, entry.ID
+				result.Reason = "hash does not match the entry content, the entry was modified"
+				return result, nil
+			}
+			prevHash = entry.Hash
+			result.Checked++
+		}
+		if len(entries) < auditBatchSize {
+			return result, nil
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+}
+
+// auditHash 计算审计记录的哈希：SHA-256(上一条记录的哈希 + 换行 + 记录内容的JSON)
+func auditHash(entry *model.AuditLog) string {
+	content, _ := json.Marshal([]interface{}{
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano), entry.UserID, entry.Username, entry.AuthMethod,
+		entry.ClientIP, entry.Method, entry.Path, entry.Action, entry.ResourceType, entry.ResourceID,
+		entry.Params, string(entry.Result), entry.StatusCode, entry.Message,
+	})
+	sum := sha256.Sum256(append([]byte(entry.PrevHash+"\n"), content...))
+	return hex.EncodeToString(sum[:])
+}
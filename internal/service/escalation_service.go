@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// defaultEscalationInterval 检查未确认告警是否需要升级的间隔
+const defaultEscalationInterval = 30 * time.Second
+
+// EscalationService 处理告警升级策略和值班表
+type EscalationService struct {
+	db              *gorm.DB
+	policyRepo      *repository.EscalationPolicyRepository
+	scheduleRepo    *repository.OnCallScheduleRepository
+	alertRuleRepo   *repository.AlertRuleRepository
+	alertEventRepo  *repository.AlertEventRepository
+	notificationSvc *NotificationService
+	silenceSvc      *SilenceService
+}
+
+// NewEscalationService 创建新的升级服务
+func NewEscalationService(db *gorm.DB, notificationSvc *NotificationService) *EscalationService {
+	return &EscalationService{
+		db:              db,
+		policyRepo:      repository.NewEscalationPolicyRepository(db),
+		scheduleRepo:    repository.NewOnCallScheduleRepository(db),
+		alertRuleRepo:   repository.NewAlertRuleRepository(db),
+		alertEventRepo:  repository.NewAlertEventRepository(db),
+		notificationSvc: notificationSvc,
+		silenceSvc:      NewSilenceService(db),
+	}
+}
+
+// validateEscalationPolicy 校验升级策略，步骤按 Position 排序后延迟不能递减
+func (s *EscalationService) validateEscalationPolicy(policy *model.EscalationPolicy) error {
+	if policy.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(policy.Steps) == 0 {
+		return errors.New("at least one escalation step is required")
+	}
+
+	policy.SortSteps()
+	for i := range policy.Steps {
+		step := &policy.Steps[i]
+		step.Position = i
+		if step.Delay < 0 {
+			return fmt.Errorf("step %d: delay must not be negative", i)
+		}
+		if i > 0 && step.Delay < policy.Steps[i-1].Delay {
+			return fmt.Errorf("step %d: delay must not be less than the previous step", i)
+		}
+		if len(parseNotificationIDs(step.NotificationIDs)) == 0 {
+			return fmt.Errorf("step %d: notification_ids is required", i)
+		}
+		if step.OnCallScheduleID != nil {
+			if _, err := s.scheduleRepo.GetByID(*step.OnCallScheduleID); err != nil {
+				return fmt.Errorf("step %d: on-call schedule %d not found", i, *step.OnCallScheduleID)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateEscalationPolicy 创建升级策略
+func (s *EscalationService) CreateEscalationPolicy(policy *model.EscalationPolicy) error {
+	if err := s.validateEscalationPolicy(policy); err != nil {
+		return err
+	}
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = time.Now()
+	return s.policyRepo.Create(policy)
+}
+
+// UpdateEscalationPolicy 更新升级策略
+func (s *EscalationService) UpdateEscalationPolicy(policy *model.EscalationPolicy) error {
+	if err := s.validateEscalationPolicy(policy); err != nil {
+		return err
+	}
+	policy.UpdatedAt = time.Now()
+	return s.policyRepo.Update(policy)
+}
+
+// DeleteEscalationPolicy 删除升级策略，仍被告警规则引用时不允许删除
+func (s *EscalationService) DeleteEscalationPolicy(id uint) error {
+	var count int64
+	if err := s.db.Model(&model.AlertRule{}).Where("escalation_policy_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("escalation policy is used by %d alert rules", count)
+	}
+	return s.policyRepo.Delete(id)
+}
+
+// GetEscalationPolicy 获取升级策略
+func (s *EscalationService) GetEscalationPolicy(id uint) (*model.EscalationPolicy, error) {
+	return s.policyRepo.GetByID(id)
+}
+
+// ListEscalationPolicies 列出升级策略
+func (s *EscalationService) ListEscalationPolicies(page, pageSize int, filters map[string]interface{}) ([]*model.EscalationPolicy, int64, error) {
+	return s.policyRepo.List(page, pageSize, filters)
+}
+
+// validateOnCallSchedule 校验值班表
+func (s *EscalationService) validateOnCallSchedule(schedule *model.OnCallSchedule) error {
+	if schedule.Name == "" {
+		return errors.New("name is required")
+	}
+	if schedule.RotationHours <= 0 {
+		return errors.New("rotation_hours must be greater than 0")
+	}
+	if schedule.RotationStart.IsZero() {
+		return errors.New("rotation_start is required")
+	}
+	if len(schedule.Participants) == 0 {
+		return errors.New("at least one participant is required")
+	}
+	for i := range schedule.Participants {
+		if err := validateOnCallContact(&schedule.Participants[i].OnCallContact); err != nil {
+			return fmt.Errorf("participant %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// validateOnCallContact 校验值班联系人，至少需要一种联系方式
+func validateOnCallContact(contact *model.OnCallContact) error {
+	if contact.Name == "" {
+		return errors.New("name is required")
+	}
+	if contact.Email == "" && contact.Phone == "" {
+		return errors.New("email or phone is required")
+	}
+	return nil
+}
+
+// CreateOnCallSchedule 创建值班表
+func (s *EscalationService) CreateOnCallSchedule(schedule *model.OnCallSchedule) error {
+	if err := s.validateOnCallSchedule(schedule); err != nil {
+		return err
+	}
+	schedule.Overrides = nil
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = time.Now()
+	return s.scheduleRepo.Create(schedule)
+}
+
+// UpdateOnCallSchedule 更新值班表
+func (s *EscalationService) UpdateOnCallSchedule(schedule *model.OnCallSchedule) error {
+	if err := s.validateOnCallSchedule(schedule); err != nil {
+		return err
+	}
+	schedule.UpdatedAt = time.Now()
+	return s.scheduleRepo.Update(schedule)
+}
+
+// DeleteOnCallSchedule 删除值班表，仍被升级步骤引用时不允许删除
+func (s *EscalationService) DeleteOnCallSchedule(id uint) error {
+	var count int64
+	if err := s.db.Model(&model.EscalationStep{}).Where("on_call_schedule_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("on-call schedule is used by %d escalation steps", count)
+	}
+	return s.scheduleRepo.Delete(id)
+}
+
+// GetOnCallSchedule 获取值班表
+func (s *EscalationService) GetOnCallSchedule(id uint) (*model.OnCallSchedule, error) {
+	return s.scheduleRepo.GetByID(id)
+}
+
+// ListOnCallSchedules 列出值班表
+func (s *EscalationService) ListOnCallSchedules(page, pageSize int, filters map[string]interface{}) ([]*model.OnCallSchedule, int64, error) {
+	return s.scheduleRepo.List(page, pageSize, filters)
+}
+
+// CreateOnCallOverride 为值班表添加替换
+func (s *EscalationService) CreateOnCallOverride(override *model.OnCallOverride) error {
+	if _, err := s.scheduleRepo.GetByID(override.ScheduleID); err != nil {
+		return err
+	}
+	if err := validateOnCallContact(&override.OnCallContact); err != nil {
+		return err
+	}
+	if !override.EndsAt.After(override.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	override.CreatedAt = time.Now()
+	return s.scheduleRepo.CreateOverride(override)
+}
+
+// DeleteOnCallOverride 删除值班替换
+func (s *EscalationService) DeleteOnCallOverride(scheduleID, overrideID uint) error {
+	return s.scheduleRepo.DeleteOverride(scheduleID, overrideID)
+}
+
+// CurrentOnCall 获取值班表在指定时间的值班人员
+func (s *EscalationService) CurrentOnCall(scheduleID uint, now time.Time) (*model.OnCallContact, error) {
+	schedule, err := s.scheduleRepo.GetByID(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.CurrentOnCall(now), nil
+}
+
+// Start 启动未确认告警的定时升级检查，ctx 取消后停止
+func (s *EscalationService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultEscalationInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := s.Escalate(ctx, now); err != nil {
+					log.Printf("告警升级检查失败: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// escalationBatch 同一规则在同一升级步骤的待通知告警
+type escalationBatch struct {
+	step   *model.EscalationStep
+	level  int
+	events []*model.AlertEvent
+}
+
+// Escalate 对仍处于OPEN状态的告警执行已到期的升级步骤，告警被确认或解决后不再升级
+func (s *EscalationService) Escalate(ctx context.Context, now time.Time) error {
+	events, err := s.alertEventRepo.ListOpenEscalatingAlerts()
+	if err != nil {
+		return err
+	}
+	events, err = s.silenceSvc.FilterUnsilenced(events, now)
+	if err != nil {
+		return err
+	}
+
+	rules := make(map[uint]*model.AlertRule)
+	policies := make(map[uint]*model.EscalationPolicy)
+	batches := make(map[string]*escalationBatch)
+	var keys []string
+
+	for _, event := range events {
+		rule, ok := rules[event.AlertRuleID]
+		if !ok {
+			rule, err = s.alertRuleRepo.GetByID(event.AlertRuleID)
+			if err != nil {
+				log.Printf("查询告警规则 %d 失败: %v", event.AlertRuleID, err)
+				continue
+			}
+			rules[event.AlertRuleID] = rule
+		}
+		if rule.EscalationPolicyID == nil {
+			continue
+		}
+
+		policy, ok := policies[*rule.EscalationPolicyID]
+		if !ok {
+			policy, err = s.policyRepo.GetByID(*rule.EscalationPolicyID)
+			if err != nil {
+				log.Printf("查询升级策略 %d 失败: %v", *rule.EscalationPolicyID, err)
+				continue
+			}
+			policies[*rule.EscalationPolicyID] = policy
+		}
+
+		// 找到已到期的最后一个步骤，跳过的中间步骤不再补发
+		elapsed := now.Sub(event.TriggeredAt)
+		reached := -1
+		for i, step := range policy.Steps {
+			if elapsed >= time.Duration(step.Delay)*time.Minute {
+				reached = i
+			}
+		}
+		if reached < event.EscalationLevel {
+			continue
+		}
+
+		key := fmt.Sprintf("%d/%d", rule.ID, reached)
+		batch, ok := batches[key]
+		if !ok {
+			batch = &escalationBatch{step: &policy.Steps[reached], level: reached + 1}
+			batches[key] = batch
+			keys = append(keys, key)
+		}
+		batch.events = append(batch.events, event)
+	}
+
+	for _, key := range keys {
+		s.sendEscalation(ctx, batches[key], now)
+	}
+	return nil
+}
+
+// sendEscalation 发送一个升级步骤的合并通知并记录升级进度
+func (s *EscalationService) sendEscalation(ctx context.Context, batch *escalationBatch, now time.Time) {
+	var onCall *model.OnCallContact
+	if batch.step.OnCallScheduleID != nil {
+		contact, err := s.CurrentOnCall(*batch.step.OnCallScheduleID, now)
+		if err != nil {
+			log.Printf("查询值班表 %d 失败: %v", *batch.step.OnCallScheduleID, err)
+		}
+		onCall = contact
+	}
+
+	for _, id := range parseNotificationIDs(batch.step.NotificationIDs) {
+		s.notificationSvc.SendEscalationNotification(ctx, id, batch.events, onCall)
+	}
+
+	ids := make([]uint, 0, len(batch.events))
+	for _, event := range batch.events {
+		ids = append(ids, event.ID)
+	}
+	if err := s.alertEventRepo.UpdateEscalationLevel(ids, batch.level); err != nil {
+		log.Printf("更新告警升级进度失败: %v", err)
+	}
+}
+
+// parseNotificationIDs 解析逗号分隔的通知ID，忽略无效项
+func parseNotificationIDs(value string) []uint {
+	var ids []uint
+	for _, idStr := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(idStr), 10, 32)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
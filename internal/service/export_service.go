@@ -0,0 +1,625 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/logstore"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// 导出默认参数
+const (
+	defaultExportDir       = "./data/export"
+	defaultExportWorkers   = 2
+	defaultExportBatchSize = 5000
+	defaultExportExpire    = 24 * time.Hour
+
+	exportPollInterval     = 10 * time.Second
+	exportProgressInterval = 5 * time.Second
+	exportCleanupInterval  = 10 * time.Minute
+	exportBufferSize       = 256 * 1024
+)
+
+// 导出错误
+var (
+	ErrExportNotReady = errors.New("export is not finished")
+
+	// errExportLimit 达到最大导出行数
+	errExportLimit = errors.New("export row limit reached")
+)
+
+// 导出文件的列，NDJSON 格式中为对象的字段
+var (
+	logExportColumns    = []string{"id", "timestamp", "host_id", "hostname", "service_id", "service_name", "component_id", "component_type", "log_level", "logger", "thread", "message"}
+	metricExportColumns = []string{"id", "timestamp", "host_id", "hostname", "cluster_id", "service_id", "service_name", "metric_name", "value"}
+)
+
+// ExportParams 导出条件，日志导出的条件与日志查询相同，指标导出使用主机、集群、服务、指标名和时间范围
+type ExportParams struct {
+	Query       string     `json:"q,omitempty"` // 日志检索语法
+	HostID      int        `json:"host_id,omitempty"`
+	ServiceID   int        `json:"service_id,omitempty"`
+	ComponentID int        `json:"component_id,omitempty"`
+	ClusterID   int        `json:"cluster_id,omitempty"`
+	Levels      []string   `json:"log_levels,omitempty"`
+	MetricNames []string   `json:"metric_names,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+
+	// Scope 创建任务的用户有权查看的范围，为nil时不限定
+	Scope *AccessScope `json:"scope,omitempty"`
+}
+
+// logSearchRequest 转换为日志检索条件
+func (p *ExportParams) logSearchRequest() *LogSearchRequest {
+	return &LogSearchRequest{
+		Query:       p.Query,
+		HostID:      p.HostID,
+		ServiceID:   p.ServiceID,
+		ComponentID: p.ComponentID,
+		Levels:      p.Levels,
+		Scope:       p.Scope,
+	}
+}
+
+// exportEncoder 按导出格式写入行
+type exportEncoder struct {
+	csv     *csv.Writer
+	json    *json.Encoder
+	columns []string
+}
+
+// newExportEncoder 创建导出编码器，CSV 格式先写入表头
+func newExportEncoder(format model.ExportFormat, w io.Writer, columns []string) (*exportEncoder, error) {
+	e := &exportEncoder{columns: columns}
+	if format == model.ExportFormatNDJSON {
+		e.json = json.NewEncoder(w)
+		e.json.SetEscapeHTML(false)
+		return e, nil
+	}
+	e.csv = csv.NewWriter(w)
+	return e, e.csv.Write(columns)
+}
+
+// encode 写入一行，values 与列一一对应
+func (e *exportEncoder) encode(values []interface{}) error {
+	if e.json != nil {
+		obj := make(map[string]interface{}, len(values))
+		for i, value := range values {
+			obj[e.columns[i]] = value
+		}
+		return e.json.Encode(obj)
+	}
+
+	row := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case string:
+			row[i] = v
+		case int:
+			row[i] = strconv.Itoa(v)
+		case int64:
+			row[i] = strconv.FormatInt(v, 10)
+		case float64:
+			row[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			row[i] = v.Format(time.RFC3339Nano)
+		default:
+			row[i] = fmt.Sprint(v)
+		}
+	}
+	return e.csv.Write(row)
+}
+
+// flush 将缓冲的 CSV 行写入下层
+func (e *exportEncoder) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// ExportService 日志和指标导出服务，导出任务由后台工作协程按ID顺序分批读取数据写入文件，不在内存中保存全部结果
+type ExportService struct {
+	cfg        *config.Config
+	repo       *repository.ExportJobRepository
+	logRepo    *repository.LogRepository
+	metricRepo *repository.MetricRepository
+	dir        string
+	batchSize  int
+	wake       chan struct{}
+
+	mu      sync.Mutex
+	running map[uint]context.CancelFunc // 导出中的任务，删除任务时取消
+}
+
+// NewExportService 创建导出服务
+func NewExportService(db *gorm.DB, cfg *config.Config) *ExportService {
+	s := &ExportService{
+		cfg:        cfg,
+		repo:       repository.NewExportJobRepository(db),
+		logRepo:    repository.NewLogRepository(db),
+		metricRepo: repository.NewMetricRepository(db),
+		dir:        cfg.Export.Dir,
+		batchSize:  cfg.Export.BatchSize,
+		running:    make(map[uint]context.CancelFunc),
+	}
+	if s.dir == "" {
+		s.dir = defaultExportDir
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultExportBatchSize
+	}
+	s.wake = make(chan struct{}, s.workers())
+	return s
+}
+
+// workers 同时执行的导出任务数
+func (s *ExportService) workers() int {
+	if s.cfg.Export.Workers > 0 {
+		return s.cfg.Export.Workers
+	}
+	return defaultExportWorkers
+}
+
+// expire 导出文件保留时长
+func (s *ExportService) expire() time.Duration {
+	if s.cfg.Export.ExpireHours > 0 {
+		return time.Duration(s.cfg.Export.ExpireHours) * time.Hour
+	}
+	return defaultExportExpire
+}
+
+// Start 恢复进程退出时中断的任务，启动导出工作协程和过期文件清理，ctx 取消后停止
+func (s *ExportService) Start(ctx context.Context) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		log.Printf("创建导出目录失败，导出任务将不会执行: %v", err)
+		return
+	}
+	if n, err := s.repo.RequeueRunning(); err != nil {
+		log.Printf("恢复中断的导出任务失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已重新排队 %d 个中断的导出任务", n)
+	}
+
+	for i := 0; i < s.workers(); i++ {
+		go s.work(ctx)
+	}
+
+	go func() {
+		ticker := time.NewTicker(exportCleanupInterval)
+		defer ticker.Stop()
+		for {
+			s.cleanup()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CreateExportJob 创建导出任务，日志检索语法错误时返回 *logstore.SyntaxError
+func (s *ExportService) CreateExportJob(source model.ExportSource, format model.ExportFormat, gzipped bool, params *ExportParams, userID uint) (*model.ExportJob, error) {
+	switch source {
+	case model.ExportSourceLog:
+		if _, err := buildLogQuery(params.logSearchRequest()); err != nil {
+			return nil, err
+		}
+	case model.ExportSourceMetric:
+	default:
+		return nil, fmt.Errorf("unsupported export source: %s", source)
+	}
+	if format != model.ExportFormatCSV && format != model.ExportFormatNDJSON {
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+	if params.StartTime != nil && params.EndTime != nil && params.EndTime.Before(*params.StartTime) {
+		return nil, errors.New("end time is before start time")
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	job := &model.ExportJob{
+		Source:    source,
+		Format:    format,
+		Gzip:      gzipped,
+		Params:    string(data),
+		Status:    model.ExportJobPending,
+		CreatedBy: userID,
+	}
+	if err := s.repo.Create(job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// GetExportJob 获取导出任务
+func (s *ExportService) GetExportJob(id uint) (*model.ExportJob, error) {
+	return s.repo.GetByID(id)
+}
+
+// ListExportJobs 列出导出任务
+func (s *ExportService) ListExportJobs(page, pageSize int, filters map[string]interface{}) ([]*model.ExportJob, int64, error) {
+	return s.repo.List(page, pageSize, filters)
+}
+
+// DeleteExportJob 删除导出任务及导出文件，导出中的任务被取消
+func (s *ExportService) DeleteExportJob(job *model.ExportJob) error {
+	s.mu.Lock()
+	if cancel, ok := s.running[job.ID]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	if err := s.repo.Delete(job.ID); err != nil {
+		return err
+	}
+	if job.FileName != "" {
+		if err := os.Remove(filepath.Join(s.dir, job.FileName)); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除导出文件 %s 失败: %v", job.FileName, err)
+		}
+	}
+	return nil
+}
+
+// ExportFilePath 导出完成的任务的文件路径，任务未完成时返回 ErrExportNotReady
+func (s *ExportService) ExportFilePath(job *model.ExportJob) (string, error) {
+	if job.Status != model.ExportJobSucceeded || job.FileName == "" {
+		return "", ErrExportNotReady
+	}
+	return filepath.Join(s.dir, job.FileName), nil
+}
+
+// work 依次领取并执行待执行的任务，没有任务时等待新任务或定期检查
+func (s *ExportService) work(ctx context.Context) {
+	ticker := time.NewTicker(exportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := s.repo.ClaimNext()
+			if err != nil {
+				log.Printf("领取导出任务失败: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			s.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// run 执行导出任务，先写入临时文件，完成后重命名；任务被取消或服务停止时不更新任务状态
+func (s *ExportService) run(ctx context.Context, job *model.ExportJob) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.running[job.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	ext := string(job.Format)
+	if job.Gzip {
+		ext += ".gz"
+	}
+	job.FileName = fmt.Sprintf("%s-export-%d-%s.%s", strings.ToLower(string(job.Source)), job.ID, job.CreatedAt.Format("20060102150405"), ext)
+	path := filepath.Join(s.dir, job.FileName)
+
+	rows, size, err := s.writeFile(jobCtx, job, path+".tmp")
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.expire())
+	job.RowCount = rows
+	job.FinishedAt = &now
+	job.ExpiresAt = &expiresAt
+	if err != nil {
+		log.Printf("导出任务 %d 失败: %v", job.ID, err)
+		job.Status = model.ExportJobFailed
+		job.Error = err.Error()
+		job.FileName = ""
+	} else {
+		job.Status = model.ExportJobSucceeded
+		job.FileSize = size
+	}
+	if err := s.repo.Update(job); err != nil {
+		log.Printf("更新导出任务 %d 失败: %v", job.ID, err)
+	}
+}
+
+// writeFile 将导出结果写入文件，返回导出的行数和文件大小
+func (s *ExportService) writeFile(ctx context.Context, job *model.ExportJob, path string) (int64, int64, error) {
+	var params ExportParams
+	if err := json.Unmarshal([]byte(job.Params), &params); err != nil {
+		return 0, 0, fmt.Errorf("invalid export params: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriterSize(f, exportBufferSize)
+	var w io.Writer = buf
+	var gz *gzip.Writer
+	if job.Gzip {
+		gz = gzip.NewWriter(buf)
+		w = gz
+	}
+
+	columns := logExportColumns
+	if job.Source == model.ExportSourceMetric {
+		columns = metricExportColumns
+	}
+	enc, err := newExportEncoder(job.Format, w, columns)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var rows int64
+	lastProgress := time.Now()
+	emit := func(values []interface{}) error {
+		if s.cfg.Export.MaxRows > 0 && rows >= s.cfg.Export.MaxRows {
+			return errExportLimit
+		}
+		if err := enc.encode(values); err != nil {
+			return err
+		}
+		rows++
+		return nil
+	}
+	// progress 每批数据写入后检查是否取消，并定期更新进度
+	progress := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if time.Since(lastProgress) >= exportProgressInterval {
+			lastProgress = time.Now()
+			if err := s.repo.UpdateProgress(job.ID, rows); err != nil {
+				log.Printf("更新导出任务 %d 进度失败: %v", job.ID, err)
+			}
+		}
+		return nil
+	}
+
+	if job.Source == model.ExportSourceMetric {
+		err = s.exportMetrics(&params, emit, progress)
+	} else {
+		err = s.exportLogs(&params, emit, progress)
+	}
+	if errors.Is(err, errExportLimit) {
+		job.Truncated = true
+		err = nil
+	}
+	if err != nil {
+		return rows, 0, err
+	}
+
+	if err := enc.flush(); err != nil {
+		return rows, 0, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return rows, 0, err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return rows, 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return rows, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return rows, 0, err
+	}
+	return rows, info.Size(), nil
+}
+
+// exportLogs 按ID顺序分批读取任务开始时已写入的日志，检索语法在内存中匹配
+func (s *ExportService) exportLogs(params *ExportParams, emit func([]interface{}) error, progress func() error) error {
+	req := params.logSearchRequest()
+	query, err := buildLogQuery(req)
+	if err != nil {
+		return err
+	}
+	matchAll := strings.TrimSpace(req.Query) == ""
+
+	maxID, err := s.logRepo.MaxID()
+	if err != nil {
+		return err
+	}
+	filters := map[string]interface{}{"lr.id <= ?": maxID}
+	if req.HostID > 0 {
+		filters["lr.host_id = ?"] = req.HostID
+	}
+	if req.ServiceID > 0 {
+		filters["lr.service_id = ?"] = req.ServiceID
+	}
+	if req.ComponentID > 0 {
+		filters["lr.component_id = ?"] = req.ComponentID
+	}
+	var levels []string
+	for _, level := range req.Levels {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) > 0 {
+		filters["lr.log_level IN ?"] = levels
+	}
+	if params.StartTime != nil {
+		filters["lr.timestamp >= ?"] = *params.StartTime
+	}
+	if params.EndTime != nil {
+		filters["lr.timestamp <= ?"] = *params.EndTime
+	}
+	if req.Scope != nil {
+		condition, args := req.Scope.filter("lr.host_id", "lr.service_id")
+		filters[condition] = args
+	}
+
+	var after int64
+	for {
+		records, err := s.logRepo.ListRange(after, filters, s.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		after = records[len(records)-1].ID
+
+		var matched map[int64]bool
+		if !matchAll {
+			docs := make([]*logstore.Document, len(records))
+			for i, record := range records {
+				docs[i] = logDocument(record)
+			}
+			matched = make(map[int64]bool)
+			for _, id := range logstore.NewBatch(docs).Match(query) {
+				matched[id] = true
+			}
+		}
+		for _, r := range records {
+			if !matchAll && !matched[r.ID] {
+				continue
+			}
+			err := emit([]interface{}{r.ID, r.Timestamp, r.HostID, r.Hostname, r.ServiceID, r.ServiceName,
+				r.ComponentID, r.ComponentType, r.LogLevel, r.Logger, r.Thread, r.Message})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := progress(); err != nil {
+			return err
+		}
+		if len(records) < s.batchSize {
+			return nil
+		}
+	}
+}
+
+// exportMetrics 按ID顺序分批读取任务开始时已写入的指标数据
+func (s *ExportService) exportMetrics(params *ExportParams, emit func([]interface{}) error, progress func() error) error {
+	maxID, err := s.metricRepo.MaxID()
+	if err != nil {
+		return err
+	}
+	filters := map[string]interface{}{"m.id <= ?": maxID}
+	if params.HostID > 0 {
+		filters["m.host_id = ?"] = params.HostID
+	}
+	if params.ServiceID > 0 {
+		filters["m.service_id = ?"] = params.ServiceID
+	}
+	if params.ClusterID > 0 {
+		filters["h.cluster_id = ?"] = params.ClusterID
+	}
+	if len(params.MetricNames) > 0 {
+		filters["m.metric_name IN ?"] = params.MetricNames
+	}
+	if params.StartTime != nil {
+		filters["m.timestamp >= ?"] = *params.StartTime
+	}
+	if params.EndTime != nil {
+		filters["m.timestamp <= ?"] = *params.EndTime
+	}
+	if params.Scope != nil {
+		condition, args := params.Scope.filter("m.host_id", "m.service_id")
+		filters[condition] = args
+	}
+
+	var after int64
+	for {
+		records, err := s.metricRepo.ListRange(after, filters, s.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		after = records[len(records)-1].ID
+
+		for _, r := range records {
+			err := emit([]interface{}{r.ID, r.Timestamp, r.HostID, r.Hostname, r.ClusterID, r.ServiceID,
+				r.ServiceName, r.MetricName, r.Value})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := progress(); err != nil {
+			return err
+		}
+		if len(records) < s.batchSize {
+			return nil
+		}
+	}
+}
+
+// cleanup 删除过期的导出任务和文件
+func (s *ExportService) cleanup() {
+	jobs, err := s.repo.ListExpired(time.Now())
+	if err != nil {
+		log.Printf("查询过期导出任务失败: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if err := s.DeleteExportJob(job); err != nil {
+			log.Printf("删除过期导出任务 %d 失败: %v", job.ID, err)
+		}
+	}
+	if len(jobs) > 0 {
+		log.Printf("已删除 %d 个过期导出任务", len(jobs))
+	}
+}
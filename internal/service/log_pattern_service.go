@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/logpattern"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// 日志模式默认参数
+const (
+	defaultLogPatternPollInterval = 5 * time.Second
+	defaultLogPatternBatchSize    = 5000
+	defaultLogPatternBucket       = 5 * time.Minute
+	defaultLogPatternSimilarity   = 0.5
+	defaultLogPatternMaxPatterns  = 1000
+	defaultLogPatternBackfill     = 24 * time.Hour
+	defaultLogPatternWindow       = time.Hour
+	defaultLogPatternBaseline     = 24 * time.Hour
+	defaultLogPatternSpikeRatio   = 3
+	defaultLogPatternMinCount     = 10
+	defaultLogPatternAnomalyLimit = 50
+
+	logPatternSampleLength = 2000
+)
+
+// patternSource 日志来源，模式按服务和组件分别聚类
+type patternSource struct {
+	serviceID   int
+	componentID int
+}
+
+// LogPatternAnomalyRequest 日志模式异常检测条件，零值使用配置的默认值
+type LogPatternAnomalyRequest struct {
+	ServiceID   int
+	ServiceIDs  []int // 只检测这些服务的模式，为nil时不限定
+	ComponentID int
+	Levels      []string
+	Window      time.Duration // 观察窗口
+	Baseline    time.Duration // 观察窗口之前用作基线的时长
+	SpikeRatio  float64
+	MinCount    int64
+	Limit       int
+}
+
+// LogPatternAnomaly 新出现或日志数突增的模式
+type LogPatternAnomaly struct {
+	*model.LogPattern
+	WindowCount   int64   `json:"window_count"`   // 观察窗口内的日志数
+	BaselineCount int64   `json:"baseline_count"` // 基线时段内的日志数
+	Expected      float64 `json:"expected"`       // 按基线速率推算的观察窗口日志数
+	Ratio         float64 `json:"ratio"`          // 观察窗口日志数与预期的比值
+}
+
+// LogPatternAnomalies 日志模式异常检测结果
+type LogPatternAnomalies struct {
+	WindowStart   time.Time            `json:"window_start"`
+	BaselineStart time.Time            `json:"baseline_start"`
+	New           []*LogPatternAnomaly `json:"new"`     // 观察窗口内首次出现的模式，按日志数降序
+	Spiking       []*LogPatternAnomaly `json:"spiking"` // 日志数相比基线突增的模式，按比值降序
+}
+
+// LogPatternSeries 模式在各时间桶的日志数
+type LogPatternSeries struct {
+	*model.LogPattern
+	BucketMinutes int                      `json:"bucket_minutes"`
+	Counts        []*model.LogPatternCount `json:"counts"`
+}
+
+// LogPatternService 日志模式服务，按ID顺序读取新写入的日志，聚类为模式并按时间桶统计日志数
+type LogPatternService struct {
+	cfg         *config.Config
+	logRepo     *repository.LogRepository
+	patternRepo *repository.LogPatternRepository
+	wake        chan struct{}
+
+	// 以下字段只由处理协程访问
+	cursor   int64
+	gap      idGap
+	miners   map[patternSource]*logpattern.Miner
+	patterns map[*logpattern.Cluster]*model.LogPattern
+}
+
+// NewLogPatternService 创建日志模式服务
+func NewLogPatternService(db *gorm.DB, cfg *config.Config) *LogPatternService {
+	return &LogPatternService{
+		cfg:         cfg,
+		logRepo:     repository.NewLogRepository(db),
+		patternRepo: repository.NewLogPatternRepository(db),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Enabled 是否启用日志模式聚类
+func (s *LogPatternService) Enabled() bool {
+	return s.cfg.LogPattern.Enabled
+}
+
+// Start 恢复已有模式后持续处理新写入的日志，ctx 取消后停止
+func (s *LogPatternService) Start(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	pollInterval := defaultLogPatternPollInterval
+	if s.cfg.LogPattern.PollInterval > 0 {
+		pollInterval = time.Duration(s.cfg.LogPattern.PollInterval) * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		loaded := false
+		for {
+			if !loaded {
+				if err := s.load(); err != nil {
+					log.Printf("加载日志模式失败: %v", err)
+				} else {
+					loaded = true
+				}
+			}
+			if loaded {
+				loaded = s.processPending(ctx)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// NotifyIngested 通知有新日志写入，立即触发模式聚类
+func (s *LogPatternService) NotifyIngested() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// load 从数据库恢复模式聚类状态，处理位置为已保存的最大日志ID；首次启动时只处理最近 backfill_hours 小时写入的日志
+func (s *LogPatternService) load() error {
+	patterns, err := s.patternRepo.ListAll()
+	if err != nil {
+		return err
+	}
+
+	s.miners = make(map[patternSource]*logpattern.Miner)
+	s.patterns = make(map[*logpattern.Cluster]*model.LogPattern, len(patterns))
+	s.gap = idGap{}
+	for _, pattern := range patterns {
+		cluster := &logpattern.Cluster{Tokens: strings.Fields(pattern.Template)}
+		s.miner(patternSource{pattern.ServiceID, pattern.ComponentID}).Add(cluster)
+		s.patterns[cluster] = pattern
+	}
+
+	if s.cursor, err = s.patternRepo.MaxLastLogID(); err != nil || s.cursor > 0 {
+		return err
+	}
+
+	backfill := defaultLogPatternBackfill
+	if s.cfg.LogPattern.BackfillHours > 0 {
+		backfill = time.Duration(s.cfg.LogPattern.BackfillHours) * time.Hour
+	}
+	firstID, err := s.logRepo.FirstIDSince(time.Now().Add(-backfill))
+	if err != nil {
+		return err
+	}
+	if firstID > 0 {
+		s.cursor = firstID - 1
+		return nil
+	}
+	s.cursor, err = s.logRepo.MaxID()
+	return err
+}
+
+// miner 获取日志来源的模式聚类
+func (s *LogPatternService) miner(source patternSource) *logpattern.Miner {
+	miner, ok := s.miners[source]
+	if !ok {
+		similarity := s.cfg.LogPattern.Similarity
+		if similarity <= 0 || similarity > 1 {
+			similarity = defaultLogPatternSimilarity
+		}
+		maxPatterns := s.cfg.LogPattern.MaxPatterns
+		if maxPatterns <= 0 {
+			maxPatterns = defaultLogPatternMaxPatterns
+		}
+		miner = logpattern.NewMiner(similarity, maxPatterns)
+		s.miners[source] = miner
+	}
+	return miner
+}
+
+// bucketSize 日志数统计的时间粒度
+func (s *LogPatternService) bucketSize() time.Duration {
+	if s.cfg.LogPattern.BucketMinutes > 0 {
+		return time.Duration(s.cfg.LogPattern.BucketMinutes) * time.Minute
+	}
+	return defaultLogPatternBucket
+}
+
+// processPending 分批处理所有尚未处理的日志，保存失败时返回false，需要重新加载模式
+func (s *LogPatternService) processPending(ctx context.Context) bool {
+	batchSize := s.cfg.LogPattern.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultLogPatternBatchSize
+	}
+
+	for ctx.Err() == nil {
+		fetched, err := s.logRepo.ListAfter(s.cursor, batchSize)
+		if err != nil {
+			log.Printf("读取待聚类日志失败: %v", err)
+			return true
+		}
+		records := s.gap.until(s.cursor, fetched)
+		if len(records) == 0 {
+			return true
+		}
+
+		if err := s.process(records); err != nil {
+			log.Printf("保存日志模式失败: %v", err)
+			return false
+		}
+		s.cursor = records[len(records)-1].ID
+
+		if len(records) < len(fetched) || len(fetched) < batchSize {
+			return true
+		}
+	}
+	return true
+}
+
+// process 将一批日志归入模式并保存模式和各时间桶的日志数
+func (s *LogPatternService) process(records []*model.LogRecord) error {
+	bucketSize := s.bucketSize()
+	seen := make(map[*model.LogPattern]bool)
+	var dirty []*model.LogPattern
+	counts := make(map[*model.LogPattern]map[time.Time]int64)
+
+	for _, record := range records {
+		source := patternSource{record.ServiceID, record.ComponentID}
+		cluster, created, _ := s.miner(source).Match(logpattern.Tokenize(record.Message))
+
+		pattern := s.patterns[cluster]
+		if created || pattern == nil {
+			pattern = &model.LogPattern{
+				ServiceID:   record.ServiceID,
+				ComponentID: record.ComponentID,
+				FirstSeen:   record.Timestamp,
+			}
+			s.patterns[cluster] = pattern
+		}
+		pattern.Template = cluster.Template()
+		pattern.Level = record.LogLevel
+		pattern.Sample = truncate(record.Message, logPatternSampleLength)
+		pattern.Count++
+		pattern.LastLogID = record.ID
+		if record.Timestamp.After(pattern.LastSeen) {
+			pattern.LastSeen = record.Timestamp
+		}
+		if record.Timestamp.Before(pattern.FirstSeen) {
+			pattern.FirstSeen = record.Timestamp
+		}
+		if !seen[pattern] {
+			seen[pattern] = true
+			dirty = append(dirty, pattern)
+		}
+
+		if counts[pattern] == nil {
+			counts[pattern] = make(map[time.Time]int64)
+		}
+		counts[pattern][record.Timestamp.Truncate(bucketSize)]++
+	}
+
+	return s.patternRepo.Save(dirty, counts)
+}
+
+// ListLogPatterns 列出日志模式，sortBy 为 count、last_seen 或 first_seen，默认按日志数降序
+func (s *LogPatternService) ListLogPatterns(page, pageSize int, filters map[string]interface{}, sortBy string) ([]*model.LogPattern, int64, error) {
+	orderBy := "lp.count DESC"
+	switch sortBy {
+	case "last_seen":
+		orderBy = "lp.last_seen DESC"
+	case "first_seen":
+		orderBy = "lp.first_seen DESC"
+	}
+	return s.patternRepo.List(page, pageSize, filters, orderBy)
+}
+
+// GetLogPatternSeries 获取模式及其在 since 之后各时间桶的日志数
+func (s *LogPatternService) GetLogPatternSeries(id int64, since time.Time) (*LogPatternSeries, error) {
+	pattern, err := s.patternRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.patternRepo.ListCounts(id, since)
+	if err != nil {
+		return nil, err
+	}
+	return &LogPatternSeries{
+		LogPattern:    pattern,
+		BucketMinutes: int(s.bucketSize() / time.Minute),
+		Counts:        counts,
+	}, nil
+}
+
+// DetectLogPatternAnomalies 找出观察窗口内新出现的模式和日志数相比基线突增的模式
+//
+// 基线为观察窗口之前的一段时间，模式首次出现晚于基线开始时只按出现后的时长计算基线速率。
+func (s *LogPatternService) DetectLogPatternAnomalies(req *LogPatternAnomalyRequest) (*LogPatternAnomalies, error) {
+	cfg := s.cfg.LogPattern
+	window, baseline := req.Window, req.Baseline
+	if window <= 0 {
+		window = time.Duration(cfg.WindowMinutes) * time.Minute
+		if window <= 0 {
+			window = defaultLogPatternWindow
+		}
+	}
+	if baseline <= 0 {
+		baseline = time.Duration(cfg.BaselineMinutes) * time.Minute
+		if baseline <= 0 {
+			baseline = defaultLogPatternBaseline
+		}
+	}
+	spikeRatio := req.SpikeRatio
+	if spikeRatio <= 0 {
+		spikeRatio = cfg.SpikeRatio
+		if spikeRatio <= 0 {
+			spikeRatio = defaultLogPatternSpikeRatio
+		}
+	}
+	minCount := req.MinCount
+	if minCount <= 0 {
+		minCount = int64(cfg.MinCount)
+		if minCount <= 0 {
+			minCount = defaultLogPatternMinCount
+		}
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultLogPatternAnomalyLimit
+	}
+
+	bucketSize := s.bucketSize()
+	windowStart := time.Now().Add(-window).Truncate(bucketSize)
+	baselineStart := windowStart.Add(-baseline)
+	window = time.Since(windowStart)
+
+	filters := map[string]interface{}{}
+	if req.ServiceID > 0 {
+		filters["lp.service_id = ?"] = req.ServiceID
+	}
+	if req.ServiceIDs != nil {
+		filters["lp.service_id IN ?"] = req.ServiceIDs
+	}
+	if req.ComponentID > 0 {
+		filters["lp.component_id = ?"] = req.ComponentID
+	}
+	if len(req.Levels) > 0 {
+		filters["lp.level IN ?"] = req.Levels
+	}
+	counts, err := s.patternRepo.WindowCounts(baselineStart, windowStart, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(counts))
+	for i, count := range counts {
+		ids[i] = count.PatternID
+	}
+	patterns, err := s.patternRepo.ListByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	patternByID := make(map[int64]*model.LogPattern, len(patterns))
+	for _, pattern := range patterns {
+		patternByID[pattern.ID] = pattern
+	}
+
+	result := &LogPatternAnomalies{
+		WindowStart:   windowStart,
+		BaselineStart: baselineStart,
+		New:           []*LogPatternAnomaly{},
+		Spiking:       []*LogPatternAnomaly{},
+	}
+	for _, count := range counts {
+		pattern := patternByID[count.PatternID]
+		if pattern == nil {
+			continue
+		}
+		anomaly := &LogPatternAnomaly{
+			LogPattern:    pattern,
+			WindowCount:   count.WindowCount,
+			BaselineCount: count.BaselineCount,
+		}
+		if !pattern.FirstSeen.Before(windowStart) {
+			result.New = append(result.New, anomaly)
+			continue
+		}
+
+		// 基线时长从模式首次出现算起，至少一个时间桶
+		since := baselineStart
+		if first := pattern.FirstSeen.Truncate(bucketSize); first.After(since) {
+			since = first
+		}
+		span := math.Max(float64(windowStart.Sub(since)), float64(bucketSize))
+		anomaly.Expected = float64(count.BaselineCount) * float64(window) / span
+		anomaly.Ratio = float64(count.WindowCount) / math.Max(anomaly.Expected, 1)
+		if count.WindowCount >= minCount && anomaly.Ratio >= spikeRatio {
+			result.Spiking = append(result.Spiking, anomaly)
+		}
+	}
+
+	sort.Slice(result.New, func(i, j int) bool {
+		return result.New[i].WindowCount > result.New[j].WindowCount
+	})
+	sort.Slice(result.Spiking, func(i, j int) bool {
+		return result.Spiking[i].Ratio > result.Spiking[j].Ratio
+	})
+	if len(result.New) > limit {
+		result.New = result.New[:limit]
+	}
+	if len(result.Spiking) > limit {
+		result.Spiking = result.Spiking[:limit]
+	}
+	return result, nil
+}
@@ -0,0 +1,438 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/logstore"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// 日志索引默认参数
+const (
+	defaultLogIndexDir           = "./data/log-index"
+	defaultLogIndexPollInterval  = 2 * time.Second
+	defaultLogIndexBatchSize     = 5000
+	defaultLogIndexFlushInterval = 30 * time.Second
+	defaultLogIndexBackfillDays  = 7
+
+	// 并发写入时自增ID可能乱序提交，新写入日志之前的ID空洞最多等待的时间
+	logIndexGapWait   = 5 * time.Second
+	logIndexGapRecent = time.Minute
+)
+
+// LogSearchFields 日志查询中可按 field:value 形式查询的字段
+var LogSearchFields = []string{"host", "host_id", "service", "service_id", "component", "component_id", "level", "logger", "thread"}
+
+// LogSearchRequest 日志检索条件，Query 为检索语法，其余条件与 Query 同时生效
+type LogSearchRequest struct {
+	Query       string
+	HostID      int
+	ServiceID   int
+	ComponentID int
+	Levels      []string
+	StartTime   time.Time
+	EndTime     time.Time
+	Page        int
+	PageSize    int
+	Scope       *AccessScope // 用户有权查看的范围，为nil时不限定
+}
+
+// AccessScope 日志和指标的访问范围，只包含指定主机或指定服务的数据
+type AccessScope struct {
+	HostIDs    []int `json:"host_ids"`
+	ServiceIDs []int `json:"service_ids"`
+}
+
+// query 生成限定访问范围的日志查询条件
+func (a *AccessScope) query() logstore.Query {
+	var clauses []logstore.Query
+	for _, id := range a.HostIDs {
+		clauses = append(clauses, logstore.Field("host_id", strconv.Itoa(id)))
+	}
+	for _, id := range a.ServiceIDs {
+		clauses = append(clauses, logstore.Field("service_id", strconv.Itoa(id)))
+	}
+	if len(clauses) == 0 {
+		return &logstore.MatchNoneQuery{}
+	}
+	return logstore.Or(clauses...)
+}
+
+// filter 生成限定访问范围的过滤条件，条件使用命名参数，可作为过滤条件的一项
+func (a *AccessScope) filter(hostColumn, serviceColumn string) (string, map[string]interface{}) {
+	hostIDs, serviceIDs := a.HostIDs, a.ServiceIDs
+	if hostIDs == nil {
+		hostIDs = []int{}
+	}
+	if serviceIDs == nil {
+		serviceIDs = []int{}
+	}
+	return "(" + hostColumn + " IN @scope_hosts OR " + serviceColumn + " IN @scope_services)",
+		map[string]interface{}{"scope_hosts": hostIDs, "scope_services": serviceIDs}
+}
+
+// LogSearchHit 日志检索结果，Highlights 为日志内容中与查询匹配的位置
+type LogSearchHit struct {
+	*model.LogRecord
+	Highlights []logstore.Span `json:"highlights,omitempty"`
+}
+
+// LogIndexStatus 日志索引状态
+type LogIndexStatus struct {
+	Enabled bool `json:"enabled"`
+	logstore.Stats
+	MaxLogID int64 `json:"max_log_id"` // 日志表中的最大日志ID
+	Lag      int64 `json:"lag"`        // 尚未索引的日志数
+}
+
+// LogService 日志检索服务，维护日志表的全文索引
+type LogService struct {
+	db      *gorm.DB
+	cfg     *config.Config
+	logRepo *repository.LogRepository
+	store   *logstore.Store // 未启用索引时为nil
+
+	cursor   atomic.Int64 // 已索引到的日志ID
+	wake     chan struct{}
+	indexGap idGap // 只由索引协程访问
+
+	// 实时日志流
+	streamMu     sync.Mutex
+	streamCursor int64 // 已推送到的日志ID
+	streamGap    idGap
+	subscribers  map[*LogSubscription]struct{}
+	streamWake   chan struct{}
+}
+
+// idGap 按ID顺序读取日志时正在等待的ID空洞位置及开始等待的时间
+type idGap struct {
+	at    int64
+	since time.Time
+}
+
+// NewLogService 创建日志检索服务，索引打开失败时回退为数据库查询
+func NewLogService(db *gorm.DB, cfg *config.Config) *LogService {
+	s := &LogService{
+		db:          db,
+		cfg:         cfg,
+		logRepo:     repository.NewLogRepository(db),
+		wake:        make(chan struct{}, 1),
+		subscribers: make(map[*LogSubscription]struct{}),
+		streamWake:  make(chan struct{}, 1),
+	}
+	if !cfg.LogIndex.Enabled {
+		return s
+	}
+
+	dir := cfg.LogIndex.Dir
+	if dir == "" {
+		dir = defaultLogIndexDir
+	}
+	store, err := logstore.Open(logstore.Options{
+		Dir:       dir,
+		Partition: time.Duration(cfg.LogIndex.PartitionMinutes) * time.Minute,
+	})
+	if err != nil {
+		log.Printf("打开日志索引失败，日志查询将使用数据库: %v", err)
+		return s
+	}
+	s.store = store
+	s.cursor.Store(store.LastID())
+	return s
+}
+
+// IndexEnabled 是否使用全文索引检索日志
+func (s *LogService) IndexEnabled() bool {
+	return s.store != nil
+}
+
+// Start 启动实时日志流和日志索引，持续索引新写入的日志并定期写入磁盘，ctx 取消后停止
+func (s *LogService) Start(ctx context.Context) {
+	go s.runStream(ctx)
+
+	if s.store == nil {
+		return
+	}
+
+	if s.cursor.Load() == 0 {
+		s.initCursor()
+	}
+
+	pollInterval := defaultLogIndexPollInterval
+	if s.cfg.LogIndex.PollInterval > 0 {
+		pollInterval = time.Duration(s.cfg.LogIndex.PollInterval) * time.Second
+	}
+	flushInterval := defaultLogIndexFlushInterval
+	if s.cfg.LogIndex.FlushInterval > 0 {
+		flushInterval = time.Duration(s.cfg.LogIndex.FlushInterval) * time.Second
+	}
+
+	go func() {
+		pollTicker := time.NewTicker(pollInterval)
+		defer pollTicker.Stop()
+		flushTicker := time.NewTicker(flushInterval)
+		defer flushTicker.Stop()
+
+		for {
+			s.indexPending(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-pollTicker.C:
+			case <-s.wake:
+			case <-flushTicker.C:
+				s.flush()
+				s.deleteExpiredSegments()
+			}
+		}
+	}()
+}
+
+// Close 将索引写入磁盘
+func (s *LogService) Close() {
+	if s.store != nil {
+		s.flush()
+	}
+}
+
+// NotifyIngested 通知有新日志写入，立即触发索引和实时日志推送
+func (s *LogService) NotifyIngested() {
+	select {
+	case s.streamWake <- struct{}{}:
+	default:
+	}
+	if s.store == nil {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// initCursor 首次启动时只索引最近 backfill_days 天写入的日志
+func (s *LogService) initCursor() {
+	days := s.cfg.LogIndex.BackfillDays
+	if days <= 0 {
+		days = defaultLogIndexBackfillDays
+	}
+
+	firstID, err := s.logRepo.FirstIDSince(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("查询待索引日志失败: %v", err)
+		return
+	}
+	cursor := firstID - 1
+	if firstID == 0 {
+		// 近期没有日志，从当前最大ID之后开始索引
+		if cursor, err = s.logRepo.MaxID(); err != nil {
+			log.Printf("查询待索引日志失败: %v", err)
+			return
+		}
+	}
+
+	s.cursor.Store(cursor)
+}
+
+// indexPending 分批索引所有尚未索引的日志
+func (s *LogService) indexPending(ctx context.Context) {
+	batchSize := s.cfg.LogIndex.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultLogIndexBatchSize
+	}
+
+	for ctx.Err() == nil {
+		cursor := s.cursor.Load()
+		records, err := s.logRepo.ListAfter(cursor, batchSize)
+		if err != nil {
+			log.Printf("读取待索引日志失败: %v", err)
+			return
+		}
+		fetched := len(records)
+		records = s.indexGap.until(cursor, records)
+		if len(records) == 0 {
+			return
+		}
+
+		docs := make([]*logstore.Document, len(records))
+		for i, record := range records {
+			docs[i] = logDocument(record)
+		}
+		s.store.Index(docs)
+		s.cursor.Store(records[len(records)-1].ID)
+
+		if len(records) < fetched || fetched < batchSize {
+			return
+		}
+	}
+}
+
+// until 截取到第一个需要等待的ID空洞之前的日志
+//
+// 索引和实时日志流按ID顺序推进，较小ID的事务晚提交时会被跳过。ID不连续且空洞之后的日志是刚写入的，
+// 先等待一段时间再越过空洞；回滚的事务和删除的日志留下的空洞在等待后正常越过。
+func (g *idGap) until(cursor int64, records []*model.LogRecord) []*model.LogRecord {
+	prev := cursor
+	for i, record := range records {
+		if record.ID == prev+1 || time.Since(record.CreatedAt) > logIndexGapRecent {
+			prev = record.ID
+			continue
+		}
+		if g.at != prev {
+			g.at, g.since = prev, time.Now()
+		}
+		if time.Since(g.since) < logIndexGapWait {
+			return records[:i]
+		}
+		prev = record.ID
+	}
+	return records
+}
+
+// flush 将索引写入磁盘
+func (s *LogService) flush() {
+	if err := s.store.Flush(); err != nil {
+		log.Printf("写入日志索引失败: %v", err)
+	}
+}
+
+// deleteExpiredSegments 删除超过保留天数的索引段
+func (s *LogService) deleteExpiredSegments() {
+	if s.cfg.LogIndex.RetentionDays <= 0 {
+		return
+	}
+	deleted, err := s.store.DeleteBefore(time.Now().AddDate(0, 0, -s.cfg.LogIndex.RetentionDays))
+	if err != nil {
+		log.Printf("删除过期日志索引失败: %v", err)
+	}
+	if deleted > 0 {
+		log.Printf("已删除 %d 个过期日志索引段", deleted)
+	}
+}
+
+// logDocument 将日志转换为索引文档，主机、服务、组件同时按名称和ID索引
+func logDocument(record *model.LogRecord) *logstore.Document {
+	fields := map[string]string{
+		"host":      record.Hostname,
+		"service":   record.ServiceName,
+		"component": record.ComponentType,
+		"level":     record.LogLevel,
+		"logger":    record.Logger,
+		"thread":    record.Thread,
+	}
+	if record.HostID > 0 {
+		fields["host_id"] = strconv.Itoa(record.HostID)
+	}
+	if record.ServiceID > 0 {
+		fields["service_id"] = strconv.Itoa(record.ServiceID)
+	}
+	if record.ComponentID > 0 {
+		fields["component_id"] = strconv.Itoa(record.ComponentID)
+	}
+	return &logstore.Document{
+		ID:        record.ID,
+		Timestamp: record.Timestamp,
+		Message:   record.Message,
+		Fields:    fields,
+	}
+}
+
+// buildLogQuery 解析检索语法并与过滤条件组合
+func buildLogQuery(req *LogSearchRequest) (logstore.Query, error) {
+	query, err := logstore.ParseQuery(req.Query, LogSearchFields...)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := []logstore.Query{query}
+	if req.HostID > 0 {
+		clauses = append(clauses, logstore.Field("host_id", strconv.Itoa(req.HostID)))
+	}
+	if req.ServiceID > 0 {
+		clauses = append(clauses, logstore.Field("service_id", strconv.Itoa(req.ServiceID)))
+	}
+	if req.ComponentID > 0 {
+		clauses = append(clauses, logstore.Field("component_id", strconv.Itoa(req.ComponentID)))
+	}
+	var levels []logstore.Query
+	for _, level := range req.Levels {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, logstore.Field("level", level))
+		}
+	}
+	clauses = append(clauses, logstore.Or(levels...))
+	if req.Scope != nil {
+		clauses = append(clauses, req.Scope.query())
+	}
+	return logstore.And(clauses...), nil
+}
+
+// SearchLogs 使用全文索引检索日志，结果按日志时间倒序排列；查询语法错误时返回 *logstore.SyntaxError
+func (s *LogService) SearchLogs(req *LogSearchRequest) ([]*LogSearchHit, int64, error) {
+	query, err := buildLogQuery(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := s.store.Search(&logstore.SearchRequest{
+		Query:  query,
+		Start:  req.StartTime,
+		End:    req.EndTime,
+		Offset: (req.Page - 1) * req.PageSize,
+		Limit:  req.PageSize,
+	})
+
+	records, err := s.logRepo.ListByIDs(result.IDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	recordByID := make(map[int64]*model.LogRecord, len(records))
+	for _, record := range records {
+		recordByID[record.ID] = record
+	}
+
+	highlighter := logstore.NewHighlighter(query)
+	hits := make([]*LogSearchHit, 0, len(result.IDs))
+	for _, id := range result.IDs {
+		// 已从日志表删除但仍在索引中的日志不返回
+		record, ok := recordByID[id]
+		if !ok {
+			continue
+		}
+		hits = append(hits, &LogSearchHit{
+			LogRecord:  record,
+			Highlights: highlighter.Highlight(record.Message),
+		})
+	}
+	return hits, int64(result.Total), nil
+}
+
+// GetLogIndexStatus 获取日志索引状态
+func (s *LogService) GetLogIndexStatus() (*LogIndexStatus, error) {
+	maxID, err := s.logRepo.MaxID()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &LogIndexStatus{Enabled: s.store != nil, MaxLogID: maxID}
+	if s.store == nil {
+		return status, nil
+	}
+
+	status.Stats = s.store.Stats()
+	if cursor := s.cursor.Load(); maxID > cursor {
+		status.Lag = maxID - cursor
+	}
+	return status, nil
+}
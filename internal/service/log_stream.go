@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/logstore"
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// 实时日志流参数
+const (
+	LogStreamMaxBacklog = 1000 // 开始订阅或断线重连时最多补发的历史日志数
+
+	logStreamPollInterval = time.Second
+	logStreamBatchSize    = 1000
+	logStreamBuffer       = 2000  // 每个订阅者最多缓存的日志数，超出时断开订阅，由客户端从最后收到的日志ID重连补发
+	logStreamBacklogPage  = 500   // 补发历史日志时每次查询的日志数
+	logStreamBacklogScan  = 10000 // 补发历史日志时最多扫描的日志数
+)
+
+// LogStreamRequest 实时日志订阅条件，过滤条件与日志检索相同（不使用时间范围和分页）
+type LogStreamRequest struct {
+	LogSearchRequest
+	LastID int64 // 客户端最后收到的日志ID，大于0时先补发之后的日志
+	Tail   int   // LastID 为0时先发送最近的日志条数
+}
+
+// LogSubscription 实时日志订阅
+type LogSubscription struct {
+	req         *LogStreamRequest
+	query       logstore.Query
+	highlighter *logstore.Highlighter
+	cursor      int64 // 订阅时已推送到的日志ID，之后的日志通过 C 推送
+
+	ch         chan *LogSearchHit
+	overflowed atomic.Bool
+}
+
+// C 新日志通道，取消订阅或订阅者处理过慢导致缓冲区满时关闭
+func (sub *LogSubscription) C() <-chan *LogSearchHit {
+	return sub.ch
+}
+
+// Overflowed 是否因缓冲区满被断开
+func (sub *LogSubscription) Overflowed() bool {
+	return sub.overflowed.Load()
+}
+
+// SubscribeLogs 订阅新写入的日志；查询语法错误时返回 *logstore.SyntaxError
+func (s *LogService) SubscribeLogs(req *LogStreamRequest) (*LogSubscription, error) {
+	query, err := buildLogQuery(&req.LogSearchRequest)
+	if err != nil {
+		return nil, err
+	}
+	sub := &LogSubscription{
+		req:         req,
+		query:       query,
+		highlighter: logstore.NewHighlighter(query),
+		ch:          make(chan *LogSearchHit, logStreamBuffer),
+	}
+
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	// 没有订阅者时不读取日志，第一个订阅者从当前最大ID开始推送
+	if len(s.subscribers) == 0 {
+		maxID, err := s.logRepo.MaxID()
+		if err != nil {
+			return nil, err
+		}
+		s.streamCursor = maxID
+		s.streamGap = idGap{}
+	}
+	sub.cursor = s.streamCursor
+	s.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// UnsubscribeLogs 取消订阅
+func (s *LogService) UnsubscribeLogs(sub *LogSubscription) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	s.removeSubscriber(sub)
+}
+
+// removeSubscriber 删除订阅者并关闭其通道，调用方需持有 streamMu
+func (s *LogService) removeSubscriber(sub *LogSubscription) {
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// StreamBacklog 订阅前的历史日志，按ID递增排列：断线重连时为 LastID 之后的日志，否则为最近 Tail 条日志
+//
+// 最多补发 LogStreamMaxBacklog 条，超出时只补发最新的部分，truncated 为true表示可能有日志未补发。
+func (s *LogService) StreamBacklog(sub *LogSubscription) (hits []*LogSearchHit, truncated bool, err error) {
+	limit := sub.req.Tail
+	if sub.req.LastID > 0 {
+		limit = LogStreamMaxBacklog
+	}
+	if limit > LogStreamMaxBacklog {
+		limit = LogStreamMaxBacklog
+	}
+	if limit <= 0 {
+		return nil, false, nil
+	}
+
+	filters := map[string]interface{}{
+		"lr.host_id = ?":      sub.req.HostID,
+		"lr.service_id = ?":   sub.req.ServiceID,
+		"lr.component_id = ?": sub.req.ComponentID,
+	}
+	for key, value := range filters {
+		if value == 0 {
+			delete(filters, key)
+		}
+	}
+	var levels []string
+	for _, level := range sub.req.Levels {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) > 0 {
+		filters["lr.log_level IN ?"] = levels
+	}
+	if sub.req.Scope != nil {
+		condition, args := sub.req.Scope.filter("lr.host_id", "lr.service_id")
+		filters[condition] = args
+	}
+
+	// 从订阅位置向前分页查询，关键词在内存中匹配
+	before := sub.cursor + 1
+	scanned := 0
+	for len(hits) < limit {
+		if scanned >= logStreamBacklogScan {
+			truncated = sub.req.LastID > 0
+			break
+		}
+		records, err := s.logRepo.ListBetween(sub.req.LastID, before, filters, logStreamBacklogPage)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		scanned += len(records)
+		before = records[len(records)-1].ID
+
+		docs := make([]*logstore.Document, len(records))
+		for i, record := range records {
+			docs[len(records)-1-i] = logDocument(record)
+		}
+		matched := make(map[int64]bool)
+		for _, id := range logstore.NewBatch(docs).Match(sub.query) {
+			matched[id] = true
+		}
+		for _, record := range records {
+			if !matched[record.ID] {
+				continue
+			}
+			if len(hits) == limit {
+				truncated = sub.req.LastID > 0
+				break
+			}
+			hits = append(hits, &LogSearchHit{
+				LogRecord:  record,
+				Highlights: sub.highlighter.Highlight(record.Message),
+			})
+		}
+		if len(records) < logStreamBacklogPage {
+			break
+		}
+	}
+
+	for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+		hits[i], hits[j] = hits[j], hits[i]
+	}
+	return hits, truncated, nil
+}
+
+// runStream 有新日志写入或定期读取新日志并推送给订阅者，ctx 取消后关闭全部订阅
+func (s *LogService) runStream(ctx context.Context) {
+	ticker := time.NewTicker(logStreamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.streamMu.Lock()
+			for sub := range s.subscribers {
+				s.removeSubscriber(sub)
+			}
+			s.streamMu.Unlock()
+			return
+		case <-ticker.C:
+		case <-s.streamWake:
+		}
+		s.pushStream()
+	}
+}
+
+// pushStream 读取推送位置之后的日志，按各订阅者的条件匹配后推送；订阅者缓冲区满时断开该订阅
+func (s *LogService) pushStream() {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	for len(s.subscribers) > 0 {
+		fetched, err := s.logRepo.ListAfter(s.streamCursor, logStreamBatchSize)
+		if err != nil {
+			log.Printf("读取实时日志失败: %v", err)
+			return
+		}
+		records := s.streamGap.until(s.streamCursor, fetched)
+		if len(records) == 0 {
+			return
+		}
+
+		docs := make([]*logstore.Document, len(records))
+		recordByID := make(map[int64]*model.LogRecord, len(records))
+		for i, record := range records {
+			docs[i] = logDocument(record)
+			recordByID[record.ID] = record
+		}
+		batch := logstore.NewBatch(docs)
+
+		for sub := range s.subscribers {
+			for _, id := range batch.Match(sub.query) {
+				record := recordByID[id]
+				hit := &LogSearchHit{
+					LogRecord:  record,
+					Highlights: sub.highlighter.Highlight(record.Message),
+				}
+				select {
+				case sub.ch <- hit:
+					continue
+				default:
+				}
+				sub.overflowed.Store(true)
+				s.removeSubscriber(sub)
+				break
+			}
+		}
+
+		s.streamCursor = records[len(records)-1].ID
+		if len(records) < len(fetched) || len(fetched) < logStreamBatchSize {
+			return
+		}
+	}
+}
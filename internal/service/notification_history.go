@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// TestDeliveryResult 测试通知单个接收人的发送结果
+type TestDeliveryResult struct {
+	Recipient string `json:"recipient"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// NotificationTestResult 测试通知的发送结果
+type NotificationTestResult struct {
+	NotificationConfigID uint                   `json:"notification_config_id"`
+	Type                 model.NotificationType `json:"type"`
+	Success              bool                   `json:"success"`
+	Duration             int64                  `json:"duration_ms"`
+	Results              []TestDeliveryResult   `json:"results"`
+}
+
+// NotificationChannelStats 通知配置在统计时间范围内的发送情况
+type NotificationChannelStats struct {
+	NotificationConfigID uint                   `json:"notification_config_id"`
+	Name                 string                 `json:"name"`
+	Type                 model.NotificationType `json:"type"`
+	Total                int64                  `json:"total"`
+	Success              int64                  `json:"success"`
+	Failed               int64                  `json:"failed"`
+	Suppressed           int64                  `json:"suppressed"`
+	SuccessRate          float64                `json:"success_rate"` // 成功数占成功与失败总数的百分比，不计被抑制的通知
+}
+
+// TestNotificationConfig 通过通知配置立即发送一条测试通知，不经过发送队列和速率限制，也不记录通知历史；
+// recipients 不为空时替换配置中的邮件或短信接收人，severity 为空时按提示级别发送
+func (s *NotificationService) TestNotificationConfig(ctx context.Context, id uint, recipients string, severity model.AlertSeverity) (*NotificationTestResult, error) {
+	config, err := s.GetNotificationConfig(id)
+	if err != nil {
+		return nil, err
+	}
+	if severity == "" {
+		severity = model.SeverityInfo
+	}
+	if severity != model.SeverityCritical && severity != model.SeverityWarning && severity != model.SeverityInfo {
+		return nil, fmt.Errorf("invalid severity: %s", severity)
+	}
+	if _, ok := s.getNotifier(config.Type); !ok {
+		return nil, fmt.Errorf("no notifier registered for type %s", config.Type)
+	}
+	applyRecipients(config, recipients)
+
+	notification := sampleNotification(config.Type)
+	notification.Config = config
+	notification.BaseURL = s.cfg.Server.ExternalURL
+	event := notification.AlertEvents[0]
+	event.AlertName = "测试通知"
+	event.Message = fmt.Sprintf("这是一条来自通知配置「%s」的测试通知，收到说明通知渠道配置正确", config.Name)
+	event.Severity = severity
+	event.TriggeredAt = time.Now()
+	notification.Template = s.findTemplate(config, severity)
+
+	sendCtx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+	defer cancel()
+
+	start := time.Now()
+	deliveries := s.deliver(sendCtx, notification)
+
+	result := &NotificationTestResult{
+		NotificationConfigID: config.ID,
+		Type:                 config.Type,
+		Success:              true,
+		Duration:             time.Since(start).Milliseconds(),
+		Results:              make([]TestDeliveryResult, 0, len(deliveries)),
+	}
+	for _, delivery := range deliveries {
+		item := TestDeliveryResult{Recipient: delivery.Recipient, Success: delivery.Err == nil}
+		if delivery.Err != nil {
+			item.Error = delivery.Err.Error()
+			result.Success = false
+		}
+		result.Results = append(result.Results, item)
+	}
+	return result, nil
+}
+
+// ListNotificationHistory 列出通知历史
+func (s *NotificationService) ListNotificationHistory(page, pageSize int, filters map[string]interface{}) ([]*model.NotificationHistory, int64, error) {
+	return s.notificationHistoryRepo.List(page, pageSize, filters)
+}
+
+// GetAlertEventNotifications 获取告警事件的全部通知历史
+func (s *NotificationService) GetAlertEventNotifications(alertEventID uint) ([]*model.NotificationHistory, error) {
+	return s.notificationHistoryRepo.ListByAlertEvent(alertEventID)
+}
+
+// GetNotificationStats 统计各通知配置在时间范围内的发送情况，按发送总数降序排列
+func (s *NotificationService) GetNotificationStats(start, end time.Time) ([]*NotificationChannelStats, error) {
+	if !start.Before(end) {
+		return nil, errors.New("start time must be before end time")
+	}
+
+	counts, err := s.notificationHistoryRepo.CountByConfigAndStatus(0, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	statsByConfig := make(map[uint]*NotificationChannelStats)
+	for _, count := range counts {
+		stats, ok := statsByConfig[count.NotificationConfigID]
+		if !ok {
+			stats = &NotificationChannelStats{NotificationConfigID: count.NotificationConfigID}
+			statsByConfig[count.NotificationConfigID] = stats
+		}
+		stats.add(count.Status, count.Count)
+	}
+
+	result := make([]*NotificationChannelStats, 0, len(statsByConfig))
+	for configID, stats := range statsByConfig {
+		// 已删除的配置仍保留统计，只是没有名称
+		if config, err := s.GetNotificationConfig(configID); err == nil {
+			stats.Name = config.Name
+			stats.Type = config.Type
+		}
+		stats.finish()
+		result = append(result, stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].NotificationConfigID < result[j].NotificationConfigID
+	})
+	return result, nil
+}
+
+// GetNotificationConfigStats 统计单个通知配置在时间范围内的发送情况
+func (s *NotificationService) GetNotificationConfigStats(id uint, start, end time.Time) (*NotificationChannelStats, error) {
+	if !start.Before(end) {
+		return nil, errors.New("start time must be before end time")
+	}
+
+	config, err := s.GetNotificationConfig(id)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.notificationHistoryRepo.CountByConfigAndStatus(id, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &NotificationChannelStats{
+		NotificationConfigID: config.ID,
+		Name:                 config.Name,
+		Type:                 config.Type,
+	}
+	for _, count := range counts {
+		stats.add(count.Status, count.Count)
+	}
+	stats.finish()
+	return stats, nil
+}
+
+// add 累加一种发送状态的记录数
+func (st *NotificationChannelStats) add(status string, count int64) {
+	st.Total += count
+	switch status {
+	case "SUCCESS":
+		st.Success += count
+	case "FAILED":
+		st.Failed += count
+	case "SUPPRESSED":
+		st.Suppressed += count
+	}
+}
+
+// finish 计算成功率，保留两位小数
+func (st *NotificationChannelStats) finish() {
+	if attempted := st.Success + st.Failed; attempted > 0 {
+		st.SuccessRate = float64(st.Success*10000/attempted) / 100
+	}
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// 通知发送队列默认参数
+const (
+	defaultQueueWorkers      = 4
+	defaultQueuePollInterval = 5 * time.Second
+	defaultQueueMaxAttempts  = 5
+	defaultQueueRetryBackoff = 30 * time.Second
+	defaultQueueMaxBackoff   = time.Hour
+	notificationSendTimeout  = 30 * time.Second
+)
+
+// enqueue 创建通知发送任务并唤醒发送队列
+func (s *NotificationService) enqueue(config *model.NotificationConfig, kind NotificationKind, alertEvents []*model.AlertEvent, recipients string) error {
+	ids := make([]string, 0, len(alertEvents))
+	for _, event := range alertEvents {
+		ids = append(ids, strconv.FormatUint(uint64(event.ID), 10))
+	}
+
+	now := time.Now()
+	job := &model.NotificationJob{
+		NotificationConfigID: config.ID,
+		Kind:                 string(kind),
+		AlertEventIDs:        strings.Join(ids, ","),
+		Recipients:           recipients,
+		Status:               model.NotificationJobPending,
+		MaxAttempts:          s.maxAttempts(),
+		NextAttemptAt:        now,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return err
+	}
+
+	s.wakeQueue()
+	return nil
+}
+
+// wakeQueue 通知发送队列立即扫描待发送任务
+func (s *NotificationService) wakeQueue() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartQueue 启动通知发送队列，ctx 取消后停止，进程退出时未完成的任务会在下次启动时重新发送
+func (s *NotificationService) StartQueue(ctx context.Context) {
+	// 上次运行中断的任务重新进入待发送状态
+	if count, err := s.jobRepo.RequeueProcessing(time.Now()); err != nil {
+		log.Printf("恢复通知发送任务失败: %v", err)
+	} else if count > 0 {
+		log.Printf("已恢复 %d 个中断的通知发送任务", count)
+	}
+
+	interval := defaultQueuePollInterval
+	if s.cfg.Notification.PollInterval > 0 {
+		interval = time.Duration(s.cfg.Notification.PollInterval) * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.processDueJobs(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// processDueJobs 并发处理所有到期的发送任务
+func (s *NotificationService) processDueJobs(ctx context.Context) {
+	workers := s.cfg.Notification.Workers
+	if workers <= 0 {
+		workers = defaultQueueWorkers
+	}
+	batchSize := workers * 10
+
+	for ctx.Err() == nil {
+		jobs, err := s.jobRepo.ClaimDue(time.Now(), batchSize)
+		if err != nil {
+			log.Printf("领取通知发送任务失败: %v", err)
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for _, job := range jobs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(job *model.NotificationJob) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				s.processJob(ctx, job)
+			}(job)
+		}
+		wg.Wait()
+
+		if len(jobs) < batchSize {
+			return
+		}
+	}
+}
+
+// processJob 发送一个任务，失败时按指数退避安排重试，重试耗尽后进入死信状态
+func (s *NotificationService) processJob(ctx context.Context, job *model.NotificationJob) {
+	config, err := s.GetNotificationConfig(job.NotificationConfigID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.finishJob(job, model.NotificationJobDead, "notification config not found")
+		return
+	}
+	if err != nil {
+		s.retryJob(job, fmt.Sprintf("failed to get notification config: %v", err))
+		return
+	}
+	if !config.Enabled {
+		s.finishJob(job, model.NotificationJobDead, "notification config is disabled")
+		return
+	}
+
+	alertEvents, err := s.alertEventRepo.ListByIDs(parseNotificationIDs(job.AlertEventIDs))
+	if err != nil {
+		s.retryJob(job, fmt.Sprintf("failed to load alert events: %v", err))
+		return
+	}
+	if len(alertEvents) == 0 {
+		s.finishJob(job, model.NotificationJobDead, "alert events not found")
+		return
+	}
+
+	// 超过发送速率限制时延后发送，不计入发送次数
+	if wait := s.limiter.reserve(config.ID, s.rateLimit(config), time.Now()); wait > 0 {
+		job.Status = model.NotificationJobPending
+		job.NextAttemptAt = time.Now().Add(wait)
+		job.UpdatedAt = time.Now()
+		if err := s.jobRepo.Update(job); err != nil {
+			log.Printf("更新通知发送任务失败 (任务ID: %d): %v", job.ID, err)
+		}
+		return
+	}
+
+	applyRecipients(config, job.Recipients)
+
+	sendCtx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+	results := s.deliver(sendCtx, s.newNotification(NotificationKind(job.Kind), config, alertEvents))
+	cancel()
+
+	job.Attempts++
+	var failedRecipients, errs []string
+	retryAll := false
+	for _, result := range results {
+		if result.Err == nil {
+			s.logNotification(job, alertEvents, config.ID, result.Recipient, "SUCCESS", "")
+			continue
+		}
+		s.logNotification(job, alertEvents, config.ID, result.Recipient, "FAILED", result.Err.Error())
+		errs = append(errs, result.Err.Error())
+		if result.Recipient == "" {
+			retryAll = true
+		} else {
+			failedRecipients = append(failedRecipients, result.Recipient)
+		}
+	}
+
+	if len(errs) == 0 {
+		s.finishJob(job, model.NotificationJobSucceeded, "")
+		return
+	}
+
+	// 部分接收人失败时只重试失败的接收人
+	if !retryAll && len(failedRecipients) < len(results) && hasRecipientList(config.Type) {
+		job.Recipients = strings.Join(failedRecipients, ",")
+	}
+	s.retryJob(job, strings.Join(errs, "; "))
+}
+
+// retryJob 安排任务重试，达到最大发送次数时进入死信状态
+func (s *NotificationService) retryJob(job *model.NotificationJob, lastError string) {
+	if job.MaxAttempts > 0 && job.Attempts >= job.MaxAttempts {
+		s.finishJob(job, model.NotificationJobDead, lastError)
+		return
+	}
+
+	now := time.Now()
+	job.Status = model.NotificationJobPending
+	job.LastError = truncate(lastError, 1000)
+	job.NextAttemptAt = now.Add(s.retryDelay(job.Attempts))
+	job.UpdatedAt = now
+	if err := s.jobRepo.Update(job); err != nil {
+		log.Printf("更新通知发送任务失败 (任务ID: %d): %v", job.ID, err)
+	}
+}
+
+// finishJob 将任务置为成功或死信状态
+func (s *NotificationService) finishJob(job *model.NotificationJob, status model.NotificationJobStatus, lastError string) {
+	now := time.Now()
+	job.Status = status
+	job.LastError = truncate(lastError, 1000)
+	job.UpdatedAt = now
+	if status == model.NotificationJobSucceeded {
+		job.SentAt = &now
+	} else {
+		log.Printf("通知发送任务进入死信状态 (任务ID: %d): %s", job.ID, lastError)
+	}
+	if err := s.jobRepo.Update(job); err != nil {
+		log.Printf("更新通知发送任务失败 (任务ID: %d): %v", job.ID, err)
+	}
+}
+
+// retryDelay 第 attempts 次发送失败后的等待时间，按指数增长并加入随机抖动
+func (s *NotificationService) retryDelay(attempts int) time.Duration {
+	backoff := defaultQueueRetryBackoff
+	if s.cfg.Notification.RetryBackoff > 0 {
+		backoff = time.Duration(s.cfg.Notification.RetryBackoff) * time.Second
+	}
+	maxBackoff := defaultQueueMaxBackoff
+	if s.cfg.Notification.MaxBackoff > 0 {
+		maxBackoff = time.Duration(s.cfg.Notification.MaxBackoff) * time.Second
+	}
+
+	delay := backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	// 加入最多10%的抖动，避免大量任务同时重试
+	delay += time.Duration(rand.Int63n(int64(delay)/10 + 1))
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// maxAttempts 新任务的最大发送次数
+func (s *NotificationService) maxAttempts() int {
+	if s.cfg.Notification.MaxAttempts > 0 {
+		return s.cfg.Notification.MaxAttempts
+	}
+	return defaultQueueMaxAttempts
+}
+
+// rateLimit 通知配置每分钟最多发送的通知数，0表示不限制
+func (s *NotificationService) rateLimit(config *model.NotificationConfig) int {
+	if config.RateLimit > 0 {
+		return config.RateLimit
+	}
+	return s.cfg.Notification.RateLimits[strings.ToLower(string(config.Type))]
+}
+
+// hasRecipientList 通知类型是否有多个可单独重试的接收人
+func hasRecipientList(notificationType model.NotificationType) bool {
+	return notificationType == model.NotificationTypeEmail || notificationType == model.NotificationTypeSMS
+}
+
+// applyRecipients 使用任务中的接收人替换通知配置中的接收人
+func applyRecipients(config *model.NotificationConfig, recipients string) {
+	if recipients == "" {
+		return
+	}
+	switch config.Type {
+	case model.NotificationTypeEmail:
+		config.EmailRecipients = recipients
+	case model.NotificationTypeSMS:
+		config.SMSRecipients = recipients
+	}
+}
+
+// GetNotificationJob 获取通知发送任务
+func (s *NotificationService) GetNotificationJob(id uint) (*model.NotificationJob, error) {
+	return s.jobRepo.GetByID(id)
+}
+
+// ListNotificationJobs 列出通知发送任务
+func (s *NotificationService) ListNotificationJobs(page, pageSize int, filters map[string]interface{}) ([]*model.NotificationJob, int64, error) {
+	return s.jobRepo.List(page, pageSize, filters)
+}
+
+// ResendNotificationJob 重新发送死信或已成功的任务，发送次数重新计算
+func (s *NotificationService) ResendNotificationJob(id uint) (*model.NotificationJob, error) {
+	job, err := s.jobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != model.NotificationJobDead && job.Status != model.NotificationJobSucceeded {
+		return nil, errors.New("only dead or succeeded jobs can be resent")
+	}
+
+	now := time.Now()
+	job.Status = model.NotificationJobPending
+	job.Attempts = 0
+	job.MaxAttempts = s.maxAttempts()
+	job.NextAttemptAt = now
+	job.LastError = ""
+	job.UpdatedAt = now
+	if err := s.jobRepo.Update(job); err != nil {
+		return nil, err
+	}
+
+	s.wakeQueue()
+	return job, nil
+}
+
+// rateLimiter 按通知配置限制发送速率的令牌桶
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[uint]*tokenBucket
+}
+
+// tokenBucket 令牌桶，容量为每分钟的发送上限
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter 创建发送速率限制器
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[uint]*tokenBucket)}
+}
+
+// reserve 尝试取得一个发送令牌，成功返回0，否则返回需要等待的时间；perMinute 不大于0时不限制
+func (l *rateLimiter) reserve(key uint, perMinute int, now time.Time) time.Duration {
+	if perMinute <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(perMinute)
+	rate := capacity / float64(time.Minute)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens += float64(now.Sub(bucket.last)) * rate
+	if bucket.tokens > capacity {
+		bucket.tokens = capacity
+	}
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) / rate)
+}
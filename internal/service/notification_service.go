@@ -1,64 +1,89 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"html/template"
-	"net/http"
-	"net/smtp"
-	"strings"
+	"log"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 
-	"bigdata_manager/internal/config"
-	"bigdata_manager/internal/model"
-	"bigdata_manager/internal/repository"
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
 )
 
 // NotificationService 处理通知发送
 type NotificationService struct {
-	db                    *gorm.DB
-	notificationConfigRepo *repository.NotificationConfigRepository
+	db                      *gorm.DB
+	notificationConfigRepo  *repository.NotificationConfigRepository
 	notificationHistoryRepo *repository.NotificationHistoryRepository
-	cfg                   *config.Config
-	emailTemplate         *template.Template
-	smsTemplate           *template.Template
+	silenceSvc              *SilenceService
+	cfg                     *config.Config
+	notifiers               map[model.NotificationType]Notifier
+	notifiersMu             sync.RWMutex
+
+	// 通知发送队列
+	jobRepo        *repository.NotificationJobRepository
+	alertEventRepo *repository.AlertEventRepository
+	alertRuleRepo  *repository.AlertRuleRepository
+	templateRepo   *repository.NotificationTemplateRepository
+	inventoryRepo  *repository.InventoryRepository
+	limiter        *rateLimiter
+	wake           chan struct{}
 }
 
-// NewNotificationService 创建新的通知服务
+// NewNotificationService 创建新的通知服务，注册各类型的通知发送器，通知经发送队列异步发送
 func NewNotificationService(db *gorm.DB, cfg *config.Config) *NotificationService {
-	// 初始化邮件模板
-	emailTpl, _ := template.New("email").Parse(`
-Subject: 【告警】{{ .AlertEvent.Severity }} - {{ .AlertEvent.AlertName }}
-
-告警信息:
-- 级别: {{ .AlertEvent.Severity }}
-- 名称: {{ .AlertEvent.AlertName }}
-- 时间: {{ .AlertEvent.TriggeredAt.Format "2006-01-02 15:04:05" }}
-- 主机: {{ .AlertEvent.Hostname }}
-{{ if .AlertEvent.ServiceName }}- 服务: {{ .AlertEvent.ServiceName }}{{ end }}
-- 指标: {{ .AlertEvent.MetricName }}
-- 当前值: {{ .AlertEvent.MetricValue }}
-- 阈值: {{ .AlertEvent.Operator }} {{ .AlertEvent.Threshold }}
-- 详情: {{ .AlertEvent.Message }}
-
-请及时处理!
-`)
-
-	// 初始化SMS模板
-	smsTpl, _ := template.New("sms").Parse(`【告警】{{ .AlertEvent.Severity }}-{{ .AlertEvent.AlertName }}: {{ .AlertEvent.Hostname }} {{ .AlertEvent.MetricName }} {{ .AlertEvent.MetricValue }} {{ .AlertEvent.Operator }} {{ .AlertEvent.Threshold }}`)
-
-	return &NotificationService{
-		db:                    db,
-		notificationConfigRepo: repository.NewNotificationConfigRepository(db),
+	s := &NotificationService{
+		db:                      db,
+		notificationConfigRepo:  repository.NewNotificationConfigRepository(db),
 		notificationHistoryRepo: repository.NewNotificationHistoryRepository(db),
-		cfg:                   cfg,
-		emailTemplate:         emailTpl,
-		smsTemplate:           smsTpl,
+		silenceSvc:              NewSilenceService(db),
+		cfg:                     cfg,
+		notifiers:               make(map[model.NotificationType]Notifier),
+		jobRepo:                 repository.NewNotificationJobRepository(db),
+		alertEventRepo:          repository.NewAlertEventRepository(db),
+		alertRuleRepo:           repository.NewAlertRuleRepository(db),
+		templateRepo:            repository.NewNotificationTemplateRepository(db),
+		inventoryRepo:           repository.NewInventoryRepository(db),
+		limiter:                 newRateLimiter(),
+		wake:                    make(chan struct{}, 1),
 	}
+
+	var smsProvider SMSProvider
+	if cfg.SMS.Provider != "" {
+		provider, err := NewSMSProvider(cfg.SMS, nil)
+		if err != nil {
+			log.Printf("初始化短信服务商失败: %v", err)
+		} else {
+			smsProvider = provider
+		}
+	}
+
+	s.RegisterNotifier(NewEmailNotifier(cfg.Email))
+	s.RegisterNotifier(NewSMSNotifier(smsProvider))
+	s.RegisterNotifier(NewWebhookNotifier(nil))
+	for _, notifier := range NewChatNotifiers(cfg.Server.ExternalURL, nil) {
+		s.RegisterNotifier(notifier)
+	}
+	return s
+}
+
+// RegisterNotifier 注册通知发送器，同类型的发送器会被替换
+func (s *NotificationService) RegisterNotifier(notifier Notifier) {
+	s.notifiersMu.Lock()
+	defer s.notifiersMu.Unlock()
+	s.notifiers[notifier.Type()] = notifier
+}
+
+// getNotifier 获取通知类型对应的发送器
+func (s *NotificationService) getNotifier(notificationType model.NotificationType) (Notifier, bool) {
+	s.notifiersMu.RLock()
+	defer s.notifiersMu.RUnlock()
+	notifier, ok := s.notifiers[notificationType]
+	return notifier, ok
 }
 
 // CreateNotificationConfig 创建通知配置
@@ -89,307 +114,150 @@ func (s *NotificationService) ListNotificationConfigs(page, pageSize int, filter
 	return s.notificationConfigRepo.List(page, pageSize, filters)
 }
 
-// SendAlertNotification 发送告警通知
+// SendAlertNotification 发送单个告警的通知
 func (s *NotificationService) SendAlertNotification(ctx context.Context, notificationID uint, alertEvent *model.AlertEvent) {
-	// 异步发送通知
-	go func() {
-		// 创建一个带取消的上下文，避免长时间阻塞
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		config, err := s.GetNotificationConfig(notificationID)
-		if err != nil {
-			s.logNotificationError(alertEvent.ID, notificationID, "", fmt.Sprintf("Failed to get notification config: %v", err))
-			return
-		}
-
-		// 检查通知配置是否启用
-		if !config.Enabled {
-			return
-		}
-
-		// 根据通知类型发送相应的通知
-		switch config.Type {
-		case model.NotificationTypeEmail:
-			s.sendEmailNotification(ctx, config, alertEvent)
-		case model.NotificationTypeSMS:
-			s.sendSMSNotification(ctx, config, alertEvent)
-		case model.NotificationTypeWebhook:
-			s.sendWebhookNotification(ctx, config, alertEvent)
-		}
-	}()
+	s.SendGroupNotification(ctx, notificationID, []*model.AlertEvent{alertEvent})
 }
 
-// sendEmailNotification 发送邮件通知
-func (s *NotificationService) sendEmailNotification(ctx context.Context, config *model.NotificationConfig, alertEvent *model.AlertEvent) {
-	// 检查邮件配置
-	if s.cfg.Email.SMTPServer == "" || s.cfg.Email.SMTPPort == 0 || s.cfg.Email.From == "" {
-		s.logNotificationError(alertEvent.ID, config.ID, "", "Email configuration is incomplete")
-		return
-	}
-
-	// 解析收件人列表
-	recipients := strings.Split(config.EmailRecipients, ",")
-	if len(recipients) == 0 {
-		s.logNotificationError(alertEvent.ID, config.ID, "", "No email recipients configured")
-		return
-	}
-
-	// 渲染邮件内容
-	data := struct {
-		AlertEvent *model.AlertEvent
-		Config     *model.NotificationConfig
-	}{
-		AlertEvent: alertEvent,
-		Config:     config,
-	}
-
-	var bodyBuf bytes.Buffer
-	if err := s.emailTemplate.Execute(&bodyBuf, data); err != nil {
-		s.logNotificationError(alertEvent.ID, config.ID, "", fmt.Sprintf("Failed to render email template: %v", err))
-		return
-	}
+// SendGroupNotification 将一组告警合并为一条通知发送
+func (s *NotificationService) SendGroupNotification(ctx context.Context, notificationID uint, alertEvents []*model.AlertEvent) {
+	s.sendGroupNotification(notificationID, NotificationKindFiring, alertEvents, nil)
+}
 
-	// 构建邮件头
-	body := bodyBuf.String()
-	parts := strings.SplitN(body, "\n\n", 2)
-	subject := strings.TrimPrefix(parts[0], "Subject: ")
-	
-	headers := make(map[string]string)
-	headers["From"] = s.cfg.Email.From
-	headers["To"] = strings.Join(recipients, ",")
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/plain; charset=\"utf-8\""
-	
-	var message bytes.Buffer
-	for k, v := range headers {
-		message.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
-	message.WriteString("\r\n")
-	message.WriteString(parts[1])
-
-	// 连接到SMTP服务器
-	auth := smtp.PlainAuth("", s.cfg.Email.Username, s.cfg.Email.Password, s.cfg.Email.SMTPServer)
-	
-	// 发送邮件
-	err := smtp.SendMail(
-		fmt.Sprintf("%s:%d", s.cfg.Email.SMTPServer, s.cfg.Email.SMTPPort),
-		auth,
-		s.cfg.Email.From,
-		recipients,
-		message.Bytes(),
-	)
-	
-	if err != nil {
-		s.logNotificationError(alertEvent.ID, config.ID, strings.Join(recipients, ","), fmt.Sprintf("Failed to send email: %v", err))
-		return
-	}
-	
-	// 记录发送成功
-	for _, recipient := range recipients {
-		s.logNotificationSuccess(alertEvent.ID, config.ID, model.NotificationTypeEmail, recipient)
-	}
+// SendEscalationNotification 发送告警升级通知，指定值班人员时邮件和短信只发送给该值班人员
+func (s *NotificationService) SendEscalationNotification(ctx context.Context, notificationID uint, alertEvents []*model.AlertEvent, onCall *model.OnCallContact) {
+	s.sendGroupNotification(notificationID, NotificationKindFiring, alertEvents, onCall)
 }
 
-// sendSMSNotification 发送短信通知
-func (s *NotificationService) sendSMSNotification(ctx context.Context, config *model.NotificationConfig, alertEvent *model.AlertEvent) {
-	// 检查SMS配置
-	if s.cfg.SMS.Provider == "" || s.cfg.SMS.APIKey == "" {
-		s.logNotificationError(alertEvent.ID, config.ID, "", "SMS configuration is incomplete")
-		return
-	}
+// SendFollowUpNotification 发送告警已确认或已恢复的后续消息，仅支持后续消息的通知渠道会发送
+func (s *NotificationService) SendFollowUpNotification(ctx context.Context, notificationID uint, kind NotificationKind, alertEvents []*model.AlertEvent) {
+	s.sendGroupNotification(notificationID, kind, alertEvents, nil)
+}
 
-	// 解析收件人列表
-	recipients := strings.Split(config.SMSRecipients, ",")
-	if len(recipients) == 0 {
-		s.logNotificationError(alertEvent.ID, config.ID, "", "No SMS recipients configured")
+// sendGroupNotification 将合并通知写入发送队列，onCall 不为空时替换通知配置中的接收人
+func (s *NotificationService) sendGroupNotification(notificationID uint, kind NotificationKind, alertEvents []*model.AlertEvent, onCall *model.OnCallContact) {
+	if len(alertEvents) == 0 {
 		return
 	}
 
-	// 渲染短信内容
-	data := struct {
-		AlertEvent *model.AlertEvent
-		Config     *model.NotificationConfig
-	}{
-		AlertEvent: alertEvent,
-		Config:     config,
-	}
-
-	var contentBuf bytes.Buffer
-	if err := s.smsTemplate.Execute(&contentBuf, data); err != nil {
-		s.logNotificationError(alertEvent.ID, config.ID, "", fmt.Sprintf("Failed to render SMS template: %v", err))
+	config, err := s.GetNotificationConfig(notificationID)
+	if err != nil {
+		s.logNotification(nil, alertEvents, notificationID, "", "FAILED", fmt.Sprintf("Failed to get notification config: %v", err))
 		return
 	}
-	content := contentBuf.String()
-
-	// 由于短信服务提供商不同，这里只是模拟发送短信的逻辑
-	// 实际项目中需要替换为真正的短信发送API
-	for _, recipient := range recipients {
-		// 模拟发送短信
-		// 实际项目中需要调用短信服务商的API
-		fmt.Printf("Sending SMS to %s: %s\n", recipient, content)
-		
-		// 记录发送成功
-		s.logNotificationSuccess(alertEvent.ID, config.ID, model.NotificationTypeSMS, recipient)
-	}
-}
 
-// sendWebhookNotification 发送Webhook通知
-func (s *NotificationService) sendWebhookNotification(ctx context.Context, config *model.NotificationConfig, alertEvent *model.AlertEvent) {
-	// 检查Webhook配置
-	if config.WebhookURL == "" {
-		s.logNotificationError(alertEvent.ID, config.ID, "", "Webhook URL is not configured")
+	// 检查通知配置是否启用
+	if !config.Enabled {
 		return
 	}
 
-	// 准备要发送的数据
-	var payload interface{}
-	
-	// 如果有自定义模板，使用模板渲染
-	if config.WebhookTemplate != "" {
-		tpl, err := template.New("webhook").Parse(config.WebhookTemplate)
-		if err != nil {
-			s.logNotificationError(alertEvent.ID, config.ID, config.WebhookURL, fmt.Sprintf("Failed to parse webhook template: %v", err))
+	// 不支持后续消息的通知渠道只发送告警通知
+	if kind != NotificationKindFiring {
+		notifier, ok := s.getNotifier(config.Type)
+		if !ok {
 			return
 		}
-		
-		data := struct {
-			AlertEvent *model.AlertEvent
-			Config     *model.NotificationConfig
-		}{
-			AlertEvent: alertEvent,
-			Config:     config,
-		}
-		
-		var contentBuf bytes.Buffer
-		if err := tpl.Execute(&contentBuf, data); err != nil {
-			s.logNotificationError(alertEvent.ID, config.ID, config.WebhookURL, fmt.Sprintf("Failed to render webhook template: %v", err))
+		if _, ok := notifier.(FollowUpNotifier); !ok {
 			return
 		}
-		
-		// 尝试解析JSON
-		var jsonPayload interface{}
-		if err := json.Unmarshal(contentBuf.Bytes(), &jsonPayload); err == nil {
-			payload = jsonPayload
-		} else {
-			// 如果不是有效的JSON，使用纯文本
-			payload = map[string]string{
-				"text": contentBuf.String(),
-			}
-		}
-	} else {
-		// 使用默认格式
-		payload = map[string]interface{}{
-			"alert": map[string]interface{}{
-				"id":          alertEvent.ID,
-				"name":        alertEvent.AlertName,
-				"severity":    alertEvent.Severity,
-				"status":      alertEvent.Status,
-				"hostname":    alertEvent.Hostname,
-				"service":     alertEvent.ServiceName,
-				"metric_name": alertEvent.MetricName,
-				"value":       alertEvent.MetricValue,
-				"threshold":   alertEvent.Threshold,
-				"operator":    alertEvent.Operator,
-				"message":     alertEvent.Message,
-				"triggered_at": alertEvent.TriggeredAt,
-			},
-		}
 	}
 
-	// 序列化为JSON
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		s.logNotificationError(alertEvent.ID, config.ID, config.WebhookURL, fmt.Sprintf("Failed to marshal JSON: %v", err))
-		return
+	// 值班人员缺少对应联系方式时仍发送给配置中的接收人
+	var recipients string
+	if onCall != nil && config.Type == model.NotificationTypeEmail {
+		recipients = onCall.Email
 	}
-
-	// 设置HTTP请求方法
-	method := config.WebhookMethod
-	if method == "" {
-		method = "POST"
+	if onCall != nil && config.Type == model.NotificationTypeSMS {
+		recipients = onCall.Phone
 	}
 
-	// 创建HTTP请求
-	req, err := http.NewRequestWithContext(ctx, method, config.WebhookURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		s.logNotificationError(alertEvent.ID, config.ID, config.WebhookURL, fmt.Sprintf("Failed to create HTTP request: %v", err))
+	// 维护窗口内只记录告警事件，不发送通知
+	var pending []*model.AlertEvent
+	for _, alertEvent := range alertEvents {
+		window, err := s.silenceSvc.FindActiveMaintenanceWindow(alertEvent, time.Now())
+		if err != nil {
+			fmt.Printf("Failed to check maintenance windows: %v\n", err)
+		} else if window != nil {
+			s.logNotificationSuppressed(alertEvent.ID, config, fmt.Sprintf("Suppressed by maintenance window %d (%s)", window.ID, window.Name))
+			continue
+		}
+		pending = append(pending, alertEvent)
+	}
+	if len(pending) == 0 {
 		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	
-	// 添加自定义HTTP头
-	if config.WebhookHeaders != "" {
-		var headers map[string]string
-		if err := json.Unmarshal([]byte(config.WebhookHeaders), &headers); err == nil {
-			for key, value := range headers {
-				req.Header.Set(key, value)
-			}
-		}
+	if err := s.enqueue(config, kind, pending, recipients); err != nil {
+		s.logNotification(nil, pending, config.ID, "", "FAILED", fmt.Sprintf("Failed to enqueue notification: %v", err))
 	}
+}
 
-	// 发送HTTP请求
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	
-	resp, err := client.Do(req)
-	if err != nil {
-		s.logNotificationError(alertEvent.ID, config.ID, config.WebhookURL, fmt.Sprintf("Failed to send webhook: %v", err))
-		return
+// deliver 使用通知类型对应的发送器发送通知，返回每个接收人的结果
+func (s *NotificationService) deliver(ctx context.Context, notification *Notification) []DeliveryResult {
+	config := notification.Config
+	notifier, ok := s.getNotifier(config.Type)
+	if !ok {
+		return resultsFor(nil, fmt.Errorf("no notifier registered for type %s", config.Type))
 	}
-	defer resp.Body.Close()
 
-	// 检查响应状态
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		s.logNotificationError(alertEvent.ID, config.ID, config.WebhookURL, fmt.Sprintf("Webhook returned non-success status: %d", resp.StatusCode))
-		return
+	if notification.Kind == NotificationKindFiring {
+		return notifier.Send(ctx, notification)
 	}
-
-	// 记录发送成功
-	s.logNotificationSuccess(alertEvent.ID, config.ID, model.NotificationTypeWebhook, config.WebhookURL)
+	if followUp, ok := notifier.(FollowUpNotifier); ok {
+		return followUp.SendFollowUp(ctx, notification)
+	}
+	return resultsFor(nil, fmt.Errorf("notifier %s does not support %s messages", config.Type, notification.Kind))
 }
 
-// logNotificationSuccess 记录通知发送成功
-func (s *NotificationService) logNotificationSuccess(alertEventID, notificationConfigID uint, notificationType model.NotificationType, recipient string) {
+// logNotificationSuppressed 记录通知被维护窗口抑制
+func (s *NotificationService) logNotificationSuppressed(alertEventID uint, config *model.NotificationConfig, message string) {
 	history := &model.NotificationHistory{
-		AlertEventID:        alertEventID,
-		NotificationConfigID: notificationConfigID,
-		Type:                notificationType,
-		Status:              "SUCCESS",
-		Recipient:           recipient,
-		SentAt:              time.Now(),
-		CreatedAt:           time.Now(),
+		AlertEventID:         alertEventID,
+		NotificationConfigID: config.ID,
+		Type:                 config.Type,
+		Status:               "SUPPRESSED",
+		Message:              message,
+		SentAt:               time.Now(),
+		CreatedAt:            time.Now(),
 	}
-	
+
 	if err := s.notificationHistoryRepo.Create(history); err != nil {
-		fmt.Printf("Failed to log notification success: %v\n", err)
+		fmt.Printf("Failed to log notification suppression: %v\n", err)
 	}
 }
 
-// logNotificationError 记录通知发送失败
-func (s *NotificationService) logNotificationError(alertEventID, notificationConfigID uint, recipient, message string) {
+// logNotification 记录一次发送结果，合并通知为组内每个告警各记录一条，job 为nil表示未进入发送队列
+func (s *NotificationService) logNotification(job *model.NotificationJob, alertEvents []*model.AlertEvent, notificationConfigID uint, recipient, status, message string) {
 	var notificationType model.NotificationType
-	
+
 	config, err := s.GetNotificationConfig(notificationConfigID)
 	if err == nil {
 		notificationType = config.Type
 	}
-	
-	history := &model.NotificationHistory{
-		AlertEventID:        alertEventID,
-		NotificationConfigID: notificationConfigID,
-		Type:                notificationType,
-		Status:              "FAILED",
-		Message:             message,
-		Recipient:           recipient,
-		SentAt:              time.Now(),
-		CreatedAt:           time.Now(),
+
+	var jobID uint
+	var attempt int
+	if job != nil {
+		jobID = job.ID
+		attempt = job.Attempts
 	}
-	
-	if err := s.notificationHistoryRepo.Create(history); err != nil {
-		fmt.Printf("Failed to log notification error: %v\n", err)
+
+	for _, alertEvent := range alertEvents {
+		history := &model.NotificationHistory{
+			AlertEventID:         alertEvent.ID,
+			NotificationConfigID: notificationConfigID,
+			Type:                 notificationType,
+			Status:               status,
+			Message:              truncate(message, 500),
+			Recipient:            recipient,
+			JobID:                jobID,
+			Attempt:              attempt,
+			SentAt:               time.Now(),
+			CreatedAt:            time.Now(),
+		}
+
+		if err := s.notificationHistoryRepo.Create(history); err != nil {
+			fmt.Printf("Failed to log notification result: %v\n", err)
+		}
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// templateFuncs 通知模板可用的函数
+var templateFuncs = template.FuncMap{
+	"join":         strings.Join,
+	"upper":        strings.ToUpper,
+	"lower":        strings.ToLower,
+	"trim":         strings.TrimSpace,
+	"contains":     strings.Contains,
+	"replace":      strings.ReplaceAll,
+	"truncate":     templateTruncate,
+	"formatTime":   templateFormatTime,
+	"since":        templateSince,
+	"default":      templateDefault,
+	"formatValue":  templateFormatValue,
+	"severityText": templateSeverityText,
+	"toJSON":       templateToJSON,
+}
+
+// alertTemplateData 通知模板数据，AlertEvent 为分组中最早触发的告警，Rule、Host、Cluster 查询不到时为空值
+type alertTemplateData struct {
+	AlertEvent  *model.AlertEvent
+	AlertEvents []*model.AlertEvent
+	Count       int
+	Hosts       []string
+	Config      *model.NotificationConfig
+	Rule        *model.AlertRule
+	Host        *model.HostInfo
+	Cluster     *model.ClusterInfo
+	EventURL    string
+}
+
+// newAlertTemplateData 构造通知模板数据
+func newAlertTemplateData(config *model.NotificationConfig, alertEvents []*model.AlertEvent) alertTemplateData {
+	return alertTemplateData{
+		AlertEvent:  alertEvents[0],
+		AlertEvents: alertEvents,
+		Count:       len(alertEvents),
+		Hosts:       affectedHosts(alertEvents),
+		Config:      config,
+		Rule:        &model.AlertRule{},
+		Host:        &model.HostInfo{},
+		Cluster:     &model.ClusterInfo{},
+	}
+}
+
+// affectedHosts 按首次出现顺序返回告警涉及的主机名
+func affectedHosts(alertEvents []*model.AlertEvent) []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, event := range alertEvents {
+		if event.Hostname == "" || seen[event.Hostname] {
+			continue
+		}
+		seen[event.Hostname] = true
+		hosts = append(hosts, event.Hostname)
+	}
+	return hosts
+}
+
+// templateTruncate 截取前 n 个字符，用法: {{ truncate 20 .AlertEvent.Message }}
+func templateTruncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// templateFormatTime 格式化时间，支持 time.Time 和 *time.Time，用法: {{ formatTime "15:04" .AlertEvent.TriggeredAt }}
+func templateFormatTime(layout string, t interface{}) string {
+	switch v := t.(type) {
+	case time.Time:
+		return v.Format(layout)
+	case *time.Time:
+		if v != nil {
+			return v.Format(layout)
+		}
+	}
+	return ""
+}
+
+// templateSince 距今的时长，精确到秒
+func templateSince(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
+
+// templateDefault 值为空时使用默认值，用法: {{ default "-" .AlertEvent.ServiceName }}
+func templateDefault(def, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if v == "" {
+			return def
+		}
+	}
+	return value
+}
+
+// templateFormatValue 格式化指标值，最多保留两位小数
+func templateFormatValue(value float64) string {
+	text := strings.TrimRight(strconv.FormatFloat(value, 'f', 2, 64), "0")
+	return strings.TrimSuffix(text, ".")
+}
+
+// templateSeverityText 告警级别的中文名称
+func templateSeverityText(severity model.AlertSeverity) string {
+	switch severity {
+	case model.SeverityCritical:
+		return "严重"
+	case model.SeverityWarning:
+		return "警告"
+	case model.SeverityInfo:
+		return "提示"
+	default:
+		return string(severity)
+	}
+}
+
+// templateToJSON 序列化为JSON，用于在Webhook模板中安全地嵌入字符串
+func templateToJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// parseNotificationTemplate 解析通知模板
+func parseNotificationTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// renderCustomTemplate 渲染自定义模板的主题和内容，主题为空时返回空字符串
+func renderCustomTemplate(tpl *model.NotificationTemplate, data alertTemplateData) (string, string, error) {
+	var subject string
+	if tpl.Subject != "" {
+		subjectTpl, err := parseNotificationTemplate("subject", tpl.Subject)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse template subject: %v", err)
+		}
+		if subject, err = renderTemplate(subjectTpl, data); err != nil {
+			return "", "", fmt.Errorf("failed to render template subject: %v", err)
+		}
+		subject = strings.TrimSpace(subject)
+	}
+
+	contentTpl, err := parseNotificationTemplate("content", tpl.Content)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse template content: %v", err)
+	}
+	content, err := renderTemplate(contentTpl, data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render template content: %v", err)
+	}
+	return subject, content, nil
+}
+
+// TemplatePreview 通知模板预览结果
+type TemplatePreview struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+	Length  int    `json:"length"` // 内容字符数，用于估算短信条数
+}
+
+// CreateNotificationTemplate 创建通知模板
+func (s *NotificationService) CreateNotificationTemplate(tpl *model.NotificationTemplate) error {
+	if err := s.ValidateNotificationTemplate(tpl); err != nil {
+		return err
+	}
+	tpl.CreatedAt = time.Now()
+	tpl.UpdatedAt = time.Now()
+	return s.templateRepo.Create(tpl)
+}
+
+// UpdateNotificationTemplate 更新通知模板
+func (s *NotificationService) UpdateNotificationTemplate(tpl *model.NotificationTemplate) error {
+	if err := s.ValidateNotificationTemplate(tpl); err != nil {
+		return err
+	}
+	tpl.UpdatedAt = time.Now()
+	return s.templateRepo.Update(tpl)
+}
+
+// DeleteNotificationTemplate 删除通知模板
+func (s *NotificationService) DeleteNotificationTemplate(id uint) error {
+	return s.templateRepo.Delete(id)
+}
+
+// GetNotificationTemplate 获取通知模板
+func (s *NotificationService) GetNotificationTemplate(id uint) (*model.NotificationTemplate, error) {
+	return s.templateRepo.GetByID(id)
+}
+
+// ListNotificationTemplates 列出通知模板
+func (s *NotificationService) ListNotificationTemplates(page, pageSize int, filters map[string]interface{}) ([]*model.NotificationTemplate, int64, error) {
+	return s.templateRepo.List(page, pageSize, filters)
+}
+
+// ValidateNotificationTemplate 校验通知模板的适用范围，并使用示例告警试渲染模板
+func (s *NotificationService) ValidateNotificationTemplate(tpl *model.NotificationTemplate) error {
+	if strings.TrimSpace(tpl.Name) == "" {
+		return errors.New("template name is required")
+	}
+	if strings.TrimSpace(tpl.Content) == "" {
+		return errors.New("template content is required")
+	}
+	if _, ok := s.getNotifier(tpl.Type); !ok {
+		return fmt.Errorf("unsupported notification type: %q", tpl.Type)
+	}
+	switch tpl.Severity {
+	case "", model.SeverityCritical, model.SeverityWarning, model.SeverityInfo:
+	default:
+		return fmt.Errorf("invalid severity: %q", tpl.Severity)
+	}
+	if tpl.NotificationConfigID != nil {
+		config, err := s.GetNotificationConfig(*tpl.NotificationConfigID)
+		if err != nil {
+			return fmt.Errorf("notification config %d not found", *tpl.NotificationConfigID)
+		}
+		if config.Type != tpl.Type {
+			return fmt.Errorf("notification config %d is of type %s, not %s", config.ID, config.Type, tpl.Type)
+		}
+	}
+
+	_, err := s.PreviewNotificationTemplate(tpl, 0)
+	return err
+}
+
+// PreviewNotificationTemplate 渲染模板预览，alertEventID 为0时使用示例告警
+func (s *NotificationService) PreviewNotificationTemplate(tpl *model.NotificationTemplate, alertEventID uint) (*TemplatePreview, error) {
+	var notification *Notification
+	if alertEventID == 0 {
+		notification = sampleNotification(tpl.Type)
+	} else {
+		event, err := s.alertEventRepo.GetByID(alertEventID)
+		if err != nil {
+			return nil, fmt.Errorf("alert event %d not found", alertEventID)
+		}
+		config := &model.NotificationConfig{Type: tpl.Type}
+		if tpl.NotificationConfigID != nil {
+			if c, err := s.GetNotificationConfig(*tpl.NotificationConfigID); err == nil {
+				config = c
+			}
+		}
+		notification = s.newNotification(NotificationKindFiring, config, []*model.AlertEvent{event})
+	}
+
+	subject, content, err := renderCustomTemplate(tpl, notification.templateData())
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePreview{
+		Subject: subject,
+		Content: content,
+		Length:  len([]rune(content)),
+	}, nil
+}
+
+// newNotification 构造待发送的通知，加载模板需要的规则、主机和集群信息，告警通知使用匹配的自定义模板
+func (s *NotificationService) newNotification(kind NotificationKind, config *model.NotificationConfig, alertEvents []*model.AlertEvent) *Notification {
+	first := alertEvents[0]
+	notification := &Notification{
+		Kind:        kind,
+		Config:      config,
+		AlertEvents: alertEvents,
+		BaseURL:     s.cfg.Server.ExternalURL,
+	}
+
+	if rule, err := s.alertRuleRepo.GetByID(first.AlertRuleID); err == nil {
+		notification.Rule = rule
+	}
+	clusterID := first.ClusterID
+	if first.HostID != nil {
+		if host, err := s.inventoryRepo.GetHost(*first.HostID); err == nil {
+			notification.Host = host
+			if clusterID == nil {
+				clusterID = &host.ClusterID
+			}
+		}
+	}
+	if clusterID != nil {
+		if cluster, err := s.inventoryRepo.GetCluster(*clusterID); err == nil {
+			notification.Cluster = cluster
+		}
+	}
+
+	// 确认、恢复等后续消息使用内置格式
+	if kind == NotificationKindFiring && config.ID != 0 {
+		notification.Template = s.findTemplate(config, first.Severity)
+	}
+	return notification
+}
+
+// findTemplate 选择通知配置适用的模板，专用模板优先于通用模板，指定级别的模板优先于全部级别的模板
+func (s *NotificationService) findTemplate(config *model.NotificationConfig, severity model.AlertSeverity) *model.NotificationTemplate {
+	candidates, err := s.templateRepo.ListCandidates(config.Type, config.ID)
+	if err != nil {
+		fmt.Printf("Failed to load notification templates: %v\n", err)
+		return nil
+	}
+
+	var best *model.NotificationTemplate
+	bestScore := -1
+	for _, tpl := range candidates {
+		if tpl.Severity != "" && tpl.Severity != severity {
+			continue
+		}
+		score := 0
+		if tpl.NotificationConfigID != nil {
+			score += 2
+		}
+		if tpl.Severity != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = tpl, score
+		}
+	}
+	return best
+}
+
+// sampleNotification 预览和校验模板使用的示例通知
+func sampleNotification(notificationType model.NotificationType) *Notification {
+	now := time.Now()
+	clusterID, hostID, serviceID := uint(1), uint(1), uint(1)
+	event := &model.AlertEvent{
+		ID:          1,
+		AlertRuleID: 1,
+		AlertName:   "CPU使用率过高",
+		ClusterID:   &clusterID,
+		ServiceID:   &serviceID,
+		HostID:      &hostID,
+		Hostname:    "node-01",
+		ServiceName: "HDFS",
+		MetricName:  "cpu_usage",
+		MetricValue: 95.5,
+		Threshold:   90,
+		Operator:    ">",
+		Message:     "node-01 cpu_usage 95.5 > 90",
+		Severity:    model.SeverityCritical,
+		Status:      model.AlertStatusOpen,
+		TriggeredAt: now.Add(-5 * time.Minute),
+		CreatedAt:   now.Add(-5 * time.Minute),
+		UpdatedAt:   now,
+	}
+
+	return &Notification{
+		Kind: NotificationKindFiring,
+		Config: &model.NotificationConfig{
+			ID:              1,
+			Name:            "示例通知配置",
+			Type:            notificationType,
+			EmailRecipients: "ops@example.com",
+			SMSRecipients:   "13800000000",
+			Enabled:         true,
+		},
+		AlertEvents: []*model.AlertEvent{event},
+		Rule: &model.AlertRule{
+			ID:          1,
+			Name:        event.AlertName,
+			Description: "CPU使用率持续超过90%",
+			MetricName:  event.MetricName,
+			ClusterID:   &clusterID,
+			Operator:    model.OpGreaterThan,
+			Threshold:   event.Threshold,
+			Duration:    60,
+			Severity:    event.Severity,
+			Enabled:     true,
+		},
+		Host: &model.HostInfo{
+			ID:           hostID,
+			Hostname:     event.Hostname,
+			IP:           "192.168.1.11",
+			ClusterID:    clusterID,
+			CPUCores:     16,
+			MemorySize:   64 * 1024,
+			Status:       "ONLINE",
+			AgentVersion: "1.0.0",
+		},
+		Cluster: &model.ClusterInfo{ID: clusterID, Name: "production", Description: "生产集群"},
+		BaseURL: "http://localhost:8080",
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// NotificationKind 通知类别
+type NotificationKind string
+
+const (
+	NotificationKindFiring       NotificationKind = "FIRING"       // 告警触发
+	NotificationKindAcknowledged NotificationKind = "ACKNOWLEDGED" // 告警已确认的后续消息
+	NotificationKindResolved     NotificationKind = "RESOLVED"     // 告警已恢复的后续消息
+)
+
+// Notification 一次待发送的通知，AlertEvents 为合并发送的同组告警
+type Notification struct {
+	Kind        NotificationKind
+	Config      *model.NotificationConfig
+	AlertEvents []*model.AlertEvent
+
+	// 模板可用的关联信息，查询不到时为nil
+	Rule    *model.AlertRule
+	Host    *model.HostInfo
+	Cluster *model.ClusterInfo
+	BaseURL string // 平台对外访问地址
+
+	// 匹配的自定义模板，为nil时使用发送器的内置模板
+	Template *model.NotificationTemplate
+}
+
+// templateData 构造通知模板数据
+func (n *Notification) templateData() alertTemplateData {
+	data := newAlertTemplateData(n.Config, n.AlertEvents)
+	if n.Rule != nil {
+		data.Rule = n.Rule
+	}
+	if n.Host != nil {
+		data.Host = n.Host
+	}
+	if n.Cluster != nil {
+		data.Cluster = n.Cluster
+	}
+	if n.BaseURL != "" {
+		data.EventURL = fmt.Sprintf("%s/alert-events/%d", strings.TrimRight(n.BaseURL, "/"), data.AlertEvent.ID)
+	}
+	return data
+}
+
+// renderCustom 渲染匹配的自定义模板，没有自定义模板时 ok 为false
+func (n *Notification) renderCustom() (subject, content string, ok bool, err error) {
+	if n.Template == nil {
+		return "", "", false, nil
+	}
+	subject, content, err = renderCustomTemplate(n.Template, n.templateData())
+	return subject, content, true, err
+}
+
+// DeliveryResult 单个接收人的发送结果，Recipient 为空表示发送前即失败
+type DeliveryResult struct {
+	Recipient string
+	Err       error
+}
+
+// Notifier 通知发送器，每种通知类型对应一个实现
+type Notifier interface {
+	// Type 返回发送器处理的通知类型
+	Type() model.NotificationType
+	// Send 发送通知并返回每个接收人的发送结果
+	Send(ctx context.Context, n *Notification) []DeliveryResult
+}
+
+// FollowUpNotifier 支持发送告警确认、恢复后续消息的通知发送器
+type FollowUpNotifier interface {
+	Notifier
+	// SendFollowUp 发送后续消息，Notification.Kind 表示消息类别
+	SendFollowUp(ctx context.Context, n *Notification) []DeliveryResult
+}
+
+// resultsFor 为所有接收人生成相同的发送结果，没有接收人时生成一条不带接收人的结果
+func resultsFor(recipients []string, err error) []DeliveryResult {
+	if len(recipients) == 0 {
+		return []DeliveryResult{{Err: err}}
+	}
+	results := make([]DeliveryResult, 0, len(recipients))
+	for _, recipient := range recipients {
+		results = append(results, DeliveryResult{Recipient: recipient, Err: err})
+	}
+	return results
+}
+
+// splitRecipients 解析逗号分隔的接收人列表，忽略空项
+func splitRecipients(value string) []string {
+	var recipients []string
+	for _, recipient := range strings.Split(value, ",") {
+		recipient = strings.TrimSpace(recipient)
+		if recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	return recipients
+}
+
+// renderTemplate 渲染文本模板
+func renderTemplate(tpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,575 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// maxChatAlertLines 群消息中最多列出的告警明细条数
+const maxChatAlertLines = 10
+
+// chatMessage 群机器人消息的通用内容，由各平台转换为自己的卡片格式，Body 不为空时代替 Fields 和 Lines
+type chatMessage struct {
+	Title  string
+	Color  string // red、orange、blue、green
+	Fields []chatField
+	Lines  []string
+	Body   string // 自定义模板渲染的 markdown 内容
+	Links  []chatLink
+}
+
+// chatField 消息中的概要字段
+type chatField struct {
+	Name  string
+	Value string
+}
+
+// chatLink 消息中的跳转链接
+type chatLink struct {
+	Text string
+	URL  string
+}
+
+// buildChatMessage 根据通知类别构造群消息内容，baseURL 为空时不生成跳转链接
+func buildChatMessage(n *Notification, baseURL string) *chatMessage {
+	events := n.AlertEvents
+	first := events[0]
+	msg := &chatMessage{}
+
+	switch n.Kind {
+	case NotificationKindAcknowledged:
+		msg.Title = "[已确认] " + first.AlertName
+		msg.Color = "blue"
+		if first.AcknowledgedAt != nil {
+			msg.Fields = append(msg.Fields, chatField{"确认时间", first.AcknowledgedAt.Format("2006-01-02 15:04:05")})
+		}
+		if first.AcknowledgedBy != nil {
+			msg.Fields = append(msg.Fields, chatField{"确认人", "用户 " + strconv.FormatUint(uint64(*first.AcknowledgedBy), 10)})
+		}
+	case NotificationKindResolved:
+		msg.Title = "[已恢复] " + first.AlertName
+		msg.Color = "green"
+		if first.ResolvedAt != nil {
+			msg.Fields = append(msg.Fields,
+				chatField{"恢复时间", first.ResolvedAt.Format("2006-01-02 15:04:05")},
+				chatField{"持续时间", first.ResolvedAt.Sub(first.TriggeredAt).Round(time.Second).String()},
+			)
+		}
+	default:
+		msg.Title = fmt.Sprintf("[%s] %s", first.Severity, first.AlertName)
+		msg.Color = severityColor(first.Severity)
+		msg.Fields = append(msg.Fields,
+			chatField{"级别", string(first.Severity)},
+			chatField{"触发时间", first.TriggeredAt.Format("2006-01-02 15:04:05")},
+		)
+		if first.Operator != "" {
+			msg.Fields = append(msg.Fields, chatField{"阈值", fmt.Sprintf("%s %s %v", first.MetricName, first.Operator, first.Threshold)})
+		}
+	}
+
+	if len(events) > 1 {
+		msg.Title += fmt.Sprintf(" (%d个告警)", len(events))
+	}
+	if hosts := affectedHosts(events); len(hosts) > 0 {
+		msg.Fields = append(msg.Fields, chatField{"主机", strings.Join(hosts, ", ")})
+	}
+
+	for i, event := range events {
+		if i == maxChatAlertLines {
+			msg.Lines = append(msg.Lines, fmt.Sprintf("……及其他 %d 个告警", len(events)-maxChatAlertLines))
+			break
+		}
+		line := event.Message
+		if line == "" {
+			line = fmt.Sprintf("%s %s = %v", event.Hostname, event.MetricName, event.MetricValue)
+		}
+		msg.Lines = append(msg.Lines, line)
+	}
+
+	if baseURL != "" {
+		baseURL = strings.TrimRight(baseURL, "/")
+		if len(events) == 1 {
+			msg.Links = append(msg.Links, chatLink{"查看告警", fmt.Sprintf("%s/alert-events/%d", baseURL, first.ID)})
+		} else {
+			msg.Links = append(msg.Links, chatLink{"查看全部告警", fmt.Sprintf("%s/alert-events?alert_rule_id=%d", baseURL, first.AlertRuleID)})
+		}
+		if n.Kind == NotificationKindFiring {
+			msg.Links = append(msg.Links, chatLink{"静默", fmt.Sprintf("%s/silences/new?alert_rule_id=%d", baseURL, first.AlertRuleID)})
+		}
+	}
+	return msg
+}
+
+// severityColor 告警级别对应的消息颜色
+func severityColor(severity model.AlertSeverity) string {
+	switch severity {
+	case model.SeverityCritical:
+		return "red"
+	case model.SeverityWarning:
+		return "orange"
+	default:
+		return "blue"
+	}
+}
+
+// chatNotifier 群机器人通知的公共部分
+type chatNotifier struct {
+	baseURL string
+	client  *http.Client
+}
+
+// post 以JSON格式发送消息，check 用于校验平台返回的业务状态
+func (c *chatNotifier) post(ctx context.Context, target string, payload interface{}, check func(body []byte) error) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("robot returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return check(body)
+}
+
+// checkErrcode 校验钉钉、企业微信返回的 errcode
+func checkErrcode(body []byte) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("robot error %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// send 校验机器人配置、构造消息并发送，接收人记录为机器人地址
+func (c *chatNotifier) send(ctx context.Context, notification *Notification, deliver func(msg *chatMessage) error) []DeliveryResult {
+	config := notification.Config
+	if config.WebhookURL == "" {
+		return resultsFor(nil, errors.New("robot webhook URL is not configured"))
+	}
+	msg := buildChatMessage(notification, c.baseURL)
+
+	// 自定义模板替换消息内容，未设置主题时保留内置标题
+	subject, content, custom, err := notification.renderCustom()
+	if err != nil {
+		return resultsFor(nil, fmt.Errorf("failed to render robot message template: %v", err))
+	}
+	if custom {
+		if subject != "" {
+			msg.Title = subject
+		}
+		msg.Body = strings.TrimSpace(content)
+	}
+	return resultsFor([]string{config.WebhookURL}, deliver(msg))
+}
+
+// NewChatNotifiers 创建钉钉、企业微信、飞书和Slack群机器人通知发送器，client 为nil时使用10秒超时的默认客户端
+func NewChatNotifiers(baseURL string, client *http.Client) []Notifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	base := chatNotifier{baseURL: baseURL, client: client}
+	return []Notifier{
+		&DingTalkNotifier{base},
+		&WeComNotifier{base},
+		&FeishuNotifier{base},
+		&SlackNotifier{base},
+	}
+}
+
+// DingTalkNotifier 钉钉群机器人，配置 BotSecret 时使用加签方式
+type DingTalkNotifier struct {
+	chatNotifier
+}
+
+// Type 返回钉钉通知类型
+func (n *DingTalkNotifier) Type() model.NotificationType {
+	return model.NotificationTypeDingTalk
+}
+
+// Send 发送告警消息
+func (n *DingTalkNotifier) Send(ctx context.Context, notification *Notification) []DeliveryResult {
+	secret := notification.Config.BotSecret
+	return n.send(ctx, notification, func(msg *chatMessage) error {
+		target, err := dingTalkSignedURL(notification.Config.WebhookURL, secret, time.Now())
+		if err != nil {
+			return err
+		}
+		return n.post(ctx, target, dingTalkPayload(msg), checkErrcode)
+	})
+}
+
+// SendFollowUp 发送确认、恢复后续消息
+func (n *DingTalkNotifier) SendFollowUp(ctx context.Context, notification *Notification) []DeliveryResult {
+	return n.Send(ctx, notification)
+}
+
+// dingTalkSignedURL 为机器人地址追加加签参数: sign = Base64(HMAC-SHA256(secret, timestamp + "\n" + secret))
+func dingTalkSignedURL(webhookURL, secret string, now time.Time) (string, error) {
+	if secret == "" {
+		return webhookURL, nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid webhook URL: %v", err)
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// dingTalkColors 钉钉 markdown 中使用的颜色
+var dingTalkColors = map[string]string{
+	"red":    "#E5484D",
+	"orange": "#F76B15",
+	"blue":   "#0090FF",
+	"green":  "#30A46C",
+}
+
+// dingTalkPayload 构造钉钉 ActionCard 消息，没有链接时使用 markdown 消息
+func dingTalkPayload(msg *chatMessage) map[string]interface{} {
+	var text strings.Builder
+	fmt.Fprintf(&text, "### <font color=%s>%s</font>\n\n", dingTalkColors[msg.Color], msg.Title)
+	if msg.Body != "" {
+		text.WriteString(msg.Body + "\n")
+	} else {
+		for _, field := range msg.Fields {
+			fmt.Fprintf(&text, "**%s**: %s  \n", field.Name, field.Value)
+		}
+		text.WriteString("\n")
+		for _, line := range msg.Lines {
+			fmt.Fprintf(&text, "- %s\n", line)
+		}
+	}
+
+	if len(msg.Links) == 0 {
+		return map[string]interface{}{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"title": msg.Title,
+				"text":  text.String(),
+			},
+		}
+	}
+
+	buttons := make([]map[string]string, 0, len(msg.Links))
+	for _, link := range msg.Links {
+		buttons = append(buttons, map[string]string{"title": link.Text, "actionURL": link.URL})
+	}
+	return map[string]interface{}{
+		"msgtype": "actionCard",
+		"actionCard": map[string]interface{}{
+			"title":          msg.Title,
+			"text":           text.String(),
+			"btnOrientation": "1",
+			"btns":           buttons,
+		},
+	}
+}
+
+// WeComNotifier 企业微信群机器人，机器人地址中的 key 即为凭证
+type WeComNotifier struct {
+	chatNotifier
+}
+
+// Type 返回企业微信通知类型
+func (n *WeComNotifier) Type() model.NotificationType {
+	return model.NotificationTypeWeCom
+}
+
+// Send 发送告警消息
+func (n *WeComNotifier) Send(ctx context.Context, notification *Notification) []DeliveryResult {
+	return n.send(ctx, notification, func(msg *chatMessage) error {
+		return n.post(ctx, notification.Config.WebhookURL, weComPayload(msg), checkErrcode)
+	})
+}
+
+// SendFollowUp 发送确认、恢复后续消息
+func (n *WeComNotifier) SendFollowUp(ctx context.Context, notification *Notification) []DeliveryResult {
+	return n.Send(ctx, notification)
+}
+
+// weComColors 企业微信 markdown 支持的颜色
+var weComColors = map[string]string{
+	"red":    "warning",
+	"orange": "warning",
+	"blue":   "comment",
+	"green":  "info",
+}
+
+// weComPayload 构造企业微信 markdown 消息
+func weComPayload(msg *chatMessage) map[string]interface{} {
+	var text strings.Builder
+	fmt.Fprintf(&text, "**<font color=\"%s\">%s</font>**\n", weComColors[msg.Color], msg.Title)
+	if msg.Body != "" {
+		text.WriteString(msg.Body + "\n")
+	} else {
+		for _, field := range msg.Fields {
+			fmt.Fprintf(&text, "> %s: %s\n", field.Name, field.Value)
+		}
+		for _, line := range msg.Lines {
+			fmt.Fprintf(&text, "- %s\n", line)
+		}
+	}
+	for _, link := range msg.Links {
+		fmt.Fprintf(&text, "[%s](%s)  ", link.Text, link.URL)
+	}
+
+	return map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": strings.TrimSpace(text.String()),
+		},
+	}
+}
+
+// FeishuNotifier 飞书群机器人，配置 BotSecret 时在消息体中携带签名
+type FeishuNotifier struct {
+	chatNotifier
+}
+
+// Type 返回飞书通知类型
+func (n *FeishuNotifier) Type() model.NotificationType {
+	return model.NotificationTypeFeishu
+}
+
+// Send 发送告警消息
+func (n *FeishuNotifier) Send(ctx context.Context, notification *Notification) []DeliveryResult {
+	secret := notification.Config.BotSecret
+	return n.send(ctx, notification, func(msg *chatMessage) error {
+		payload := feishuPayload(msg)
+		if secret != "" {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			payload["timestamp"] = timestamp
+			payload["sign"] = feishuSign(secret, timestamp)
+		}
+		return n.post(ctx, notification.Config.WebhookURL, payload, checkFeishuResponse)
+	})
+}
+
+// SendFollowUp 发送确认、恢复后续消息
+func (n *FeishuNotifier) SendFollowUp(ctx context.Context, notification *Notification) []DeliveryResult {
+	return n.Send(ctx, notification)
+}
+
+// feishuSign 计算飞书签名: Base64(HMAC-SHA256(key = timestamp + "\n" + secret, data = ""))
+func feishuSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkFeishuResponse 校验飞书返回的 code
+func checkFeishuResponse(body []byte) error {
+	var result struct {
+		Code       int    `json:"code"`
+		Msg        string `json:"msg"`
+		StatusCode int    `json:"StatusCode"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("robot error %d: %s", result.Code, result.Msg)
+	}
+	if result.StatusCode != 0 {
+		return fmt.Errorf("robot error %d", result.StatusCode)
+	}
+	return nil
+}
+
+// feishuPayload 构造飞书消息卡片
+func feishuPayload(msg *chatMessage) map[string]interface{} {
+	content := msg.Body
+	if content == "" {
+		var text strings.Builder
+		for _, field := range msg.Fields {
+			fmt.Fprintf(&text, "**%s**: %s\n", field.Name, field.Value)
+		}
+		content = strings.TrimSpace(text.String())
+	}
+
+	elements := []interface{}{
+		map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]string{"tag": "lark_md", "content": content},
+		},
+	}
+	if msg.Body == "" && len(msg.Lines) > 0 {
+		elements = append(elements,
+			map[string]string{"tag": "hr"},
+			map[string]interface{}{
+				"tag":  "div",
+				"text": map[string]string{"tag": "lark_md", "content": "- " + strings.Join(msg.Lines, "\n- ")},
+			},
+		)
+	}
+	if len(msg.Links) > 0 {
+		actions := make([]interface{}, 0, len(msg.Links))
+		for i, link := range msg.Links {
+			buttonType := "default"
+			if i == 0 {
+				buttonType = "primary"
+			}
+			actions = append(actions, map[string]interface{}{
+				"tag":  "button",
+				"text": map[string]string{"tag": "plain_text", "content": link.Text},
+				"type": buttonType,
+				"url":  link.URL,
+			})
+		}
+		elements = append(elements, map[string]interface{}{"tag": "action", "actions": actions})
+	}
+
+	return map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"config": map[string]bool{"wide_screen_mode": true},
+			"header": map[string]interface{}{
+				"title":    map[string]string{"tag": "plain_text", "content": msg.Title},
+				"template": msg.Color,
+			},
+			"elements": elements,
+		},
+	}
+}
+
+// SlackNotifier Slack Incoming Webhook
+type SlackNotifier struct {
+	chatNotifier
+}
+
+// Type 返回Slack通知类型
+func (n *SlackNotifier) Type() model.NotificationType {
+	return model.NotificationTypeSlack
+}
+
+// Send 发送告警消息
+func (n *SlackNotifier) Send(ctx context.Context, notification *Notification) []DeliveryResult {
+	return n.send(ctx, notification, func(msg *chatMessage) error {
+		return n.post(ctx, notification.Config.WebhookURL, slackPayload(msg), func(body []byte) error {
+			if text := strings.TrimSpace(string(body)); text != "" && text != "ok" {
+				return fmt.Errorf("slack error: %s", text)
+			}
+			return nil
+		})
+	})
+}
+
+// SendFollowUp 发送确认、恢复后续消息
+func (n *SlackNotifier) SendFollowUp(ctx context.Context, notification *Notification) []DeliveryResult {
+	return n.Send(ctx, notification)
+}
+
+// slackColors Slack 附件颜色
+var slackColors = map[string]string{
+	"red":    "#E01E5A",
+	"orange": "#ECB22E",
+	"blue":   "#36C5F0",
+	"green":  "#2EB67D",
+}
+
+// slackEscape 转义 Slack mrkdwn 中的控制字符
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// slackPayload 构造 Slack Block Kit 消息
+func slackPayload(msg *chatMessage) map[string]interface{} {
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": "*" + slackEscape(msg.Title) + "*"},
+		},
+	}
+
+	// 自定义模板内容按 mrkdwn 原样发送
+	if msg.Body != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": msg.Body},
+		})
+	}
+
+	if msg.Body == "" && len(msg.Fields) > 0 {
+		fields := make([]interface{}, 0, len(msg.Fields))
+		for _, field := range msg.Fields {
+			fields = append(fields, map[string]string{
+				"type": "mrkdwn",
+				"text": fmt.Sprintf("*%s*\n%s", slackEscape(field.Name), slackEscape(field.Value)),
+			})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+	}
+
+	if msg.Body == "" && len(msg.Lines) > 0 {
+		lines := make([]string, 0, len(msg.Lines))
+		for _, line := range msg.Lines {
+			lines = append(lines, "• "+slackEscape(line))
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": strings.Join(lines, "\n")},
+		})
+	}
+
+	if len(msg.Links) > 0 {
+		elements := make([]interface{}, 0, len(msg.Links))
+		for _, link := range msg.Links {
+			elements = append(elements, map[string]interface{}{
+				"type": "button",
+				"text": map[string]string{"type": "plain_text", "text": link.Text},
+				"url":  link.URL,
+			})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "actions", "elements": elements})
+	}
+
+	return map[string]interface{}{
+		"text": msg.Title,
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"color":  slackColors[msg.Color],
+				"blocks": blocks,
+			},
+		},
+	}
+}
@@ -0,0 +1,461 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// defaultEmailTemplate 默认邮件模板，首行为邮件主题
+const defaultEmailTemplate = `
+Subject: 【告警】{{ .AlertEvent.Severity }} - {{ .AlertEvent.AlertName }}{{ if gt .Count 1 }} ({{ .Count }}个告警){{ end }}
+
+告警信息:
+- 级别: {{ .AlertEvent.Severity }}
+- 名称: {{ .AlertEvent.AlertName }}
+- 时间: {{ .AlertEvent.TriggeredAt.Format "2006-01-02 15:04:05" }}
+- 主机: {{ .AlertEvent.Hostname }}
+{{ if .AlertEvent.ServiceName }}- 服务: {{ .AlertEvent.ServiceName }}{{ end }}
+- 指标: {{ .AlertEvent.MetricName }}
+- 当前值: {{ .AlertEvent.MetricValue }}
+- 阈值: {{ .AlertEvent.Operator }} {{ .AlertEvent.Threshold }}
+- 详情: {{ .AlertEvent.Message }}
+{{ if gt .Count 1 }}
+同组共 {{ .Count }} 个告警，涉及主机({{ len .Hosts }}): {{ join .Hosts ", " }}
+{{ range .AlertEvents }}- [{{ .TriggeredAt.Format "15:04:05" }}] {{ .Hostname }} {{ .MetricName }} = {{ .MetricValue }}
+{{ end }}{{ end }}{{ if .EventURL }}
+告警详情: {{ .EventURL }}
+{{ end }}
+请及时处理!
+`
+
+// defaultEmailHTMLTemplate 默认邮件HTML正文
+const defaultEmailHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, 'Microsoft YaHei', sans-serif; font-size: 14px; color: #333;">
+<h3 style="color: {{ if eq .AlertEvent.Severity "CRITICAL" }}#d93026{{ else if eq .AlertEvent.Severity "WARNING" }}#e37400{{ else }}#1a73e8{{ end }};">【告警】{{ .AlertEvent.Severity }} - {{ .AlertEvent.AlertName }}{{ if gt .Count 1 }} ({{ .Count }}个告警){{ end }}</h3>
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr><td><b>级别</b></td><td>{{ .AlertEvent.Severity }}</td></tr>
+<tr><td><b>名称</b></td><td>{{ .AlertEvent.AlertName }}</td></tr>
+<tr><td><b>时间</b></td><td>{{ .AlertEvent.TriggeredAt.Format "2006-01-02 15:04:05" }}</td></tr>
+<tr><td><b>主机</b></td><td>{{ .AlertEvent.Hostname }}</td></tr>
+{{ if .AlertEvent.ServiceName }}<tr><td><b>服务</b></td><td>{{ .AlertEvent.ServiceName }}</td></tr>
+{{ end }}<tr><td><b>指标</b></td><td>{{ .AlertEvent.MetricName }}</td></tr>
+<tr><td><b>当前值</b></td><td>{{ .AlertEvent.MetricValue }}</td></tr>
+<tr><td><b>阈值</b></td><td>{{ .AlertEvent.Operator }} {{ .AlertEvent.Threshold }}</td></tr>
+<tr><td><b>详情</b></td><td>{{ .AlertEvent.Message }}</td></tr>
+</table>
+{{ if gt .Count 1 }}<p>同组共 {{ .Count }} 个告警，涉及主机({{ len .Hosts }}): {{ join .Hosts ", " }}</p>
+<table border="1" cellpadding="4" style="border-collapse: collapse; border-color: #ddd;">
+<tr><th>时间</th><th>主机</th><th>指标</th><th>当前值</th></tr>
+{{ range .AlertEvents }}<tr><td>{{ .TriggeredAt.Format "15:04:05" }}</td><td>{{ .Hostname }}</td><td>{{ .MetricName }}</td><td>{{ .MetricValue }}</td></tr>
+{{ end }}</table>
+{{ end }}{{ if .EventURL }}<p><a href="{{ .EventURL }}">查看告警详情</a></p>
+{{ end }}<p>请及时处理!</p>
+</body>
+</html>
+`
+
+// defaultEmailTimeout SMTP 默认连接和发送超时
+const defaultEmailTimeout = 30 * time.Second
+
+// 邮件加密方式
+const (
+	emailSecurityTLS      = "tls"
+	emailSecuritySTARTTLS = "starttls"
+	emailSecurityNone     = "none"
+)
+
+// EmailNotifier 通过SMTP发送邮件通知，支持隐式TLS和STARTTLS，逐个收件人返回发送结果
+type EmailNotifier struct {
+	cfg          config.EmailConfig
+	template     *template.Template
+	htmlTemplate *htmltemplate.Template
+}
+
+// NewEmailNotifier 创建邮件通知发送器
+func NewEmailNotifier(cfg config.EmailConfig) *EmailNotifier {
+	return &EmailNotifier{
+		cfg:          cfg,
+		template:     template.Must(template.New("email").Funcs(templateFuncs).Parse(defaultEmailTemplate)),
+		htmlTemplate: htmltemplate.Must(htmltemplate.New("email_html").Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(defaultEmailHTMLTemplate)),
+	}
+}
+
+// Type 返回邮件通知类型
+func (n *EmailNotifier) Type() model.NotificationType {
+	return model.NotificationTypeEmail
+}
+
+// Send 发送邮件通知，地址无效或被服务器拒绝的收件人单独记录失败
+func (n *EmailNotifier) Send(ctx context.Context, notification *Notification) []DeliveryResult {
+	// 检查邮件配置
+	if n.cfg.SMTPServer == "" || n.cfg.SMTPPort == 0 || n.cfg.From == "" {
+		return resultsFor(nil, errors.New("email configuration is incomplete"))
+	}
+	from, err := mail.ParseAddress(n.cfg.From)
+	if err != nil {
+		return resultsFor(nil, fmt.Errorf("invalid sender address %q: %v", n.cfg.From, err))
+	}
+
+	// 解析收件人列表
+	recipients := splitRecipients(notification.Config.EmailRecipients)
+	if len(recipients) == 0 {
+		return resultsFor(nil, errors.New("no email recipients configured"))
+	}
+
+	// 渲染邮件内容
+	subject, textBody, htmlBody, err := n.render(notification)
+	if err != nil {
+		return resultsFor(nil, fmt.Errorf("failed to render email template: %v", err))
+	}
+
+	var results []DeliveryResult
+	var addresses []string
+	for _, recipient := range recipients {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			results = append(results, DeliveryResult{Recipient: recipient, Err: fmt.Errorf("invalid email address: %v", err)})
+			continue
+		}
+		addresses = append(addresses, address.Address)
+	}
+	if len(addresses) == 0 {
+		return results
+	}
+
+	message, err := buildEmailMessage(from, addresses, subject, textBody, htmlBody, time.Now())
+	if err != nil {
+		return append(results, resultsFor(addresses, fmt.Errorf("failed to build email: %v", err))...)
+	}
+
+	return append(results, n.sendMail(ctx, from.Address, addresses, message)...)
+}
+
+// render 渲染邮件主题、纯文本正文和HTML正文
+//
+// 自定义模板以 "<" 开头时视为HTML，纯文本正文由HTML去除标签得到；否则只发送纯文本正文。
+// 自定义模板未设置主题时使用内置模板的主题。
+func (n *EmailNotifier) render(notification *Notification) (subject, textBody, htmlBody string, err error) {
+	subject, content, custom, err := notification.renderCustom()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if custom {
+		if strings.HasPrefix(strings.TrimSpace(content), "<") {
+			htmlBody = content
+			textBody = htmlToText(content)
+		} else {
+			textBody = content
+		}
+		if subject != "" {
+			return subject, textBody, htmlBody, nil
+		}
+	}
+
+	defaultSubject, defaultContent, err := n.renderDefault(notification)
+	if err != nil {
+		return "", "", "", err
+	}
+	if custom {
+		return defaultSubject, textBody, htmlBody, nil
+	}
+
+	var htmlBuf bytes.Buffer
+	if err := n.htmlTemplate.Execute(&htmlBuf, notification.templateData()); err != nil {
+		return "", "", "", err
+	}
+	return defaultSubject, defaultContent, htmlBuf.String(), nil
+}
+
+// renderDefault 使用内置模板渲染邮件主题和正文
+func (n *EmailNotifier) renderDefault(notification *Notification) (string, string, error) {
+	body, err := renderTemplate(n.template, notification.templateData())
+	if err != nil {
+		return "", "", err
+	}
+
+	body = strings.TrimLeft(body, "\r\n")
+	parts := strings.SplitN(body, "\n\n", 2)
+	subject := strings.TrimPrefix(parts[0], "Subject: ")
+	content := ""
+	if len(parts) > 1 {
+		content = parts[1]
+	}
+	return subject, content, nil
+}
+
+// sendMail 在一个SMTP会话中发送邮件，逐个收件人返回 RCPT 结果，DATA 失败时所有已接受的收件人均失败
+func (n *EmailNotifier) sendMail(ctx context.Context, from string, recipients []string, message []byte) []DeliveryResult {
+	client, err := n.dial(ctx)
+	if err != nil {
+		return resultsFor(recipients, err)
+	}
+	defer client.Close()
+
+	if err := n.authenticate(client); err != nil {
+		return resultsFor(recipients, err)
+	}
+	if err := client.Mail(from); err != nil {
+		return resultsFor(recipients, fmt.Errorf("MAIL FROM rejected: %v", err))
+	}
+
+	var results []DeliveryResult
+	var accepted []string
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			results = append(results, DeliveryResult{Recipient: recipient, Err: fmt.Errorf("recipient rejected: %v", err)})
+			continue
+		}
+		accepted = append(accepted, recipient)
+	}
+	if len(accepted) == 0 {
+		return results
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return append(results, resultsFor(accepted, fmt.Errorf("DATA rejected: %v", err))...)
+	}
+	if _, err := writer.Write(message); err != nil {
+		writer.Close()
+		return append(results, resultsFor(accepted, fmt.Errorf("failed to write message: %v", err))...)
+	}
+	if err := writer.Close(); err != nil {
+		return append(results, resultsFor(accepted, fmt.Errorf("message rejected: %v", err))...)
+	}
+	client.Quit()
+
+	return append(results, resultsFor(accepted, nil)...)
+}
+
+// security 实际使用的加密方式，未配置时465端口使用隐式TLS，其余端口在服务器支持时使用STARTTLS
+func (n *EmailNotifier) security() string {
+	security := strings.ToLower(n.cfg.Security)
+	if security == "" && n.cfg.SMTPPort == 465 {
+		return emailSecurityTLS
+	}
+	return security
+}
+
+// dial 连接SMTP服务器并按配置建立TLS
+func (n *EmailNotifier) dial(ctx context.Context) (*smtp.Client, error) {
+	timeout := defaultEmailTimeout
+	if n.cfg.Timeout > 0 {
+		timeout = time.Duration(n.cfg.Timeout) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	addr := net.JoinHostPort(n.cfg.SMTPServer, strconv.Itoa(n.cfg.SMTPPort))
+	dialer := &net.Dialer{Deadline: deadline}
+	tlsConfig := &tls.Config{
+		ServerName:         n.cfg.SMTPServer,
+		InsecureSkipVerify: n.cfg.SkipVerify,
+	}
+
+	security := n.security()
+	var conn net.Conn
+	var err error
+	if security == emailSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.cfg.SMTPServer)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+
+	if security != emailSecurityTLS && security != emailSecurityNone {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, fmt.Errorf("STARTTLS failed: %v", err)
+			}
+		} else if security == emailSecuritySTARTTLS {
+			client.Close()
+			return nil, errors.New("SMTP server does not support STARTTLS")
+		}
+	}
+	return client, nil
+}
+
+// authenticate 配置了用户名时进行SMTP认证，服务器只支持 LOGIN 时使用 LOGIN 认证
+func (n *EmailNotifier) authenticate(client *smtp.Client) error {
+	if n.cfg.Username == "" {
+		return nil
+	}
+
+	ok, mechanisms := client.Extension("AUTH")
+	if !ok {
+		return errors.New("SMTP server does not support authentication")
+	}
+
+	var auth smtp.Auth
+	if !strings.Contains(strings.ToUpper(mechanisms), "PLAIN") && strings.Contains(strings.ToUpper(mechanisms), "LOGIN") {
+		auth = &loginAuth{username: n.cfg.Username, password: n.cfg.Password, host: n.cfg.SMTPServer}
+	} else {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.SMTPServer)
+	}
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("SMTP authentication failed: %v", err)
+	}
+	return nil
+}
+
+// loginAuth SMTP LOGIN 认证，与 smtp.PlainAuth 一样只在加密连接或本机上发送密码
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+// Start 开始 LOGIN 认证
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && a.host != "localhost" && a.host != "127.0.0.1" && a.host != "::1" {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+// Next 按服务器提示依次发送用户名和密码
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	prompt := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %q", fromServer)
+	}
+}
+
+// buildEmailMessage 构造邮件，主题和发件人名称按 RFC 2047 编码，有HTML正文时使用 multipart/alternative
+func buildEmailMessage(from *mail.Address, to []string, subject, textBody, htmlBody string, now time.Time) ([]byte, error) {
+	messageID, err := newMessageID(from.Address, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	writeHeader("From", from.String())
+	writeHeader("To", strings.Join(to, ", "))
+	writeHeader("Subject", mime.BEncoding.Encode("UTF-8", subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID)
+	writeHeader("MIME-Version", "1.0")
+
+	if htmlBody == "" {
+		writeHeader("Content-Type", "text/plain; charset=UTF-8")
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, textBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	writeHeader("Content-Type", "multipart/alternative; boundary=\""+writer.Boundary()+"\"")
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(partWriter, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable 以 quoted-printable 编码写入正文，换行统一为CRLF
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body, "\r\n", "\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// newMessageID 生成 Message-ID，域名取自发件人地址
+func newMessageID(from string, now time.Time) (string, error) {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	random, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<%d.%s@%s>", now.UnixNano(), random, domain), nil
+}
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|h[1-6]|li|table)>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLinePattern = regexp.MustCompile(`\n[ \t]*\n(\s*\n)+`)
+)
+
+// htmlToText 去除HTML标签生成纯文本正文
+func htmlToText(content string) string {
+	text := htmlBreakPattern.ReplaceAllString(content, "$0\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = blankLinePattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
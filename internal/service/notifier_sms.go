@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// defaultSMSTemplate 默认短信模板
+const defaultSMSTemplate = `【告警】{{ .AlertEvent.Severity }}-{{ .AlertEvent.AlertName }}: {{ .AlertEvent.Hostname }} {{ .AlertEvent.MetricName }} {{ .AlertEvent.MetricValue }} {{ .AlertEvent.Operator }} {{ .AlertEvent.Threshold }}{{ if gt .Count 1 }} 等{{ .Count }}个告警({{ len .Hosts }}台主机){{ end }}`
+
+// defaultSMSTimeout 短信接口默认请求超时
+const defaultSMSTimeout = 10 * time.Second
+
+// SMSMessage 待发送的短信，Content 为渲染后的完整内容，Params 为模板短信的变量
+type SMSMessage struct {
+	Content string
+	Params  map[string]string
+}
+
+// SMSProvider 短信服务商
+type SMSProvider interface {
+	// Name 返回服务商名称
+	Name() string
+	// Send 向一组手机号发送短信并返回每个号码的发送结果
+	Send(ctx context.Context, phones []string, msg *SMSMessage) []DeliveryResult
+}
+
+// NewSMSProvider 根据配置创建短信服务商，client 为nil时按配置的超时创建
+func NewSMSProvider(cfg config.SMSConfig, client *http.Client) (SMSProvider, error) {
+	if client == nil {
+		timeout := defaultSMSTimeout
+		if cfg.Timeout > 0 {
+			timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	switch strings.ToLower(cfg.Provider) {
+	case "aliyun":
+		return NewAliyunSMSProvider(cfg, client)
+	case "tencent":
+		return NewTencentSMSProvider(cfg, client)
+	case "http":
+		return NewHTTPSMSProvider(cfg, client)
+	default:
+		return nil, fmt.Errorf("unsupported SMS provider: %q", cfg.Provider)
+	}
+}
+
+// SMSNotifier 通过短信服务商发送短信通知
+type SMSNotifier struct {
+	provider SMSProvider
+	template *template.Template
+}
+
+// NewSMSNotifier 创建短信通知发送器，provider 为nil时发送会直接失败
+func NewSMSNotifier(provider SMSProvider) *SMSNotifier {
+	return &SMSNotifier{
+		provider: provider,
+		template: template.Must(template.New("sms").Funcs(templateFuncs).Parse(defaultSMSTemplate)),
+	}
+}
+
+// Type 返回短信通知类型
+func (n *SMSNotifier) Type() model.NotificationType {
+	return model.NotificationTypeSMS
+}
+
+// Send 发送短信通知
+func (n *SMSNotifier) Send(ctx context.Context, notification *Notification) []DeliveryResult {
+	// 检查SMS配置
+	if n.provider == nil {
+		return resultsFor(nil, errors.New("SMS provider is not configured"))
+	}
+
+	// 解析收件人列表
+	recipients := splitRecipients(notification.Config.SMSRecipients)
+	if len(recipients) == 0 {
+		return resultsFor(nil, errors.New("no SMS recipients configured"))
+	}
+
+	// 渲染短信内容，优先使用自定义模板
+	data := notification.templateData()
+	_, content, custom, err := notification.renderCustom()
+	if !custom {
+		content, err = renderTemplate(n.template, data)
+	}
+	if err != nil {
+		return resultsFor(nil, fmt.Errorf("failed to render SMS template: %v", err))
+	}
+	content = strings.TrimSpace(content)
+
+	return n.provider.Send(ctx, recipients, &SMSMessage{
+		Content: content,
+		Params:  smsTemplateParams(data, content),
+	})
+}
+
+// smsTemplateParams 模板短信可用的变量
+func smsTemplateParams(data alertTemplateData, content string) map[string]string {
+	event := data.AlertEvent
+	hostname := event.Hostname
+	if len(data.Hosts) > 1 {
+		hostname = fmt.Sprintf("%s等%d台主机", data.Hosts[0], len(data.Hosts))
+	}
+
+	return map[string]string{
+		"alert_name":   event.AlertName,
+		"severity":     string(event.Severity),
+		"hostname":     hostname,
+		"service_name": event.ServiceName,
+		"metric_name":  event.MetricName,
+		"value":        strconv.FormatFloat(event.MetricValue, 'f', -1, 64),
+		"threshold":    strconv.FormatFloat(event.Threshold, 'f', -1, 64),
+		"count":        strconv.Itoa(data.Count),
+		"content":      content,
+	}
+}
+
+// orderedParams 按配置的变量名顺序取出模板变量，未配置时只传完整内容
+func orderedParams(names []string, msg *SMSMessage) ([]string, []string) {
+	if len(names) == 0 {
+		names = []string{"content"}
+	}
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, msg.Params[name])
+	}
+	return names, values
+}
+
+// HTTPSMSProvider 通用HTTP短信网关
+//
+// 以JSON格式POST {"phones": [...], "content": "...", "params": {...}} 到配置的地址，
+// 配置了 api_key 时携带 Authorization: Bearer 头，配置了 secret 时携带
+// X-Timestamp 和 X-Signature 头，签名为 hex(HMAC-SHA256(secret, timestamp + "\n" + body))。
+type HTTPSMSProvider struct {
+	endpoint string
+	apiKey   string
+	secret   string
+	client   *http.Client
+}
+
+// NewHTTPSMSProvider 创建通用HTTP短信网关
+func NewHTTPSMSProvider(cfg config.SMSConfig, client *http.Client) (*HTTPSMSProvider, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("endpoint is required for http SMS gateway")
+	}
+	return &HTTPSMSProvider{
+		endpoint: cfg.Endpoint,
+		apiKey:   cfg.APIKey,
+		secret:   cfg.Secret,
+		client:   client,
+	}, nil
+}
+
+// Name 返回服务商名称
+func (p *HTTPSMSProvider) Name() string {
+	return "http"
+}
+
+// Send 通过HTTP网关发送短信
+func (p *HTTPSMSProvider) Send(ctx context.Context, phones []string, msg *SMSMessage) []DeliveryResult {
+	body, err := json.Marshal(map[string]interface{}{
+		"phones":  phones,
+		"content": msg.Content,
+		"params":  msg.Params,
+	})
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to marshal JSON: %v", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to create HTTP request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	if p.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Timestamp", timestamp)
+		req.Header.Set("X-Signature", signHTTPSMSRequest(p.secret, timestamp, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to send SMS: %v", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resultsFor(phones, fmt.Errorf("SMS gateway returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+	return resultsFor(phones, nil)
+}
+
+// signHTTPSMSRequest 计算通用网关请求签名
+func signHTTPSMSRequest(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
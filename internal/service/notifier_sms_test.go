@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// gatewayRequest 通用短信网关收到的请求
+type gatewayRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newGatewayStub 模拟通用短信网关，按 status 和 response 响应每个请求
+func newGatewayStub(t *testing.T, status int, response string) (*httptest.Server, func() []gatewayRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		received []gatewayRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, gatewayRequest{header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []gatewayRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]gatewayRequest(nil), received...)
+	}
+}
+
+func TestHTTPSMSProviderSend(t *testing.T) {
+	server, requests := newGatewayStub(t, http.StatusOK, `{"ok":true}`)
+	provider, err := NewHTTPSMSProvider(config.SMSConfig{
+		Provider: "http",
+		Endpoint: server.URL + "/sms",
+		APIKey:   "gateway-token",
+		Secret:   "gateway-secret",
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phones := []string{"13800000000", "13900000000"}
+
+	results := provider.Send(context.Background(), phones, &SMSMessage{
+		Content: "【告警】磁盘使用率过高",
+		Params:  map[string]string{"alert_name": "磁盘使用率", "value": "95.5"},
+	})
+	if len(results) != len(phones) {
+		t.Fatalf("results = %+v, want one per phone", results)
+	}
+	for i, result := range results {
+		if result.Recipient != phones[i] || result.Err != nil {
+			t.Errorf("results[%d] = %+v, want success for %s", i, result, phones[i])
+		}
+	}
+
+	received := requests()
+	if len(received) != 1 {
+		t.Fatalf("requests = %d, want 1", len(received))
+	}
+	header, body := received[0].header, received[0].body
+	if got := header.Get("Authorization"); got != "Bearer gateway-token" {
+		t.Errorf("Authorization = %q, want bearer token", got)
+	}
+	mac := hmac.New(sha256.New, []byte("gateway-secret"))
+	mac.Write([]byte(header.Get("X-Timestamp") + "\n"))
+	mac.Write(body)
+	if header.Get("X-Timestamp") == "" || header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("X-Timestamp = %q, X-Signature = %q, want HMAC-SHA256 of timestamp and body",
+			header.Get("X-Timestamp"), header.Get("X-Signature"))
+	}
+
+	var payload struct {
+		Phones  []string          `json:"phones"`
+		Content string            `json:"content"`
+		Params  map[string]string `json:"params"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("body %s: %v", body, err)
+	}
+	if strings.Join(payload.Phones, ",") != "13800000000,13900000000" || payload.Content != "【告警】磁盘使用率过高" ||
+		payload.Params["value"] != "95.5" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestHTTPSMSProviderSendWithoutCredentials(t *testing.T) {
+	server, requests := newGatewayStub(t, http.StatusNoContent, "")
+	provider, err := NewHTTPSMSProvider(config.SMSConfig{Provider: "http", Endpoint: server.URL}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := provider.Send(context.Background(), []string{"13800000000"}, &SMSMessage{Content: "test"})
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("results = %+v, want success", results)
+	}
+	header := requests()[0].header
+	for _, name := range []string{"Authorization", "X-Timestamp", "X-Signature"} {
+		if header.Get(name) != "" {
+			t.Errorf("%s = %q, want no header", name, header.Get(name))
+		}
+	}
+}
+
+func TestHTTPSMSProviderSendError(t *testing.T) {
+	server, _ := newGatewayStub(t, http.StatusTooManyRequests, "quota exceeded\n")
+	provider, err := NewHTTPSMSProvider(config.SMSConfig{Provider: "http", Endpoint: server.URL}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phones := []string{"13800000000", "13900000000"}
+
+	results := provider.Send(context.Background(), phones, &SMSMessage{Content: "test"})
+	if len(results) != len(phones) {
+		t.Fatalf("results = %+v, want one per phone", results)
+	}
+	for i, result := range results {
+		if result.Recipient != phones[i] || result.Err == nil ||
+			!strings.Contains(result.Err.Error(), "status 429: quota exceeded") {
+			t.Errorf("results[%d] = %+v, want status 429 error for %s", i, result, phones[i])
+		}
+	}
+}
+
+func TestNewSMSProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.SMSConfig
+		wantName string
+		wantErr  bool
+	}{
+		{
+			name:     "aliyun",
+			cfg:      config.SMSConfig{Provider: "Aliyun", APIKey: "id", Secret: "secret", SignName: "sign", TemplateCode: "SMS_1"},
+			wantName: "aliyun",
+		},
+		{
+			name:     "tencent",
+			cfg:      config.SMSConfig{Provider: "tencent", APIKey: "id", Secret: "secret", SDKAppID: "1400000000", SignName: "sign", TemplateCode: "1"},
+			wantName: "tencent",
+		},
+		{
+			name:     "http",
+			cfg:      config.SMSConfig{Provider: "http", Endpoint: "http://127.0.0.1/sms"},
+			wantName: "http",
+		},
+		{name: "aliyun without template", cfg: config.SMSConfig{Provider: "aliyun", APIKey: "id", Secret: "secret"}, wantErr: true},
+		{name: "tencent without sdk app id", cfg: config.SMSConfig{Provider: "tencent", APIKey: "id", Secret: "secret", SignName: "sign", TemplateCode: "1"}, wantErr: true},
+		{name: "http without endpoint", cfg: config.SMSConfig{Provider: "http"}, wantErr: true},
+		{name: "unknown provider", cfg: config.SMSConfig{Provider: "twilio"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewSMSProvider(tt.cfg, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewSMSProvider() = %s, want error", provider.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSMSProvider() error: %v", err)
+			}
+			if provider.Name() != tt.wantName {
+				t.Errorf("provider = %s, want %s", provider.Name(), tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+)
+
+// WebhookNotifier 通过HTTP请求发送Webhook通知
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+// NewWebhookNotifier 创建Webhook通知发送器，client 为nil时使用10秒超时的默认客户端
+func NewWebhookNotifier(client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookNotifier{client: client}
+}
+
+// Type 返回Webhook通知类型
+func (n *WebhookNotifier) Type() model.NotificationType {
+	return model.NotificationTypeWebhook
+}
+
+// Send 发送Webhook通知
+func (n *WebhookNotifier) Send(ctx context.Context, notification *Notification) []DeliveryResult {
+	config := notification.Config
+
+	// 检查Webhook配置
+	if config.WebhookURL == "" {
+		return resultsFor(nil, errors.New("webhook URL is not configured"))
+	}
+	recipients := []string{config.WebhookURL}
+
+	payload, err := n.buildPayload(notification)
+	if err != nil {
+		return resultsFor(recipients, err)
+	}
+
+	// 序列化为JSON
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return resultsFor(recipients, fmt.Errorf("failed to marshal JSON: %v", err))
+	}
+
+	// 设置HTTP请求方法
+	method := config.WebhookMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	// 创建HTTP请求
+	req, err := http.NewRequestWithContext(ctx, method, config.WebhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return resultsFor(recipients, fmt.Errorf("failed to create HTTP request: %v", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// 添加自定义HTTP头
+	if config.WebhookHeaders != "" {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(config.WebhookHeaders), &headers); err == nil {
+			for key, value := range headers {
+				req.Header.Set(key, value)
+			}
+		}
+	}
+
+	// 发送HTTP请求
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return resultsFor(recipients, fmt.Errorf("failed to send webhook: %v", err))
+	}
+	defer resp.Body.Close()
+
+	// 检查响应状态
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resultsFor(recipients, fmt.Errorf("webhook returned non-success status: %d", resp.StatusCode))
+	}
+
+	return resultsFor(recipients, nil)
+}
+
+// buildPayload 构造Webhook内容，匹配到通知模板或通知配置中设置了模板时使用模板渲染，通知模板优先
+func (n *WebhookNotifier) buildPayload(notification *Notification) (interface{}, error) {
+	config := notification.Config
+
+	_, content, custom, err := notification.renderCustom()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %v", err)
+	}
+	if !custom && config.WebhookTemplate != "" {
+		tpl, err := parseNotificationTemplate("webhook", config.WebhookTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook template: %v", err)
+		}
+
+		content, err = renderTemplate(tpl, notification.templateData())
+		if err != nil {
+			return nil, fmt.Errorf("failed to render webhook template: %v", err)
+		}
+		custom = true
+	}
+
+	if custom {
+		// 尝试解析JSON，不是有效的JSON时使用纯文本
+		var jsonPayload interface{}
+		if err := json.Unmarshal([]byte(content), &jsonPayload); err == nil {
+			return jsonPayload, nil
+		}
+		return map[string]string{"text": content}, nil
+	}
+
+	// 使用默认格式，alert 为最早触发的告警，alerts 为同组的全部告警
+	alerts := make([]map[string]interface{}, 0, len(notification.AlertEvents))
+	for _, alertEvent := range notification.AlertEvents {
+		alerts = append(alerts, webhookAlertPayload(alertEvent))
+	}
+	return map[string]interface{}{
+		"alert":  alerts[0],
+		"alerts": alerts,
+		"count":  len(alerts),
+		"hosts":  affectedHosts(notification.AlertEvents),
+	}, nil
+}
+
+// webhookAlertPayload Webhook默认格式中单个告警的内容
+func webhookAlertPayload(alertEvent *model.AlertEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           alertEvent.ID,
+		"name":         alertEvent.AlertName,
+		"severity":     alertEvent.Severity,
+		"status":       alertEvent.Status,
+		"hostname":     alertEvent.Hostname,
+		"service":      alertEvent.ServiceName,
+		"metric_name":  alertEvent.MetricName,
+		"value":        alertEvent.MetricValue,
+		"threshold":    alertEvent.Threshold,
+		"operator":     alertEvent.Operator,
+		"message":      alertEvent.Message,
+		"triggered_at": alertEvent.TriggeredAt,
+	}
+}
@@ -0,0 +1,616 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/archive"
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// 数据保留默认参数
+const (
+	defaultRetentionCheckInterval = time.Hour
+	defaultRetentionBatchSize     = 10000
+	defaultPartitionAheadDays     = 3
+	retentionArchivePage          = 5000
+)
+
+// 数据保留错误
+var (
+	ErrRetentionDisabled = errors.New("retention is disabled")
+	ErrRetentionRunning  = errors.New("retention is already running")
+)
+
+// retentionTable 支持清理过期数据的表
+type retentionTable struct {
+	Name          string
+	TimeColumn    string
+	Condition     string // 只清理满足条件的行，如已结束的任务
+	ServiceColumn string // 支持按服务配置保留天数的表的服务ID列
+	Partitionable bool   // 支持按天分区
+}
+
+// retentionTables 支持清理的表，log_record、metric、alert_events、log_pattern_counts 的默认保留天数来自对应模块的配置
+var retentionTables = []retentionTable{
+	{Name: "log_record", TimeColumn: "timestamp", ServiceColumn: "service_id", Partitionable: true},
+	{Name: "metric", TimeColumn: "timestamp", ServiceColumn: "service_id", Partitionable: true},
+	{Name: "notification_histories", TimeColumn: "sent_at"},
+	{Name: "notification_jobs", TimeColumn: "updated_at", Condition: "status = 'SUCCEEDED'"},
+	{Name: "alert_events", TimeColumn: "resolved_at", Condition: "status = 'RESOLVED'"},
+	{Name: "task", TimeColumn: "created_at", Condition: "status IN ('SUCCESS', 'FAILED')"},
+	{Name: "log_pattern_counts", TimeColumn: "bucket"},
+}
+
+// RetentionPolicy 表的保留策略
+type RetentionPolicy struct {
+	Table       string         `json:"table"`
+	Days        int            `json:"days"`               // 保留天数，0表示不删除
+	Services    map[string]int `json:"services,omitempty"` // 按服务名配置的保留天数
+	Partitioned bool           `json:"partitioned"`        // 按天分区，过期数据整个分区删除
+	Archived    bool           `json:"archived"`           // 删除前归档
+}
+
+// RetentionTableResult 单个表的清理结果
+type RetentionTableResult struct {
+	Table             string   `json:"table"`
+	DeletedRows       int64    `json:"deleted_rows"`
+	DroppedPartitions []string `json:"dropped_partitions,omitempty"`
+	ArchivedRows      int64    `json:"archived_rows"`
+	ArchiveFiles      []string `json:"archive_files,omitempty"`
+	Error             string   `json:"error,omitempty"`
+	DurationMs        int64    `json:"duration_ms"`
+}
+
+// RetentionStatus 数据保留状态
+type RetentionStatus struct {
+	Enabled        bool                   `json:"enabled"`
+	Running        bool                   `json:"running"`
+	LastStartedAt  *time.Time             `json:"last_started_at"`
+	LastFinishedAt *time.Time             `json:"last_finished_at"`
+	NextRunAt      *time.Time             `json:"next_run_at"`
+	Policies       []RetentionPolicy      `json:"policies"`
+	LastResults    []RetentionTableResult `json:"last_results"`
+}
+
+// retentionGroup 保留天数相同的一组行
+type retentionGroup struct {
+	label string
+	where string
+	args  []interface{}
+	days  int
+}
+
+// RetentionService 按保留策略定期清理过期数据，按天分区的表删除过期分区，其余表分批按行删除
+type RetentionService struct {
+	cfg     *config.Config
+	repo    *repository.RetentionRepository
+	archive archive.Store // 未启用归档时为nil
+	trigger chan struct{}
+
+	mu             sync.Mutex
+	running        bool
+	lastStartedAt  *time.Time
+	lastFinishedAt *time.Time
+	nextRunAt      *time.Time
+	lastResults    []RetentionTableResult
+}
+
+// NewRetentionService 创建数据保留服务，归档存储配置错误时不清理需要归档的表
+func NewRetentionService(db *gorm.DB, cfg *config.Config) *RetentionService {
+	s := &RetentionService{
+		cfg:     cfg,
+		repo:    repository.NewRetentionRepository(db),
+		trigger: make(chan struct{}, 1),
+	}
+	if cfg.Retention.Archive.Enabled {
+		store, err := archive.New(cfg.Retention.Archive)
+		if err != nil {
+			log.Printf("初始化数据归档失败，需要归档的表将不会清理: %v", err)
+		} else {
+			s.archive = store
+		}
+	}
+	return s
+}
+
+// Start 转换需要分区的表并定期清理过期数据，ctx 取消后停止
+func (s *RetentionService) Start(ctx context.Context) {
+	if !s.cfg.Retention.Enabled {
+		return
+	}
+
+	interval := defaultRetentionCheckInterval
+	if s.cfg.Retention.CheckInterval > 0 {
+		interval = time.Duration(s.cfg.Retention.CheckInterval) * time.Second
+	}
+
+	go func() {
+		s.partitionTables()
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			case <-s.trigger:
+				if !timer.Stop() {
+					<-timer.C
+				}
+			}
+
+			s.run(ctx)
+			next := time.Now().Add(interval)
+			s.mu.Lock()
+			s.nextRunAt = &next
+			s.mu.Unlock()
+			timer.Reset(interval)
+		}
+	}()
+}
+
+// RunNow 立即执行一次清理，正在清理或未启用时返回错误
+func (s *RetentionService) RunNow() error {
+	if !s.cfg.Retention.Enabled {
+		return ErrRetentionDisabled
+	}
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+	if running {
+		return ErrRetentionRunning
+	}
+
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// GetRetentionStatus 获取保留策略和最近一次清理结果
+func (s *RetentionService) GetRetentionStatus() *RetentionStatus {
+	policies := s.policies()
+	for i := range policies {
+		table := findRetentionTable(policies[i].Table)
+		if table.Partitionable {
+			partitions, err := s.repo.ListPartitions(table.Name)
+			policies[i].Partitioned = err == nil && len(partitions) > 0
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &RetentionStatus{
+		Enabled:        s.cfg.Retention.Enabled,
+		Running:        s.running,
+		LastStartedAt:  s.lastStartedAt,
+		LastFinishedAt: s.lastFinishedAt,
+		NextRunAt:      s.nextRunAt,
+		Policies:       policies,
+		LastResults:    s.lastResults,
+	}
+}
+
+// policies 根据配置计算各表的保留策略
+func (s *RetentionService) policies() []RetentionPolicy {
+	retention := s.cfg.Retention
+	policies := make([]RetentionPolicy, 0, len(retentionTables))
+	for _, table := range retentionTables {
+		policy := RetentionPolicy{Table: table.Name}
+		switch table.Name {
+		case "log_record":
+			policy.Days = s.cfg.Log.RetentionDays
+		case "metric":
+			policy.Days = s.cfg.Monitor.RetentionDays
+		case "alert_events":
+			policy.Days = s.cfg.Alert.RetentionDays
+		case "log_pattern_counts":
+			policy.Days = s.cfg.LogPattern.RetentionDays
+		}
+		if days, ok := retention.Tables[table.Name]; ok {
+			policy.Days = days
+		}
+
+		for name, service := range retention.Services {
+			days := service.LogDays
+			if table.Name == "metric" {
+				days = service.MetricDays
+			} else if table.Name != "log_record" {
+				continue
+			}
+			if days > 0 {
+				if policy.Services == nil {
+					policy.Services = make(map[string]int)
+				}
+				policy.Services[strings.ToLower(name)] = days
+			}
+		}
+
+		policy.Archived = retention.Archive.Enabled && containsString(archiveTables(retention.Archive), table.Name)
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// archiveTables 需要归档的表，默认只归档日志
+func archiveTables(cfg config.ArchiveConfig) []string {
+	if len(cfg.Tables) == 0 {
+		return []string{"log_record"}
+	}
+	return cfg.Tables
+}
+
+func findRetentionTable(name string) retentionTable {
+	for _, table := range retentionTables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return retentionTable{}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// startOfDay 本地时区的当天零点
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// partitionTables 将配置为按天分区但尚未分区的表转换为分区表
+func (s *RetentionService) partitionTables() {
+	for _, name := range s.cfg.Retention.Partition.Tables {
+		table := findRetentionTable(name)
+		if !table.Partitionable {
+			log.Printf("表 %s 不支持按天分区", name)
+			continue
+		}
+		partitions, err := s.repo.ListPartitions(table.Name)
+		if err != nil {
+			log.Printf("查询表 %s 的分区失败: %v", table.Name, err)
+			continue
+		}
+		if len(partitions) > 0 {
+			continue
+		}
+
+		first := time.Now()
+		if min, ok, err := s.repo.MinTime(table.Name, table.TimeColumn); err != nil {
+			log.Printf("查询表 %s 的最早数据失败: %v", table.Name, err)
+			continue
+		} else if ok && min.Before(first) {
+			first = min
+		}
+
+		var days []time.Time
+		last := startOfDay(time.Now()).AddDate(0, 0, s.aheadDays())
+		for day := startOfDay(first); !day.After(last); day = day.AddDate(0, 0, 1) {
+			days = append(days, day)
+		}
+
+		log.Printf("开始将表 %s 转换为按天分区（%d 个分区），数据量大时耗时较长", table.Name, len(days))
+		start := time.Now()
+		if err := s.repo.PartitionByDay(table.Name, table.TimeColumn, days); err != nil {
+			log.Printf("将表 %s 转换为分区表失败: %v", table.Name, err)
+			continue
+		}
+		log.Printf("表 %s 已转换为按天分区，耗时 %v", table.Name, time.Since(start))
+	}
+}
+
+// aheadDays 提前创建的分区天数
+func (s *RetentionService) aheadDays() int {
+	if days := s.cfg.Retention.Partition.AheadDays; days > 0 {
+		return days
+	}
+	return defaultPartitionAheadDays
+}
+
+// run 按保留策略清理全部表
+func (s *RetentionService) run(ctx context.Context) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	started := time.Now()
+	s.lastStartedAt = &started
+	s.mu.Unlock()
+
+	var results []RetentionTableResult
+	serviceIDs, err := s.repo.ServiceIDsByName()
+	if err != nil {
+		log.Printf("查询服务列表失败，按服务配置的保留天数本次不生效: %v", err)
+	}
+	for _, policy := range s.policies() {
+		if ctx.Err() != nil {
+			break
+		}
+		result := s.cleanTable(ctx, policy, serviceIDs)
+		if result.Error != "" {
+			log.Printf("清理表 %s 的过期数据失败: %s", policy.Table, result.Error)
+		} else if result.DeletedRows > 0 || len(result.DroppedPartitions) > 0 {
+			log.Printf("清理表 %s 的过期数据: 删除 %d 行，删除分区 %v，归档 %d 行",
+				policy.Table, result.DeletedRows, result.DroppedPartitions, result.ArchivedRows)
+		}
+		results = append(results, result)
+	}
+
+	finished := time.Now()
+	s.mu.Lock()
+	s.running = false
+	s.lastFinishedAt = &finished
+	s.lastResults = results
+	s.mu.Unlock()
+}
+
+// groups 按保留天数将表中的行分组：按服务配置的各服务一组，其余行一组
+func (s *RetentionService) groups(table retentionTable, policy RetentionPolicy, serviceIDs map[string][]int) []retentionGroup {
+	var groups []retentionGroup
+	var overridden []int
+	if table.ServiceColumn != "" {
+		names := make([]string, 0, len(policy.Services))
+		for name := range policy.Services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			ids := serviceIDs[name]
+			if len(ids) == 0 {
+				continue
+			}
+			overridden = append(overridden, ids...)
+			groups = append(groups, retentionGroup{
+				label: name,
+				where: table.ServiceColumn + " IN ?",
+				args:  []interface{}{ids},
+				days:  policy.Services[name],
+			})
+		}
+	}
+
+	if len(overridden) > 0 {
+		groups = append(groups, retentionGroup{
+			label: "default",
+			where: fmt.Sprintf("(%s IS NULL OR %s NOT IN ?)", table.ServiceColumn, table.ServiceColumn),
+			args:  []interface{}{overridden},
+			days:  policy.Days,
+		})
+	} else {
+		groups = append(groups, retentionGroup{label: "default", where: "1 = 1", days: policy.Days})
+	}
+
+	if table.Condition != "" {
+		for i := range groups {
+			groups[i].where += " AND " + table.Condition
+		}
+	}
+	return groups
+}
+
+// cleanTable 清理单个表的过期数据
+func (s *RetentionService) cleanTable(ctx context.Context, policy RetentionPolicy, serviceIDs map[string][]int) (result RetentionTableResult) {
+	start := time.Now()
+	result.Table = policy.Table
+	defer func() {
+		result.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	table := findRetentionTable(policy.Table)
+	if policy.Archived && s.archive == nil {
+		result.Error = "archive store is not available"
+		return result
+	}
+	groups := s.groups(table, policy, serviceIDs)
+
+	// 按天分区的表先删除所有行都已过期的分区，其余保留天数更短的行再按行删除
+	if table.Partitionable {
+		partitions, err := s.repo.ListPartitions(table.Name)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if len(partitions) > 0 {
+			if err := s.ensurePartitions(table, partitions); err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			dropDays := 0
+			for _, group := range groups {
+				if group.days <= 0 {
+					dropDays = 0
+					break
+				}
+				dropDays = int(math.Max(float64(dropDays), float64(group.days)))
+			}
+			if dropDays > 0 {
+				if err := s.dropExpiredPartitions(ctx, table, policy, partitions, dropDays, &result); err != nil {
+					result.Error = err.Error()
+					return result
+				}
+				var shorter []retentionGroup
+				for _, group := range groups {
+					if group.days < dropDays {
+						shorter = append(shorter, group)
+					}
+				}
+				groups = shorter
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, group := range groups {
+		if group.days <= 0 {
+			continue
+		}
+		where := group.where + " AND " + table.TimeColumn + " < ?"
+		args := append(append([]interface{}{}, group.args...), now.AddDate(0, 0, -group.days))
+
+		maxID := int64(math.MaxInt64)
+		if policy.Archived {
+			id, err := s.archiveRows(ctx, table.Name, group.label, where, args, &result)
+			if err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			maxID = id
+		}
+		if maxID == 0 {
+			continue
+		}
+
+		deleted, err := s.deleteRows(ctx, table.Name, where, args, maxID)
+		result.DeletedRows += deleted
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	return result
+}
+
+// ensurePartitions 提前创建未来几天的分区
+func (s *RetentionService) ensurePartitions(table retentionTable, partitions []repository.TablePartition) error {
+	hasMax := false
+	var lastBound int64
+	for _, partition := range partitions {
+		if partition.LessThan == math.MaxInt64 {
+			hasMax = true
+		} else if partition.LessThan > lastBound {
+			lastBound = partition.LessThan
+		}
+	}
+
+	var days []time.Time
+	last := startOfDay(time.Now()).AddDate(0, 0, s.aheadDays())
+	for day := startOfDay(time.Unix(lastBound, 0)); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if day.Unix() >= lastBound {
+			days = append(days, day)
+		}
+	}
+	return s.repo.AddPartitions(table.Name, days, hasMax)
+}
+
+// dropExpiredPartitions 删除所有行都早于保留期的分区，需要归档时先归档分区中的数据
+func (s *RetentionService) dropExpiredPartitions(ctx context.Context, table retentionTable, policy RetentionPolicy,
+	partitions []repository.TablePartition, days int, result *RetentionTableResult) error {
+	cutoff := time.Now().AddDate(0, 0, -days).Unix()
+	var expired []string
+	var bound int64
+	for _, partition := range partitions {
+		if partition.LessThan <= cutoff {
+			expired = append(expired, partition.Name)
+			bound = partition.LessThan
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	if policy.Archived {
+		where := table.TimeColumn + " < ?"
+		if _, err := s.archiveRows(ctx, table.Name, "partitions", where, []interface{}{time.Unix(bound, 0)}, result); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.DropPartitions(table.Name, expired); err != nil {
+		return err
+	}
+	result.DroppedPartitions = append(result.DroppedPartitions, expired...)
+	return nil
+}
+
+// archiveRows 将满足条件的行写入一个 gzip 压缩的 NDJSON 归档文件，返回已归档的最大ID，没有数据时为0
+func (s *RetentionService) archiveRows(ctx context.Context, table, label, where string, args []interface{}, result *RetentionTableResult) (int64, error) {
+	now := time.Now()
+	name := fmt.Sprintf("%s/%s/%s-%s-%s.ndjson.gz", table, now.Format("2006/01"), table, label, now.Format("20060102T150405"))
+	file, err := s.archive.Create(name)
+	if err != nil {
+		return 0, err
+	}
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+
+	var afterID, count int64
+	for {
+		if err := ctx.Err(); err != nil {
+			file.Abort()
+			return 0, err
+		}
+		rows, lastID, err := s.repo.ListRows(table, where, args, afterID, retentionArchivePage)
+		if err != nil {
+			file.Abort()
+			return 0, err
+		}
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				file.Abort()
+				return 0, err
+			}
+		}
+		count += int64(len(rows))
+		afterID = lastID
+		if len(rows) < retentionArchivePage {
+			break
+		}
+	}
+
+	if count == 0 {
+		file.Abort()
+		return 0, nil
+	}
+	if err := gz.Close(); err != nil {
+		file.Abort()
+		return 0, err
+	}
+	if err := file.Commit(); err != nil {
+		return 0, fmt.Errorf("commit archive %s: %v", s.archive.Location(name), err)
+	}
+
+	result.ArchivedRows += count
+	result.ArchiveFiles = append(result.ArchiveFiles, s.archive.Location(name))
+	return afterID, nil
+}
+
+// deleteRows 分批删除满足条件且ID不大于 maxID 的行
+func (s *RetentionService) deleteRows(ctx context.Context, table, where string, args []interface{}, maxID int64) (int64, error) {
+	batchSize := s.cfg.Retention.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRetentionBatchSize
+	}
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		deleted, err := s.repo.DeleteRows(table, where, args, maxID, batchSize)
+		total += deleted
+		if err != nil || deleted < int64(batchSize) {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+	"gorm.io/gorm"
+)
+
+var (
+	notificationServiceInstance *NotificationService
+	alertServiceInstance        *AlertService
+	silenceServiceInstance      *SilenceService
+	escalationServiceInstance   *EscalationService
+	logServiceInstance          *LogService
+	logPatternServiceInstance   *LogPatternService
+	retentionServiceInstance    *RetentionService
+	exportServiceInstance       *ExportService
+	userServiceInstance         *UserService
+	apiTokenServiceInstance     *APITokenService
+	auditServiceInstance        *AuditService
+	servicesOnce                sync.Once
+)
+
+// InitServices 初始化告警、通知、日志检索、日志模式、数据保留、导出、用户管理、API令牌与审计服务单例，仅首次调用生效
+func InitServices(db *gorm.DB, cfg *config.Config) {
+	servicesOnce.Do(func() {
+		notificationServiceInstance = NewNotificationService(db, cfg)
+		alertServiceInstance = NewAlertService(db, cfg, notificationServiceInstance)
+		silenceServiceInstance = NewSilenceService(db)
+		escalationServiceInstance = NewEscalationService(db, notificationServiceInstance)
+		logServiceInstance = NewLogService(db, cfg)
+		logPatternServiceInstance = NewLogPatternService(db, cfg)
+		retentionServiceInstance = NewRetentionService(db, cfg)
+		exportServiceInstance = NewExportService(db, cfg)
+		userServiceInstance = NewUserService(db)
+		apiTokenServiceInstance = NewAPITokenService(db)
+		auditServiceInstance = NewAuditService(db, cfg)
+	})
+}
+
+// GetNotificationService 获取通知服务实例，需先调用 InitServices
+func GetNotificationService() *NotificationService {
+	return notificationServiceInstance
+}
+
+// GetAlertService 获取告警服务实例，需先调用 InitServices
+func GetAlertService() *AlertService {
+	return alertServiceInstance
+}
+
+// GetSilenceService 获取静默服务实例，需先调用 InitServices
+func GetSilenceService() *SilenceService {
+	return silenceServiceInstance
+}
+
+// GetEscalationService 获取升级服务实例，需先调用 InitServices
+func GetEscalationService() *EscalationService {
+	return escalationServiceInstance
+}
+
+// GetLogService 获取日志检索服务实例，需先调用 InitServices
+func GetLogService() *LogService {
+	return logServiceInstance
+}
+
+// GetLogPatternService 获取日志模式服务实例，需先调用 InitServices
+func GetLogPatternService() *LogPatternService {
+	return logPatternServiceInstance
+}
+
+// GetRetentionService 获取数据保留服务实例，需先调用 InitServices
+func GetRetentionService() *RetentionService {
+	return retentionServiceInstance
+}
+
+// GetExportService 获取导出服务实例，需先调用 InitServices
+func GetExportService() *ExportService {
+	return exportServiceInstance
+}
+
+// GetUserService 获取用户管理服务实例，需先调用 InitServices
+func GetUserService() *UserService {
+	return userServiceInstance
+}
+
+// GetAPITokenService 获取API令牌服务实例，需先调用 InitServices
+func GetAPITokenService() *APITokenService {
+	return apiTokenServiceInstance
+}
+
+// GetAuditService 获取审计服务实例，需先调用 InitServices
+func GetAuditService() *AuditService {
+	return auditServiceInstance
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+	"github.com/robfig/cron/v3"
+
+	"gorm.io/gorm"
+)
+
+// SilenceService 处理告警静默和维护窗口
+type SilenceService struct {
+	db                    *gorm.DB
+	silenceRepo           *repository.SilenceRepository
+	maintenanceWindowRepo *repository.MaintenanceWindowRepository
+}
+
+// NewSilenceService 创建新的静默服务
+func NewSilenceService(db *gorm.DB) *SilenceService {
+	return &SilenceService{
+		db:                    db,
+		silenceRepo:           repository.NewSilenceRepository(db),
+		maintenanceWindowRepo: repository.NewMaintenanceWindowRepository(db),
+	}
+}
+
+// CreateSilence 创建静默
+func (s *SilenceService) CreateSilence(silence *model.Silence) error {
+	if silence.AlertMatcher.IsEmpty() {
+		return errors.New("at least one matcher (rule, host, service, cluster or severity) is required")
+	}
+	if silence.Comment == "" {
+		return errors.New("comment is required")
+	}
+
+	now := time.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if !silence.EndsAt.After(now) {
+		return errors.New("ends_at must be in the future")
+	}
+
+	silence.CreatedAt = now
+	silence.UpdatedAt = now
+	return s.silenceRepo.Create(silence)
+}
+
+// GetSilence 获取静默
+func (s *SilenceService) GetSilence(id uint) (*model.Silence, error) {
+	return s.silenceRepo.GetByID(id)
+}
+
+// ListSilences 列出静默
+func (s *SilenceService) ListSilences(page, pageSize int, filters map[string]interface{}) ([]*model.Silence, int64, error) {
+	return s.silenceRepo.List(page, pageSize, filters)
+}
+
+// ExpireSilence 立即结束静默
+func (s *SilenceService) ExpireSilence(id uint) error {
+	silence, err := s.GetSilence(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !silence.EndsAt.After(now) {
+		return errors.New("silence is already expired")
+	}
+
+	silence.EndsAt = now
+	if silence.StartsAt.After(now) {
+		silence.StartsAt = now
+	}
+	silence.UpdatedAt = now
+	return s.silenceRepo.Update(silence)
+}
+
+// FindMatchingSilence 查找命中告警事件的生效静默，未命中时返回nil
+func (s *SilenceService) FindMatchingSilence(event *model.AlertEvent, now time.Time) (*model.Silence, error) {
+	silences, err := s.silenceRepo.FindActive(now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, silence := range silences {
+		if silence.Matches(event) {
+			return silence, nil
+		}
+	}
+	return nil, nil
+}
+
+// FilterUnsilenced 过滤掉命中生效静默的告警事件
+func (s *SilenceService) FilterUnsilenced(events []*model.AlertEvent, now time.Time) ([]*model.AlertEvent, error) {
+	silences, err := s.silenceRepo.FindActive(now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.AlertEvent, 0, len(events))
+	for _, event := range events {
+		silenced := event.SilenceID != nil
+		for _, silence := range silences {
+			if silenced {
+				break
+			}
+			silenced = silence.Matches(event)
+		}
+		if !silenced {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+// validateMaintenanceWindow 校验维护窗口配置
+func (s *SilenceService) validateMaintenanceWindow(window *model.MaintenanceWindow) error {
+	if window.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if window.CronExpression != "" {
+		if _, err := cron.ParseStandard(window.CronExpression); err != nil {
+			return fmt.Errorf("invalid cron expression: %v", err)
+		}
+		if window.Duration <= 0 {
+			return errors.New("duration must be greater than 0 for recurring windows")
+		}
+		if window.StartsAt != nil && window.EndsAt != nil && !window.EndsAt.After(*window.StartsAt) {
+			return errors.New("ends_at must be after starts_at")
+		}
+		return nil
+	}
+
+	// 一次性窗口必须指定起止时间
+	if window.StartsAt == nil || window.EndsAt == nil {
+		return errors.New("starts_at and ends_at are required for one-off windows")
+	}
+	if !window.EndsAt.After(*window.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// CreateMaintenanceWindow 创建维护窗口
+func (s *SilenceService) CreateMaintenanceWindow(window *model.MaintenanceWindow) error {
+	if err := s.validateMaintenanceWindow(window); err != nil {
+		return err
+	}
+	window.CreatedAt = time.Now()
+	window.UpdatedAt = time.Now()
+	return s.maintenanceWindowRepo.Create(window)
+}
+
+// UpdateMaintenanceWindow 更新维护窗口
+func (s *SilenceService) UpdateMaintenanceWindow(window *model.MaintenanceWindow) error {
+	if err := s.validateMaintenanceWindow(window); err != nil {
+		return err
+	}
+	window.UpdatedAt = time.Now()
+	return s.maintenanceWindowRepo.Update(window)
+}
+
+// DeleteMaintenanceWindow 删除维护窗口
+func (s *SilenceService) DeleteMaintenanceWindow(id uint) error {
+	return s.maintenanceWindowRepo.Delete(id)
+}
+
+// GetMaintenanceWindow 获取维护窗口
+func (s *SilenceService) GetMaintenanceWindow(id uint) (*model.MaintenanceWindow, error) {
+	return s.maintenanceWindowRepo.GetByID(id)
+}
+
+// ListMaintenanceWindows 列出维护窗口
+func (s *SilenceService) ListMaintenanceWindows(page, pageSize int, filters map[string]interface{}) ([]*model.MaintenanceWindow, int64, error) {
+	return s.maintenanceWindowRepo.List(page, pageSize, filters)
+}
+
+// IsMaintenanceWindowActive 判断维护窗口在指定时间是否处于维护期
+func (s *SilenceService) IsMaintenanceWindowActive(window *model.MaintenanceWindow, now time.Time) (bool, error) {
+	if !window.Enabled {
+		return false, nil
+	}
+	if window.StartsAt != nil && now.Before(*window.StartsAt) {
+		return false, nil
+	}
+	if window.EndsAt != nil && !now.Before(*window.EndsAt) {
+		return false, nil
+	}
+
+	// 一次性窗口在起止时间内即生效
+	if window.CronExpression == "" {
+		return true, nil
+	}
+
+	schedule, err := cron.ParseStandard(window.CronExpression)
+	if err != nil {
+		return false, err
+	}
+
+	// 若 (now-duration, now] 内存在一次触发，则 now 位于该次窗口内
+	duration := time.Duration(window.Duration) * time.Minute
+	next := schedule.Next(now.Add(-duration))
+	return !next.After(now), nil
+}
+
+// FindActiveMaintenanceWindow 查找命中告警事件且正处于维护期的窗口，未命中时返回nil
+func (s *SilenceService) FindActiveMaintenanceWindow(event *model.AlertEvent, now time.Time) (*model.MaintenanceWindow, error) {
+	windows, err := s.maintenanceWindowRepo.FindEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, window := range windows {
+		if !window.Matches(event) {
+			continue
+		}
+		active, err := s.IsMaintenanceWindowActive(window, now)
+		if err != nil {
+			continue
+		}
+		if active {
+			return window, nil
+		}
+	}
+	return nil, nil
+}
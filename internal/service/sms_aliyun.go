@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// 阿里云短信接口默认参数
+const (
+	aliyunSMSEndpoint = "https://dysmsapi.aliyuncs.com/"
+	aliyunSMSRegion   = "cn-hangzhou"
+	aliyunSMSVersion  = "2017-05-25"
+)
+
+// AliyunSMSProvider 阿里云短信服务，使用RPC风格的 HMAC-SHA1 签名调用 SendSms 接口
+type AliyunSMSProvider struct {
+	cfg      config.SMSConfig
+	endpoint string
+	client   *http.Client
+}
+
+// NewAliyunSMSProvider 创建阿里云短信服务
+func NewAliyunSMSProvider(cfg config.SMSConfig, client *http.Client) (*AliyunSMSProvider, error) {
+	if cfg.APIKey == "" || cfg.Secret == "" {
+		return nil, errors.New("api_key and secret are required for aliyun SMS")
+	}
+	if cfg.SignName == "" || cfg.TemplateCode == "" {
+		return nil, errors.New("sign_name and template_code are required for aliyun SMS")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = aliyunSMSEndpoint
+	}
+	if cfg.Region == "" {
+		cfg.Region = aliyunSMSRegion
+	}
+	return &AliyunSMSProvider{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+// Name 返回服务商名称
+func (p *AliyunSMSProvider) Name() string {
+	return "aliyun"
+}
+
+// aliyunSMSResponse SendSms 接口响应
+type aliyunSMSResponse struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	RequestID string `json:"RequestId"`
+	BizID     string `json:"BizId"`
+}
+
+// Send 调用 SendSms 接口批量发送，同一请求内的号码共享发送结果
+func (p *AliyunSMSProvider) Send(ctx context.Context, phones []string, msg *SMSMessage) []DeliveryResult {
+	names, values := orderedParams(p.cfg.TemplateParams, msg)
+	templateParam := make(map[string]string, len(names))
+	for i, name := range names {
+		templateParam[name] = values[i]
+	}
+	templateParamJSON, err := json.Marshal(templateParam)
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to marshal template params: %v", err))
+	}
+
+	nonce, err := randomHex(16)
+	if err != nil {
+		return resultsFor(phones, err)
+	}
+
+	params := map[string]string{
+		"AccessKeyId":      p.cfg.APIKey,
+		"Action":           "SendSms",
+		"Format":           "JSON",
+		"RegionId":         p.cfg.Region,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   nonce,
+		"SignatureVersion": "1.0",
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Version":          aliyunSMSVersion,
+		"PhoneNumbers":     strings.Join(phones, ","),
+		"SignName":         p.cfg.SignName,
+		"TemplateCode":     p.cfg.TemplateCode,
+		"TemplateParam":    string(templateParamJSON),
+	}
+	query := aliyunCanonicalQuery(params)
+	signature := signAliyunRequest(http.MethodGet, query, p.cfg.Secret)
+	requestURL := p.endpoint + "?Signature=" + aliyunPercentEncode(signature) + "&" + query
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to create HTTP request: %v", err))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to send SMS: %v", err))
+	}
+	defer resp.Body.Close()
+
+	var result aliyunSMSResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to decode aliyun response (status %d): %v", resp.StatusCode, err))
+	}
+	if result.Code != "OK" {
+		return resultsFor(phones, fmt.Errorf("aliyun SMS error %s: %s (request id %s)", result.Code, result.Message, result.RequestID))
+	}
+	return resultsFor(phones, nil)
+}
+
+// aliyunCanonicalQuery 按参数名排序并编码请求参数
+func aliyunCanonicalQuery(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, aliyunPercentEncode(key)+"="+aliyunPercentEncode(params[key]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// signAliyunRequest 计算RPC风格接口签名: Base64(HMAC-SHA1(secret + "&", method + "&%2F&" + encode(query)))
+func signAliyunRequest(method, canonicalQuery, secret string) string {
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(canonicalQuery)
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunPercentEncode 按阿里云规范进行URL编码，空格编码为%20，保留~
+func aliyunPercentEncode(value string) string {
+	encoded := url.QueryEscape(value)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	encoded = strings.ReplaceAll(encoded, "%7E", "~")
+	return encoded
+}
+
+// randomHex 生成指定字节数的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// 阿里云签名文档中的示例请求和签名
+func TestSignAliyunRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		params map[string]string
+		want   string
+	}{
+		{
+			name:   "DescribeRegions",
+			secret: "testsecret",
+			params: map[string]string{
+				"AccessKeyId":      "testid",
+				"Action":           "DescribeRegions",
+				"Format":           "XML",
+				"SignatureMethod":  "HMAC-SHA1",
+				"SignatureNonce":   "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf",
+				"SignatureVersion": "1.0",
+				"Timestamp":        "2016-02-23T12:46:24Z",
+				"Version":          "2014-05-26",
+			},
+			want: "OLeaidS1JvxuMvnyHOwuJ+uX5qY=",
+		},
+		{
+			name:   "SendSms",
+			secret: "testSecret",
+			params: map[string]string{
+				"AccessKeyId":      "testId",
+				"Action":           "SendSms",
+				"Format":           "XML",
+				"OutId":            "123",
+				"PhoneNumbers":     "15300000001",
+				"RegionId":         "cn-hangzhou",
+				"SignName":         "阿里云短信测试专用",
+				"SignatureMethod":  "HMAC-SHA1",
+				"SignatureNonce":   "45e25e9b-0a6f-4070-8c85-2956eda1b466",
+				"SignatureVersion": "1.0",
+				"TemplateCode":     "SMS_71390007",
+				"TemplateParam":    `{"customer":"test"}`,
+				"Timestamp":        "2017-07-12T02:42:19Z",
+				"Version":          "2017-05-25",
+			},
+			want: "zJDF+Lrzhj/ThnlvIToysFRq6t4=",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := signAliyunRequest(http.MethodGet, aliyunCanonicalQuery(tt.params), tt.secret)
+			if got != tt.want {
+				t.Errorf("signAliyunRequest() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliyunPercentEncode(t *testing.T) {
+	tests := map[string]string{
+		"a b":     "a%20b",
+		"a*b":     "a%2Ab",
+		"a~b":     "a~b",
+		"a+b":     "a%2Bb",
+		"a/b:c":   "a%2Fb%3Ac",
+		"告警":      "%E5%91%8A%E8%AD%A6",
+		"-_.AZ09": "-_.AZ09",
+	}
+	for value, want := range tests {
+		if got := aliyunPercentEncode(value); got != want {
+			t.Errorf("aliyunPercentEncode(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+// aliyunStub 模拟阿里云 SendSms 接口，签名校验失败时返回 SignatureDoesNotMatch
+type aliyunStub struct {
+	server   *httptest.Server
+	secret   string
+	status   int
+	response string
+
+	mu      sync.Mutex
+	queries []url.Values
+}
+
+func newAliyunStub(t *testing.T, secret string, status int, response string) *aliyunStub {
+	t.Helper()
+	stub := &aliyunStub{secret: secret, status: status, response: response}
+	stub.server = httptest.NewServer(http.HandlerFunc(stub.handle))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func (s *aliyunStub) handle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	s.mu.Unlock()
+
+	params := make(map[string]string, len(query))
+	for name := range query {
+		if name != "Signature" {
+			params[name] = query.Get(name)
+		}
+	}
+	if r.Method != http.MethodGet || query.Get("Signature") != signAliyunRequest(r.Method, aliyunCanonicalQuery(params), s.secret) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"Code":"SignatureDoesNotMatch","Message":"Specified signature is not matched with our calculation.","RequestId":"bad-sign"}`))
+		return
+	}
+
+	w.WriteHeader(s.status)
+	w.Write([]byte(s.response))
+}
+
+// requests 收到的请求参数
+func (s *aliyunStub) requests() []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]url.Values(nil), s.queries...)
+}
+
+func newTestAliyunProvider(t *testing.T, endpoint string) *AliyunSMSProvider {
+	t.Helper()
+	provider, err := NewAliyunSMSProvider(config.SMSConfig{
+		Provider:       "aliyun",
+		APIKey:         "test-key-id",
+		Secret:         "test-secret",
+		Endpoint:       endpoint,
+		SignName:       "大数据平台",
+		TemplateCode:   "SMS_123456",
+		TemplateParams: []string{"alert_name", "value"},
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestAliyunSMSProviderSend(t *testing.T) {
+	stub := newAliyunStub(t, "test-secret", http.StatusOK, `{"Code":"OK","Message":"OK","RequestId":"req-1","BizId":"biz-1"}`)
+	provider := newTestAliyunProvider(t, stub.server.URL+"/")
+	phones := []string{"13800000000", "13900000000"}
+
+	results := provider.Send(context.Background(), phones, &SMSMessage{
+		Content: "磁盘使用率过高",
+		Params:  map[string]string{"alert_name": "磁盘使用率 > 90%", "value": "95.5", "hostname": "node-1"},
+	})
+	if len(results) != len(phones) {
+		t.Fatalf("results = %+v, want one per phone", results)
+	}
+	for i, result := range results {
+		if result.Recipient != phones[i] || result.Err != nil {
+			t.Errorf("results[%d] = %+v, want success for %s", i, result, phones[i])
+		}
+	}
+
+	queries := stub.requests()
+	if len(queries) != 1 {
+		t.Fatalf("requests = %d, want 1", len(queries))
+	}
+	query := queries[0]
+	for name, want := range map[string]string{
+		"AccessKeyId":  "test-key-id",
+		"Action":       "SendSms",
+		"RegionId":     aliyunSMSRegion,
+		"Version":      aliyunSMSVersion,
+		"PhoneNumbers": "13800000000,13900000000",
+		"SignName":     "大数据平台",
+		"TemplateCode": "SMS_123456",
+	} {
+		if got := query.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	var templateParam map[string]string
+	if err := json.Unmarshal([]byte(query.Get("TemplateParam")), &templateParam); err != nil {
+		t.Fatalf("TemplateParam %q: %v", query.Get("TemplateParam"), err)
+	}
+	if len(templateParam) != 2 || templateParam["alert_name"] != "磁盘使用率 > 90%" || templateParam["value"] != "95.5" {
+		t.Errorf("TemplateParam = %v, want only the configured params", templateParam)
+	}
+}
+
+func TestAliyunSMSProviderSendErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string // 接口使用的密钥，与客户端不同时签名校验失败
+		status   int
+		response string
+		wantErr  []string
+	}{
+		{
+			name:     "business error",
+			secret:   "test-secret",
+			status:   http.StatusOK,
+			response: `{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"触发分钟级流控","RequestId":"req-2"}`,
+			wantErr:  []string{"isv.BUSINESS_LIMIT_CONTROL", "触发分钟级流控", "req-2"},
+		},
+		{
+			name:    "signature mismatch",
+			secret:  "other-secret",
+			status:  http.StatusOK,
+			wantErr: []string{"SignatureDoesNotMatch", "bad-sign"},
+		},
+		{
+			name:     "invalid response",
+			secret:   "test-secret",
+			status:   http.StatusBadGateway,
+			response: "<html>bad gateway</html>",
+			wantErr:  []string{"status 502"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newAliyunStub(t, tt.secret, tt.status, tt.response)
+			provider := newTestAliyunProvider(t, stub.server.URL+"/")
+			phones := []string{"13800000000", "13900000000"}
+
+			results := provider.Send(context.Background(), phones, &SMSMessage{Content: "test"})
+			if len(results) != len(phones) {
+				t.Fatalf("results = %+v, want one per phone", results)
+			}
+			for i, result := range results {
+				if result.Recipient != phones[i] || result.Err == nil {
+					t.Fatalf("results[%d] = %+v, want error for %s", i, result, phones[i])
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(result.Err.Error(), want) {
+						t.Errorf("results[%d] error = %v, want it to contain %q", i, result.Err, want)
+					}
+				}
+			}
+		})
+	}
+}
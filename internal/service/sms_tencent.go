@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// 腾讯云短信接口默认参数
+const (
+	tencentSMSEndpoint = "https://sms.tencentcloudapi.com/"
+	tencentSMSRegion   = "ap-guangzhou"
+	tencentSMSVersion  = "2021-01-11"
+	tencentSMSService  = "sms"
+)
+
+// TencentSMSProvider 腾讯云短信服务，使用 TC3-HMAC-SHA256 签名调用 SendSms 接口
+type TencentSMSProvider struct {
+	cfg      config.SMSConfig
+	endpoint string
+	host     string
+	client   *http.Client
+}
+
+// NewTencentSMSProvider 创建腾讯云短信服务
+func NewTencentSMSProvider(cfg config.SMSConfig, client *http.Client) (*TencentSMSProvider, error) {
+	if cfg.APIKey == "" || cfg.Secret == "" {
+		return nil, errors.New("api_key and secret are required for tencent SMS")
+	}
+	if cfg.SDKAppID == "" || cfg.SignName == "" || cfg.TemplateCode == "" {
+		return nil, errors.New("sdk_app_id, sign_name and template_code are required for tencent SMS")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = tencentSMSEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid tencent SMS endpoint: %q", endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = tencentSMSRegion
+	}
+	return &TencentSMSProvider{cfg: cfg, endpoint: endpoint, host: u.Host, client: client}, nil
+}
+
+// Name 返回服务商名称
+func (p *TencentSMSProvider) Name() string {
+	return "tencent"
+}
+
+// tencentSMSResponse SendSms 接口响应
+type tencentSMSResponse struct {
+	Response struct {
+		SendStatusSet []struct {
+			PhoneNumber string `json:"PhoneNumber"`
+			Code        string `json:"Code"`
+			Message     string `json:"Message"`
+		} `json:"SendStatusSet"`
+		Error *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+		RequestID string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// Send 调用 SendSms 接口批量发送，按响应中每个号码的状态返回结果
+func (p *TencentSMSProvider) Send(ctx context.Context, phones []string, msg *SMSMessage) []DeliveryResult {
+	// 未带国家码的号码按中国大陆号码处理
+	normalized := make([]string, 0, len(phones))
+	original := make(map[string]string, len(phones))
+	for _, phone := range phones {
+		number := phone
+		if !strings.HasPrefix(number, "+") {
+			number = "+86" + number
+		}
+		normalized = append(normalized, number)
+		original[number] = phone
+	}
+
+	_, values := orderedParams(p.cfg.TemplateParams, msg)
+	payload, err := json.Marshal(map[string]interface{}{
+		"PhoneNumberSet":   normalized,
+		"SmsSdkAppId":      p.cfg.SDKAppID,
+		"SignName":         p.cfg.SignName,
+		"TemplateId":       p.cfg.TemplateCode,
+		"TemplateParamSet": values,
+	})
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to marshal JSON: %v", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to create HTTP request: %v", err))
+	}
+
+	timestamp := time.Now().Unix()
+	contentType := "application/json; charset=utf-8"
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-TC-Action", "SendSms")
+	req.Header.Set("X-TC-Version", tencentSMSVersion)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-TC-Region", p.cfg.Region)
+	req.Header.Set("Authorization", signTencentRequest(p.cfg.APIKey, p.cfg.Secret, tencentSMSService, p.host, contentType, payload, timestamp))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to send SMS: %v", err))
+	}
+	defer resp.Body.Close()
+
+	var result tencentSMSResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return resultsFor(phones, fmt.Errorf("failed to decode tencent response (status %d): %v", resp.StatusCode, err))
+	}
+	if result.Response.Error != nil {
+		return resultsFor(phones, fmt.Errorf("tencent SMS error %s: %s (request id %s)", result.Response.Error.Code, result.Response.Error.Message, result.Response.RequestID))
+	}
+
+	results := make([]DeliveryResult, 0, len(phones))
+	reported := make(map[string]bool, len(phones))
+	for _, status := range result.Response.SendStatusSet {
+		phone, ok := original[status.PhoneNumber]
+		if !ok {
+			phone = status.PhoneNumber
+		}
+		reported[phone] = true
+
+		var sendErr error
+		if !strings.EqualFold(status.Code, "Ok") {
+			sendErr = fmt.Errorf("tencent SMS error %s: %s", status.Code, status.Message)
+		}
+		results = append(results, DeliveryResult{Recipient: phone, Err: sendErr})
+	}
+	for _, phone := range phones {
+		if !reported[phone] {
+			results = append(results, DeliveryResult{Recipient: phone, Err: errors.New("no send status returned")})
+		}
+	}
+	return results
+}
+
+// signTencentRequest 计算 TC3-HMAC-SHA256 签名并返回 Authorization 头，service 为云产品名，如 sms
+func signTencentRequest(secretID, secretKey, service, host, contentType string, payload []byte, timestamp int64) string {
+	const algorithm = "TC3-HMAC-SHA256"
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+
+	signedHeaders := "content-type;host"
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		"content-type:" + contentType + "\nhost:" + host + "\n",
+		signedHeaders,
+		sha256Hex(payload),
+	}, "\n")
+
+	credentialScope := date + "/" + service + "/tc3_request"
+	stringToSign := strings.Join([]string{
+		algorithm,
+		strconv.FormatInt(timestamp, 10),
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+secretKey), date)
+	secretService := hmacSHA256(secretDate, service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, secretID, credentialScope, signedHeaders, signature)
+}
+
+// sha256Hex 计算SHA256并返回十六进制字符串
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TejParker/bigdata-manager/internal/config"
+)
+
+// 腾讯云 API 签名 v3 文档中的示例请求和签名
+func TestSignTencentRequest(t *testing.T) {
+	payload := []byte("{\"Limit\": 1, \"Filters\": [{\"Values\": [\"\\u672a\\u547d\\u540d\"], \"Name\": \"instance-name\"}]}")
+	got := signTencentRequest(
+		"AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE", "Gu5t9xGARNpq86cd98joQYCN3EXAMPLE",
+		"cvm", "cvm.tencentcloudapi.com", "application/json; charset=utf-8", payload, 1551113065,
+	)
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE/2019-02-25/cvm/tc3_request, " +
+		"SignedHeaders=content-type;host, Signature=72e494ea809ad7a8c8f7a4507b9bddcbaa8e581f516e8da2f66e2c5a96525168"
+	if got != want {
+		t.Errorf("signTencentRequest() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// tencentRequest 腾讯云接口收到的请求
+type tencentRequest struct {
+	header  http.Header
+	payload map[string]interface{}
+}
+
+// tencentStub 模拟腾讯云 SendSms 接口，签名校验失败时返回 AuthFailure.SignatureFailure
+type tencentStub struct {
+	server    *httptest.Server
+	secretKey string
+	response  string
+
+	mu       sync.Mutex
+	received []tencentRequest
+}
+
+func newTencentStub(t *testing.T, secretKey, response string) *tencentStub {
+	t.Helper()
+	stub := &tencentStub{secretKey: secretKey, response: response}
+	stub.server = httptest.NewServer(http.HandlerFunc(stub.handle))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func (s *tencentStub) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	request := tencentRequest{header: r.Header.Clone()}
+	json.Unmarshal(body, &request.payload)
+	s.mu.Lock()
+	s.received = append(s.received, request)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	timestamp, _ := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+	secretID := strings.TrimPrefix(strings.SplitN(r.Header.Get("Authorization"), "/", 2)[0], "TC3-HMAC-SHA256 Credential=")
+	want := signTencentRequest(secretID, s.secretKey, tencentSMSService, r.Host, r.Header.Get("Content-Type"), body, timestamp)
+	if r.Method != http.MethodPost || r.Header.Get("Authorization") != want {
+		w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"The provided credentials could not be validated."},"RequestId":"bad-sign"}}`))
+		return
+	}
+	w.Write([]byte(s.response))
+}
+
+// requests 收到的请求
+func (s *tencentStub) requests() []tencentRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]tencentRequest(nil), s.received...)
+}
+
+func newTestTencentProvider(t *testing.T, endpoint string) *TencentSMSProvider {
+	t.Helper()
+	provider, err := NewTencentSMSProvider(config.SMSConfig{
+		Provider:       "tencent",
+		APIKey:         "test-secret-id",
+		Secret:         "test-secret-key",
+		Endpoint:       endpoint,
+		SDKAppID:       "1400000000",
+		SignName:       "大数据平台",
+		TemplateCode:   "123456",
+		TemplateParams: []string{"alert_name", "value"},
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestTencentSMSProviderSend(t *testing.T) {
+	stub := newTencentStub(t, "test-secret-key", `{"Response":{"SendStatusSet":[
+		{"SerialNo":"1","PhoneNumber":"+8613800000000","Fee":1,"Code":"Ok","Message":"send success"},
+		{"SerialNo":"","PhoneNumber":"+14155550100","Fee":0,"Code":"LimitExceeded.PhoneNumberDailyLimit","Message":"the number of sms messages sent from a single mobile number every day exceeds the upper limit"}
+	],"RequestId":"req-1"}}`)
+	provider := newTestTencentProvider(t, stub.server.URL+"/")
+	phones := []string{"13800000000", "+14155550100", "13900000000"}
+
+	results := provider.Send(context.Background(), phones, &SMSMessage{
+		Content: "磁盘使用率过高",
+		Params:  map[string]string{"alert_name": "磁盘使用率", "value": "95.5", "hostname": "node-1"},
+	})
+
+	// 按号码映射发送状态，未带国家码的号码对应响应中的 +86 号码，响应中缺少的号码发送失败
+	if len(results) != len(phones) {
+		t.Fatalf("results = %+v, want one per phone", results)
+	}
+	if results[0].Recipient != "13800000000" || results[0].Err != nil {
+		t.Errorf("results[0] = %+v, want success for 13800000000", results[0])
+	}
+	if results[1].Recipient != "+14155550100" || results[1].Err == nil ||
+		!strings.Contains(results[1].Err.Error(), "LimitExceeded.PhoneNumberDailyLimit") {
+		t.Errorf("results[1] = %+v, want LimitExceeded error for +14155550100", results[1])
+	}
+	if results[2].Recipient != "13900000000" || results[2].Err == nil ||
+		!strings.Contains(results[2].Err.Error(), "no send status") {
+		t.Errorf("results[2] = %+v, want missing status error for 13900000000", results[2])
+	}
+
+	requests := stub.requests()
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	for name, want := range map[string]string{
+		"X-TC-Action":  "SendSms",
+		"X-TC-Version": tencentSMSVersion,
+		"X-TC-Region":  tencentSMSRegion,
+	} {
+		if got := requests[0].header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	payload := requests[0].payload
+	if want := []interface{}{"+8613800000000", "+14155550100", "+8613900000000"}; !reflect.DeepEqual(payload["PhoneNumberSet"], want) {
+		t.Errorf("PhoneNumberSet = %v, want %v", payload["PhoneNumberSet"], want)
+	}
+	if want := []interface{}{"磁盘使用率", "95.5"}; !reflect.DeepEqual(payload["TemplateParamSet"], want) {
+		t.Errorf("TemplateParamSet = %v, want %v", payload["TemplateParamSet"], want)
+	}
+	if payload["SmsSdkAppId"] != "1400000000" || payload["SignName"] != "大数据平台" || payload["TemplateId"] != "123456" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestTencentSMSProviderSendErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		secretKey string // 接口使用的密钥，与客户端不同时签名校验失败
+		response  string
+		wantErr   []string
+	}{
+		{
+			name:      "api error",
+			secretKey: "test-secret-key",
+			response:  `{"Response":{"Error":{"Code":"FailedOperation.InsufficientBalanceInSmsPackage","Message":"套餐包余量不足"},"RequestId":"req-2"}}`,
+			wantErr:   []string{"FailedOperation.InsufficientBalanceInSmsPackage", "套餐包余量不足", "req-2"},
+		},
+		{
+			name:      "signature mismatch",
+			secretKey: "other-secret-key",
+			wantErr:   []string{"AuthFailure.SignatureFailure", "bad-sign"},
+		},
+		{
+			name:      "invalid response",
+			secretKey: "test-secret-key",
+			response:  "<html>bad gateway</html>",
+			wantErr:   []string{"decode tencent response"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newTencentStub(t, tt.secretKey, tt.response)
+			provider := newTestTencentProvider(t, stub.server.URL+"/")
+			phones := []string{"13800000000", "13900000000"}
+
+			results := provider.Send(context.Background(), phones, &SMSMessage{Content: "test"})
+			if len(results) != len(phones) {
+				t.Fatalf("results = %+v, want one per phone", results)
+			}
+			for i, result := range results {
+				if result.Recipient != phones[i] || result.Err == nil {
+					t.Fatalf("results[%d] = %+v, want error for %s", i, result, phones[i])
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(result.Err.Error(), want) {
+						t.Errorf("results[%d] error = %v, want it to contain %q", i, result.Err, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/TejParker/bigdata-manager/internal/auth"
+	"github.com/TejParker/bigdata-manager/internal/model"
+	"github.com/TejParker/bigdata-manager/internal/repository"
+)
+
+// PrivilegeManageUser 用户管理权限，修改用户和角色后至少保留一个拥有该权限的启用用户
+const PrivilegeManageUser = "MANAGE_USER"
+
+// minPasswordLength 密码最小长度，与修改密码接口一致
+const minPasswordLength = 6
+
+// 用户管理错误
+var (
+	ErrUsernameExists    = errors.New("username already exists")
+	ErrRoleNameExists    = errors.New("role name already exists")
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrPrivilegeNotFound = errors.New("privilege not found")
+	ErrPasswordTooShort  = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	ErrLastUserManager   = errors.New("at least one active user must keep the " + PrivilegeManageUser + " privilege")
+	ErrCannotModifySelf  = errors.New("cannot disable or delete the current user")
+	ErrInvalidUserStatus = errors.New("invalid user status")
+	ErrUsernameRequired  = errors.New("username is required")
+	ErrRoleNameRequired  = errors.New("role name is required")
+	ErrExternalUser      = errors.New("password of an externally authenticated user cannot be changed")
+
+	ErrClusterNotFound     = errors.New("cluster not found")
+	ErrServiceNotInCluster = errors.New("service not found in cluster")
+	ErrRoleBindingExists   = errors.New("role binding already exists")
+)
+
+// UserService 用户、角色和权限管理服务
+type UserService struct {
+	repo *repository.UserRepository
+}
+
+// NewUserService 创建用户管理服务
+func NewUserService(db *gorm.DB) *UserService {
+	return &UserService{repo: repository.NewUserRepository(db)}
+}
+
+// guard 在事务中执行修改，修改后没有拥有用户管理权限的启用用户时回滚并返回 ErrLastUserManager，
+// 服务账号不能登录，不计入其中
+func (s *UserService) guard(fn func(tx *repository.UserRepository) error) error {
+	return s.repo.Transaction(func(tx *repository.UserRepository) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		count, err := tx.CountActiveUsersWithPrivilege(PrivilegeManageUser)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrLastUserManager
+		}
+		return nil
+	})
+}
+
+// uniqueIDs 去除重复和无效的ID
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	result := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// checkRoles 检查角色都存在
+func checkRoles(repo *repository.UserRepository, roleIDs []int) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+	count, err := repo.CountRoles(roleIDs)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(roleIDs)) {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// ListUsers 列出用户
+func (s *UserService) ListUsers(page, pageSize int, filters map[string]interface{}) ([]*model.User, int64, error) {
+	return s.repo.ListUsers(page, pageSize, filters)
+}
+
+// GetUser 获取用户
+func (s *UserService) GetUser(id int) (*model.User, error) {
+	return s.repo.GetUser(id)
+}
+
+// CreateUser 创建用户并分配角色，未指定状态时为启用
+func (s *UserService) CreateUser(user *model.User, password string, roleIDs []int) error {
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username == "" {
+		return ErrUsernameRequired
+	}
+	if len(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if user.Status == "" {
+		user.Status = model.UserStatusActive
+	}
+	if user.Status != model.UserStatusActive && user.Status != model.UserStatusDisabled {
+		return ErrInvalidUserStatus
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	user.AuthSource = model.UserAuthSourceLocal
+	roleIDs = uniqueIDs(roleIDs)
+
+	return s.repo.Transaction(func(tx *repository.UserRepository) error {
+		exists, err := tx.UsernameExists(user.Username, 0)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUsernameExists
+		}
+		if err := checkRoles(tx, roleIDs); err != nil {
+			return err
+		}
+		if err := tx.CreateUser(user); err != nil {
+			return err
+		}
+		return tx.SetUserRoles(user.ID, roleIDs)
+	})
+}
+
+// CreateServiceAccount 创建服务账号并分配角色，服务账号没有密码，不能登录，只能通过API令牌访问接口
+func (s *UserService) CreateServiceAccount(user *model.User, roleIDs []int) error {
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username == "" {
+		return ErrUsernameRequired
+	}
+	user.Status = model.UserStatusActive
+	user.PasswordHash = ""
+	user.AuthSource = model.UserAuthSourceService
+	roleIDs = uniqueIDs(roleIDs)
+
+	return s.repo.Transaction(func(tx *repository.UserRepository) error {
+		exists, err := tx.UsernameExists(user.Username, 0)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUsernameExists
+		}
+		if err := checkRoles(tx, roleIDs); err != nil {
+			return err
+		}
+		if err := tx.CreateUser(user); err != nil {
+			return err
+		}
+		return tx.SetUserRoles(user.ID, roleIDs)
+	})
+}
+
+// UpdateUser 更新用户的邮箱和手机号，用户名不能修改
+func (s *UserService) UpdateUser(user *model.User) error {
+	return s.repo.UpdateUser(user)
+}
+
+// SetUserStatus 启用或禁用用户，禁用时吊销用户的全部登录会话；不能禁用当前用户
+func (s *UserService) SetUserStatus(id int, status model.UserStatus, operatorID int) error {
+	if status != model.UserStatusActive && status != model.UserStatusDisabled {
+		return ErrInvalidUserStatus
+	}
+	if status == model.UserStatusDisabled && id == operatorID {
+		return ErrCannotModifySelf
+	}
+	return s.guard(func(tx *repository.UserRepository) error {
+		if err := tx.UpdateStatus(id, status); err != nil {
+			return err
+		}
+		// 禁用的用户重新启用后也需要重新登录
+		if status == model.UserStatusDisabled {
+			return tx.RevokeSessions(id)
+		}
+		return nil
+	})
+}
+
+// ResetPassword 管理员重置用户密码，不需要原密码，并吊销用户的全部登录会话；LDAP 等外部认证的用户没有本地密码
+func (s *UserService) ResetPassword(id int, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	user, err := s.repo.GetUser(id)
+	if err != nil {
+		return err
+	}
+	if user.AuthSource != model.UserAuthSourceLocal {
+		return ErrExternalUser
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(tx *repository.UserRepository) error {
+		if err := tx.UpdatePassword(id, hash); err != nil {
+			return err
+		}
+		return tx.RevokeSessions(id)
+	})
+}
+
+// DeleteUser 删除用户，不能删除当前用户
+func (s *UserService) DeleteUser(id int, operatorID int) error {
+	if id == operatorID {
+		return ErrCannotModifySelf
+	}
+	return s.guard(func(tx *repository.UserRepository) error {
+		return tx.DeleteUser(id)
+	})
+}
+
+// SetUserRoles 将用户的角色替换为 roleIDs
+func (s *UserService) SetUserRoles(userID int, roleIDs []int) error {
+	roleIDs = uniqueIDs(roleIDs)
+	return s.guard(func(tx *repository.UserRepository) error {
+		if err := checkRoles(tx, roleIDs); err != nil {
+			return err
+		}
+		return tx.SetUserRoles(userID, roleIDs)
+	})
+}
+
+// ListSessions 列出用户有效的登录会话
+func (s *UserService) ListSessions(userID int) ([]*model.UserSession, error) {
+	return s.repo.ListSessions(userID)
+}
+
+// GetSession 获取登录会话
+func (s *UserService) GetSession(id uint) (*model.UserSession, error) {
+	return s.repo.GetSession(id)
+}
+
+// RevokeSessions 吊销用户的全部登录会话，用户需要重新登录
+func (s *UserService) RevokeSessions(userID int) error {
+	return s.repo.RevokeSessions(userID)
+}
+
+// ListRoles 列出角色
+func (s *UserService) ListRoles() ([]*model.Role, error) {
+	return s.repo.ListRoles()
+}
+
+// GetRole 获取角色
+func (s *UserService) GetRole(id int) (*model.Role, error) {
+	return s.repo.GetRole(id)
+}
+
+// checkPrivileges 检查权限都存在
+func checkPrivileges(repo *repository.UserRepository, privilegeIDs []int) error {
+	if len(privilegeIDs) == 0 {
+		return nil
+	}
+	count, err := repo.CountPrivileges(privilegeIDs)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(privilegeIDs)) {
+		return ErrPrivilegeNotFound
+	}
+	return nil
+}
+
+// checkRoleName 检查角色名非空且未被其他角色使用
+func checkRoleName(repo *repository.UserRepository, role *model.Role) error {
+	role.Name = strings.TrimSpace(role.Name)
+	if role.Name == "" {
+		return ErrRoleNameRequired
+	}
+	exists, err := repo.RoleNameExists(role.Name, role.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrRoleNameExists
+	}
+	return nil
+}
+
+// CreateRole 创建角色并分配权限
+func (s *UserService) CreateRole(role *model.Role, privilegeIDs []int) error {
+	privilegeIDs = uniqueIDs(privilegeIDs)
+	return s.repo.Transaction(func(tx *repository.UserRepository) error {
+		if err := checkRoleName(tx, role); err != nil {
+			return err
+		}
+		if err := checkPrivileges(tx, privilegeIDs); err != nil {
+			return err
+		}
+		if err := tx.CreateRole(role); err != nil {
+			return err
+		}
+		return tx.SetRolePrivileges(role.ID, privilegeIDs)
+	})
+}
+
+// UpdateRole 更新角色名称和描述
+func (s *UserService) UpdateRole(role *model.Role) error {
+	return s.repo.Transaction(func(tx *repository.UserRepository) error {
+		if err := checkRoleName(tx, role); err != nil {
+			return err
+		}
+		return tx.UpdateRole(role)
+	})
+}
+
+// DeleteRole 删除角色，拥有该角色的用户失去角色对应的权限
+func (s *UserService) DeleteRole(id int) error {
+	return s.guard(func(tx *repository.UserRepository) error {
+		return tx.DeleteRole(id)
+	})
+}
+
+// SetRolePrivileges 将角色的权限替换为 privilegeIDs
+func (s *UserService) SetRolePrivileges(roleID int, privilegeIDs []int) error {
+	privilegeIDs = uniqueIDs(privilegeIDs)
+	return s.guard(func(tx *repository.UserRepository) error {
+		if err := checkPrivileges(tx, privilegeIDs); err != nil {
+			return err
+		}
+		return tx.SetRolePrivileges(roleID, privilegeIDs)
+	})
+}
+
+// ListPrivileges 列出全部权限
+func (s *UserService) ListPrivileges() ([]*model.Privilege, error) {
+	return s.repo.ListPrivileges()
+}
+
+// ListRoleBindings 列出用户在集群和服务范围内的角色绑定
+func (s *UserService) ListRoleBindings(userID int) ([]*model.RoleBinding, error) {
+	return s.repo.ListRoleBindings(userID)
+}
+
+// GetRoleBinding 获取角色绑定
+func (s *UserService) GetRoleBinding(id int) (*model.RoleBinding, error) {
+	return s.repo.GetRoleBinding(id)
+}
+
+// CreateRoleBinding 在集群或集群中的服务范围内为用户绑定角色，同一范围不能重复绑定
+func (s *UserService) CreateRoleBinding(binding *model.RoleBinding) error {
+	if binding.ServiceID != nil && *binding.ServiceID <= 0 {
+		binding.ServiceID = nil
+	}
+	return s.repo.Transaction(func(tx *repository.UserRepository) error {
+		if err := checkRoles(tx, []int{binding.RoleID}); err != nil {
+			return err
+		}
+		exists, err := tx.ClusterExists(binding.ClusterID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrClusterNotFound
+		}
+		if binding.ServiceID != nil {
+			clusterID, err := tx.ServiceClusterID(*binding.ServiceID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err != nil || clusterID != binding.ClusterID {
+				return ErrServiceNotInCluster
+			}
+		}
+		exists, err = tx.RoleBindingExists(binding)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrRoleBindingExists
+		}
+		return tx.CreateRoleBinding(binding)
+	})
+}
+
+// DeleteRoleBinding 删除角色绑定
+func (s *UserService) DeleteRoleBinding(id int) error {
+	return s.repo.DeleteRoleBinding(id)
+}
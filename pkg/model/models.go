@@ -129,6 +129,8 @@ type LogRecord struct {
 	ServiceID   int       `json:"service_id"`
 	ComponentID int       `json:"component_id"`
 	LogLevel    string    `json:"log_level"`
+	Logger      string    `json:"logger,omitempty"`
+	Thread      string    `json:"thread,omitempty"`
 	Timestamp   time.Time `json:"timestamp"`
 	Message     string    `json:"message"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -232,8 +234,15 @@ type LoginRequest struct {
 
 // 登录响应模型
 type LoginResponse struct {
-	Token  string `json:"token"`
-	UserID int    `json:"user_id"`
+	Token        string `json:"token"`         // 访问令牌
+	RefreshToken string `json:"refresh_token"` // 刷新令牌，每次刷新后更换
+	ExpiresIn    int    `json:"expires_in"`    // 访问令牌有效期，单位秒
+	UserID       int    `json:"user_id"`
+}
+
+// 刷新令牌请求模型
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // Agent心跳请求模型
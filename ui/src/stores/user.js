@@ -1,9 +1,13 @@
 import { defineStore } from 'pinia';
 import axios from 'axios';
 
+// 正在进行的刷新请求，多个请求同时收到401时共用一次刷新
+let refreshing = null;
+
 export const useUserStore = defineStore('user', {
   state: () => ({
     token: localStorage.getItem('token') || '',
+    refreshToken: localStorage.getItem('refreshToken') || '',
     userInfo: JSON.parse(localStorage.getItem('userInfo') || 'null'),
   }),
   
@@ -24,9 +28,8 @@ export const useUserStore = defineStore('user', {
         });
         
         if (response.data.success) {
-          const { token, user_id } = response.data.data;
-          this.token = token;
-          localStorage.setItem('token', token);
+          const { token, refresh_token } = response.data.data;
+          this.setTokens(token, refresh_token);
           
           // 获取用户信息
           await this.fetchUserInfo();
@@ -58,11 +61,69 @@ export const useUserStore = defineStore('user', {
       }
     },
     
-    logout() {
+    setTokens(token, refreshToken) {
+      this.token = token;
+      this.refreshToken = refreshToken;
+      localStorage.setItem('token', token);
+      localStorage.setItem('refreshToken', refreshToken);
+    },
+    
+    // 使用刷新令牌获取新的访问令牌，刷新令牌同时更换；失败时清除登录状态
+    refresh() {
+      if (!refreshing) {
+        const refreshToken = this.refreshToken;
+        refreshing = (async () => {
+          if (!refreshToken) {
+            throw new Error('没有刷新令牌');
+          }
+          try {
+            const response = await axios.post('/api/v1/auth/refresh', { refresh_token: refreshToken });
+            const { token, refresh_token } = response.data.data;
+            this.setTokens(token, refresh_token);
+            return token;
+          } catch (error) {
+            this.clearSession();
+            throw error;
+          }
+        })().finally(() => {
+          refreshing = null;
+        });
+      }
+      return refreshing;
+    },
+    
+    // 退出登录，吊销服务端会话后清除本地登录状态
+    async logout() {
+      const revoke = () => axios.post('/api/v1/logout', null, {
+        headers: { Authorization: `Bearer ${this.token}` }
+      });
+      if (this.token) {
+        try {
+          await revoke();
+        } catch (error) {
+          // 访问令牌已过期时先刷新，否则会话在服务端仍然有效
+          if (error.response && error.response.status === 401 && this.refreshToken) {
+            try {
+              await this.refresh();
+              await revoke();
+            } catch (retryError) {
+              console.error('退出登录失败', retryError);
+            }
+          } else {
+            console.error('退出登录失败', error);
+          }
+        }
+      }
+      this.clearSession();
+    },
+    
+    clearSession() {
       this.token = '';
+      this.refreshToken = '';
       this.userInfo = null;
       localStorage.removeItem('token');
+      localStorage.removeItem('refreshToken');
       localStorage.removeItem('userInfo');
     }
   }
-}); 
\ No newline at end of file
+});
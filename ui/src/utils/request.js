@@ -1,6 +1,7 @@
 import axios from 'axios';
 import { Message } from '@arco-design/web-vue';
 import router from '@/router';
+import { useUserStore } from '@/stores/user';
 
 const request = axios.create({
   baseURL: '/api/v1',
@@ -27,17 +28,29 @@ request.interceptors.response.use(
     // 对于业务错误，直接返回完整响应，在业务代码中处理
     return response;
   },
-  (error) => {
+  async (error) => {
     if (error.response) {
       const { status, data } = error.response;
+      const userStore = useUserStore();
+      
+      // 访问令牌过期时使用刷新令牌换取新令牌，并重试原请求一次
+      if (status === 401 && !error.config._retried && userStore.refreshToken) {
+        error.config._retried = true;
+        try {
+          const token = await userStore.refresh();
+          error.config.headers.Authorization = `Bearer ${token}`;
+          return request(error.config);
+        } catch (refreshError) {
+          // 刷新失败，按未认证处理
+        }
+      }
       
       switch (status) {
         case 401:
           // 未认证，重定向到登录页
           if (router.currentRoute.value.name !== 'Login') {
             Message.error('登录已过期，请重新登录');
-            localStorage.removeItem('token');
-            localStorage.removeItem('userInfo');
+            userStore.clearSession();
             router.push({
               name: 'Login',
               query: { redirect: router.currentRoute.value.fullPath },